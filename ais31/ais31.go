@@ -0,0 +1,540 @@
+// Package ais31 implements the statistical test procedures A and B of BSI AIS 20/31 (2011): the disjointness,
+// monobit, poker, runs, long run, and autocorrelation tests T0 to T5, and the uniform distribution, homogeneity, and
+// entropy tests T6 to T8.
+//
+// Procedure A checks that output is indistinguishable from ideal random bits; it is meant for whitened output.
+// Procedure B checks that raw output has the entropy and the lack of dependence between bits its stochastic model
+// assumes, which raw Infinite Noise output does not: its bits are biased and correlated by design, so only its
+// whitened output is expected to pass, and XOR-folded output depending on the fold factor. Like the sts package,
+// the tests are evidence for an evaluation, not a certification.
+package ais31
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// BytesA is the input one run of procedure A consumes: 2^16 48-bit words for T0 and 257 sequences of 20000
+	// bits for T1 to T5. Procedure A needs twice as much to repeat itself after a single failed test.
+	BytesA = 1<<16*48/8 + 257*seqBits/8
+
+	// seqBits is the length of the sequences T1 to T5 are applied to.
+	seqBits = 20000
+
+	// tuples is the number of tuples per prefix T7 compares, and t6Bits the input of T6.
+	tuples = 100000
+	t6Bits = 100000
+
+	// t8L, t8Q, and t8K are the word length, initialization words, and test words of T8.
+	t8L = 8
+	t8Q = 2560
+	t8K = 256000
+)
+
+// Result is the outcome of one basic test of a procedure. Tests applied more than once, such as T1 in procedure A,
+// report how many of their runs failed.
+type Result struct {
+	Name     string `json:"name"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+	Pass     bool   `json:"pass"`
+
+	// Value is the test statistic of T6 (the proportion of ones), T7 (the largest of its chi-squared values), and
+	// T8 (the entropy per 8-bit word); zero for the others.
+	Value float64 `json:"value,omitempty"`
+}
+
+// Report is the outcome of a procedure. A procedure with exactly one failed basic test is repeated once on the
+// following input, as AIS 31 prescribes, and passes if the repetition passes entirely; Tests then holds the
+// repetition's results.
+type Report struct {
+	Procedure string   `json:"procedure"`
+	Bytes     int      `json:"bytes"`
+	Repeated  bool     `json:"repeated"`
+	Pass      bool     `json:"pass"`
+	Tests     []Result `json:"tests"`
+}
+
+// Run applies procedure A to the start of data and procedure B to the rest.
+func Run(data []byte) ([]Report, error) {
+	a, err := ProcedureA(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ProcedureB(data[a.Bytes:])
+	if err != nil {
+		return nil, err
+	}
+
+	return []Report{a, b}, nil
+}
+
+// ProcedureA applies T0 once and T1 to T5 to each of 257 sequences of 20000 bits, reading data most significant
+// bit first. It needs BytesA of input, or twice that if it has to repeat itself; it fails if the repetition does
+// not fit.
+func ProcedureA(data []byte) (Report, error) {
+	if len(data) < BytesA {
+		return Report{}, fmt.Errorf("input too short for procedure A: %d bytes (need at least %d)", len(data), BytesA)
+	}
+
+	return repeat("A", data, func(data []byte) ([]Result, int, error) {
+		if len(data) < BytesA {
+			return nil, 0, errShort
+		}
+
+		return procedureA(data), BytesA, nil
+	})
+}
+
+// ProcedureB applies T6, T7 to tuples of 2, 3, and 4 bits, and T8, each to fresh input, reading data most
+// significant bit first. How much input it needs depends on the bias of data: about 900 KB for unbiased input, and
+// twice that if it has to repeat itself.
+func ProcedureB(data []byte) (Report, error) {
+	report, err := repeat("B", data, procedureB)
+	if err != nil {
+		return Report{}, fmt.Errorf("input too short for procedure B: %d bytes", len(data))
+	}
+
+	return report, nil
+}
+
+// errShort reports that a run of a procedure ran out of input.
+var errShort = errors.New("input too short")
+
+// repeat runs a procedure and, after exactly one failed basic test, runs it again on the following input.
+func repeat(name string, data []byte, run func([]byte) ([]Result, int, error)) (Report, error) {
+	results, used, err := run(data)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Procedure: name, Bytes: used, Tests: results, Pass: failures(results) == 0}
+
+	if failures(results) != 1 {
+		return report, nil
+	}
+
+	report.Repeated = true
+
+	results, n, err := run(data[used:])
+	if err != nil {
+		// Without input for the repetition, the single failure stands.
+		return report, nil
+	}
+
+	report.Bytes += n
+	report.Tests = results
+	report.Pass = failures(results) == 0
+
+	return report, nil
+}
+
+// failures counts the failed runs of the basic tests.
+func failures(results []Result) int {
+	var n int
+
+	for _, r := range results {
+		n += r.Failures
+	}
+
+	return n
+}
+
+// tally accumulates the runs of one basic test.
+func (r *Result) tally(pass bool) {
+	r.Runs++
+
+	if !pass {
+		r.Failures++
+	}
+
+	r.Pass = r.Failures == 0
+}
+
+// bitsOf unpacks data into one bit per byte, most significant first.
+func bitsOf(data []byte) []byte {
+	seq := make([]byte, 8*len(data))
+
+	for i := range seq {
+		seq[i] = data[i/8] >> (7 - i%8) & 1
+	}
+
+	return seq
+}
+
+func procedureA(data []byte) []Result {
+	const t0Bytes = 1 << 16 * 48 / 8
+
+	results := []Result{
+		{Name: "T0 disjointness"},
+		{Name: "T1 monobit"},
+		{Name: "T2 poker"},
+		{Name: "T3 runs"},
+		{Name: "T4 long run"},
+		{Name: "T5 autocorrelation"},
+	}
+
+	results[0].tally(disjointness(data[:t0Bytes]))
+
+	for i := range 257 {
+		off := t0Bytes + i*seqBits/8
+		seq := bitsOf(data[off : off+seqBits/8])
+
+		results[1].tally(monobit(seq))
+		results[2].tally(poker(seq))
+		results[3].tally(runs(seq))
+		results[4].tally(longRun(seq))
+		results[5].tally(autocorrelation(seq))
+	}
+
+	return results
+}
+
+// disjointness is T0: the 48-bit words of data must all differ.
+func disjointness(data []byte) bool {
+	words := make([]uint64, len(data)/6)
+
+	for i := range words {
+		for _, b := range data[6*i : 6*i+6] {
+			words[i] = words[i]<<8 | uint64(b)
+		}
+	}
+
+	slices.Sort(words)
+
+	for i := 1; i < len(words); i++ {
+		if words[i] == words[i-1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// monobit is T1: the ones in 20000 bits must number between 9655 and 10345.
+func monobit(seq []byte) bool {
+	var ones int
+
+	for _, b := range seq {
+		ones += int(b)
+	}
+
+	return ones > 9654 && ones < 10346
+}
+
+// poker is T2: the chi-squared statistic of the 5000 4-bit words of 20000 bits must lie between 1.03 and 57.4.
+func poker(seq []byte) bool {
+	var counts [16]float64
+
+	for i := 0; i+4 <= len(seq); i += 4 {
+		counts[seq[i]<<3|seq[i+1]<<2|seq[i+2]<<1|seq[i+3]]++
+	}
+
+	var sum float64
+
+	for _, c := range counts {
+		sum += c * c
+	}
+
+	x := 16*sum/5000 - 5000
+
+	return x > 1.03 && x < 57.4
+}
+
+// runBounds are the inclusive bounds T3 places on the runs of 1 to 5 and of 6 or more zeros, and of ones.
+var runBounds = [6][2]int{{2267, 2733}, {1079, 1421}, {502, 748}, {223, 402}, {90, 223}, {90, 223}}
+
+// runLengths calls fn with the bit and length of each run in seq.
+func runLengths(seq []byte, fn func(bit byte, length int)) {
+	start := 0
+
+	for i := 1; i <= len(seq); i++ {
+		if i == len(seq) || seq[i] != seq[start] {
+			fn(seq[start], i-start)
+
+			start = i
+		}
+	}
+}
+
+// runs is T3: the runs of each length, counted separately for zeros and ones, must lie within runBounds.
+func runs(seq []byte) bool {
+	var counts [2][6]int
+
+	runLengths(seq, func(bit byte, length int) {
+		counts[bit][min(length, 6)-1]++
+	})
+
+	for _, c := range counts {
+		for i, n := range c {
+			if n < runBounds[i][0] || n > runBounds[i][1] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// longRun is T4: no run may be 34 bits or longer.
+func longRun(seq []byte) bool {
+	pass := true
+
+	runLengths(seq, func(_ byte, length int) {
+		pass = pass && length < 34
+	})
+
+	return pass
+}
+
+// autocorrelation is T5: the shift whose autocorrelation over the first 10000 bits deviates most from 2500 is
+// tested on the second 10000 bits, where it must lie between 2327 and 2673.
+func autocorrelation(seq []byte) bool {
+	w := packBits(seq)
+
+	tau, worst := 1, -1
+
+	for t := 1; t <= 5000; t++ {
+		if d := abs(xorCount(w, 0, t, 5000) - 2500); d > worst {
+			tau, worst = t, d
+		}
+	}
+
+	z := xorCount(w, 10000, 10000+tau, 5000)
+
+	return z > 2326 && z < 2674
+}
+
+func abs(x int) int {
+	return max(x, -x)
+}
+
+// packBits packs a sequence of bits into words, most significant first, with a zero word of padding.
+func packBits(seq []byte) []uint64 {
+	w := make([]uint64, len(seq)/64+2)
+
+	for i, b := range seq {
+		w[i/64] |= uint64(b) << (63 - i%64)
+	}
+
+	return w
+}
+
+// window returns the 64 bits of w starting at bit off.
+func window(w []uint64, off int) uint64 {
+	i, s := off/64, uint(off%64)
+
+	if s == 0 {
+		return w[i]
+	}
+
+	return w[i]<<s | w[i+1]>>(64-s)
+}
+
+// xorCount counts the positions j < n at which bits a+j and b+j of w differ.
+func xorCount(w []uint64, a, b, n int) int {
+	var count int
+
+	for j := 0; j < n; j += 64 {
+		x := window(w, a+j) ^ window(w, b+j)
+
+		if n-j < 64 {
+			x &= ^uint64(0) << (64 - (n - j))
+		}
+
+		count += bits.OnesCount64(x)
+	}
+
+	return count
+}
+
+// bitStream hands out the bits of data in order.
+type bitStream struct {
+	data []byte
+	pos  int
+}
+
+func (s *bitStream) next() (byte, bool) {
+	if s.pos >= 8*len(s.data) {
+		return 0, false
+	}
+
+	b := s.data[s.pos/8] >> (7 - s.pos%8) & 1
+
+	s.pos++
+
+	return b, true
+}
+
+func procedureB(data []byte) ([]Result, int, error) {
+	s := &bitStream{data: data}
+
+	t6 := Result{Name: "T6 uniform distribution"}
+
+	ones, ok := uniformity(s)
+	if !ok {
+		return nil, 0, errShort
+	}
+
+	t6.Value = ones
+	t6.tally(math.Abs(ones-0.5) < 0.025)
+
+	results := []Result{t6}
+
+	for h := 2; h <= 4; h++ {
+		r := Result{Name: fmt.Sprintf("T7 homogeneity (%d-bit tuples)", h)}
+
+		stats, ok := homogeneity(s, h)
+		if !ok {
+			return nil, 0, errShort
+		}
+
+		for _, x := range stats {
+			r.Value = max(r.Value, x)
+			r.tally(x < 15.13)
+		}
+
+		results = append(results, r)
+	}
+
+	t8 := Result{Name: "T8 entropy"}
+
+	f, ok := entropy(s)
+	if !ok {
+		return nil, 0, errShort
+	}
+
+	t8.Value = f
+	t8.tally(f > 7.976)
+
+	results = append(results, t8)
+
+	// Whole bytes are consumed; the rest of the last one is skipped.
+	return results, (s.pos + 7) / 8, nil
+}
+
+// uniformity is T6 for single bits: the proportion of ones in the next 100000 bits, which must lie within 0.025 of
+// one half.
+func uniformity(s *bitStream) (float64, bool) {
+	var ones int
+
+	for range t6Bits {
+		b, ok := s.next()
+		if !ok {
+			return 0, false
+		}
+
+		ones += int(b)
+	}
+
+	return float64(ones) / t6Bits, true
+}
+
+// homogeneity is T7 for tuples of h bits: it reads disjoint tuples until each (h-1)-bit prefix has occurred 100000
+// times, then compares the distribution of the last bit between the prefixes differing only in their first bit.
+// Each comparison is a chi-squared statistic with one degree of freedom, which must stay below 15.13.
+func homogeneity(s *bitStream, h int) ([]float64, bool) {
+	prefixes := 1 << (h - 1)
+
+	seen := make([]int, prefixes)
+	ones := make([]int, prefixes)
+
+	for done := 0; done < prefixes; {
+		var tuple int
+
+		for range h {
+			b, ok := s.next()
+			if !ok {
+				return nil, false
+			}
+
+			tuple = tuple<<1 | int(b)
+		}
+
+		p := tuple >> 1
+
+		if seen[p] == tuples {
+			continue
+		}
+
+		seen[p]++
+		ones[p] += tuple & 1
+
+		if seen[p] == tuples {
+			done++
+		}
+	}
+
+	// Prefix p and p with its first bit set.
+	half := prefixes / 2
+
+	stats := make([]float64, half)
+
+	for p := range half {
+		stats[p] = chi2(ones[p], ones[p+half])
+	}
+
+	return stats, true
+}
+
+// chi2 is the homogeneity statistic of two samples of 100000 bits with a and b ones.
+func chi2(a, b int) float64 {
+	var x float64
+
+	for _, counts := range [][2]int{{a, b}, {tuples - a, tuples - b}} {
+		e := float64(counts[0]+counts[1]) / 2
+
+		if e == 0 {
+			continue
+		}
+
+		for _, c := range counts {
+			d := float64(c) - e
+
+			x += d * d / e
+		}
+	}
+
+	return x
+}
+
+// entropy is T8, Coron's refinement of Maurer's universal test: the mean of g(distance to the previous occurrence)
+// over 256000 8-bit words after 2560 initializing ones estimates the entropy per word, which must exceed 7.976.
+func entropy(s *bitStream) (float64, bool) {
+	var last [1 << t8L]int
+
+	// g[i] is the harmonic number H(i-1) in bits.
+	g := make([]float64, t8Q+t8K+1)
+
+	for i := 2; i < len(g); i++ {
+		g[i] = g[i-1] + 1/(float64(i-1)*math.Ln2)
+	}
+
+	var sum float64
+
+	for n := 1; n <= t8Q+t8K; n++ {
+		var word int
+
+		for range t8L {
+			b, ok := s.next()
+			if !ok {
+				return 0, false
+			}
+
+			word = word<<1 | int(b)
+		}
+
+		if n > t8Q {
+			sum += g[n-last[word]]
+		}
+
+		last[word] = n
+	}
+
+	return sum / t8K, true
+}
@@ -0,0 +1,134 @@
+package ais31
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+// hashed returns n bytes of a SHA-256 counter stream, standing in for good random data.
+func hashed(n int) []byte {
+	var data []byte
+
+	for i := uint64(0); len(data) < n; i++ {
+		sum := sha256.Sum256(binary.BigEndian.AppendUint64(nil, i))
+
+		data = append(data, sum[:]...)
+	}
+
+	return data[:n]
+}
+
+func repeated(b byte, n int) []byte {
+	seq := make([]byte, n)
+
+	for i := range seq {
+		seq[i] = b
+	}
+
+	return seq
+}
+
+func TestBasicTests(t *testing.T) {
+	good := bitsOf(hashed(seqBits / 8))
+
+	tests := []struct {
+		name string
+		fn   func([]byte) bool
+	}{
+		{"monobit", monobit},
+		{"poker", poker},
+		{"runs", runs},
+		{"long run", longRun},
+		{"autocorrelation", autocorrelation},
+	}
+
+	for _, tt := range tests {
+		if !tt.fn(good) {
+			t.Errorf("%s failed on hashed data", tt.name)
+		}
+	}
+
+	// Alternating bits are balanced, so only the tests looking at order catch them.
+	alternating := bitsOf(repeated(0x55, seqBits/8))
+
+	if !monobit(alternating) || poker(alternating) || runs(alternating) || autocorrelation(alternating) {
+		t.Error("alternating bits: wrong verdicts")
+	}
+
+	long := append(bitsOf(repeated(0xff, 5)), good[40:]...)
+
+	if longRun(long) {
+		t.Error("long run passed a run of 40 ones")
+	}
+
+	if monobit(bitsOf(repeated(0xf7, seqBits/8))) {
+		t.Error("monobit passed 7 ones in 8")
+	}
+
+	words := hashed(6 * 1000)
+	copy(words[6*999:], words[6*3:6*4])
+
+	if disjointness(words) {
+		t.Error("disjointness passed a repeated word")
+	}
+}
+
+func TestProcedures(t *testing.T) {
+	data := hashed(2*BytesA + 1<<20)
+
+	reports, err := Run(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range reports {
+		if !r.Pass || r.Repeated {
+			t.Errorf("procedure %s on hashed data: %+v", r.Procedure, r)
+		}
+	}
+
+	b := reports[1]
+
+	if b.Bytes < 800000 || b.Bytes > 1000000 {
+		t.Errorf("procedure B consumed %d bytes", b.Bytes)
+	}
+
+	if f := b.Tests[len(b.Tests)-1].Value; f < 7.976 || f > 8.02 {
+		t.Errorf("T8 = %.4f on hashed data", f)
+	}
+
+	// The second bit of every pair repeats the first three times in four, which T7 catches.
+	var seq []byte
+
+	src := bitsOf(hashed(3 * 8 << 20 / 2))
+
+	for i := 0; i+3 <= len(src); i += 3 {
+		seq = append(seq, src[i], src[i]^src[i+1]&src[i+2])
+	}
+
+	pairs := make([]byte, len(seq)/8)
+
+	for i := range pairs {
+		for _, bit := range seq[8*i : 8*i+8] {
+			pairs[i] = pairs[i]<<1 | bit
+		}
+	}
+
+	b, err = ProcedureB(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Pass || b.Repeated || b.Tests[1].Failures == 0 {
+		t.Fatalf("procedure B on correlated pairs: %+v", b)
+	}
+
+	if _, err := ProcedureA(data[:BytesA-1]); err == nil {
+		t.Fatal("procedure A accepted a short input")
+	}
+
+	if _, err := ProcedureB(data[:100000]); err == nil {
+		t.Fatal("procedure B accepted a short input")
+	}
+}
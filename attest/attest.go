@@ -0,0 +1,135 @@
+// Package attest signs entropy chunks so consumers can verify which device produced them, in what
+// order, and when.
+//
+// An attestation covers the SHA-256 hash of the chunk, the device serial, a per-signer counter, a
+// timestamp, and optionally how the chunk was derived, and is signed with an Ed25519 key held by the
+// serving process.
+package attest
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	domain = "infnoise-attestation-v1"
+
+	// derivationDomain signs attestations recording a derivation, which v1 has no room for.
+	derivationDomain = "infnoise-attestation-v2"
+)
+
+// Attestation is the signed provenance record for one chunk.
+type Attestation struct {
+	Hash      [sha256.Size]byte
+	Serial    string
+	Counter   uint64
+	Timestamp time.Time
+
+	// Derivation is how the chunk was derived (see WithDerivation), or empty.
+	Derivation string
+
+	Signature []byte
+}
+
+// Signer issues attestations for chunks from one device.
+type Signer struct {
+	key        ed25519.PrivateKey
+	serial     string
+	derivation string
+
+	mu      sync.Mutex
+	counter uint64
+}
+
+// NewSigner returns a Signer attributing chunks to serial and signing with key.
+func NewSigner(key ed25519.PrivateKey, serial string, opts ...option) (*Signer, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length %d", len(key))
+	}
+
+	var conf options
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	if len(serial) > 0xFFFF || len(conf.derivation) > 0xFFFF {
+		return nil, errors.New("serial or derivation too long")
+	}
+
+	return &Signer{
+		key:        key,
+		serial:     serial,
+		derivation: conf.derivation,
+	}, nil
+}
+
+// Public returns the key consumers need to verify attestations.
+func (s *Signer) Public() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign attests chunk with the next counter value and the current time.
+func (s *Signer) Sign(chunk []byte) Attestation {
+	s.mu.Lock()
+
+	counter := s.counter
+
+	s.counter++
+
+	s.mu.Unlock()
+
+	a := Attestation{
+		Hash:       sha256.Sum256(chunk),
+		Serial:     s.serial,
+		Counter:    counter,
+		Timestamp:  time.Now().UTC(),
+		Derivation: s.derivation,
+	}
+
+	a.Signature = ed25519.Sign(s.key, a.message())
+
+	return a
+}
+
+// Verify checks that a was signed by pub and covers chunk.
+func Verify(pub ed25519.PublicKey, chunk []byte, a Attestation) error {
+	if sha256.Sum256(chunk) != a.Hash {
+		return errors.New("chunk does not match attested hash")
+	}
+
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, a.message(), a.Signature) {
+		return errors.New("invalid attestation signature")
+	}
+
+	return nil
+}
+
+// message is the byte string covered by the signature. Attestations without a derivation keep the v1 format.
+func (a Attestation) message() []byte {
+	msg := make([]byte, 0, len(derivationDomain)+sha256.Size+2+len(a.Serial)+16+2+len(a.Derivation))
+
+	if a.Derivation == "" {
+		msg = append(msg, domain...)
+	} else {
+		msg = append(msg, derivationDomain...)
+	}
+
+	msg = append(msg, a.Hash[:]...)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(a.Serial)))
+	msg = append(msg, a.Serial...)
+	msg = binary.BigEndian.AppendUint64(msg, a.Counter)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(a.Timestamp.UnixNano()))
+
+	if a.Derivation != "" {
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(a.Derivation)))
+		msg = append(msg, a.Derivation...)
+	}
+
+	return msg
+}
@@ -0,0 +1,89 @@
+package attest
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewSigner(key, "INFNOISE01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := []byte("whitened chunk")
+
+	first := signer.Sign(chunk)
+	second := signer.Sign(chunk)
+
+	if first.Counter != 0 || second.Counter != 1 {
+		t.Fatalf("got counters %d, %d, want 0, 1", first.Counter, second.Counter)
+	}
+
+	err = Verify(signer.Public(), chunk, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []func(a *Attestation){
+		func(a *Attestation) { a.Counter++ },
+		func(a *Attestation) { a.Serial = "OTHER" },
+		func(a *Attestation) { a.Timestamp = a.Timestamp.Add(time.Second) },
+	}
+
+	for i, tamper := range tampered {
+		a := second
+
+		tamper(&a)
+
+		if Verify(signer.Public(), chunk, a) == nil {
+			t.Fatalf("tampered attestation %d verified", i)
+		}
+	}
+
+	if Verify(signer.Public(), []byte("other chunk"), second) == nil {
+		t.Fatal("attestation verified for a different chunk")
+	}
+}
+
+func TestDerivation(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewSigner(key, "INFNOISE01", WithDerivation("scheme=1;conditioner=cshake256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := []byte("whitened chunk")
+
+	a := signer.Sign(chunk)
+
+	if a.Derivation != "scheme=1;conditioner=cshake256" {
+		t.Fatalf("Derivation = %q", a.Derivation)
+	}
+
+	err = Verify(signer.Public(), chunk, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropping the derivation must not leave a valid v1 attestation behind.
+	for _, derivation := range []string{"", "scheme=2;conditioner=cshake256"} {
+		tampered := a
+
+		tampered.Derivation = derivation
+
+		if Verify(signer.Public(), chunk, tampered) == nil {
+			t.Fatalf("attestation verified with derivation %q", derivation)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package attest
+
+type options struct {
+	derivation string
+}
+
+type option func(*options)
+
+// WithDerivation records id, such as the device's DerivationID, in every attestation, so the signature also covers
+// how the chunks were derived.
+func WithDerivation(id string) option {
+	return func(o *options) {
+		o.derivation = id
+	}
+}
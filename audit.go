@@ -0,0 +1,211 @@
+package infnoise
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// minAuditChunk is the smallest chunk WithAuditLog accepts: the hash of a shorter one could be inverted by trying
+// every possible chunk.
+const minAuditChunk = 32
+
+// AuditEntry is one line of an audit log (see WithAuditLog).
+type AuditEntry struct {
+	// Seq numbers the entries of a session from 0.
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+
+	// Prev is the hex-encoded SHA-256 of the previous line, or all zeros for the first entry of a session.
+	Prev string `json:"prev"`
+
+	// Kind is "session" for the first entry, "chunk" for a whitened chunk, "reseed", or the EventKind of a device
+	// event ("started", "stopped", "health degraded", ...).
+	Kind string `json:"kind"`
+
+	// Data holds the details of the kind: the configuration for "session", the index, size, and hex-encoded SHA-256
+	// of the chunk for "chunk", the count for "reseed", and the estimate or error of an event.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type auditSession struct {
+	Derivation    string   `json:"derivation"`
+	Backend       string   `json:"backend,omitempty"`
+	Mode          ReadMode `json:"mode"`
+	TargetEntropy float64  `json:"targetEntropy"`
+	Tolerance     float64  `json:"tolerance"`
+}
+
+type auditChunk struct {
+	Index  uint64 `json:"index"`
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+type auditEvent struct {
+	Estimate float64 `json:"estimate,omitempty"`
+	Err      string  `json:"error,omitempty"`
+	Dropped  uint64  `json:"dropped,omitempty"`
+}
+
+// auditLog writes the hash-chained entries of one session. It stops at the first write error.
+type auditLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	seq    uint64
+	prev   [sha256.Size]byte
+	chunks uint64
+	failed bool
+}
+
+func (a *auditLog) record(kind string, data any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.failed {
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		a.failed = true
+
+		return
+	}
+
+	line, err := json.Marshal(AuditEntry{
+		Seq:  a.seq,
+		Time: time.Now().UTC(),
+		Prev: hex.EncodeToString(a.prev[:]),
+		Kind: kind,
+		Data: raw,
+	})
+	if err != nil {
+		a.failed = true
+
+		return
+	}
+
+	_, err = a.w.Write(append(line, '\n'))
+	if err != nil {
+		a.failed = true
+
+		return
+	}
+
+	a.seq++
+	a.prev = sha256.Sum256(line)
+}
+
+// chunk records the hash of a whitened chunk.
+func (a *auditLog) chunk(p []byte) {
+	sum := sha256.Sum256(p)
+
+	a.mu.Lock()
+	index := a.chunks
+	a.chunks++
+	a.mu.Unlock()
+
+	a.record("chunk", auditChunk{Index: index, Bytes: len(p), SHA256: hex.EncodeToString(sum[:])})
+}
+
+// audit records a device event, if the device keeps an audit log.
+func (d *Device) audit(ev Event) {
+	if d.auditLog == nil {
+		return
+	}
+
+	data := auditEvent{Dropped: ev.Dropped}
+
+	if ev.Kind == EventHealthDegraded || ev.Kind == EventHealthRecovered {
+		data.Estimate = ev.Estimate
+	}
+
+	if ev.Err != nil {
+		data.Err = ev.Err.Error()
+	}
+
+	d.auditLog.record(ev.Kind.String(), data)
+}
+
+// openAudit starts the session with the device's configuration, once.
+func (d *Device) openAudit() {
+	if d.auditLog == nil {
+		return
+	}
+
+	d.auditLog.mu.Lock()
+	first := d.auditLog.seq == 0 && !d.auditLog.failed
+	d.auditLog.mu.Unlock()
+
+	if !first {
+		return
+	}
+
+	d.auditLog.record("session", auditSession{
+		Derivation:    d.DerivationID(),
+		Backend:       string(d.backend),
+		Mode:          d.mode,
+		TargetEntropy: d.drift.target,
+		Tolerance:     d.drift.tolerance,
+	})
+}
+
+// VerifyAuditLog checks the hash chain of an audit log written by WithAuditLog and returns the number of entries.
+// A log may hold several sessions, each starting again from sequence number 0; any other gap, reordering, or
+// modified line fails the check. Truncating the log, appending a forged session, or removing whole sessions is not
+// detected.
+func VerifyAuditLog(r io.Reader) (int, error) {
+	sc := bufio.NewScanner(r)
+
+	sc.Buffer(nil, 1<<20)
+
+	var (
+		n    int
+		prev [sha256.Size]byte
+		seq  uint64
+	)
+
+	for sc.Scan() {
+		line := sc.Bytes()
+
+		var e AuditEntry
+
+		err := json.Unmarshal(line, &e)
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		want := prev
+
+		if e.Seq == 0 {
+			want = [sha256.Size]byte{}
+		} else if n == 0 || e.Seq != seq+1 {
+			return n, fmt.Errorf("line %d: sequence number %d out of order", n+1, e.Seq)
+		}
+
+		if e.Prev != hex.EncodeToString(want[:]) {
+			return n, fmt.Errorf("line %d: chain broken (previous line modified or missing)", n+1)
+		}
+
+		prev = sha256.Sum256(line)
+		seq = e.Seq
+		n++
+	}
+
+	if err := sc.Err(); err != nil {
+		return n, err
+	}
+
+	if n == 0 {
+		return 0, errors.New("empty audit log")
+	}
+
+	return n, nil
+}
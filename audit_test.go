@@ -0,0 +1,105 @@
+package infnoise
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	var log bytes.Buffer
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithChunkSize(64), WithAuditLog(&log))
+
+	out := make([]byte, 128)
+
+	_, err := dv.ReadWhitened(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dv.Reseed()
+	dv.Close()
+
+	n, err := VerifyAuditLog(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+
+	var kinds []string
+
+	for _, line := range lines {
+		var e AuditEntry
+
+		err = json.Unmarshal([]byte(line), &e)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kinds = append(kinds, e.Kind)
+
+		if e.Kind == "session" && !strings.Contains(string(e.Data), dv.DerivationID()) {
+			t.Fatalf("session entry without the derivation: %s", e.Data)
+		}
+
+		if e.Kind == "chunk" {
+			var c auditChunk
+
+			json.Unmarshal(e.Data, &c)
+
+			sum := sha256.Sum256(out[64*c.Index : 64*(c.Index+1)])
+
+			if c.SHA256 != hex.EncodeToString(sum[:]) {
+				t.Fatalf("chunk %d: hash %s does not match the output", c.Index, c.SHA256)
+			}
+		}
+	}
+
+	if want := []string{"session", "started", "chunk", "chunk", "reseed", "stopped"}; !slices.Equal(kinds, want) || n != len(want) {
+		t.Fatalf("logged %v (%d verified), want %v", kinds, n, want)
+	}
+
+	if bytes.Contains(log.Bytes(), []byte(hex.EncodeToString(out[:32]))) {
+		t.Fatal("the log holds output")
+	}
+
+	// A second session in the same log starts a new chain.
+	second, _ := openSimulator(t, 2, DefaultSimulatorGain, WithChunkSize(64), WithAuditLog(&log))
+	second.Close()
+
+	if _, err := VerifyAuditLog(bytes.NewReader(log.Bytes())); err != nil {
+		t.Fatalf("two sessions: %v", err)
+	}
+
+	tampered := []func([]string) []string{
+		func(l []string) []string { return slices.Delete(slices.Clone(l), 3, 4) },
+		func(l []string) []string {
+			l = slices.Clone(l)
+			l[2] = strings.Replace(l[2], `"index":0`, `"index":7`, 1)
+
+			return l
+		},
+		func(l []string) []string {
+			l = slices.Clone(l)
+			l[2], l[3] = l[3], l[2]
+
+			return l
+		},
+	}
+
+	for i, tamper := range tampered {
+		if _, err := VerifyAuditLog(strings.NewReader(strings.Join(tamper(lines), "\n"))); err == nil {
+			t.Fatalf("tampered log %d verified", i)
+		}
+	}
+
+	if err := New(WithChunkSize(16), WithAuditLog(&log)).Start(); err == nil {
+		t.Fatal("Start accepted an audit log with 16-byte chunks")
+	}
+}
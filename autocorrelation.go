@@ -0,0 +1,144 @@
+package infnoise
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// MaxAutocorrelationLag is the longest lag, in bits, of the autocorrelation monitor.
+	MaxAutocorrelationLag = 32
+
+	// AutocorrelationWindow is the number of raw bits each set of autocorrelation coefficients is computed over.
+	// At this size a coefficient of an uncorrelated stream has a standard error of about 0.004.
+	AutocorrelationWindow = 1 << 16
+
+	// DefaultAutocorrelationLimit is the default largest magnitude a coefficient may reach (see
+	// WithAutocorrelationLimit). A healthy multiplier with a gain of 1.82 has a lag-1 coefficient of about -0.26;
+	// it approaches -0.5 as the gain sinks towards 1.5.
+	DefaultAutocorrelationLimit = 0.4
+)
+
+// Autocorrelation is the latest result of the autocorrelation monitor.
+type Autocorrelation struct {
+	// Coefficients holds the Pearson autocorrelation of the raw bits at lags 1 to MaxAutocorrelationLag (index 0 is
+	// lag 1), computed over the last complete window of AutocorrelationWindow bits. It is empty until then.
+	Coefficients []float64 `json:"coefficients"`
+
+	// Limit is the largest magnitude allowed; Windows counts the complete windows and Failures those exceeding it.
+	Limit    float64 `json:"limit"`
+	Windows  uint64  `json:"windows"`
+	Failures uint64  `json:"failures"`
+}
+
+// autocorrelation estimates the correlation of each raw bit with the MaxAutocorrelationLag bits before it, window
+// by window. The circuit's dominant failure mode is correlation between successive bits, such as a multiplier whose
+// gain has drifted towards 1, which a bias check cannot see.
+type autocorrelation struct {
+	limit float64
+
+	// hist holds the preceding bits of the window, the most recent in bit 0; both counts, per lag, the pairs of
+	// bits that are both ones.
+	hist uint64
+	n    uint64
+	ones uint64
+	both [MaxAutocorrelationLag]uint64
+
+	latest   []float64
+	windows  uint64
+	failures uint64
+}
+
+// add feeds one bit and returns a description of the failure if it completed a window exceeding the limit.
+func (a *autocorrelation) add(bit byte) string {
+	if bit == 1 {
+		a.ones++
+
+		for h := a.hist; h != 0; h &= h - 1 {
+			a.both[bits.TrailingZeros64(h)]++
+		}
+	}
+
+	a.hist = (a.hist<<1 | uint64(bit)) & (1<<MaxAutocorrelationLag - 1)
+	a.n++
+
+	if a.n < AutocorrelationWindow {
+		return ""
+	}
+
+	return a.finish()
+}
+
+// finish computes the coefficients of the complete window and starts the next one.
+func (a *autocorrelation) finish() string {
+	p := float64(a.ones) / float64(a.n)
+
+	a.latest = make([]float64, MaxAutocorrelationLag)
+
+	worst := 0
+
+	for i := range a.latest {
+		// The first i+1 bits of the window have no partner at this lag.
+		pairs := float64(a.n) - float64(i+1)
+
+		if p > 0 && p < 1 {
+			a.latest[i] = (float64(a.both[i])/pairs - p*p) / (p * (1 - p))
+		} else {
+			// A constant window is perfectly correlated.
+			a.latest[i] = 1
+		}
+
+		if math.Abs(a.latest[i]) > math.Abs(a.latest[worst]) {
+			worst = i
+		}
+	}
+
+	a.windows++
+
+	a.hist, a.n, a.ones, a.both = 0, 0, 0, [MaxAutocorrelationLag]uint64{}
+
+	if a.limit <= 0 || math.Abs(a.latest[worst]) <= a.limit {
+		return ""
+	}
+
+	a.failures++
+
+	return fmt.Sprintf("autocorrelation %.4f at lag %d exceeds %.4f", a.latest[worst], worst+1, a.limit)
+}
+
+// reset discards the current window, the latest coefficients, and the counts.
+func (a *autocorrelation) reset() {
+	*a = autocorrelation{limit: a.limit}
+}
+
+func (a *autocorrelation) report() Autocorrelation {
+	return Autocorrelation{
+		Coefficients: append([]float64{}, a.latest...),
+		Limit:        a.limit,
+		Windows:      a.windows,
+		Failures:     a.failures,
+	}
+}
+
+func (a *autocorrelation) result() TestResult {
+	t := TestResult{Name: "autocorrelation", Pass: a.failures == 0}
+
+	if a.latest == nil {
+		t.Detail = fmt.Sprintf("window not filled (%d of %d bits)", a.n, AutocorrelationWindow)
+
+		return t
+	}
+
+	worst := 0
+
+	for i, r := range a.latest {
+		if math.Abs(r) > math.Abs(a.latest[worst]) {
+			worst = i
+		}
+	}
+
+	t.Detail = fmt.Sprintf("%d of %d windows failed, latest peak %.4f at lag %d, limit %.4f", a.failures, a.windows, a.latest[worst], worst+1, a.limit)
+
+	return t
+}
@@ -0,0 +1,188 @@
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BackendName identifies a USB transport implementation.
+type BackendName string
+
+const (
+	// BackendDefault selects the platform's default transport (libusb on Linux, D2XX on Windows).
+	BackendDefault BackendName = ""
+
+	// BackendLibUSB talks to the FTDI chip directly through libusb-1.0.
+	BackendLibUSB BackendName = "libusb"
+
+	// BackendD2XX uses FTDI's proprietary D2XX driver (ftd2xx.dll or libftd2xx.so).
+	BackendD2XX BackendName = "d2xx"
+
+	// BackendWinUSB talks to the FTDI chip through Windows' built-in WinUSB driver, for machines where D2XX cannot
+	// be installed. The board must be bound to WinUSB, e.g. with Zadig.
+	BackendWinUSB BackendName = "winusb"
+
+	// BackendSimulator is an in-memory model of the noise source that needs no hardware.
+	BackendSimulator BackendName = "simulator"
+)
+
+const (
+	// BitModeReset returns the FTDI chip to its default serial mode.
+	BitModeReset = 0x00
+
+	// BitModeMPSSE enables the multi-protocol synchronous serial engine.
+	BitModeMPSSE = 0x02
+
+	// BitModeSyncBitbang enables synchronous bitbang mode, as used by the Infinite Noise TRNG.
+	BitModeSyncBitbang = 0x04
+)
+
+// Backend is a transport that drives an FTDI chip in one of its bit modes.
+// A Backend is used by a single Device and is not required to be safe for concurrent use.
+type Backend interface {
+	// Open connects to the first device matching vid:pid. On failure the backend releases everything it acquired.
+	Open(vid, pid uint16) error
+
+	// SetBitMode sets the pin direction mask and the bit mode (see the BitMode constants).
+	SetBitMode(mask byte, mode byte) error
+
+	// Write clocks all of p out on the output pins.
+	Write(p []byte) error
+
+	// Read fills all of p with the pin states sampled during previous writes.
+	Read(p []byte) error
+
+	// Close releases the device.
+	Close() error
+}
+
+// TimeoutBackend is implemented by backends that can bound how long a single Write or Read blocks.
+type TimeoutBackend interface {
+	Backend
+
+	// SetTimeout limits subsequent transfers to d; d <= 0 restores the backend's default.
+	SetTimeout(d time.Duration) error
+}
+
+// Interrupter is implemented by backends whose blocked Read can be woken from another goroutine.
+// Interrupt may be called concurrently with Read and Write; after it, Read fails until the backend is reopened.
+type Interrupter interface {
+	Interrupt()
+}
+
+// LatencyBackend is implemented by backends that can set the FTDI latency timer, which bounds how long the chip
+// holds back a partly filled bulk-in packet. Backends set it to 2 ms when opening the device.
+type LatencyBackend interface {
+	Backend
+
+	SetLatencyTimer(ms byte) error
+}
+
+// KernelDriverBackend is implemented by backends that can take the board's interface from a kernel driver such
+// as Linux's ftdi_sio. SetDetachKernelDriver is called before Open; with detach false, Open fails with
+// ErrKernelDriver instead of detaching.
+type KernelDriverBackend interface {
+	Backend
+
+	SetDetachKernelDriver(detach bool)
+}
+
+// Suspender is implemented by backends that poll the device in the background. Suspend stops polling and
+// returns once no transfer is in flight; Resume restarts it.
+type Suspender interface {
+	Suspend()
+	Resume()
+}
+
+// defaultTimeoutMillis bounds USB transfers when no deadline is set.
+const defaultTimeoutMillis = 5000
+
+// timeoutMillis converts a SetTimeout argument to the millisecond timeout USB APIs expect.
+func timeoutMillis(d time.Duration) uint32 {
+	if d <= 0 {
+		return defaultTimeoutMillis
+	}
+
+	return uint32(max(d.Milliseconds(), 1))
+}
+
+// ErrDriverUnavailable is wrapped by Open when the driver library a backend needs cannot be loaded.
+var ErrDriverUnavailable = errors.New("driver not available")
+
+// ErrKernelDriver is wrapped by Open when a kernel driver holds the board's interface and it may not or could not
+// be detached. The error names the driver.
+var ErrKernelDriver = errors.New("interface held by a kernel driver")
+
+// defaultFallback, if set, is tried when the default backend's driver is unavailable.
+var defaultFallback BackendName
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[BackendName]func() Backend{}
+)
+
+// RegisterBackend makes a backend available to WithBackend under name, replacing any previous registration.
+func RegisterBackend(name BackendName, factory func() Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[name] = factory
+}
+
+// Backends returns the names of all registered backends in sorted order.
+func Backends() []BackendName {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]BackendName, 0, len(backends))
+
+	for name := range backends {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// OpenBackend creates the named backend and opens the first device matching vid:pid. If the default backend's
+// driver is missing, the default falls back to another one where the platform has it (WinUSB on Windows).
+func OpenBackend(name BackendName, vid, pid uint16) (Backend, error) {
+	return openBackend(name, vid, pid, nil)
+}
+
+// openBackend is OpenBackend with a hook that configures the backend before it is opened.
+func openBackend(name BackendName, vid, pid uint16, prepare func(Backend)) (Backend, error) {
+	if name == BackendDefault {
+		b, err := openBackend(defaultBackend, vid, pid, prepare)
+		if errors.Is(err, ErrDriverUnavailable) && defaultFallback != "" {
+			b, err = openBackend(defaultFallback, vid, pid, prepare)
+		}
+
+		return b, err
+	}
+
+	backendsMu.RLock()
+	factory, ok := backends[name]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("backend %q is not available on this platform", name)
+	}
+
+	b := factory()
+
+	if prepare != nil {
+		prepare(b)
+	}
+
+	err := b.Open(vid, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows && !(darwin && gousb)
+// +build !linux
+// +build !windows
+// +build !darwin !gousb
+
+package infnoise
+
+// No native USB backend is built on this platform; Start fails unless another backend is selected.
+const defaultBackend = BackendLibUSB
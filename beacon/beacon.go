@@ -0,0 +1,269 @@
+// Package beacon runs a randomness beacon in the style of the NIST beacon: every interval it emits a
+// signed 512-bit pulse derived from device output and chained to the previous pulse by hash.
+//
+// Each pulse's output is SHA-512 over the previous output, the pulse index, its timestamp, and 64
+// fresh bytes from the source; the signature covers all of these plus the output itself. Chaining
+// means a published pulse commits the beacon to its entire history.
+package beacon
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+const domain = "infnoise-beacon-v1"
+
+// OutputSize is the size of a pulse's output value in bytes.
+const OutputSize = sha512.Size
+
+// ErrNotFound is returned when no retained pulse matches a lookup.
+var ErrNotFound = errors.New("pulse not found")
+
+// Pulse is one beacon output.
+type Pulse struct {
+	Index     uint64
+	Timestamp time.Time
+	Previous  [OutputSize]byte
+	Output    [OutputSize]byte
+	Signature []byte
+}
+
+// Beacon emits pulses from a source.
+type Beacon struct {
+	src      io.Reader
+	key      ed25519.PrivateKey
+	interval time.Duration
+	history  int
+
+	mu     sync.RWMutex
+	pulses []Pulse
+	next   uint64
+	last   [OutputSize]byte
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a beacon drawing fresh randomness from src and signing pulses with key.
+func New(src io.Reader, key ed25519.PrivateKey, opts ...option) (*Beacon, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length %d", len(key))
+	}
+
+	conf := &options{
+		interval: time.Minute,
+		history:  1440,
+	}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	if conf.interval <= 0 {
+		return nil, fmt.Errorf("invalid interval %v", conf.interval)
+	}
+
+	return &Beacon{
+		src:      src,
+		key:      key,
+		interval: conf.interval,
+		history:  max(conf.history, 1),
+	}, nil
+}
+
+// Public returns the key pulses are verified with.
+func (b *Beacon) Public() ed25519.PublicKey {
+	return b.key.Public().(ed25519.PublicKey)
+}
+
+// Start emits a pulse immediately and then once per interval until Close is called.
+func (b *Beacon) Start() error {
+	b.mu.Lock()
+
+	if b.stop != nil {
+		b.mu.Unlock()
+
+		return errors.New("beacon already running")
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+
+	b.stop, b.done = stop, done
+
+	b.mu.Unlock()
+
+	_, err := b.Emit()
+	if err != nil {
+		// The loop never started; closing done releases a Close that raced with the failed pulse.
+		b.mu.Lock()
+
+		if b.stop == stop {
+			b.stop, b.done = nil, nil
+		}
+
+		b.mu.Unlock()
+
+		close(done)
+
+		return err
+	}
+
+	go b.loop(stop, done)
+
+	return nil
+}
+
+// Close stops emitting pulses. Retained pulses stay available.
+func (b *Beacon) Close() error {
+	b.mu.Lock()
+
+	stop, done := b.stop, b.done
+
+	b.stop, b.done = nil, nil
+
+	b.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+
+	close(stop)
+
+	<-done
+
+	return nil
+}
+
+func (b *Beacon) loop(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// A failed read skips this pulse; the chain continues from the last one emitted.
+			b.Emit()
+		}
+	}
+}
+
+// Emit produces, retains, and returns the next pulse.
+func (b *Beacon) Emit() (Pulse, error) {
+	var fresh [OutputSize]byte
+
+	defer clear(fresh[:])
+
+	_, err := io.ReadFull(b.src, fresh[:])
+	if err != nil {
+		return Pulse{}, fmt.Errorf("failed to read entropy: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p := Pulse{
+		Index:     b.next,
+		Timestamp: time.Now().UTC(),
+		Previous:  b.last,
+	}
+
+	h := sha512.New()
+
+	h.Write(p.header())
+	h.Write(fresh[:])
+	h.Sum(p.Output[:0])
+
+	p.Signature = ed25519.Sign(b.key, p.message())
+
+	b.next++
+	b.last = p.Output
+
+	b.pulses = append(b.pulses, p)
+
+	if len(b.pulses) > b.history {
+		b.pulses = b.pulses[len(b.pulses)-b.history:]
+	}
+
+	return p, nil
+}
+
+// Last returns the most recent pulse.
+func (b *Beacon) Last() (Pulse, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.pulses) == 0 {
+		return Pulse{}, ErrNotFound
+	}
+
+	return b.pulses[len(b.pulses)-1], nil
+}
+
+// Index returns the pulse with the given index if it is still retained.
+func (b *Beacon) Index(index uint64) (Pulse, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.pulses) == 0 || index < b.pulses[0].Index || index >= b.next {
+		return Pulse{}, ErrNotFound
+	}
+
+	return b.pulses[index-b.pulses[0].Index], nil
+}
+
+// At returns the latest pulse emitted at or before t.
+func (b *Beacon) At(t time.Time) (Pulse, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	i := sort.Search(len(b.pulses), func(i int) bool {
+		return b.pulses[i].Timestamp.After(t)
+	})
+
+	if i == 0 {
+		return Pulse{}, ErrNotFound
+	}
+
+	return b.pulses[i-1], nil
+}
+
+// Verify checks p's signature and, if prev is non-nil, that p directly follows it in the chain.
+func Verify(pub ed25519.PublicKey, p Pulse, prev *Pulse) error {
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, p.message(), p.Signature) {
+		return errors.New("invalid pulse signature")
+	}
+
+	if prev != nil && (prev.Index+1 != p.Index || prev.Output != p.Previous) {
+		return fmt.Errorf("pulse %d does not follow pulse %d", p.Index, prev.Index)
+	}
+
+	return nil
+}
+
+// header is the chain state mixed into the output ahead of the fresh randomness.
+func (p Pulse) header() []byte {
+	msg := make([]byte, 0, len(domain)+OutputSize+16)
+
+	msg = append(msg, domain...)
+	msg = append(msg, p.Previous[:]...)
+	msg = binary.BigEndian.AppendUint64(msg, p.Index)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(p.Timestamp.UnixNano()))
+
+	return msg
+}
+
+// message is the byte string covered by the signature.
+func (p Pulse) message() []byte {
+	return append(p.header(), p.Output[:]...)
+}
@@ -0,0 +1,131 @@
+package beacon
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestChain(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := New(rand.NewChaCha8([32]byte{1}), key, WithHistory(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prev *Pulse
+
+	for i := range 5 {
+		p, err := b.Emit()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if p.Index != uint64(i) {
+			t.Fatalf("got index %d, want %d", p.Index, i)
+		}
+
+		err = Verify(b.Public(), p, prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prev = &p
+	}
+
+	if _, err := b.Index(1); err != ErrNotFound {
+		t.Fatalf("evicted pulse: got %v, want ErrNotFound", err)
+	}
+
+	p2, err := b.Index(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p3, err := b.Index(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if Verify(b.Public(), p3, &p2) != nil || Verify(b.Public(), *prev, &p2) == nil {
+		t.Fatal("chain verification does not detect gaps")
+	}
+
+	at, err := b.At(p3.Timestamp)
+	if err != nil || at.Index < 3 || !at.Timestamp.Equal(p3.Timestamp) {
+		t.Fatalf("lookup by time: got pulse %d (%v), want 3", at.Index, err)
+	}
+
+	if _, err := b.At(p2.Timestamp.Add(-time.Nanosecond)); err != ErrNotFound {
+		t.Fatalf("lookup before retained history: got %v, want ErrNotFound", err)
+	}
+
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/pulse/last")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	var last jsonPulse
+
+	err = json.NewDecoder(resp.Body).Decode(&last)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last.Index != 4 || len(last.Output) != 2*OutputSize {
+		t.Fatalf("got pulse %d with %d hex chars of output, want pulse 4 with %d", last.Index, len(last.Output), 2*OutputSize)
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unplugged := errors.New("device unplugged")
+
+	b, err := New(iotest.ErrReader(unplugged), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan error, 1)
+
+	go func() {
+		started <- b.Start()
+	}()
+
+	select {
+	case err := <-started:
+		if !errors.Is(err, unplugged) {
+			t.Fatalf("Start with a failing source = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start with a failing source did not return")
+	}
+
+	// The beacon can be closed and started again.
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Start(); !errors.Is(err, unplugged) {
+		t.Fatalf("second Start = %v", err)
+	}
+}
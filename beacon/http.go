@@ -0,0 +1,96 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type jsonPulse struct {
+	Index     uint64    `json:"index"`
+	Timestamp time.Time `json:"timestamp"`
+	Previous  string    `json:"previousOutput"`
+	Output    string    `json:"output"`
+	Signature string    `json:"signature"`
+}
+
+// Handler serves the beacon over HTTP:
+//
+//	GET /pulse/last           most recent pulse
+//	GET /pulse/{index}        pulse by index
+//	GET /pulse?time=RFC3339   latest pulse at or before the given time
+//	GET /key                  hex-encoded Ed25519 public key
+//
+// Pulses are JSON objects with hex-encoded output, previous output, and signature. A beacon is public by design
+// and its pulses are signed; to restrict who may fetch them, wrap the handler with httpauth.Wrap.
+func (b *Beacon) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /pulse/last", func(w http.ResponseWriter, r *http.Request) {
+		writePulse(w, func() (Pulse, error) {
+			return b.Last()
+		})
+	})
+
+	mux.HandleFunc("GET /pulse/{index}", func(w http.ResponseWriter, r *http.Request) {
+		index, err := strconv.ParseUint(r.PathValue("index"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid index", http.StatusBadRequest)
+
+			return
+		}
+
+		writePulse(w, func() (Pulse, error) {
+			return b.Index(index)
+		})
+	})
+
+	mux.HandleFunc("GET /pulse", func(w http.ResponseWriter, r *http.Request) {
+		t, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("time"))
+		if err != nil {
+			http.Error(w, "invalid or missing time", http.StatusBadRequest)
+
+			return
+		}
+
+		writePulse(w, func() (Pulse, error) {
+			return b.At(t)
+		})
+	})
+
+	mux.HandleFunc("GET /key", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+
+		w.Write([]byte(hex.EncodeToString(b.Public())))
+	})
+
+	return mux
+}
+
+func writePulse(w http.ResponseWriter, lookup func() (Pulse, error)) {
+	p, err := lookup()
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		if errors.Is(err, ErrNotFound) {
+			status = http.StatusNotFound
+		}
+
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(jsonPulse{
+		Index:     p.Index,
+		Timestamp: p.Timestamp,
+		Previous:  hex.EncodeToString(p.Previous[:]),
+		Output:    hex.EncodeToString(p.Output[:]),
+		Signature: hex.EncodeToString(p.Signature),
+	})
+}
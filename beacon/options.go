@@ -0,0 +1,24 @@
+package beacon
+
+import "time"
+
+type options struct {
+	interval time.Duration
+	history  int
+}
+
+type option func(*options)
+
+// WithInterval sets how often a pulse is emitted (default 60s).
+func WithInterval(d time.Duration) option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// WithHistory sets how many past pulses are kept for retrieval (default 1440, one day at the default interval).
+func WithHistory(pulses int) option {
+	return func(o *options) {
+		o.history = pulses
+	}
+}
@@ -0,0 +1,69 @@
+package infnoise
+
+import "math"
+
+// BiasMap is the density of ones in the raw bits broken down by the bit's position in its output byte and by the
+// switch address driven for its sample. A weak stage of the multiplier shows up as one outlying address, and a
+// misaligned decoder as outlying positions, where aggregate statistics average them away.
+type BiasMap struct {
+	// Positions is indexed by the bit's position in its byte, 0 being the most significant (first) bit.
+	Positions [8]Density `json:"positions"`
+
+	// Addresses is indexed by the address on ADDR0..ADDR3; addresses outside the pattern's sweep stay empty. It is
+	// only filled by a Device's health check, which knows the pattern.
+	Addresses [16]Density `json:"addresses"`
+}
+
+// Density counts the ones among a set of bits.
+type Density struct {
+	Ones uint64 `json:"ones"`
+	Bits uint64 `json:"bits"`
+}
+
+// Ratio returns the fraction of ones, or 0 without bits.
+func (d Density) Ratio() float64 {
+	if d.Bits == 0 {
+		return 0
+	}
+
+	return float64(d.Ones) / float64(d.Bits)
+}
+
+// Deviation returns how many standard errors d's ratio lies from overall's, assuming independent bits. Values
+// beyond about 5 in either direction are unlikely to be chance.
+func (d Density) Deviation(overall Density) float64 {
+	p := overall.Ratio()
+
+	if d.Bits == 0 || p == 0 || p == 1 {
+		return 0
+	}
+
+	return (d.Ratio() - p) / math.Sqrt(p*(1-p)/float64(d.Bits))
+}
+
+// Overall returns the density over all bits.
+func (m *BiasMap) Overall() Density {
+	var o Density
+
+	for _, d := range m.Positions {
+		o.Ones += d.Ones
+		o.Bits += d.Bits
+	}
+
+	return o
+}
+
+// add records bit as the i-th bit of the current Add call; addresses maps sample indices to switch addresses.
+func (m *BiasMap) add(i int, bit byte, addresses []uint8) {
+	m.Positions[i%8].Ones += uint64(bit)
+	m.Positions[i%8].Bits++
+
+	if len(addresses) == 0 {
+		return
+	}
+
+	d := &m.Addresses[addresses[i%len(addresses)]]
+
+	d.Ones += uint64(bit)
+	d.Bits++
+}
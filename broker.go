@@ -0,0 +1,176 @@
+package infnoise
+
+import (
+	"crypto/sha3"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// consumerLabel is the cSHAKE256 customization string under which labeled subscriptions derive their output.
+const consumerLabel = "infnoise consumer"
+
+// Broker shares the whitened output of one Device among any number of in-process consumers. A single goroutine
+// owns the device and serves the subscriptions' requests in turn, so consumers never contend for the device's
+// locks, and every byte of the stream goes to exactly one subscription.
+type Broker struct {
+	dev  *Device
+	reqs chan brokerRequest
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	// labels holds the labels of open labeled subscriptions.
+	mu     sync.Mutex
+	labels map[string]bool
+}
+
+type brokerRequest struct {
+	p     []byte
+	reply chan brokerReply
+}
+
+type brokerReply struct {
+	n   int
+	err error
+}
+
+// NewBroker starts a broker for d, which must be started. Once the broker owns d, nothing else should read from it.
+func NewBroker(d *Device) *Broker {
+	b := &Broker{
+		dev:    d,
+		reqs:   make(chan brokerRequest),
+		done:   make(chan struct{}),
+		labels: make(map[string]bool),
+	}
+
+	b.wg.Add(1)
+
+	go b.run()
+
+	return b
+}
+
+func (b *Broker) run() {
+	defer b.wg.Done()
+
+	for {
+		select {
+		case req := <-b.reqs:
+			var n int
+
+			// A panic fails the request being served; the broker carries on with the next.
+			err := Supervise("broker", func() (err error) {
+				n, err = b.dev.ReadWhitened(req.p)
+
+				return err
+			})
+
+			req.reply <- brokerReply{n: n, err: err}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// read has the broker's goroutine fill p with whitened output.
+func (b *Broker) read(p []byte) (int, error) {
+	reply := make(chan brokerReply, 1)
+
+	select {
+	case b.reqs <- brokerRequest{p: p, reply: reply}:
+	case <-b.done:
+		return 0, ErrClosed
+	}
+
+	r := <-reply
+
+	return r.n, r.err
+}
+
+// Close stops the broker once the request in progress, if any, has been served. Subscriptions fail with ErrClosed
+// afterwards. The device is left open.
+func (b *Broker) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+
+	b.wg.Wait()
+
+	return nil
+}
+
+// Subscription is one consumer's view of a Broker's stream. It buffers up to one whitening chunk, so small reads
+// are served without involving the broker.
+type Subscription struct {
+	buf bufReader
+
+	// release frees the label of a labeled subscription.
+	release func()
+}
+
+// Subscribe returns a new, independent reader of the broker's stream.
+func (b *Broker) Subscribe() *Subscription {
+	return &Subscription{
+		buf: newBufReader(b.read, len(b.dev.poolBuf)),
+	}
+}
+
+// SubscribeLabeled returns a subscription whose output is additionally derived under label: every chunk it takes
+// from the stream is passed through cSHAKE256 keyed with the label before it is returned. Streams of different labels
+// are thus separated cryptographically, not only by scheduling, which suits multi-tenant services that hand each
+// tenant its own stream. A label can only be used by one open subscription at a time.
+func (b *Broker) SubscribeLabeled(label string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.labels[label] {
+		return nil, fmt.Errorf("label %q already subscribed", label)
+	}
+
+	b.labels[label] = true
+
+	prefix := binary.LittleEndian.AppendUint64(nil, uint64(len(label)))
+	prefix = append(prefix, label...)
+
+	read := func(p []byte) (int, error) {
+		n, err := b.read(p)
+
+		h := sha3.NewCSHAKE256(nil, []byte(consumerLabel))
+
+		h.Write(prefix)
+		h.Write(p[:n])
+		h.Read(p[:n])
+
+		h.Reset()
+
+		return n, err
+	}
+
+	return &Subscription{
+		buf: newBufReader(read, len(b.dev.poolBuf)),
+		release: sync.OnceFunc(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.labels, label)
+		}),
+	}, nil
+}
+
+// Read fills p with whitened output no other subscription receives.
+func (s *Subscription) Read(p []byte) (int, error) {
+	return s.buf.Read(p)
+}
+
+// Close wipes the subscription's buffer and frees its label, if any.
+func (s *Subscription) Close() error {
+	s.buf.reset()
+
+	if s.release != nil {
+		s.release()
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package infnoise
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestBroker(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithChunkSize(64))
+
+	b := NewBroker(dv)
+
+	const (
+		subscribers = 8
+		reads       = 50
+	)
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[[16]byte]int)
+		wg   sync.WaitGroup
+	)
+
+	for i := range subscribers {
+		s := b.Subscribe()
+
+		wg.Go(func() {
+			defer s.Close()
+
+			for range reads {
+				var v [16]byte
+
+				_, err := s.Read(v[:])
+				if err != nil {
+					t.Error(err)
+
+					return
+				}
+
+				mu.Lock()
+
+				if prev, ok := seen[v]; ok {
+					t.Errorf("subscribers %d and %d received the same bytes", prev, i)
+				}
+
+				seen[v] = i
+
+				mu.Unlock()
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if len(seen) != subscribers*reads {
+		t.Fatalf("got %d distinct values, want %d", len(seen), subscribers*reads)
+	}
+
+	s := b.Subscribe()
+
+	b.Close()
+
+	_, err := s.Read(make([]byte, 16))
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("read after Close: %v", err)
+	}
+}
+
+func TestBrokerLabels(t *testing.T) {
+	read := func(label string) []byte {
+		dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+		b := NewBroker(dv)
+
+		defer b.Close()
+
+		s, err := b.SubscribeLabeled(label)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer s.Close()
+
+		buf := make([]byte, 64)
+
+		_, err = s.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return buf
+	}
+
+	// The same underlying stream yields unrelated output under different labels.
+	a, b := read("tenant-a"), read("tenant-b")
+
+	if bytes.Equal(a, b) {
+		t.Fatal("labels did not separate the streams")
+	}
+
+	if !bytes.Equal(a, read("tenant-a")) {
+		t.Fatal("derivation is not deterministic")
+	}
+
+	dv, _ := openSimulator(t, 2, DefaultSimulatorGain)
+
+	br := NewBroker(dv)
+
+	defer br.Close()
+
+	s, err := br.SubscribeLabeled("tenant-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = br.SubscribeLabeled("tenant-a")
+	if err == nil {
+		t.Fatal("label subscribed twice")
+	}
+
+	s.Close()
+
+	_, err = br.SubscribeLabeled("tenant-a")
+	if err != nil {
+		t.Fatalf("label not freed by Close: %v", err)
+	}
+}
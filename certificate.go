@@ -0,0 +1,126 @@
+package infnoise
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// certificateDomain prefixes the signed bytes so a certificate signature cannot be mistaken for any other.
+const certificateDomain = "infnoise-certificate-v1\n"
+
+// Certificate is a board's burn-in record, issued by its manufacturer after a passing soak test (see Soak) and
+// shipped with the board. SignCertificate signs it; buyers check it with VerifyCertificate and the manufacturer's
+// public key.
+type Certificate struct {
+	Version int `json:"version"`
+
+	// Serial is the manufacturer's serial number, e.g. the one printed on the board.
+	Serial string `json:"serial,omitempty"`
+
+	// BoardID is the ID in the board's EEPROM user area (see Device.Provision), if it has one.
+	BoardID string `json:"boardId,omitempty"`
+
+	Backend BackendName `json:"backend"`
+	Issued  time.Time   `json:"issued"`
+
+	// SoakStart to SoakEnd is the soak test the certificate is based on, during which Bytes of whitened output were
+	// read in Checks checks and Resets restarts.
+	SoakStart time.Time `json:"soakStart"`
+	SoakEnd   time.Time `json:"soakEnd"`
+	Bytes     uint64    `json:"bytes"`
+	Checks    int       `json:"checks"`
+	Resets    int       `json:"resets"`
+
+	// TotalBits, Estimates, and Tests are taken from the final health report.
+	TotalBits uint64       `json:"totalBits"`
+	Estimates []Estimate   `json:"estimates"`
+	Tests     []TestResult `json:"tests"`
+}
+
+// signedCertificate is the JSON form produced by SignCertificate. The signature covers the certificate's compact
+// encoding as it appears in the file, so verifying neither depends on re-encoding it identically nor breaks when the
+// file is re-indented.
+type signedCertificate struct {
+	Certificate json.RawMessage `json:"certificate"`
+	Signature   []byte          `json:"signature"`
+}
+
+// NewCertificate records a passing soak of the board described by info. It fails if the soak did not pass.
+func NewCertificate(info DeviceInfo, serial string, r SoakReport) (Certificate, error) {
+	if !r.Pass {
+		return Certificate{}, errors.New("soak test did not pass")
+	}
+
+	c := Certificate{
+		Version:   1,
+		Serial:    serial,
+		Backend:   info.Backend,
+		Issued:    time.Now().UTC(),
+		SoakStart: r.Start.UTC(),
+		SoakEnd:   r.End.UTC(),
+		Bytes:     r.Bytes,
+		Checks:    r.Checks,
+		Resets:    r.Resets,
+		TotalBits: r.Health.TotalBits,
+		Estimates: r.Health.Estimates,
+		Tests:     r.Health.Tests,
+	}
+
+	if info.HasID {
+		c.BoardID = info.ID.String()
+	}
+
+	return c, nil
+}
+
+// SignCertificate returns c as JSON signed with key.
+func SignCertificate(key ed25519.PrivateKey, c Certificate) ([]byte, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length %d", len(key))
+	}
+
+	body, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(signedCertificate{
+		Certificate: body,
+		Signature:   ed25519.Sign(key, append([]byte(certificateDomain), body...)),
+	}, "", "  ")
+}
+
+// VerifyCertificate checks that data was produced by SignCertificate with the private key of pub and returns the
+// certificate.
+func VerifyCertificate(pub ed25519.PublicKey, data []byte) (Certificate, error) {
+	var signed signedCertificate
+
+	err := json.Unmarshal(data, &signed)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("malformed certificate: %w", err)
+	}
+
+	msg := bytes.NewBufferString(certificateDomain)
+
+	err = json.Compact(msg, signed.Certificate)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("malformed certificate: %w", err)
+	}
+
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, msg.Bytes(), signed.Signature) {
+		return Certificate{}, errors.New("invalid certificate signature")
+	}
+
+	var c Certificate
+
+	err = json.Unmarshal(signed.Certificate, &c)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("malformed certificate: %w", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,74 @@
+package infnoise
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCertificate(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	r, err := Soak(context.Background(), dv, SoakOptions{Duration: 50 * time.Millisecond, Interval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewCertificate(DeviceInfo{Backend: BackendSimulator}, "INF-0001", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := SignCertificate(key, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := VerifyCertificate(pub, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Serial != "INF-0001" || got.Bytes != r.Bytes || len(got.Estimates) != len(r.Health.Estimates) {
+		t.Fatalf("verified certificate: %+v", got)
+	}
+
+	// Reformatting the file keeps the signature valid.
+	var compact bytes.Buffer
+
+	json.Compact(&compact, data)
+
+	_, err = VerifyCertificate(pub, compact.Bytes())
+	if err != nil {
+		t.Fatalf("compacted certificate: %v", err)
+	}
+
+	tampered := bytes.Replace(data, []byte("INF-0001"), []byte("INF-0002"), 1)
+
+	_, err = VerifyCertificate(pub, tampered)
+	if err == nil {
+		t.Fatal("tampered certificate verified")
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+
+	_, err = VerifyCertificate(other, data)
+	if err == nil {
+		t.Fatal("certificate verified with another key")
+	}
+
+	r.Pass = false
+
+	_, err = NewCertificate(DeviceInfo{}, "", r)
+	if err == nil {
+		t.Fatal("issued a certificate for a failed soak")
+	}
+}
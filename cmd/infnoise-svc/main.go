@@ -0,0 +1,144 @@
+//go:build windows
+// +build windows
+
+// Command infnoise-svc runs the Infinite Noise TRNG as a Windows service that hands out whitened
+// output to local processes over a named pipe (see package namedpipe for the protocol and client).
+//
+//	infnoise-svc install [-pipe name] [-sddl descriptor] [-backend name] [-client-rate n] [-rate n]
+//	                     [-seed-file file [-seed-interval d] [-seed-size n] [-seed-scope user|machine]]
+//	infnoise-svc start | stop | remove
+//	infnoise-svc run [-pipe name] [-sddl descriptor] [-backend name] [-client-rate n] [-rate n]
+//	                 [-seed-file file [-seed-interval d] [-seed-size n] [-seed-scope user|machine]]
+//
+// install registers the service for automatic start with the given flags baked into its command
+// line; run serves in the foreground (it is also what the service manager invokes).
+//
+// With -seed-file, the service also keeps a DPAPI-encrypted seed file fresh for applications that
+// cannot use the pipe, such as signing tools on CI machines that want local entropy: it rewrites the
+// file with whitened output at start and every -seed-interval (see seedfile.WriteProtected). With
+// -seed-scope machine, the default, any account on the machine can decrypt it, so put it in a
+// directory whose ACL admits only its consumers; with user, only the service's account can.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/coalaura/infnoise/namedpipe"
+	"github.com/coalaura/infnoise/seedfile"
+)
+
+const serviceName = "infnoise-svc"
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+
+	switch cmd {
+	case "install":
+		err = install(args)
+	case "remove":
+		err = remove()
+	case "start":
+		err = start()
+	case "stop":
+		err = stop()
+	case "run":
+		err = run(args)
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+
+		os.Exit(1)
+	}
+}
+
+type config struct {
+	pipe    string
+	sddl    string
+	backend string
+
+	clientRate int
+	rate       int
+
+	seedFile     string
+	seedInterval time.Duration
+	seedSize     int
+	seedScope    string
+}
+
+func parseFlags(name string, args []string) (*config, error) {
+	var conf config
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	fs.StringVar(&conf.pipe, "pipe", namedpipe.DefaultName, "named pipe to listen on")
+	fs.StringVar(&conf.sddl, "sddl", namedpipe.DefaultSDDL, "security descriptor (SDDL) applied to the pipe")
+	fs.StringVar(&conf.backend, "backend", "", "USB backend (default: platform native)")
+	fs.IntVar(&conf.clientRate, "client-rate", 0, "per-client limit in bytes per second (0: unlimited)")
+	fs.IntVar(&conf.rate, "rate", 0, "combined limit for all clients in bytes per second (0: unlimited)")
+	fs.StringVar(&conf.seedFile, "seed-file", "", "also keep this DPAPI-encrypted seed file fresh")
+	fs.DurationVar(&conf.seedInterval, "seed-interval", time.Hour, "how often to rewrite the seed file")
+	fs.IntVar(&conf.seedSize, "seed-size", 512, "size of the seed in bytes")
+	fs.StringVar(&conf.seedScope, "seed-scope", "machine", "who can decrypt the seed file: user (the service's account) or machine")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.seedInterval <= 0 || conf.seedSize <= 0 {
+		return nil, fmt.Errorf("invalid seed interval %s or size %d", conf.seedInterval, conf.seedSize)
+	}
+
+	_, err = conf.scope()
+	if err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// scope returns the DPAPI scope of the seed file.
+func (c *config) scope() (seedfile.Scope, error) {
+	switch c.seedScope {
+	case "user":
+		return seedfile.ScopeUser, nil
+	case "machine":
+		return seedfile.ScopeMachine, nil
+	}
+
+	return 0, fmt.Errorf("invalid seed scope %q (want user or machine)", c.seedScope)
+}
+
+func (c *config) args() []string {
+	return []string{
+		"run",
+		"-pipe", c.pipe,
+		"-sddl", c.sddl,
+		"-backend", c.backend,
+		"-client-rate", strconv.Itoa(c.clientRate),
+		"-rate", strconv.Itoa(c.rate),
+		"-seed-file", c.seedFile,
+		"-seed-interval", c.seedInterval.String(),
+		"-seed-size", strconv.Itoa(c.seedSize),
+		"-seed-scope", c.seedScope,
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s install|remove|start|stop|run [-pipe name] [-sddl descriptor] [-backend name] [-client-rate n] [-rate n] [-seed-file file] [-seed-interval d] [-seed-size n] [-seed-scope user|machine]\n", serviceName)
+
+	os.Exit(2)
+}
@@ -0,0 +1,41 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/coalaura/infnoise/seedfile"
+)
+
+// writeSeed replaces the seed file with a fresh seed from src.
+func writeSeed(src io.Reader, conf *config) error {
+	scope, err := conf.scope()
+	if err != nil {
+		return err
+	}
+
+	return seedfile.WriteProtected(src, conf.seedFile, conf.seedSize, 0o600, scope)
+}
+
+// refreshSeed rewrites the seed file every interval until stop is closed. A failed refresh is logged and leaves
+// the previous seed in place until the next one.
+func refreshSeed(src io.Reader, conf *config, stop <-chan struct{}) {
+	ticker := time.NewTicker(conf.seedInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := writeSeed(src, conf)
+			if err != nil {
+				log.Printf("refreshing %s: %v", conf.seedFile, err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,234 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/namedpipe"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func install(args []string) error {
+	conf, err := parseFlags("install", args)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+
+	defer m.Disconnect()
+
+	description := "Serves entropy from an Infinite Noise TRNG over " + conf.pipe
+
+	if conf.seedFile != "" {
+		description += " and keeps the seed file " + conf.seedFile + " fresh"
+	}
+
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "Infinite Noise TRNG",
+		Description: description,
+		StartType:   mgr.StartAutomatic,
+	}, conf.args()...)
+	if err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+func remove() error {
+	return withService(func(s *mgr.Service) error {
+		return s.Delete()
+	})
+}
+
+func start() error {
+	return withService(func(s *mgr.Service) error {
+		return s.Start()
+	})
+}
+
+func stop() error {
+	return withService(func(s *mgr.Service) error {
+		status, err := s.Control(svc.Stop)
+		if err != nil {
+			return err
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+
+		for status.State != svc.Stopped {
+			if time.Now().After(deadline) {
+				return errors.New("timed out waiting for service to stop")
+			}
+
+			time.Sleep(250 * time.Millisecond)
+
+			status, err = s.Query()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func withService(fn func(s *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("could not open service: %w", err)
+	}
+
+	defer s.Close()
+
+	return fn(s)
+}
+
+func run(args []string) error {
+	conf, err := parseFlags("run", args)
+	if err != nil {
+		return err
+	}
+
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+
+	if isService {
+		return svc.Run(serviceName, &handler{conf: conf})
+	}
+
+	_, done, err := serve(conf)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("serving entropy on %s", conf.pipe)
+
+	if conf.seedFile != "" {
+		log.Printf("refreshing %s every %s", conf.seedFile, conf.seedInterval)
+	}
+
+	return <-done
+}
+
+// serve opens the device and starts the pipe server, and the seed file refresher if configured, returning the
+// server and a channel that receives Serve's result.
+func serve(conf *config) (*namedpipe.Server, chan error, error) {
+	dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(conf.backend)))
+
+	err := dev.Start()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stopSeed := make(chan struct{})
+	seedDone := make(chan struct{})
+
+	if conf.seedFile == "" {
+		close(seedDone)
+	} else {
+		// Seeds come from the whitened stream the pipe serves, through their own buffer.
+		src := dev.Whitened()
+
+		// A seed file that cannot be written fails the start rather than going stale unnoticed.
+		err = writeSeed(src, conf)
+		if err != nil {
+			dev.Close()
+
+			return nil, nil, err
+		}
+
+		go func() {
+			refreshSeed(src, conf, stopSeed)
+
+			close(seedDone)
+		}()
+	}
+
+	server := namedpipe.NewServer(dev.Whitened(),
+		namedpipe.WithName(conf.pipe),
+		namedpipe.WithSDDL(conf.sddl),
+		namedpipe.WithClientRate(conf.clientRate),
+		namedpipe.WithRateLimit(conf.rate),
+	)
+
+	done := make(chan error, 1)
+
+	go func() {
+		err := server.Serve()
+
+		close(stopSeed)
+		<-seedDone
+
+		dev.Close()
+
+		done <- err
+	}()
+
+	return server, done, nil
+}
+
+type handler struct {
+	conf *config
+}
+
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	server, done, err := serve(h.conf)
+	if err != nil {
+		log.Printf("failed to start: %v", err)
+
+		return true, 1
+	}
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-done:
+			log.Printf("server stopped: %v", err)
+
+			return true, 1
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+
+				server.Close()
+
+				<-done
+
+				return false, 0
+			}
+		}
+	}
+}
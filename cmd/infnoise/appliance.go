@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/httpauth"
+	"github.com/coalaura/infnoise/lifecycle"
+	"github.com/coalaura/infnoise/osentropy"
+)
+
+// appliance runs the all-in-one deployment: it feeds the kernel, serves the device over HTTP behind a bearer
+// token or client certificate at a bounded rate, exports Prometheus metrics, and withholds output while the device is quarantined.
+func appliance(fs *flag.FlagSet) func() error {
+	var (
+		listen    string
+		tokenPath string
+		noAuth    bool
+		caPath    string
+		macPath   string
+		certPath  string
+		keyPath   string
+		feed      bool
+		feedRate  int
+		backend   string
+		config    string
+		probes    infnoise.Probes
+		auditPath string
+
+		rate, clientRate int
+
+		shutdownTimeout time.Duration
+	)
+
+	fs.StringVar(&listen, "listen", ":8080", "HTTP listen address")
+	fs.StringVar(&tokenPath, "token-file", "", "file holding the bearer token clients must send")
+	fs.BoolVar(&noAuth, "no-auth", false, "serve output without a token")
+	fs.StringVar(&caPath, "client-ca", "", "PEM file of CAs whose client certificates are admitted without a token (needs -tls-cert)")
+	fs.StringVar(&macPath, "mac-key-file", "", "file holding the key with which every response is signed (see package httpauth)")
+	fs.StringVar(&certPath, "tls-cert", "", "TLS certificate file (serves HTTPS with -tls-key)")
+	fs.StringVar(&keyPath, "tls-key", "", "TLS key file")
+	fs.IntVar(&rate, "rate", 0, "bytes per second served to all clients together (0 for unlimited)")
+	fs.IntVar(&clientRate, "client-rate", 0, "bytes per second served to each client IP (0 for unlimited)")
+	fs.BoolVar(&feed, "feed", true, "feed the kernel's random number generator")
+	fs.IntVar(&feedRate, "feed-rate", osentropy.DefaultRate, "bytes per second fed to the kernel")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+	fs.StringVar(&config, "config", "", "JSON device configuration file (infnoise.Config)")
+	probeFlags(fs, &probes)
+	fs.StringVar(&auditPath, "audit-log", "", "append a hash-chained audit log of the session to this file")
+	fs.DurationVar(&shutdownTimeout, "shutdown-timeout", lifecycle.DefaultTimeout, "how long to drain connections and stop before closing the device")
+
+	return func() (err error) {
+		auth, err := serverAuth(tokenPath, macPath)
+		if err != nil {
+			return err
+		}
+
+		var tlsConf *tls.Config
+
+		if caPath != "" {
+			if certPath == "" {
+				return errors.New("-client-ca needs -tls-cert and -tls-key")
+			}
+
+			tlsConf, err = clientCAConfig(caPath)
+			if err != nil {
+				return err
+			}
+
+			auth = append(auth, httpauth.WithClientCerts())
+		}
+
+		if tokenPath == "" && caPath == "" && !noAuth {
+			return errors.New("need -token-file or -client-ca, or -no-auth to serve without authentication")
+		}
+
+		conf, err := deviceConfig(config, backend)
+		if err != nil {
+			return err
+		}
+
+		audit, err := openAuditLog(auditPath)
+		if err != nil {
+			return err
+		}
+
+		// Closed once the device has logged its last entries.
+		defer closeAuditLog(audit)
+
+		dev := infnoise.NewWithConfig(conf, infnoise.WithAuditLog(audit))
+
+		life := lifecycle.New(lifecycle.WithTimeout(shutdownTimeout), lifecycle.WithLogf(log.Printf))
+
+		life.Add(lifecycle.PhaseDevice, "device", func(context.Context) error {
+			return dev.Close()
+		})
+
+		err = dev.Start()
+		if err != nil {
+			life.Shutdown()
+
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		life.Go(lifecycle.PhaseSinks, "event log", func(ctx context.Context) error {
+			return logEvents(ctx, dev)
+		})
+
+		mux := http.NewServeMux()
+		probe := dev.ProbeHandler(probes)
+
+		mux.Handle("GET /metrics", dev.MetricsHandler())
+		mux.Handle("GET /healthz", probe)
+		mux.Handle("GET /readyz", probe)
+		api := dev.Handler(infnoise.WithHandlerRate(rate), infnoise.WithHandlerClientRate(clientRate))
+
+		mux.Handle("/infnoise/", httpauth.Wrap(http.StripPrefix("/infnoise", quarantineGate(dev, api)), auth...))
+
+		srv := &http.Server{
+			Addr:              listen,
+			Handler:           recoverHandler(mux),
+			TLSConfig:         tlsConf,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		life.Serve("http", srv, func() error {
+			if certPath != "" {
+				return srv.ListenAndServeTLS(certPath, keyPath)
+			}
+
+			return srv.ListenAndServe()
+		})
+
+		if feed {
+			life.Go(lifecycle.PhaseProducers, "kernel feeder", supervised("kernel feeder", func(ctx context.Context) error {
+				err := osentropy.New(dev, osentropy.WithRate(feedRate)).Run(ctx)
+				if err != nil {
+					return fmt.Errorf("%w (run with -feed=false to only serve)", err)
+				}
+
+				return nil
+			}))
+		}
+
+		log.Printf("appliance serving on %s (feeding the kernel: %t)", listen, feed)
+
+		return life.Run(ctx)
+	}
+}
+
+// probeFlags registers the failure thresholds of the /healthz and /readyz probes.
+func probeFlags(fs *flag.FlagSet, p *infnoise.Probes) {
+	fs.DurationVar(&p.ReadyThreshold, "ready-threshold", 10*time.Second, "how long the device must be unready before /readyz fails")
+	fs.DurationVar(&p.LiveThreshold, "live-threshold", 0, "how long the device must be unready before /healthz fails too (0: never)")
+}
+
+// openAuditLog opens the -audit-log file for appending, or returns nil without a path.
+func openAuditLog(path string) (io.Writer, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func closeAuditLog(w io.Writer) {
+	if f, ok := w.(*os.File); ok {
+		f.Close()
+	}
+}
+
+// serverAuth reads the secrets named by the -token-file and -mac-key-file flags into httpauth options.
+func serverAuth(tokenPath, macPath string) ([]httpauth.Option, error) {
+	var opts []httpauth.Option
+
+	if tokenPath != "" {
+		token, err := readSecret(tokenPath)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, httpauth.WithAPIKeys(token))
+	}
+
+	if macPath != "" {
+		key, err := readSecret(macPath)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, httpauth.WithResponseMAC([]byte(key)))
+	}
+
+	return opts, nil
+}
+
+// readSecret reads a token or key from path, ignoring surrounding white space.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	secret := strings.TrimSpace(string(data))
+
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+
+	return secret, nil
+}
+
+// clientCAConfig makes the server ask for client certificates and verify them against the CAs in path. Clients
+// without one can still authenticate with the token.
+func clientCAConfig(path string) (*tls.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no PEM certificates", path)
+	}
+
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}, nil
+}
+
+// quarantineGate turns reads away while the device is quarantined, except for one at a time: reads are what run
+// the recovery that lifts the quarantine, and the device withholds their output until it has. Reads failing the
+// health check are answered with 503 by Handler itself.
+func quarantineGate(dev *infnoise.Device, next http.Handler) http.Handler {
+	var probing atomic.Bool
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.URL.Path == "/raw" || r.URL.Path == "/whitened") && dev.Quarantined() {
+			if !probing.CompareAndSwap(false, true) {
+				w.Header().Set("Retry-After", "1")
+
+				http.Error(w, "hardware quarantined", http.StatusServiceUnavailable)
+
+				return
+			}
+
+			defer probing.Store(false)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serverQuarantine is the number of health windows the daemons require to pass after a failure before they serve
+// output again, unless the configuration sets its own.
+const serverQuarantine = 3
+
+// deviceConfig loads the device configuration from file, if given, overridden by INFNOISE_* variables and then by
+// a non-empty backend flag. Output is quarantined after a health failure unless the configuration sets a negative
+// quarantine.
+func deviceConfig(file, backend string) (infnoise.Config, error) {
+	var conf infnoise.Config
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return conf, err
+		}
+
+		err = json.Unmarshal(data, &conf)
+		if err != nil {
+			return conf, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	err := conf.LoadEnv()
+	if err != nil {
+		return conf, err
+	}
+
+	if backend != "" {
+		conf.Backend = infnoise.BackendName(backend)
+	}
+
+	if conf.Quarantine == 0 {
+		conf.Quarantine = serverQuarantine
+	}
+
+	return conf, nil
+}
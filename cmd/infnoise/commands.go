@@ -0,0 +1,310 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/external"
+)
+
+// command is one subcommand. setup declares its flags on fs and returns the function running it once they are
+// parsed, so usage, completions, and the man page are generated from the same flags the command parses.
+type command struct {
+	name string
+
+	// synopsis lists the arguments, one line per element for long ones; summary is a one-line description and
+	// doc the full one.
+	synopsis []string
+	summary  string
+	doc      string
+
+	// env lets INFNOISE_<FLAG> environment variables, such as INFNOISE_TOKEN_FILE, set the flags.
+	env bool
+
+	setup func(fs *flag.FlagSet) func() error
+}
+
+// commands is filled in by init, since completion and man refer back to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			name: "read",
+			synopsis: []string{
+				"[-n bytes] [-seconds n] [-encoding binary|hex|base64|uint64] [-raw | -fold [-fold-factor k]]",
+				"[-backend name]",
+			},
+			summary: "write device output to stdout",
+			doc: "Writes whitened output to stdout (raw samples with -raw), endlessly unless -n (or -bytes) or -seconds is given. " +
+				"-fold writes raw output conditioned without cryptography instead, each bit the XOR of -fold-factor raw bits " +
+				"(by default as many as the measured entropy calls for), for certification schemes that disallow hashing. " +
+				"-encoding hex, base64, or uint64 writes text lines instead of binary. It exits quietly when the reader of its output goes away.",
+			setup: read,
+		},
+		{
+			name:     "setup-udev",
+			synopsis: []string{"[-group name] [-path file] [-dry-run]"},
+			summary:  "install the udev rule giving a group access to the board",
+			doc:      "Installs a udev rule giving the group access to the board and reloads udev; it must run as root.",
+			setup:    setupUdev,
+		},
+		{
+			name:     "install",
+			synopsis: []string{"[-root dir] [-bin file] [-group name] [-dry-run]"},
+			summary:  "install the systemd units, udev rule, and default configuration",
+			doc: "Installs the systemd units and udev rule the packages ship, pointing at this binary, in /etc/systemd/system " +
+				"and /etc/udev/rules.d, and the default configuration in /etc/infnoise unless it exists, then generates the " +
+				"appliance's token and reloads udev and systemd; it must run as root. -dry-run prints the files instead, and " +
+				"-root installs below a directory, such as an image being built, without running anything.",
+			setup: install,
+		},
+		{
+			name:     "provision",
+			synopsis: []string{"[-backend name]"},
+			summary:  "give the board a random ID",
+			doc:      "Gives the board a random ID in its EEPROM user area, unless it has one, and prints the ID.",
+			setup:    provision,
+		},
+		{
+			name:     "diag",
+			synopsis: []string{"[-n bytes] [-json] [-backend name]"},
+			summary:  "print the bias map of raw output",
+			doc: "Reads -n raw bytes and prints the density of ones per bit position and per switch address, marking outliers, " +
+				"which point to faults such as a weak multiplier stage.",
+			setup: diag,
+		},
+		{
+			name:     "test",
+			synopsis: []string{"[-n bytes] [-f file] [-raw] [-battery nist|ais31] [-json] [-backend name]"},
+			summary:  "run statistical tests on output or a capture",
+			doc: "Runs a subset of the NIST SP 800-22 tests and the SP 800-90B prediction estimators over -n bytes of whitened output " +
+				"(raw with -raw), or over a capture given with -f (- for stdin), and prints the p-values and entropy estimates. " +
+				"-battery ais31 runs the BSI AIS 31 test procedures A (T0 to T5) and B (T6 to T8) instead, over 4 MiB from the " +
+				"device by default. It exits with status 1 if a test failed.",
+			setup: test,
+		},
+		{
+			name:     "export",
+			synopsis: []string{"[-fifo file] [-word 1|2|4|8] [-rate n] [-n bytes] [-raw | -fold] [-backend name]"},
+			summary:  "stream output into external test suites",
+			doc: "Writes whitened output (raw with -raw, XOR-folded with -fold) to stdout, or with -fifo to the readers of a " +
+				"FIFO one after another, creating it if needed, until interrupted, for piping into PractRand or TestU01. " +
+				"It writes whole words of -word bytes only, at most -rate bytes per second if given, and -n bytes to each reader " +
+				"if given, and exits quietly when the reader of stdout goes away.",
+			setup: export,
+		},
+		{
+			name:     "suite",
+			synopsis: []string{"[-rate n] [-raw | -fold] [-json] [-backend name] practrand|testu01 [command [args...]]"},
+			summary:  "run PractRand or TestU01 on output",
+			doc: "Runs an external test suite on whitened output (raw with -raw, XOR-folded with -fold), fed on its stdin at " +
+				"most -rate bytes per second if given, and prints its verdict and the health status recording it (-json for both " +
+				"reports). practrand runs " + external.DefaultPractRand + " unless a command is given; testu01 needs the command " +
+				"of a harness reading 32-bit words from stdin and printing TestU01 battery summaries. The suite's own output " +
+				"goes to stderr. It exits with status 1 if the suite failed.",
+			setup: suite,
+		},
+		{
+			name:     "watch",
+			synopsis: []string{"[-interval duration] [-raw] [-backend name]"},
+			summary:  "show a live dashboard",
+			doc: "Reads continuously and redraws a dashboard every -interval: throughput, health status and entropy estimate, " +
+				"the ones density per bit position and switch address, read error counts, and reconnects, which it makes itself " +
+				"after transport and protocol errors.",
+			setup: watch,
+		},
+		{
+			name:     "list",
+			synopsis: []string{"[-json]"},
+			summary:  "list the attached boards",
+			doc: "Prints the attached boards (bus path, device node, serial, firmware release, speed, driver) without opening them; " +
+				"it reads sysfs and only works on Linux.",
+			setup: list,
+		},
+		{
+			name:     "status",
+			synopsis: []string{"[-n bytes] [-json] [-backend name]"},
+			summary:  "print the board's identity, health, and counters",
+			doc: "Opens the board, reads -n whitened bytes, and prints its board ID, health, entropy estimates, and counters. " +
+				"It exits with status 1 if the health check failed.",
+			setup: status,
+		},
+		{
+			name:     "doctor",
+			synopsis: []string{"[-json]"},
+			summary:  "find out why the board cannot be opened, such as in a container",
+			doc: "Follows the path from the host's USB stack to an open board and stops where it breaks: the board in sysfs, " +
+				"the usbfs mount, its device node, the device cgroup and permissions, and seccomp or LSM policy on usbfs " +
+				"ioctls. Each failure comes with the container option or host setting that fixes it. It exits with status 1 " +
+				"if a check failed; it only works on Linux.",
+			setup: doctor,
+		},
+		{
+			name: "soak",
+			synopsis: []string{
+				"[-d duration] [-interval duration] [-reset duration] [-log file] [-backend name]",
+				"[-cert file -key file [-serial serial]]",
+			},
+			summary: "burn in a board",
+			doc: "Burns in a board: it reads continuously, checks it periodically (self-test, health, and the SP 800-22 battery " +
+				"over the output read since the last check), logs each check to -log as JSON lines, and " +
+				"writes a JSON pass/fail report to stdout. It exits with status 1 if the board failed. With -cert, a passing board " +
+				"gets a burn-in certificate signed with the Ed25519 key in -key (a hex-encoded 32-byte seed).",
+			setup: soak,
+		},
+		{
+			name:     "verify-cert",
+			synopsis: []string{"-pub key file"},
+			summary:  "check a burn-in certificate",
+			doc:      "Checks a certificate against the manufacturer's hex-encoded public key and prints it.",
+			setup:    verifyCert,
+		},
+		{
+			name:     "seed",
+			synopsis: []string{"[-path file] [-creditable] [-backend name] [-unit]"},
+			summary:  "write a seed file for systemd-random-seed",
+			doc: "Writes a seed file for systemd-random-seed; -unit instead prints a systemd unit that runs it at boot and " +
+				"shutdown (install it as infnoise-seed.service and enable it).",
+			setup: seed,
+		},
+		{
+			name: "appliance",
+			synopsis: []string{
+				"[-listen addr] [-token-file file] [-client-ca file] [-no-auth] [-tls-cert file -tls-key file] [-mac-key-file file]",
+				"[-rate n] [-client-rate n] [-feed=false] [-feed-rate n] [-backend name] [-config file]",
+				"[-ready-threshold d] [-live-threshold d] [-shutdown-timeout d] [-audit-log file]",
+			},
+			summary: "feed the kernel and serve output and metrics over HTTP",
+			doc: "Feeds the kernel, serves the device's HTTP API under /infnoise/ (to clients sending the bearer token or, with " +
+				"-client-ca, a certificate signed by one of its CAs, at up to " +
+				"-rate bytes per second overall and -client-rate per client IP), and exports Prometheus metrics at /metrics, " +
+				"until interrupted. After a health failure, output is withheld until the device's quarantine (3 health " +
+				"windows unless the configuration sets another; negative disables it) has passed. With -mac-key-file, every " +
+				"response carries an HMAC under the key's contents, which clients check with httpauth.Transport. " +
+				"Each flag can also be set by an environment variable, INFNOISE_ and its name in upper case with underscores " +
+				"(INFNOISE_LISTEN, INFNOISE_TOKEN_FILE, ...), and each device setting of the -config file by INFNOISE_ and " +
+				"its name in upper snake case (INFNOISE_TARGET_ENTROPY, INFNOISE_IO_BATCH, ...). Flags take precedence over " +
+				"the environment, and the environment over the file. /healthz and /readyz are unauthenticated probes for " +
+				"orchestrators: /readyz fails once the device has been unready (not open, failing its health check, or " +
+				"starved of output) for -ready-threshold, and /healthz too after -live-threshold, if set. On SIGINT or " +
+				"SIGTERM it drains connections, stops feeding, and then wipes and closes the device, within -shutdown-timeout. -audit-log appends a " +
+				"hash-chained record of the session (configuration, health transitions, reseeds, and the hash of every " +
+				"whitened chunk) to a file; check it with infnoise.VerifyAuditLog.",
+			env:   true,
+			setup: appliance,
+		},
+		{
+			name: "sidecar",
+			synopsis: []string{
+				"[-socket file] [-mode perm] [-token-file file] [-mac-key-file file] [-backend name] [-config file] [-probe-listen addr]",
+				"[-ready-threshold d] [-live-threshold d] [-shutdown-timeout d] [-audit-log file]",
+			},
+			summary: "serve output and metrics on a Unix socket, for the containers of a pod",
+			doc: "Serves the device's HTTP API (output withheld during a quarantine, as for appliance) and Prometheus metrics at /metrics " +
+				"on a Unix socket, until interrupted. -token-file and -mac-key-file work as for appliance. Run it as a sidecar container holding the board, with the socket in an " +
+				"emptyDir volume that only the containers allowed to draw entropy mount; they read it with " +
+				"infnoise.NewRemoteDevice(\"http://infnoise\", infnoise.UnixSocketClient(socket)). Its flags and device settings " +
+				"can be set through the environment, as for appliance. The /healthz and /readyz probes of appliance are " +
+				"served on the socket and, with -probe-listen, alone on a TCP address for the kubelet; -audit-log and the " +
+				"shutdown are as for appliance.",
+			env:   true,
+			setup: sidecar,
+		},
+		{
+			name:     "release",
+			synopsis: []string{"-version version [-release n] [-arch goarch] [-binary file] [-out dir] [-formats deb,rpm]"},
+			summary:  "build Debian and RPM packages",
+			doc: "Writes Debian and RPM packages of a Linux infnoise binary (this one by default) to -out: the binary, its " +
+				"manual page and completions, the udev rule, systemd units for the appliance and boot seeds, and a default " +
+				"configuration in /etc/infnoise. Installing a package generates the appliance's token; enable the daemon " +
+				"with \"systemctl enable --now infnoise\". SOURCE_DATE_EPOCH sets the file times for reproducible builds.",
+			setup: release,
+		},
+		{
+			name:     "completion",
+			synopsis: []string{"bash|zsh|fish"},
+			summary:  "print a shell completion script",
+			doc: "Prints a completion script for the shell. Source it from the shell's startup file, or install it as " +
+				"/usr/share/bash-completion/completions/infnoise, a _infnoise file on $fpath, or " +
+				"/usr/share/fish/vendor_completions.d/infnoise.fish.",
+			setup: completion,
+		},
+		{
+			name:     "man",
+			synopsis: nil,
+			summary:  "print the manual page",
+			doc:      "Prints this manual in roff format, for installing as /usr/share/man/man1/infnoise.1.",
+			setup:    man,
+		},
+	}
+}
+
+// setFromEnv sets the flags of fs from their environment variables, before the command line is parsed and
+// overrides them.
+func setFromEnv(fs *flag.FlagSet) error {
+	var err error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		key := infnoise.EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+
+		if v := os.Getenv(key); v != "" && err == nil {
+			if serr := fs.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("invalid %s %q: %w", key, v, serr)
+			}
+		}
+	})
+
+	return err
+}
+
+func lookup(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+
+	return command{}, false
+}
+
+// flagSet returns c's flags, declared on a set that discards its own error output.
+func (c command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+
+	fs.SetOutput(io.Discard)
+
+	c.setup(fs)
+
+	return fs
+}
+
+// usage prints the synopsis of every command and exits with code.
+func usage(code int) {
+	for i, c := range commands {
+		prefix := "usage: "
+		if i > 0 {
+			prefix = "       "
+		}
+
+		line := prefix + "infnoise " + c.name
+
+		for j, s := range c.synopsis {
+			if j > 0 {
+				line = strings.Repeat(" ", len(prefix+"infnoise "+c.name))
+			}
+
+			fmt.Fprintln(os.Stderr, line+" "+s)
+		}
+
+		if len(c.synopsis) == 0 {
+			fmt.Fprintln(os.Stderr, line)
+		}
+	}
+
+	os.Exit(code)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/coalaura/infnoise"
+)
+
+// flagValues lists the values offered when completing a flag's argument; fileFlags take a path.
+var (
+	flagValues = map[string]func() []string{
+		"backend": func() []string {
+			var names []string
+
+			for _, b := range infnoise.Backends() {
+				names = append(names, string(b))
+			}
+
+			return names
+		},
+		"battery": func() []string {
+			return []string{"nist", "ais31"}
+		},
+		"encoding": func() []string {
+			return []string{"binary", "hex", "base64", "uint64"}
+		},
+		"word": func() []string {
+			return []string{"1", "2", "4", "8"}
+		},
+	}
+
+	fileFlags = []string{"audit-log", "bin", "binary", "cert", "client-ca", "config", "f", "fifo", "key", "log", "mac-key-file", "out", "path", "root", "socket", "tls-cert", "tls-key", "token-file"}
+)
+
+// completionFlag is a flag as the completion scripts see it.
+type completionFlag struct {
+	name, usage string
+	isBool      bool
+	values      []string
+	file        bool
+}
+
+func completionFlags(c command) []completionFlag {
+	var out []completionFlag
+
+	c.flagSet().VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{name: f.Name, usage: f.Usage, file: slices.Contains(fileFlags, f.Name)}
+
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			cf.isBool = true
+		}
+
+		if values, ok := flagValues[f.Name]; ok {
+			cf.values = values()
+		}
+
+		out = append(out, cf)
+	})
+
+	return out
+}
+
+// completion prints a completion script for the shell named by its argument.
+func completion(fs *flag.FlagSet) func() error {
+	return func() error {
+		if fs.NArg() != 1 {
+			return errors.New("need a shell: bash, zsh, or fish")
+		}
+
+		switch fs.Arg(0) {
+		case "bash":
+			return bashCompletion(os.Stdout)
+		case "zsh":
+			return zshCompletion(os.Stdout)
+		case "fish":
+			return fishCompletion(os.Stdout)
+		default:
+			return fmt.Errorf("unknown shell %q (want bash, zsh, or fish)", fs.Arg(0))
+		}
+	}
+}
+
+func commandNames() []string {
+	var names []string
+
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+
+	return names
+}
+
+func bashCompletion(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("# bash completion for infnoise\n\n_infnoise() {\n")
+	b.WriteString("\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}\n\n")
+	fmt.Fprintf(&b, "\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\tfi\n\n", strings.Join(commandNames(), " "))
+
+	// Flag arguments are completed by flag name, whatever the command.
+	b.WriteString("\tcase $prev in\n")
+
+	seen := map[string]bool{}
+
+	for _, c := range commands {
+		for _, f := range completionFlags(c) {
+			if seen[f.name] || (f.values == nil && !f.file) {
+				continue
+			}
+
+			seen[f.name] = true
+
+			if f.file {
+				fmt.Fprintf(&b, "\t-%s | --%s)\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn\n\t\t;;\n", f.name, f.name)
+			} else {
+				fmt.Fprintf(&b, "\t-%s | --%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\t\t;;\n", f.name, f.name, strings.Join(f.values, " "))
+			}
+		}
+	}
+
+	b.WriteString("\tesac\n\n\tcase ${COMP_WORDS[1]} in\n")
+
+	for _, c := range commands {
+		var words []string
+
+		for _, f := range completionFlags(c) {
+			words = append(words, "-"+f.name)
+		}
+
+		if c.name == "completion" {
+			words = []string{"bash", "zsh", "fish"}
+		}
+
+		if len(words) > 0 {
+			fmt.Fprintf(&b, "\t%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\t;;\n", c.name, strings.Join(words, " "))
+		}
+	}
+
+	b.WriteString("\tesac\n}\n\ncomplete -o default -F _infnoise infnoise\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// zshQuote escapes s for the inside of a single-quoted _arguments spec.
+func zshQuote(s string) string {
+	return strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+func zshCompletion(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("#compdef infnoise\n\n_infnoise() {\n\tlocal -a commands\n\tcommands=(\n")
+
+	for _, c := range commands {
+		fmt.Fprintf(&b, "\t\t'%s:%s'\n", c.name, zshQuote(c.summary))
+	}
+
+	b.WriteString("\t)\n\n\tif (( CURRENT == 2 )); then\n\t\t_describe command commands\n\t\treturn\n\tfi\n\n\tcase $words[2] in\n")
+
+	for _, c := range commands {
+		fmt.Fprintf(&b, "\t%s)\n\t\t_arguments", c.name)
+
+		for _, f := range completionFlags(c) {
+			spec := fmt.Sprintf("-%s[%s]", f.name, zshQuote(f.usage))
+
+			switch {
+			case f.isBool:
+			case f.file:
+				spec += ":file:_files"
+			case f.values != nil:
+				spec += fmt.Sprintf(":%s:(%s)", f.name, strings.Join(f.values, " "))
+			default:
+				spec += ":" + f.name + ":"
+			}
+
+			fmt.Fprintf(&b, " \\\n\t\t\t'%s'", spec)
+		}
+
+		switch c.name {
+		case "completion":
+			b.WriteString(" \\\n\t\t\t'1:shell:(bash zsh fish)'")
+		case "verify-cert":
+			b.WriteString(" \\\n\t\t\t'1:certificate:_files'")
+		}
+
+		b.WriteString("\n\t\t;;\n")
+	}
+
+	b.WriteString("\tesac\n}\n\nif [ \"$funcstack[1]\" = _infnoise ]; then\n\t_infnoise \"$@\"\nelse\n\tcompdef _infnoise infnoise\nfi\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// fishQuote quotes s as a fish single-quoted string.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func fishCompletion(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("# fish completion for infnoise\n\ncomplete -c infnoise -f\n\n")
+
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c infnoise -n __fish_use_subcommand -a %s -d %s\n", c.name, fishQuote(c.summary))
+	}
+
+	for _, c := range commands {
+		b.WriteString("\n")
+
+		cond := fishQuote("__fish_seen_subcommand_from " + c.name)
+
+		for _, f := range completionFlags(c) {
+			fmt.Fprintf(&b, "complete -c infnoise -n %s -o %s -d %s", cond, f.name, fishQuote(f.usage))
+
+			switch {
+			case f.isBool:
+			case f.file:
+				b.WriteString(" -r -F")
+			case f.values != nil:
+				fmt.Fprintf(&b, " -x -a %s", fishQuote(strings.Join(f.values, " ")))
+			default:
+				b.WriteString(" -x")
+			}
+
+			b.WriteString("\n")
+		}
+
+		switch c.name {
+		case "completion":
+			fmt.Fprintf(&b, "complete -c infnoise -n %s -a 'bash zsh fish'\n", cond)
+		case "verify-cert":
+			fmt.Fprintf(&b, "complete -c infnoise -n %s -F\n", cond)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// roffEscape escapes s for running text in a man page.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+
+	// A leading dot or quote would start a request.
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+
+	return s
+}
+
+// man prints the manual page.
+func man(fs *flag.FlagSet) func() error {
+	return func() error {
+		return writeMan(os.Stdout)
+	}
+}
+
+func writeMan(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString(".TH INFNOISE 1 \"\" infnoise \"User Commands\"\n")
+	b.WriteString(".SH NAME\ninfnoise \\- read from an Infinite Noise TRNG and set the board up\n")
+	b.WriteString(".SH SYNOPSIS\n.B infnoise\n.I command\n.RI [ flags ]\n")
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString("infnoise reads whitened or raw output from an Infinite Noise TRNG, checks the board's health, and sets it up. ")
+	b.WriteString("Flags may be written with one dash or two.\n")
+	b.WriteString(".SH COMMANDS\n")
+
+	for _, c := range commands {
+		fmt.Fprintf(&b, ".SS \"%s %s\"\n", c.name, roffEscape(strings.Join(c.synopsis, " ")))
+		fmt.Fprintf(&b, "%s\n", roffEscape(c.doc))
+
+		c.flagSet().VisitAll(func(f *flag.Flag) {
+			name, usage := flag.UnquoteUsage(f)
+
+			if name == "" {
+				fmt.Fprintf(&b, ".TP\n.B \\-%s\n", roffEscape(f.Name))
+			} else {
+				fmt.Fprintf(&b, ".TP\n.BI \\-%s \" %s\"\n", roffEscape(f.Name), roffEscape(name))
+			}
+
+			b.WriteString(roffEscape(usage))
+
+			if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "0s" {
+				fmt.Fprintf(&b, " (default %s)", roffEscape(f.DefValue))
+			}
+
+			b.WriteString("\n")
+		})
+	}
+
+	b.WriteString(".SH EXIT STATUS\n0 on success, 1 if the command failed (including failed tests and health checks), 2 on a usage error.\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/coalaura/infnoise"
+)
+
+// diag reads raw output and prints the ones density per bit position and per switch address, flagging cells that
+// deviate from the overall density by more than five standard errors.
+func diag(fs *flag.FlagSet) func() error {
+	var (
+		n       int64
+		asJSON  bool
+		backend string
+	)
+
+	fs.Int64Var(&n, "n", 1<<20, "raw bytes to analyze")
+	fs.BoolVar(&asJSON, "json", false, "print the bias map as JSON")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() (err error) {
+		dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+		defer dev.Close()
+
+		err = dev.Start()
+		if err != nil {
+			return err
+		}
+
+		// A failing health check is what diag is for, so the map is printed even if reading stopped early.
+		_, readErr := io.CopyN(io.Discard, dev.Raw(), n)
+
+		bias := dev.HealthReport().Bias
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+
+			enc.SetIndent("", "  ")
+
+			err = enc.Encode(bias)
+		} else {
+			err = printBias(os.Stdout, &bias)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return readErr
+	}
+}
+
+func printBias(w io.Writer, bias *infnoise.BiasMap) error {
+	overall := bias.Overall()
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "overall\t%.4f\t%d bits\t\n\n", overall.Ratio(), overall.Bits)
+
+	row := func(label string, i int, d infnoise.Density) {
+		if d.Bits == 0 {
+			return
+		}
+
+		z := d.Deviation(overall)
+
+		mark := ""
+		if z > 5 || z < -5 {
+			mark = "!"
+		}
+
+		fmt.Fprintf(tw, "%s %d\t%.4f\t%+.1f σ\t%s\n", label, i, d.Ratio(), z, mark)
+	}
+
+	for i, d := range bias.Positions {
+		row("bit", i, d)
+	}
+
+	fmt.Fprintln(tw)
+
+	for i, d := range bias.Addresses {
+		row("address", i, d)
+	}
+
+	return tw.Flush()
+}
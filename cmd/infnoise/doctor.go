@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/coalaura/infnoise"
+)
+
+// doctor checks the path from the host's USB stack to an open board, for containers that cannot find one.
+func doctor(fs *flag.FlagSet) func() error {
+	var asJSON bool
+
+	fs.BoolVar(&asJSON, "json", false, "print the checks as a JSON array")
+
+	return func() (err error) {
+		diagnoses, err := infnoise.DiagnosePassthrough()
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			err = printJSON(diagnoses)
+			if err != nil {
+				return err
+			}
+		} else {
+			for _, d := range diagnoses {
+				mark := "ok"
+				if !d.OK {
+					mark = "FAIL"
+				}
+
+				fmt.Printf("%-4s  %-11s  %s\n", mark, d.Check, d.Detail)
+
+				if d.Hint != "" {
+					fmt.Printf("%-4s  %-11s  %s\n", "", "", d.Hint)
+				}
+			}
+		}
+
+		if last := diagnoses[len(diagnoses)-1]; !last.OK {
+			return errors.New(last.Check + " check failed")
+		}
+
+		return nil
+	}
+}
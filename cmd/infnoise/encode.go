@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// encodings lists the output encodings of infnoise read, with the bytes per output line and the line format.
+// base64 lines hold 57 bytes, so they are 76 characters long and only the last is padded.
+var encodings = map[string]struct {
+	group  int
+	format func([]byte) string
+}{
+	"hex":    {32, hex.EncodeToString},
+	"base64": {57, base64.StdEncoding.EncodeToString},
+	"uint64": {8, func(b []byte) string { return strconv.FormatUint(binary.LittleEndian.Uint64(b), 10) }},
+}
+
+// lineEncoder writes groups of bytes as lines of text.
+type lineEncoder struct {
+	w       *bufio.Writer
+	group   int
+	format  func([]byte) string
+	partial bool
+	pending []byte
+}
+
+// newEncoder returns a writer encoding to w as name ("binary", "hex", "base64", or "uint64"), which must be
+// closed to flush it. uint64 writes one little-endian word per line and drops a trailing partial word.
+func newEncoder(w io.Writer, name string) (io.WriteCloser, error) {
+	bw := bufio.NewWriter(w)
+
+	if name == "binary" {
+		return flushCloser{bw}, nil
+	}
+
+	e, ok := encodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q (want binary, hex, base64, or uint64)", name)
+	}
+
+	return &lineEncoder{w: bw, group: e.group, format: e.format, partial: name != "uint64"}, nil
+}
+
+func (e *lineEncoder) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		take := min(e.group-len(e.pending), len(p))
+
+		e.pending = append(e.pending, p[:take]...)
+		p = p[take:]
+
+		if len(e.pending) < e.group {
+			break
+		}
+
+		_, err := e.w.WriteString(e.format(e.pending) + "\n")
+		if err != nil {
+			return 0, err
+		}
+
+		e.pending = e.pending[:0]
+	}
+
+	return n, nil
+}
+
+// Close writes the last, short line and flushes.
+func (e *lineEncoder) Close() error {
+	if len(e.pending) > 0 && e.partial {
+		_, err := e.w.WriteString(e.format(e.pending) + "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.w.Flush()
+}
+
+type flushCloser struct {
+	*bufio.Writer
+}
+
+func (f flushCloser) Close() error {
+	return f.Flush()
+}
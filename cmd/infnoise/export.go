@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/external"
+)
+
+// openOutput starts the device and returns it with its whitened output, or its raw or XOR-folded output.
+func openOutput(backend string, raw, fold bool) (*infnoise.Device, io.Reader, error) {
+	if raw && fold {
+		return nil, nil, errors.New("-raw and -fold are mutually exclusive")
+	}
+
+	dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+	err := dev.Start()
+	if err != nil {
+		dev.Close()
+
+		return nil, nil, err
+	}
+
+	switch {
+	case raw:
+		return dev, dev.Raw(), nil
+	case fold:
+		return dev, dev.Folded(), nil
+	default:
+		return dev, dev.Whitened(), nil
+	}
+}
+
+func export(fs *flag.FlagSet) func() error {
+	var (
+		fifo    string
+		word    int
+		rate    int
+		n       int64
+		raw     bool
+		fold    bool
+		backend string
+	)
+
+	fs.StringVar(&fifo, "fifo", "", "serve the readers of this FIFO one after another, creating it if needed, instead of stdout")
+	fs.IntVar(&word, "word", 4, "size in bytes of the words the reader consumes: 1, 2, 4, or 8")
+	fs.IntVar(&rate, "rate", 0, "bytes per second (0: as fast as the reader takes them)")
+	fs.Int64Var(&n, "n", 0, "number of bytes to write to each reader (0: no limit)")
+	fs.BoolVar(&raw, "raw", false, "write the raw bitstream instead of whitened output")
+	fs.BoolVar(&fold, "fold", false, "write XOR-folded raw output instead of whitened output")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() error {
+		if n < 0 || rate < 0 {
+			return errors.New("limits must not be negative")
+		}
+
+		if word != 1 && word != 2 && word != 4 && word != 8 {
+			return fmt.Errorf("invalid word size %d (want 1, 2, 4, or 8)", word)
+		}
+
+		ignoreSIGPIPE()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if fifo != "" {
+			created, err := makeFIFO(fifo)
+			if err != nil {
+				return err
+			}
+
+			if created {
+				defer os.Remove(fifo)
+			}
+		}
+
+		dev, src, err := openOutput(backend, raw, fold)
+		if err != nil {
+			return err
+		}
+
+		defer dev.Close()
+
+		exportTo := func(w io.Writer) error {
+			_, err := external.Export(ctx, w, src, external.WithWord(word), external.WithRate(rate), external.WithLimit(n))
+
+			// The reader going away, as when PractRand has seen enough, ends its output as intended.
+			if brokenPipe(err) || ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if fifo == "" {
+			return exportTo(os.Stdout)
+		}
+
+		for ctx.Err() == nil {
+			f, err := openFIFO(ctx, fifo)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				return err
+			}
+
+			err = exportTo(f)
+
+			f.Close()
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// openFIFO opens the FIFO at path for writing once a reader opens it, or gives up when ctx is cancelled. Opening
+// cannot be interrupted, so a cancelled open is left blocked; export exits right after.
+func openFIFO(ctx context.Context, path string) (*os.File, error) {
+	type opened struct {
+		f   *os.File
+		err error
+	}
+
+	done := make(chan opened, 1)
+
+	go func() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+
+		done <- opened{f, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.f, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// suiteReport is what suite prints with -json: the suite's verdict and the device's health report recording it.
+type suiteReport struct {
+	Result external.Result `json:"result"`
+	Health infnoise.Report `json:"health"`
+}
+
+func suite(fs *flag.FlagSet) func() error {
+	var (
+		rate    int
+		raw     bool
+		fold    bool
+		asJSON  bool
+		backend string
+	)
+
+	fs.IntVar(&rate, "rate", 0, "bytes per second fed to the suite (0: as fast as it takes them)")
+	fs.BoolVar(&raw, "raw", false, "test the raw bitstream instead of whitened output")
+	fs.BoolVar(&fold, "fold", false, "test XOR-folded raw output instead of whitened output")
+	fs.BoolVar(&asJSON, "json", false, "print the verdict and the health report recording it as JSON")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() error {
+		if fs.NArg() < 1 {
+			return errors.New("usage: infnoise suite [flags] practrand|testu01 [command [args...]]")
+		}
+
+		if rate < 0 {
+			return errors.New("-rate must not be negative")
+		}
+
+		var s external.Suite
+
+		switch name, args := fs.Arg(0), fs.Args()[1:]; {
+		case name == "practrand" && len(args) == 0:
+			s = external.PractRand()
+		case name == "practrand":
+			s = external.PractRand()
+
+			s.Command, s.Args = args[0], args[1:]
+		case name == "testu01" && len(args) == 0:
+			return errors.New("testu01 needs the command of a harness running TestU01 on stdin")
+		case name == "testu01":
+			s = external.TestU01(args[0], args[1:]...)
+		default:
+			return fmt.Errorf("unknown suite %q (want practrand or testu01)", name)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		dev, src, err := openOutput(backend, raw, fold)
+		if err != nil {
+			return err
+		}
+
+		defer dev.Close()
+
+		// The suite's progress goes to stderr, leaving stdout to the verdict.
+		r, err := external.Run(ctx, src, s, external.WithRate(rate), external.WithOutput(os.Stderr))
+		if err != nil {
+			return err
+		}
+
+		dev.RecordTest(r.TestResult())
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+
+			enc.SetIndent("", "  ")
+
+			err = enc.Encode(suiteReport{Result: r, Health: dev.HealthReport()})
+		} else {
+			err = printSuite(os.Stdout, r, dev.HealthReport())
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !r.Pass {
+			return fmt.Errorf("the device failed %s", r.Suite)
+		}
+
+		return nil
+	}
+}
+
+func printSuite(w io.Writer, r external.Result, health infnoise.Report) error {
+	fmt.Fprintf(w, "%s: %s, %d tests over %d bytes streamed\n", r.Suite, passFail(r.Pass), r.Tests, r.Bytes)
+
+	if len(r.Anomalies) > 0 {
+		fmt.Fprintln(w)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(tw, "anomaly\tp-value\tevaluation\t")
+
+		for _, a := range r.Anomalies {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", a.Test, a.PValue, a.Evaluation)
+		}
+
+		tw.Flush()
+	}
+
+	_, err := fmt.Fprintf(w, "\nhealth: %s\n", health.Status)
+
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/packaging"
+)
+
+// install writes the embedded systemd units, udev rule, and default configuration for this binary to the
+// administrator's directories, then runs the packages' post-install script.
+func install(fs *flag.FlagSet) func() error {
+	var (
+		root   string
+		bin    string
+		group  string
+		dryRun bool
+	)
+
+	fs.StringVar(&root, "root", "/", "install below this directory, without running the post-install script")
+	fs.StringVar(&bin, "bin", "", "binary the units run (default: this executable)")
+	fs.StringVar(&group, "group", infnoise.DefaultUdevGroup, "group granted access to the board")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the files instead of installing them")
+
+	return func() (err error) {
+		if bin == "" {
+			bin, err = os.Executable()
+			if err == nil {
+				bin, err = filepath.EvalSymlinks(bin)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// Units and rules go where local administrators put theirs, overriding any from a package.
+		layout := packaging.DefaultLayout
+
+		layout.Bin = bin
+		layout.UnitDir = "/etc/systemd/system"
+		layout.RuleDir = "/etc/udev/rules.d"
+		layout.Group = group
+
+		files, err := packaging.Assets(layout)
+		if err != nil {
+			return err
+		}
+
+		postInstall, _, _, err := packaging.Scripts(layout)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			for _, f := range files {
+				if !f.Mode.IsDir() {
+					fmt.Printf("# %s\n%s\n", f.Path, f.Data)
+				}
+			}
+
+			fmt.Printf("# post-install script\n%s", postInstall)
+
+			return nil
+		}
+
+		if runtime.GOOS != "linux" {
+			return errors.New("the units and udev rule only apply on Linux")
+		}
+
+		for _, f := range files {
+			path := filepath.Join(root, f.Path)
+
+			if f.Mode.IsDir() {
+				err = os.MkdirAll(path, f.Mode.Perm())
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			// An existing configuration is the administrator's.
+			if _, err := os.Stat(path); err == nil && f.Config {
+				fmt.Printf("kept %s\n", path)
+
+				continue
+			}
+
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+			if err == nil {
+				err = os.WriteFile(path, f.Data, f.Mode.Perm())
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("installed %s\n", path)
+		}
+
+		if filepath.Clean(root) != "/" {
+			return nil
+		}
+
+		out, err := exec.Command("/bin/sh", "-c", postInstall, "postinst", "configure").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("post-install script failed: %w: %s", err, out)
+		}
+
+		fmt.Println("run \"systemctl enable --now infnoise\" to start the appliance")
+
+		return nil
+	}
+}
@@ -0,0 +1,520 @@
+// Command infnoise reads from an Infinite Noise TRNG and helps set the board up.
+//
+//	infnoise read [-n bytes] [-seconds n] [-encoding binary|hex|base64|uint64] [-raw | -fold [-fold-factor k]]
+//	              [-backend name]
+//	infnoise setup-udev [-group name] [-path file] [-dry-run]
+//	infnoise install [-root dir] [-bin file] [-group name] [-dry-run]
+//	infnoise provision [-backend name]
+//	infnoise diag [-n bytes] [-json] [-backend name]
+//	infnoise test [-n bytes] [-f file] [-raw] [-battery nist|ais31] [-json] [-backend name]
+//	infnoise export [-fifo file] [-word 1|2|4|8] [-rate n] [-n bytes] [-raw | -fold] [-backend name]
+//	infnoise suite [-rate n] [-raw | -fold] [-json] [-backend name] practrand|testu01 [command [args...]]
+//	infnoise watch [-interval duration] [-raw] [-backend name]
+//	infnoise list [-json]
+//	infnoise status [-n bytes] [-json] [-backend name]
+//	infnoise doctor [-json]
+//	infnoise soak [-d duration] [-interval duration] [-reset duration] [-log file] [-backend name]
+//	              [-cert file -key file [-serial serial]]
+//	infnoise verify-cert -pub key file
+//	infnoise seed [-path file] [-creditable] [-backend name] [-unit]
+//	infnoise appliance [-listen addr] [-token-file file | -no-auth] [-tls-cert file -tls-key file]
+//	                   [-feed=false] [-feed-rate n] [-backend name] [-config file]
+//	                   [-ready-threshold d] [-live-threshold d] [-shutdown-timeout d] [-audit-log file]
+//	infnoise sidecar [-socket file] [-mode perm] [-backend name] [-config file] [-probe-listen addr]
+//	                 [-ready-threshold d] [-live-threshold d] [-shutdown-timeout d] [-audit-log file]
+//	infnoise release -version version [-release n] [-arch goarch] [-binary file] [-out dir] [-formats deb,rpm]
+//	infnoise completion bash|zsh|fish
+//	infnoise man
+//
+// read writes whitened output to stdout (raw samples with -raw), endlessly unless -n (or -bytes) or -seconds is
+// given. -fold writes raw output XOR-folded -fold-factor bits to one instead, for certification schemes that
+// disallow cryptographic post-processing. -encoding hex, base64, or uint64 writes text lines instead of binary. It
+// exits quietly when the reader of its output goes away.
+// diag reads -n raw bytes and prints the density of ones per bit position and per switch address, marking
+// outliers, which point to faults such as a weak multiplier stage.
+// test runs a subset of the NIST SP 800-22 tests and the SP 800-90B prediction estimators over -n bytes of
+// whitened output (raw with -raw), or over a capture given with -f (- for stdin), and prints the p-values and
+// entropy estimates. -battery ais31 runs the BSI AIS 31 test procedures A and B instead. It exits with status 1 if
+// a test failed.
+// export writes whitened output (raw with -raw, XOR-folded with -fold) in whole -word words, at most -rate bytes
+// per second, to stdout or to each reader of the FIFO -fifo in turn, for piping into PractRand or TestU01.
+// suite runs PractRand's RNG_test, or a TestU01 harness given as the command, on output fed to its stdin, prints
+// its verdict and the health status recording it, and exits with status 1 if the suite failed.
+// watch reads continuously and redraws a dashboard every -interval: throughput, health status and entropy
+// estimate, the ones density per bit position and switch address, read error counts, and reconnects, which it
+// makes itself after transport and protocol errors.
+// list prints the attached boards (bus path, device node, serial, firmware release, speed, driver) without opening
+// them; it reads sysfs and only works on Linux.
+// status opens the board, reads -n whitened bytes, and prints its board ID, health, entropy estimates, and
+// counters. It exits with status 1 if the health check failed. -json gives both commands a form for automation
+// such as Ansible facts.
+// provision gives the board a random ID in its EEPROM user area, unless it has one, and prints the ID.
+// soak burns in a board: it reads continuously, checks it periodically (self-test, health, and the SP 800-22
+// battery over the output read since the last check), logs each check to -log as JSON lines, and writes a JSON
+// pass/fail report to stdout. It exits with status 1 if the board failed. With -cert, a passing
+// board gets a burn-in certificate signed with the Ed25519 key in -key (a hex-encoded 32-byte seed).
+// seed writes a seed file for systemd-random-seed; -unit instead prints a systemd unit that runs it at boot and
+// shutdown (install it as infnoise-seed.service and enable it).
+// appliance feeds the kernel, serves the device's HTTP API under /infnoise/ (to clients sending the bearer token or,
+// with -client-ca, a certificate signed by one of its CAs, at up to -rate bytes per second overall and -client-rate
+// per client IP), and exports Prometheus metrics at /metrics, until interrupted. After a health failure, output is
+// withheld until the device's quarantine (3 health windows unless the configuration sets another; negative disables
+// it) has passed. With -mac-key-file, every response carries an HMAC that clients check with httpauth.Transport.
+// Its flags can also be set by INFNOISE_* environment variables (INFNOISE_LISTEN for -listen), and the device
+// settings of the JSON -config file by INFNOISE_* variables named after them (INFNOISE_TARGET_ENTROPY); flags take
+// precedence over the environment, and the environment over the file. /healthz and /readyz are unauthenticated
+// probes for orchestrators: /readyz fails once the device has been unready (not open, failing its health check, or
+// starved of output) for -ready-threshold, and /healthz too after -live-threshold, if set.
+// On SIGINT or SIGTERM, it drains connections, stops feeding, and then wipes and closes the device, within
+// -shutdown-timeout. -audit-log appends a hash-chained record of the session to a file.
+// verify-cert checks a certificate against the manufacturer's hex-encoded public key and prints it.
+// setup-udev installs a udev rule giving the group access to the board and reloads udev; it must run
+// as root.
+// sidecar serves the same API and metrics on a Unix socket, for the containers of a Kubernetes pod sharing it
+// through an emptyDir volume, optionally behind -token-file and signed with -mac-key-file; its flags and device
+// settings also come from the environment. It serves the probes on the socket and, with -probe-listen, alone on a
+// TCP address for the kubelet.
+// release writes Debian and RPM packages of a Linux binary with its manual page, completions, udev rule, systemd
+// units, and default configuration.
+// doctor follows the path from the host's USB stack to an open board and reports where it breaks, with the
+// container option or host setting that fixes it.
+// install writes the units, udev rule, and default configuration the packages ship, for this binary, and runs
+// the packages' post-install script; -dry-run prints them.
+// completion prints a completion script for bash, zsh, or fish, and man prints the manual page; both are
+// generated from the commands' flags.
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/seedfile"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(2)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+
+	switch name {
+	case "-h", "-help", "--help", "help":
+		usage(0)
+	}
+
+	cmd, ok := lookup(name)
+	if !ok {
+		usage(2)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	run := cmd.setup(fs)
+
+	var err error
+
+	if cmd.env {
+		err = setFromEnv(fs)
+	}
+
+	if err == nil {
+		err = fs.Parse(args)
+
+		// The flag set has already printed the command's usage.
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+	}
+
+	if err == nil {
+		err = run()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "infnoise %s: %v\n", name, err)
+
+		os.Exit(1)
+	}
+}
+
+func read(fs *flag.FlagSet) func() error {
+	var (
+		n        int64
+		seconds  float64
+		encoding string
+		raw      bool
+		fold     bool
+		factor   int
+		backend  string
+	)
+
+	fs.Int64Var(&n, "n", 0, "number of bytes to write (0: no limit)")
+	fs.Int64Var(&n, "bytes", 0, "same as -n")
+	fs.Float64Var(&seconds, "seconds", 0, "stop after this many seconds (0: no limit)")
+	fs.StringVar(&encoding, "encoding", "binary", "output encoding: binary, hex, base64, or uint64 (one per line)")
+	fs.BoolVar(&raw, "raw", false, "write the raw bitstream instead of whitened output")
+	fs.BoolVar(&fold, "fold", false, "write XOR-folded raw output instead of whitened output")
+	fs.IntVar(&factor, "fold-factor", 0, "raw bits folded into each output bit with -fold (0: from the measured entropy)")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() (err error) {
+		if n < 0 || seconds < 0 {
+			return errors.New("limits must not be negative")
+		}
+
+		if raw && fold {
+			return errors.New("-raw and -fold are mutually exclusive")
+		}
+
+		if encoding == "uint64" && n%8 != 0 {
+			return fmt.Errorf("-n %d is not a whole number of uint64 words", n)
+		}
+
+		enc, err := newEncoder(os.Stdout, encoding)
+		if err != nil {
+			return err
+		}
+
+		ignoreSIGPIPE()
+
+		dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)), infnoise.WithFoldFactor(factor))
+
+		defer dev.Close()
+
+		err = dev.Start()
+		if err != nil {
+			return err
+		}
+
+		var src io.Reader = dev.Whitened()
+
+		switch {
+		case raw:
+			src = dev.Raw()
+		case fold:
+			src = dev.Folded()
+		}
+
+		if n > 0 {
+			src = io.LimitReader(src, n)
+		}
+
+		var deadline time.Time
+
+		if seconds > 0 {
+			deadline = time.Now().Add(time.Duration(seconds * float64(time.Second)))
+		}
+
+		err = copyUntil(enc, src, deadline)
+
+		if closeErr := enc.Close(); err == nil {
+			err = closeErr
+		}
+
+		// The reader going away, as with infnoise read | head -c 32, ends the output as intended.
+		if brokenPipe(err) {
+			return nil
+		}
+
+		return err
+	}
+}
+
+// copyUntil copies src to dst until src is exhausted or, if deadline is set, the deadline passes.
+func copyUntil(dst io.Writer, src io.Reader, deadline time.Time) error {
+	buf := make([]byte, 4096)
+
+	for deadline.IsZero() || time.Now().Before(deadline) {
+		n, err := src.Read(buf)
+
+		if n > 0 {
+			_, werr := dst.Write(buf[:n])
+			if werr != nil {
+				return werr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setupUdev(fs *flag.FlagSet) func() error {
+	var (
+		group  string
+		path   string
+		dryRun bool
+	)
+
+	fs.StringVar(&group, "group", infnoise.DefaultUdevGroup, "group granted access to the board")
+	fs.StringVar(&path, "path", infnoise.DefaultUdevRulePath, "rule file to write")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the rule instead of installing it")
+
+	return func() (err error) {
+		if dryRun {
+			fmt.Print(infnoise.UdevRule(group))
+
+			return nil
+		}
+
+		if runtime.GOOS != "linux" {
+			return errors.New("udev rules only apply on Linux")
+		}
+
+		err = infnoise.InstallUdevRule(path, group)
+		if err != nil {
+			return err
+		}
+
+		for _, args := range [][]string{{"control", "--reload-rules"}, {"trigger", "--subsystem-match=usb"}} {
+			out, err := exec.Command("udevadm", args...).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("rule written to %s, but udevadm %s failed: %w: %s", path, args[0], err, out)
+			}
+		}
+
+		fmt.Printf("installed %s; members of %s can now use the board (replug it if it was attached)\n", path, group)
+
+		return nil
+	}
+}
+
+func provision(fs *flag.FlagSet) func() error {
+	var backend string
+
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() (err error) {
+		dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+		defer dev.Close()
+
+		err = dev.Start()
+		if err != nil {
+			return err
+		}
+
+		info, err := dev.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.HasID {
+			fmt.Printf("%s (already provisioned)\n", info.ID)
+
+			return nil
+		}
+
+		id, err := dev.Provision()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(id)
+
+		return nil
+	}
+}
+
+func soak(fs *flag.FlagSet) func() error {
+	var (
+		opts     infnoise.SoakOptions
+		logPath  string
+		backend  string
+		certPath string
+		keyPath  string
+		serial   string
+	)
+
+	fs.DurationVar(&opts.Duration, "d", time.Hour, "how long to read for")
+	fs.DurationVar(&opts.Interval, "interval", time.Minute, "time between checks")
+	fs.DurationVar(&opts.ResetEvery, "reset", 0, "restart the device this often (0: never)")
+	fs.StringVar(&logPath, "log", "", "file to append each check to as JSON (default: stderr)")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+	fs.StringVar(&certPath, "cert", "", "write a signed burn-in certificate here if the board passes")
+	fs.StringVar(&keyPath, "key", "", "file holding the hex-encoded Ed25519 seed that signs the certificate")
+	fs.StringVar(&serial, "serial", "", "serial number to put on the certificate")
+
+	return func() (err error) {
+		var key ed25519.PrivateKey
+
+		if certPath != "" {
+			key, err = readSeed(keyPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		opts.Log = os.Stderr
+
+		if logPath != "" {
+			f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				return err
+			}
+
+			defer f.Close()
+
+			opts.Log = f
+		}
+
+		dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+		defer dev.Close()
+
+		err = dev.Start()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		report, err := infnoise.Soak(ctx, dev, opts)
+
+		enc := json.NewEncoder(os.Stdout)
+
+		enc.SetIndent("", "  ")
+
+		encErr := enc.Encode(report)
+
+		switch {
+		case err != nil:
+			return err
+		case encErr != nil:
+			return encErr
+		case report.Interrupted:
+			return errors.New("interrupted")
+		case !report.Pass:
+			return fmt.Errorf("board failed with %d failures", len(report.Failures))
+		case certPath == "":
+			return nil
+		}
+
+		// Boards without an ID in the user area, or backends that cannot read it, are certified without one.
+		info, _ := dev.Info()
+
+		cert, err := infnoise.NewCertificate(info, serial, report)
+		if err != nil {
+			return err
+		}
+
+		data, err := infnoise.SignCertificate(key, cert)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(certPath, append(data, '\n'), 0o644)
+	}
+}
+
+func verifyCert(fs *flag.FlagSet) func() error {
+	var pubHex string
+
+	fs.StringVar(&pubHex, "pub", "", "manufacturer's hex-encoded Ed25519 public key")
+
+	return func() (err error) {
+		if fs.NArg() != 1 {
+			return errors.New("need exactly one certificate file")
+		}
+
+		pub, err := hex.DecodeString(pubHex)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return errors.New("-pub must be a hex-encoded 32-byte public key")
+		}
+
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		cert, err := infnoise.VerifyCertificate(pub, data)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(cert)
+	}
+}
+
+// readSeed reads an Ed25519 private key from a file holding its hex-encoded 32-byte seed.
+func readSeed(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, errors.New("-cert needs -key")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s must hold a hex-encoded %d-byte seed", path, ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func seed(fs *flag.FlagSet) func() error {
+	var (
+		path       string
+		creditable bool
+		backend    string
+		unit       bool
+	)
+
+	fs.StringVar(&path, "path", seedfile.SystemdPath, "seed file to write")
+	fs.BoolVar(&creditable, "creditable", false, "let systemd credit the seed to the kernel's entropy count")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+	fs.BoolVar(&unit, "unit", false, "print a systemd unit running this command at boot and shutdown")
+
+	return func() (err error) {
+		if unit {
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(seedfile.SystemdUnit(exe))
+
+			return nil
+		}
+
+		dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+		defer dev.Close()
+
+		err = dev.Start()
+		if err != nil {
+			return err
+		}
+
+		return seedfile.WriteSystemd(dev.Whitened(), path, creditable)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ignoreSIGPIPE makes writes to a closed pipe fail with EPIPE instead of killing the process, so commands can stop
+// quietly when the reader goes away.
+func ignoreSIGPIPE() {
+	signal.Ignore(syscall.SIGPIPE)
+}
+
+// brokenPipe reports whether err is a write to a pipe whose reader has gone.
+func brokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
+
+// makeFIFO creates a FIFO at path unless there is one, reporting whether it did.
+func makeFIFO(path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err == nil {
+		if fi.Mode()&os.ModeNamedPipe == 0 {
+			return false, fmt.Errorf("%s exists and is not a FIFO", path)
+		}
+
+		return false, nil
+	}
+
+	err = syscall.Mkfifo(path, 0o600)
+	if err != nil {
+		return false, &os.PathError{Op: "mkfifo", Path: path, Err: err}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// ignoreSIGPIPE does nothing: Windows reports writes to a closed pipe as errors.
+func ignoreSIGPIPE() {}
+
+// brokenPipe reports whether err is a write to a pipe whose reader has gone.
+func brokenPipe(err error) bool {
+	return errors.Is(err, windows.ERROR_BROKEN_PIPE) || errors.Is(err, windows.ERROR_NO_DATA)
+}
+
+// makeFIFO fails: Windows has no FIFOs in the file system.
+func makeFIFO(path string) (bool, error) {
+	return false, errors.New("-fifo is not supported on Windows")
+}
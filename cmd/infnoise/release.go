@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coalaura/infnoise/packaging"
+)
+
+const (
+	packageSummary     = "Infinite Noise TRNG driver, health checks, and appliance daemon"
+	packageDescription = "infnoise reads the Infinite Noise TRNG over USB without FTDI's or the kernel's drivers, checks the " +
+		"health of its output continuously, and whitens it. The package installs the infnoise command with its " +
+		"manual page and shell completions, a udev rule giving the dialout group access to the board, and systemd " +
+		"units for the appliance daemon (feeding the kernel and serving output and metrics over HTTP) and for boot seeds."
+)
+
+// release writes Debian and RPM packages of a Linux infnoise binary, with the assets from the packaging package,
+// the manual page, and completions.
+func release(fs *flag.FlagSet) func() error {
+	var (
+		version    string
+		rel        string
+		arch       string
+		binary     string
+		out        string
+		formats    string
+		maintainer string
+	)
+
+	fs.StringVar(&version, "version", "", "package version, such as 1.2.0 (required)")
+	fs.StringVar(&rel, "release", "1", "packaging revision")
+	fs.StringVar(&arch, "arch", runtime.GOARCH, "architecture of the binary, as a GOARCH")
+	fs.StringVar(&binary, "binary", "", "Linux infnoise binary to package (default: this executable)")
+	fs.StringVar(&out, "out", ".", "directory to write the packages to")
+	fs.StringVar(&formats, "formats", "deb,rpm", "comma-separated package formats: deb, rpm")
+	fs.StringVar(&maintainer, "maintainer", "infnoise maintainers <https://github.com/coalaura/infnoise>", "package maintainer")
+
+	return func() (err error) {
+		if version == "" {
+			return errors.New("need -version")
+		}
+
+		if binary == "" {
+			binary, err = os.Executable()
+			if err != nil {
+				return err
+			}
+		}
+
+		exe, err := os.ReadFile(binary)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.HasPrefix(exe, []byte("\x7fELF")) {
+			return fmt.Errorf("%s is not a Linux binary; build one with GOOS=linux", binary)
+		}
+
+		p := &packaging.Package{
+			Name:        "infnoise",
+			Version:     version,
+			Release:     rel,
+			Arch:        arch,
+			Maintainer:  maintainer,
+			Summary:     packageSummary,
+			Description: packageDescription,
+			Homepage:    "https://github.com/coalaura/infnoise",
+			Time:        time.Now(),
+		}
+
+		// SOURCE_DATE_EPOCH makes builds reproducible.
+		if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+			sec, err := strconv.ParseInt(epoch, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", epoch)
+			}
+
+			p.Time = time.Unix(sec, 0)
+		}
+
+		layout := packaging.DefaultLayout
+
+		p.Files, err = packaging.Assets(layout)
+		if err != nil {
+			return err
+		}
+
+		docs, err := docFiles(p.Time)
+		if err != nil {
+			return err
+		}
+
+		p.Files = append(p.Files, packaging.File{Path: layout.Bin, Mode: 0o755, Data: exe})
+		p.Files = append(p.Files, docs...)
+
+		p.PostInstall, p.PreRemove, p.PostRemove, err = packaging.Scripts(layout)
+		if err != nil {
+			return err
+		}
+
+		for _, format := range strings.Split(formats, ",") {
+			var (
+				name  string
+				write func(io.Writer, *packaging.Package) error
+			)
+
+			switch strings.TrimSpace(format) {
+			case "deb":
+				name, write = p.DebName(), packaging.WriteDeb
+			case "rpm":
+				name, write = p.RPMName(), packaging.WriteRPM
+			default:
+				return fmt.Errorf("unknown package format %q (want deb or rpm)", format)
+			}
+
+			var b bytes.Buffer
+
+			err = write(&b, p)
+			if err != nil {
+				return err
+			}
+
+			path := filepath.Join(out, name)
+
+			err = os.WriteFile(path, b.Bytes(), 0o644)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(path)
+		}
+
+		return nil
+	}
+}
+
+// docFiles returns the gzipped manual page and the completion scripts, in the places each shell looks for them.
+func docFiles(mtime time.Time) ([]packaging.File, error) {
+	var manPage bytes.Buffer
+
+	gz, err := gzip.NewWriterLevel(&manPage, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	gz.ModTime = mtime
+
+	err = writeMan(gz)
+	if err == nil {
+		err = gz.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := []packaging.File{{Path: "/usr/share/man/man1/infnoise.1.gz", Mode: 0o644, Data: manPage.Bytes()}}
+
+	for _, c := range []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{"/usr/share/bash-completion/completions/infnoise", bashCompletion},
+		{"/usr/share/zsh/site-functions/_infnoise", zshCompletion},
+		{"/usr/share/fish/vendor_completions.d/infnoise.fish", fishCompletion},
+	} {
+		var b bytes.Buffer
+
+		err = c.write(&b)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, packaging.File{Path: c.path, Mode: 0o644, Data: b.Bytes()})
+	}
+
+	return files, nil
+}
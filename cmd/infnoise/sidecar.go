@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/httpauth"
+	"github.com/coalaura/infnoise/lifecycle"
+)
+
+// sidecar serves the device's HTTP API on a Unix socket, for the containers of a pod that share its directory.
+func sidecar(fs *flag.FlagSet) func() error {
+	var (
+		socket  string
+		mode    string
+		backend string
+		config  string
+		listen  string
+		probes  infnoise.Probes
+
+		tokenPath string
+		macPath   string
+
+		auditPath       string
+		shutdownTimeout time.Duration
+	)
+
+	fs.StringVar(&socket, "socket", "/run/infnoise/infnoise.sock", "Unix socket to serve on, in a volume shared with the consumers")
+	fs.StringVar(&mode, "mode", "0666", "permissions of the socket")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+	fs.StringVar(&config, "config", "", "JSON device configuration file (infnoise.Config)")
+	fs.StringVar(&tokenPath, "token-file", "", "file holding a bearer token clients must send (default: the socket's permissions alone)")
+	fs.StringVar(&macPath, "mac-key-file", "", "file holding the key with which every response is signed (see package httpauth)")
+	fs.StringVar(&listen, "probe-listen", "", "also serve /healthz and /readyz, and nothing else, on this TCP address")
+	probeFlags(fs, &probes)
+	fs.StringVar(&auditPath, "audit-log", "", "append a hash-chained audit log of the session to this file")
+	fs.DurationVar(&shutdownTimeout, "shutdown-timeout", lifecycle.DefaultTimeout, "how long to drain connections and stop before closing the device")
+
+	return func() (err error) {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || perm > 0o777 {
+			return fmt.Errorf("invalid mode %q", mode)
+		}
+
+		auth, err := serverAuth(tokenPath, macPath)
+		if err != nil {
+			return err
+		}
+
+		conf, err := deviceConfig(config, backend)
+		if err != nil {
+			return err
+		}
+
+		audit, err := openAuditLog(auditPath)
+		if err != nil {
+			return err
+		}
+
+		// Closed once the device has logged its last entries.
+		defer closeAuditLog(audit)
+
+		dev := infnoise.NewWithConfig(conf, infnoise.WithAuditLog(audit))
+
+		life := lifecycle.New(lifecycle.WithTimeout(shutdownTimeout), lifecycle.WithLogf(log.Printf))
+
+		life.Add(lifecycle.PhaseDevice, "device", func(context.Context) error {
+			return dev.Close()
+		})
+
+		err = dev.Start()
+		if err != nil {
+			life.Shutdown()
+
+			return err
+		}
+
+		// A socket left behind by a killed predecessor would make Listen fail.
+		if fi, err := os.Lstat(socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(socket)
+		}
+
+		ln, err := net.Listen("unix", socket)
+		if err != nil {
+			return errors.Join(err, life.Shutdown())
+		}
+
+		err = os.Chmod(socket, os.FileMode(perm))
+		if err != nil {
+			ln.Close()
+
+			return errors.Join(err, life.Shutdown())
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		life.Go(lifecycle.PhaseSinks, "event log", func(ctx context.Context) error {
+			return logEvents(ctx, dev)
+		})
+
+		mux := http.NewServeMux()
+		probe := dev.ProbeHandler(probes)
+
+		mux.Handle("GET /metrics", dev.MetricsHandler())
+		mux.Handle("GET /healthz", probe)
+		mux.Handle("GET /readyz", probe)
+		mux.Handle("/", httpauth.Wrap(quarantineGate(dev, dev.Handler()), auth...))
+
+		srv := &http.Server{
+			Handler:           recoverHandler(mux),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		life.Serve("socket", srv, func() error {
+			return srv.Serve(ln)
+		})
+
+		// The kubelet's HTTP probes need a TCP port; it only gets the probes, never output.
+		if listen != "" {
+			probeSrv := &http.Server{
+				Addr:              listen,
+				Handler:           recoverHandler(probe),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			life.Serve("probes", probeSrv, probeSrv.ListenAndServe)
+		}
+
+		log.Printf("sidecar serving on %s", socket)
+
+		return life.Run(ctx)
+	}
+}
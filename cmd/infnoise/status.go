@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/coalaura/infnoise"
+)
+
+// list prints the attached boards without opening them.
+func list(fs *flag.FlagSet) func() error {
+	var asJSON bool
+
+	fs.BoolVar(&asJSON, "json", false, "print the boards as a JSON array")
+
+	return func() (err error) {
+		devices, err := infnoise.ListDevices()
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			if devices == nil {
+				devices = []infnoise.USBDevice{}
+			}
+
+			return printJSON(devices)
+		}
+
+		if len(devices) == 0 {
+			fmt.Println("no boards attached")
+
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+		fmt.Fprintln(tw, "PATH\tNODE\tSERIAL\tFIRMWARE\tSPEED\tDRIVER\t")
+
+		for _, d := range devices {
+			driver := d.Driver
+			if d.USBIP {
+				driver += " (usbip)"
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", d.Path, d.Node, d.Serial, d.Firmware, d.Speed, driver)
+		}
+
+		return tw.Flush()
+	}
+}
+
+// statusReport is the outcome of infnoise status. Device is only set when exactly one board is attached, since
+// otherwise it is unknown which one the backend opened.
+type statusReport struct {
+	Backend infnoise.BackendName `json:"backend"`
+	Device  *infnoise.USBDevice  `json:"device,omitempty"`
+	BoardID string               `json:"boardId,omitempty"`
+
+	Health   infnoise.HealthStatus `json:"health"`
+	Estimate float64               `json:"estimate"`
+	Drift    infnoise.DriftStats   `json:"drift"`
+	Stats    infnoise.Stats        `json:"stats"`
+
+	// Error is the failure that cut the read short, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// status opens the board, reads -n whitened bytes to exercise the health check, and prints its state.
+func status(fs *flag.FlagSet) func() error {
+	var (
+		n       int64
+		asJSON  bool
+		backend string
+	)
+
+	fs.Int64Var(&n, "n", 16<<10, "whitened bytes to read before reporting")
+	fs.BoolVar(&asJSON, "json", false, "print the status as JSON")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() (err error) {
+		dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+		defer dev.Close()
+
+		err = dev.Start()
+		if err != nil {
+			return err
+		}
+
+		_, readErr := io.CopyN(io.Discard, dev.Whitened(), n)
+
+		info, err := dev.Info()
+		if err != nil && !errors.Is(err, infnoise.ErrUserAreaUnsupported) {
+			return err
+		}
+
+		r := statusReport{
+			Backend: info.Backend,
+			Drift:   dev.Drift(),
+			Stats:   dev.Stats(),
+		}
+
+		r.Health, r.Estimate = dev.Health()
+
+		if info.HasID {
+			r.BoardID = info.ID.String()
+		}
+
+		// The simulator has no USB device to match.
+		if devices, _ := infnoise.ListDevices(); len(devices) == 1 && info.Backend != infnoise.BackendSimulator {
+			r.Device = &devices[0]
+		}
+
+		if readErr != nil {
+			r.Error = readErr.Error()
+		}
+
+		if asJSON {
+			err = printJSON(r)
+		} else {
+			err = printStatus(os.Stdout, &r)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+
+		if r.Health != infnoise.HealthOK {
+			return errors.New("health check failed")
+		}
+
+		return nil
+	}
+}
+
+func printStatus(w io.Writer, r *statusReport) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "backend\t%s\t\n", cmp.Or(string(r.Backend), "default"))
+
+	if r.Device != nil {
+		fmt.Fprintf(tw, "device\t%s (%s), serial %s, firmware %s\t\n", r.Device.Path, r.Device.Node, r.Device.Serial, r.Device.Firmware)
+	}
+
+	fmt.Fprintf(tw, "board ID\t%s\t\n", cmp.Or(r.BoardID, "none"))
+	fmt.Fprintf(tw, "health\t%s, estimate %.4f bits per bit (long-term %.4f, target %.3f)\t\n", r.Health, r.Estimate, r.Drift.LongTermEntropy, r.Drift.Target)
+	fmt.Fprintf(tw, "reads\t%d, p99 latency %s\t\n", r.Stats.Reads, r.Stats.ReadLatency.P99)
+	fmt.Fprintf(tw, "errors\ttransport %d, protocol %d, health %d, retries %d\t\n", r.Stats.TransportErrors, r.Stats.ProtocolErrors, r.Stats.HealthErrors, r.Stats.Retries)
+
+	if r.Error != "" {
+		fmt.Fprintf(tw, "error\t%s\t\n", r.Error)
+	}
+
+	return tw.Flush()
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/coalaura/infnoise"
+)
+
+// supervised runs fn under infnoise.Supervise for lifecycle.Go, so a panic shuts the daemon down cleanly.
+func supervised(name string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		err := infnoise.Supervise(name, func() error {
+			return fn(ctx)
+		})
+
+		logPanic(err)
+
+		return err
+	}
+}
+
+// recoverHandler answers a request whose handler panicked with an error, counting the panic in the metrics.
+func recoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := infnoise.Supervise("http handler", func() error {
+			next.ServeHTTP(w, r)
+
+			return nil
+		})
+
+		var pe *infnoise.PanicError
+
+		if !errors.As(err, &pe) {
+			return
+		}
+
+		// net/http aborts the response quietly for this one.
+		if pe.Value == http.ErrAbortHandler {
+			panic(pe.Value)
+		}
+
+		logPanic(err)
+
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	})
+}
+
+// logEvents logs the device's events until ctx is done, with the stack of a panic behind a USB error.
+func logEvents(ctx context.Context, dev *infnoise.Device) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-dev.Events():
+			switch {
+			case ev.Err != nil:
+				log.Printf("device %s: %v", ev.Kind, ev.Err)
+
+				logPanic(ev.Err)
+			case ev.Kind == infnoise.EventHealthDegraded || ev.Kind == infnoise.EventHealthRecovered:
+				log.Printf("device %s (entropy estimate %.3f)", ev.Kind, ev.Estimate)
+			default:
+				log.Printf("device %s", ev.Kind)
+			}
+		}
+	}
+}
+
+// logPanic logs the stack of a recovered panic behind err, if any.
+func logPanic(err error) {
+	var pe *infnoise.PanicError
+
+	if errors.As(err, &pe) {
+		log.Printf("%v\n%s", pe, pe.Stack)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/ais31"
+	"github.com/coalaura/infnoise/sts"
+)
+
+// testReport is the outcome of infnoise test. Pass reflects the statistical tests only; the estimates describe the
+// entropy of the input and have no pass mark, since whitened and raw input differ.
+type testReport struct {
+	Source  string `json:"source"`
+	Bytes   int    `json:"bytes"`
+	Battery string `json:"battery"`
+	Pass    bool   `json:"pass"`
+
+	// Tests are the results of the "nist" battery, Procedures those of "ais31".
+	Tests       []sts.Result          `json:"tests,omitempty"`
+	Procedures  []ais31.Report        `json:"procedures,omitempty"`
+	Estimates   []infnoise.Estimate   `json:"estimates"`
+	Predictions []infnoise.Prediction `json:"predictions"`
+}
+
+// test runs the SP 800-22 subset or the AIS 31 procedures, and the SP 800-90B prediction estimators, over device
+// output, a capture, or stdin.
+func test(fs *flag.FlagSet) func() error {
+	var (
+		n       int64
+		file    string
+		raw     bool
+		asJSON  bool
+		battery string
+		backend string
+	)
+
+	fs.Int64Var(&n, "n", 0, "bytes to analyze (default: 1 MiB from the device, 4 MiB for ais31, all of a file)")
+	fs.StringVar(&file, "f", "", "analyze this capture instead of the device (-: stdin)")
+	fs.BoolVar(&raw, "raw", false, "analyze raw device output instead of whitened output")
+	fs.BoolVar(&asJSON, "json", false, "print the report as JSON")
+	fs.StringVar(&battery, "battery", "nist", "statistical tests to run: nist (SP 800-22) or ais31 (AIS 31 procedures A and B)")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() (err error) {
+		if battery != "nist" && battery != "ais31" {
+			return fmt.Errorf("unknown battery %q", battery)
+		}
+
+		var (
+			src    io.Reader
+			source string
+		)
+
+		switch file {
+		case "":
+			dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+			defer dev.Close()
+
+			err = dev.Start()
+			if err != nil {
+				return err
+			}
+
+			src, source = dev.Whitened(), "device (whitened)"
+
+			if raw {
+				src, source = dev.Raw(), "device (raw)"
+			}
+
+			// Enough for both AIS 31 procedures to repeat themselves once.
+			if n <= 0 && battery == "ais31" {
+				n = 4 << 20
+			}
+
+			if n <= 0 {
+				n = 1 << 20
+			}
+		case "-":
+			src, source = os.Stdin, "stdin"
+		default:
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+
+			defer f.Close()
+
+			src, source = f, file
+		}
+
+		if n > 0 {
+			src = io.LimitReader(src, n)
+		}
+
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+
+		report, err := runTests(source, battery, data)
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+
+			enc.SetIndent("", "  ")
+
+			err = enc.Encode(report)
+		} else {
+			err = printTests(os.Stdout, &report)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !report.Pass {
+			return fmt.Errorf("%s failed the statistical tests", source)
+		}
+
+		return nil
+	}
+}
+
+// runTests applies the battery of statistical tests and, through a HealthCheck analyzing every bit, the estimators
+// to data.
+func runTests(source, battery string, data []byte) (testReport, error) {
+	report := testReport{
+		Source:  source,
+		Bytes:   len(data),
+		Battery: battery,
+		Pass:    true,
+	}
+
+	switch battery {
+	case "nist":
+		results, err := sts.Run(data)
+		if err != nil {
+			return testReport{}, err
+		}
+
+		report.Tests = results
+
+		for _, r := range results {
+			report.Pass = report.Pass && r.Pass
+		}
+	case "ais31":
+		procedures, err := ais31.Run(data)
+		if err != nil {
+			return testReport{}, err
+		}
+
+		report.Procedures = procedures
+
+		for _, p := range procedures {
+			report.Pass = report.Pass && p.Pass
+		}
+	default:
+		return testReport{}, fmt.Errorf("unknown battery %q", battery)
+	}
+
+	h := infnoise.NewHealthCheck(0.864, 0.05, 8*uint64(len(data)))
+
+	h.SetPredictors(1)
+	h.Add(data)
+
+	health := h.Report()
+
+	report.Estimates, report.Predictions = health.Estimates, health.Predictions
+
+	return report, nil
+}
+
+func passFail(pass bool) string {
+	if pass {
+		return "pass"
+	}
+
+	return "FAIL"
+}
+
+func printTests(w io.Writer, r *testReport) error {
+	fmt.Fprintf(w, "%s, %d bytes\n\n", r.Source, r.Bytes)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	if len(r.Tests) > 0 {
+		fmt.Fprintf(tw, "SP 800-22 test\tp-value\t\t\n")
+	}
+
+	for _, t := range r.Tests {
+		fmt.Fprintf(tw, "%s\t%.6f\t%s\t\n", t.Name, t.PValue, passFail(t.Pass))
+	}
+
+	for i, p := range r.Procedures {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+
+		repeated := ""
+		if p.Repeated {
+			repeated = ", repeated"
+		}
+
+		fmt.Fprintf(tw, "AIS 31 procedure %s (%d bytes%s)\tfailed runs\tvalue\t\n", p.Procedure, p.Bytes, repeated)
+
+		for _, t := range p.Tests {
+			value := ""
+			if t.Value != 0 {
+				value = fmt.Sprintf("%.4f", t.Value)
+			}
+
+			fmt.Fprintf(tw, "%s\t%d of %d\t%s\t%s\n", t.Name, t.Failures, t.Runs, value, passFail(t.Pass))
+		}
+	}
+
+	fmt.Fprintf(tw, "\nestimate\tbits per bit\t\t\n")
+
+	for _, e := range r.Estimates {
+		fmt.Fprintf(tw, "%s\t%.4f\t\t\n", e.Name, e.BitsPerBit)
+	}
+
+	for _, p := range r.Predictions {
+		fmt.Fprintf(tw, "  %s\t%.4f\t%d of %d correct\t\n", p.Name, p.MinEntropy, p.Correct, p.Predictions)
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	verdict := "passed"
+	if !r.Pass {
+		verdict = "FAILED"
+	}
+
+	_, err = fmt.Fprintf(w, "\n%s\n", verdict)
+
+	return err
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/coalaura/infnoise"
+)
+
+// watcher reads from the device in the background, reopening it after transport and protocol errors, and counts
+// what it read for the dashboard.
+type watcher struct {
+	dev *infnoise.Device
+	raw bool
+
+	bytes      atomic.Uint64
+	reconnects atomic.Uint64
+
+	mu      sync.Mutex
+	lastErr error
+	errTime time.Time
+}
+
+// watch shows a dashboard of the device, redrawn every interval, until interrupted.
+func watch(fs *flag.FlagSet) func() error {
+	var (
+		interval time.Duration
+		raw      bool
+		backend  string
+	)
+
+	fs.DurationVar(&interval, "interval", time.Second, "time between refreshes")
+	fs.BoolVar(&raw, "raw", false, "read raw output instead of whitened output")
+	fs.StringVar(&backend, "backend", "", "USB backend (default: platform native)")
+
+	return func() (err error) {
+		if interval <= 0 {
+			return fmt.Errorf("invalid interval %s", interval)
+		}
+
+		dev := infnoise.New(infnoise.WithBackend(infnoise.BackendName(backend)))
+
+		defer dev.Close()
+
+		err = dev.Start()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		w := &watcher{dev: dev, raw: raw}
+
+		go w.run(ctx)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		start, last, lastBytes := time.Now(), time.Now(), uint64(0)
+
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Println()
+
+				return nil
+			case now := <-ticker.C:
+				total := w.bytes.Load()
+
+				rate := float64(total-lastBytes) / now.Sub(last).Seconds()
+
+				last, lastBytes = now, total
+
+				w.render(os.Stdout, now.Sub(start), rate, total)
+			}
+		}
+	}
+}
+
+// run reads until ctx is done. A health failure leaves the device open, since it recovers once the source does;
+// other failures reopen it.
+func (w *watcher) run(ctx context.Context) {
+	read := w.dev.ReadWhitened
+	if w.raw {
+		read = w.dev.Read
+	}
+
+	buf := make([]byte, 4096)
+
+	for ctx.Err() == nil {
+		n, err := read(buf)
+
+		w.bytes.Add(uint64(n))
+
+		if err == nil {
+			continue
+		}
+
+		w.mu.Lock()
+		w.lastErr, w.errTime = err, time.Now()
+		w.mu.Unlock()
+
+		if infnoise.Classify(err) != infnoise.ClassHealth {
+			w.dev.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+
+		if infnoise.Classify(err) != infnoise.ClassHealth && w.dev.Start() == nil {
+			w.reconnects.Add(1)
+		}
+	}
+}
+
+func (w *watcher) render(out io.Writer, up time.Duration, rate float64, total uint64) {
+	status, estimate := w.dev.Health()
+	drift := w.dev.Drift()
+	stats := w.dev.Stats()
+	bias := w.dev.HealthReport().Bias
+	overall := bias.Overall()
+
+	var b strings.Builder
+
+	// Move home and clear the screen, so each frame replaces the last.
+	b.WriteString("\x1b[H\x1b[2J")
+
+	fmt.Fprintf(&b, "infnoise watch   up %s   (Ctrl-C to quit)\n\n", up.Round(time.Second))
+
+	fmt.Fprintf(&b, "%-12s %s/s, %s total\n", "throughput", humanBytes(rate), humanBytes(float64(total)))
+	fmt.Fprintf(&b, "%-12s %s, estimate %.4f bits per bit (target %.3f, long-term %.4f)\n", "health", status, estimate, drift.Target, drift.LongTermEntropy)
+
+	if drift.Recalibrate {
+		fmt.Fprintf(&b, "%-12s long-term estimate is drifting from the target\n", "")
+	}
+
+	fmt.Fprintf(&b, "\n%-12s %.4f over %d bits; ! marks 5 standard errors\n", "ones density", overall.Ratio(), overall.Bits)
+
+	densities := func(label string, ds []infnoise.Density) {
+		fmt.Fprintf(&b, "%-12s", label)
+
+		for _, d := range ds {
+			mark := " "
+			if z := d.Deviation(overall); z > 5 || z < -5 {
+				mark = "!"
+			}
+
+			fmt.Fprintf(&b, " %.4f%s", d.Ratio(), mark)
+		}
+
+		b.WriteString("\n")
+	}
+
+	densities("bit 0-7", bias.Positions[:])
+	densities("address 0-7", bias.Addresses[:8])
+	densities("address 8-15", bias.Addresses[8:])
+
+	fmt.Fprintf(&b, "\n%-12s transport %d, protocol %d, health %d, retries %d\n", "errors", stats.TransportErrors, stats.ProtocolErrors, stats.HealthErrors, stats.Retries)
+	fmt.Fprintf(&b, "%-12s %d\n", "reconnects", w.reconnects.Load())
+
+	w.mu.Lock()
+
+	if w.lastErr != nil {
+		fmt.Fprintf(&b, "%-12s %s ago: %v\n", "last error", time.Since(w.errTime).Round(time.Second), w.lastErr)
+	}
+
+	w.mu.Unlock()
+
+	io.WriteString(out, b.String())
+}
+
+// humanBytes formats n bytes with a binary unit.
+func humanBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+	i := 0
+
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
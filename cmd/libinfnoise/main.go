@@ -0,0 +1,133 @@
+// Command libinfnoise builds a C shared library exposing the Infinite Noise TRNG through
+// PKCS#11-compatible random number entry points, so non-Go software (OpenSSL engines, HSM stacks)
+// can draw from the device.
+//
+//	go build -buildmode=c-shared -o infnoise.dll ./cmd/libinfnoise              (Windows)
+//	go build -buildmode=c-shared -tags gousb -o libinfnoise.so ./cmd/libinfnoise (Linux/macOS)
+//
+// The bundled static libusb is not position independent, so Linux and macOS builds must use the
+// gousb tag and link against the system libusb-1.0. The backend can be chosen with the
+// INFNOISE_BACKEND environment variable (e.g. "d2xx"); it defaults to the platform's native one.
+//
+// Exported functions follow the PKCS#11 signatures and return codes:
+//
+//	CK_RV C_Initialize(void *pInitArgs);
+//	CK_RV C_Finalize(void *pReserved);
+//	CK_RV C_GenerateRandom(CK_SESSION_HANDLE hSession, CK_BYTE_PTR RandomData, CK_ULONG ulRandomLen);
+//	CK_RV C_SeedRandom(CK_SESSION_HANDLE hSession, CK_BYTE_PTR pSeed, CK_ULONG ulSeedLen);
+//
+// This is not a complete PKCS#11 module (there is no C_GetFunctionList, slots, or sessions); the
+// session handle is ignored.
+package main
+
+/*
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+typedef CK_ULONG CK_SESSION_HANDLE;
+typedef unsigned char *CK_BYTE_PTR;
+*/
+import "C"
+
+import (
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/coalaura/infnoise"
+)
+
+const (
+	ckrOK                         = 0x000
+	ckrArgumentsBad               = 0x007
+	ckrDeviceError                = 0x030
+	ckrRandomSeedNotSupported     = 0x120
+	ckrCryptokiNotInitialized     = 0x190
+	ckrCryptokiAlreadyInitialized = 0x191
+)
+
+var (
+	mu  sync.Mutex
+	dev *infnoise.Device
+)
+
+//export C_Initialize
+func C_Initialize(pInitArgs unsafe.Pointer) C.CK_RV {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if dev != nil {
+		return ckrCryptokiAlreadyInitialized
+	}
+
+	d := infnoise.New(infnoise.WithBackend(infnoise.BackendName(os.Getenv("INFNOISE_BACKEND"))))
+
+	err := d.Start()
+	if err != nil {
+		d.Close()
+
+		return ckrDeviceError
+	}
+
+	dev = d
+
+	return ckrOK
+}
+
+//export C_Finalize
+func C_Finalize(pReserved unsafe.Pointer) C.CK_RV {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pReserved != nil {
+		return ckrArgumentsBad
+	}
+
+	if dev == nil {
+		return ckrCryptokiNotInitialized
+	}
+
+	dev.Close()
+
+	dev = nil
+
+	return ckrOK
+}
+
+// C_GenerateRandom fills randomData with whitened output: PKCS#11 callers expect full-entropy bytes, not the raw
+// bitstream.
+//
+//export C_GenerateRandom
+func C_GenerateRandom(hSession C.CK_SESSION_HANDLE, randomData C.CK_BYTE_PTR, ulRandomLen C.CK_ULONG) C.CK_RV {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if dev == nil {
+		return ckrCryptokiNotInitialized
+	}
+
+	if ulRandomLen == 0 {
+		return ckrOK
+	}
+
+	if randomData == nil {
+		return ckrArgumentsBad
+	}
+
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(randomData)), int(ulRandomLen))
+
+	_, err := dev.ReadWhitened(buf)
+	if err != nil {
+		clear(buf)
+
+		return ckrDeviceError
+	}
+
+	return ckrOK
+}
+
+//export C_SeedRandom
+func C_SeedRandom(hSession C.CK_SESSION_HANDLE, pSeed C.CK_BYTE_PTR, ulSeedLen C.CK_ULONG) C.CK_RV {
+	return ckrRandomSeedNotSupported
+}
+
+func main() {}
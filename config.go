@@ -0,0 +1,258 @@
+package infnoise
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EnvPrefix starts the names of the environment variables Config.LoadEnv reads.
+const EnvPrefix = "INFNOISE_"
+
+// Config is a plain-struct alternative to the functional options, for configuration read from files or flags.
+// Zero fields keep their defaults; durations are time.Duration (nanoseconds in JSON). Settings that are not data,
+// such as callbacks, writers, and tracer providers, remain options.
+type Config struct {
+	Backend BackendName `json:"backend,omitempty"`
+
+	// Board names the board profile, "v1" or "v2" (default). Pattern, if set, overrides its pattern.
+	Board   string   `json:"board,omitempty"`
+	Pattern *Pattern `json:"pattern,omitempty"`
+
+	TargetEntropy float64 `json:"targetEntropy,omitempty"`
+	Tolerance     float64 `json:"tolerance,omitempty"`
+	HealthWindow  uint64  `json:"healthWindow,omitempty"`
+	Quarantine    int     `json:"quarantine,omitempty"`
+	HistoryBits   int     `json:"historyBits,omitempty"`
+
+	// FalsePositiveRate and MinEntropy are WithContinuousTests; either may be left zero for its default.
+	FalsePositiveRate float64 `json:"falsePositiveRate,omitempty"`
+	MinEntropy        float64 `json:"minEntropy,omitempty"`
+
+	// AutocorrelationLimit is WithAutocorrelationLimit; a negative value disables the check.
+	AutocorrelationLimit float64 `json:"autocorrelationLimit,omitempty"`
+
+	// PredictorFraction is WithPredictors; a negative value disables the estimators.
+	PredictorFraction float64 `json:"predictorFraction,omitempty"`
+
+	// HealthSampling is WithHealthSampling.
+	HealthSampling float64 `json:"healthSampling,omitempty"`
+
+	WarmupBytes    int           `json:"warmupBytes,omitempty"`
+	WarmupDuration time.Duration `json:"warmupDuration,omitempty"`
+
+	HighWater int `json:"highWater,omitempty"`
+	LowWater  int `json:"lowWater,omitempty"`
+
+	ReadMode    ReadMode      `json:"readMode,omitempty"`
+	FoldFactor  int           `json:"foldFactor,omitempty"`
+	IOBatch     int           `json:"ioBatch,omitempty"`
+	ChunkSize   int           `json:"chunkSize,omitempty"`
+	TargetRate  int           `json:"targetRate,omitempty"`
+	IdleTimeout time.Duration `json:"idleTimeout,omitempty"`
+
+	// HighLatencyLink applies WithHighLatencyLink; TransferTimeout, LatencyTimer, and IOBatch override its values.
+	HighLatencyLink bool          `json:"highLatencyLink,omitempty"`
+	TransferTimeout time.Duration `json:"transferTimeout,omitempty"`
+	LatencyTimer    int           `json:"latencyTimer,omitempty"`
+
+	// KeepKernelDriver is WithKernelDriverDetach(false).
+	KeepKernelDriver bool `json:"keepKernelDriver,omitempty"`
+
+	// DisableRatchet is WithRatchet(false).
+	DisableRatchet bool          `json:"disableRatchet,omitempty"`
+	ReseedInterval time.Duration `json:"reseedInterval,omitempty"`
+
+	// RetryAttempts and RetryBackoff are WithRetry.
+	RetryAttempts int           `json:"retryAttempts,omitempty"`
+	RetryBackoff  time.Duration `json:"retryBackoff,omitempty"`
+
+	// ReconnectAttempts and ReconnectBackoff are WithReconnect.
+	ReconnectAttempts int           `json:"reconnectAttempts,omitempty"`
+	ReconnectBackoff  time.Duration `json:"reconnectBackoff,omitempty"`
+
+	// MaxPoolAge and StalePolicy are WithMaxPoolAge; StalePolicy is "discard" (default) or "flag".
+	MaxPoolAge  time.Duration `json:"maxPoolAge,omitempty"`
+	StalePolicy StalePolicy   `json:"stalePolicy,omitempty"`
+
+	// LockMemory is WithLockedMemory; NoCoreDumps is WithoutCoreDumps.
+	LockMemory  bool `json:"lockMemory,omitempty"`
+	NoCoreDumps bool `json:"noCoreDumps,omitempty"`
+
+	TrendInterval time.Duration `json:"trendInterval,omitempty"`
+	TrendPoints   int           `json:"trendPoints,omitempty"`
+}
+
+// LoadEnv sets the fields of c named by environment variables, for containers configured through their
+// environment: each field is read from EnvPrefix and its JSON name in upper snake case, such as
+// INFNOISE_TARGET_ENTROPY for TargetEntropy. Values are written as on a command line (durations like "30s",
+// read modes and stale policies by name, Pattern as JSON). Unset and empty variables leave their fields alone, so
+// the environment overrides a configuration file loaded before.
+func (c *Config) LoadEnv() error {
+	v := reflect.ValueOf(c).Elem()
+
+	for i := range v.NumField() {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+
+		key := EnvPrefix + envName(name)
+
+		s := os.Getenv(key)
+		if s == "" {
+			continue
+		}
+
+		err := setEnvField(v.Field(i), s)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, s, err)
+		}
+	}
+
+	return nil
+}
+
+// envName converts a camel case name to upper snake case.
+func envName(name string) string {
+	var b strings.Builder
+
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(rune(name[i-1])) {
+			b.WriteByte('_')
+		}
+
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
+
+func setEnvField(f reflect.Value, s string) error {
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	if f.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		f.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		f.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			return err
+		}
+
+		f.SetUint(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+
+		f.SetFloat(x)
+	default:
+		return json.Unmarshal([]byte(s), f.Addr().Interface())
+	}
+
+	return nil
+}
+
+// boardProfiles are the profiles Config.Board can name.
+var boardProfiles = map[string]Profile{
+	ProfileV1.Name: ProfileV1,
+	ProfileV2.Name: ProfileV2,
+}
+
+// NewWithConfig is New with c applied before opts, which take precedence. Start reports invalid settings.
+func NewWithConfig(c Config, opts ...option) *Device {
+	return New(append(c.options(), opts...)...)
+}
+
+// options translates the non-zero fields of c to their options.
+func (c Config) options() []option {
+	var opts []option
+
+	add := func(set bool, opt option) {
+		if set {
+			opts = append(opts, opt)
+		}
+	}
+
+	add(c.HighLatencyLink, WithHighLatencyLink())
+	add(c.Backend != BackendDefault, WithBackend(c.Backend))
+
+	if c.Board != "" {
+		p, ok := boardProfiles[c.Board]
+
+		add(ok, WithBoardProfile(p))
+		add(!ok, func(o *options) {
+			o.err = fmt.Errorf("unknown board profile %q", c.Board)
+		})
+	}
+
+	if c.Pattern != nil {
+		opts = append(opts, WithPattern(*c.Pattern))
+	}
+
+	add(c.TargetEntropy != 0, WithTargetEntropy(c.TargetEntropy))
+	add(c.Tolerance != 0, WithTolerance(c.Tolerance))
+	add(c.HealthWindow != 0, WithHealthWindow(c.HealthWindow))
+	add(c.Quarantine != 0, WithQuarantine(c.Quarantine))
+	add(c.HistoryBits != 0, WithHealthHistoryBits(c.HistoryBits))
+	add(c.FalsePositiveRate != 0 || c.MinEntropy != 0, WithContinuousTests(
+		cmp.Or(c.FalsePositiveRate, DefaultFalsePositiveRate), cmp.Or(c.MinEntropy, DefaultMinEntropy)))
+	add(c.AutocorrelationLimit != 0, WithAutocorrelationLimit(c.AutocorrelationLimit))
+	add(c.PredictorFraction != 0, WithPredictors(max(c.PredictorFraction, 0)))
+	add(c.HealthSampling != 0, WithHealthSampling(c.HealthSampling))
+	add(c.WarmupBytes != 0, WithWarmup(c.WarmupBytes))
+	add(c.WarmupDuration != 0, WithWarmupDuration(c.WarmupDuration))
+	add(c.HighWater != 0 || c.LowWater != 0, WithRingWatermarks(c.HighWater, c.LowWater))
+	add(c.ReadMode != ModeExclusive, WithReadMode(c.ReadMode))
+	add(c.FoldFactor != 0, WithFoldFactor(c.FoldFactor))
+	add(c.IOBatch != 0, WithIOBatch(c.IOBatch))
+	add(c.ChunkSize != 0, WithChunkSize(c.ChunkSize))
+	add(c.TargetRate != 0, WithTargetRate(c.TargetRate))
+	add(c.IdleTimeout != 0, WithIdleTimeout(c.IdleTimeout))
+	add(c.TransferTimeout != 0, WithTransferTimeout(c.TransferTimeout))
+	add(c.LatencyTimer != 0, WithLatencyTimer(c.LatencyTimer))
+	add(c.KeepKernelDriver, WithKernelDriverDetach(false))
+	add(c.DisableRatchet, WithRatchet(false))
+	add(c.ReseedInterval != 0, WithReseedInterval(c.ReseedInterval))
+	add(c.RetryAttempts != 0 || c.RetryBackoff != 0, WithRetry(c.RetryAttempts, c.RetryBackoff))
+	add(c.ReconnectAttempts != 0 || c.ReconnectBackoff != 0, WithReconnect(c.ReconnectAttempts, c.ReconnectBackoff))
+	add(c.MaxPoolAge != 0 || c.StalePolicy != StaleDiscard, WithMaxPoolAge(c.MaxPoolAge, c.StalePolicy))
+	add(c.LockMemory, WithLockedMemory())
+	add(c.NoCoreDumps, WithoutCoreDumps())
+	add(c.TrendInterval != 0 || c.TrendPoints != 0, WithTrend(c.TrendInterval, c.TrendPoints))
+
+	return opts
+}
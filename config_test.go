@@ -0,0 +1,104 @@
+package infnoise
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	var c Config
+
+	err := json.Unmarshal([]byte(`{"board":"v1","readMode":"raw-tap","chunkSize":1024,"idleTimeout":1000000000}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Board != "v1" || c.ReadMode != ModeRawTap || c.ChunkSize != 1024 || c.IdleTimeout != time.Second {
+		t.Fatalf("decoded %+v", c)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(b); !strings.Contains(got, `"readMode":"raw-tap"`) || strings.Contains(got, "ioBatch") {
+		t.Fatalf("encoded %s", got)
+	}
+
+	// Options passed alongside the config take precedence.
+	dv := NewWithConfig(c, WithChunkSize(512))
+
+	if dv.mode != ModeRawTap || dv.profile.Name != ProfileV1.Name || dv.idleTimeout != time.Second || len(dv.poolBuf) != 512 {
+		t.Fatalf("device: mode %s, profile %s, idle %s, chunk %d", dv.mode, dv.profile.Name, dv.idleTimeout, len(dv.poolBuf))
+	}
+
+	err = NewWithConfig(Config{Board: "v3"}).Start()
+	if err == nil || !strings.Contains(err.Error(), "v3") {
+		t.Fatalf("Start with an unknown board: %v", err)
+	}
+
+	err = json.Unmarshal([]byte(`{"readMode":"shared"}`), &c)
+	if err == nil {
+		t.Fatal("decoded an unknown read mode")
+	}
+}
+
+func TestConfigSimulator(t *testing.T) {
+	dv := NewWithConfig(Config{Backend: registerSimulator(t, NewSimulator(1)), WarmupBytes: 64})
+
+	err := dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dv.Close()
+
+	_, err = dv.ReadWhitened(make([]byte, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	c := Config{Board: "v1", ChunkSize: 1024}
+
+	t.Setenv("INFNOISE_BACKEND", "simulator")
+	t.Setenv("INFNOISE_TARGET_ENTROPY", "0.85")
+	t.Setenv("INFNOISE_HEALTH_WINDOW", "4096")
+	t.Setenv("INFNOISE_IO_BATCH", "0x100")
+	t.Setenv("INFNOISE_IDLE_TIMEOUT", "30s")
+	t.Setenv("INFNOISE_READ_MODE", "raw-tap")
+	t.Setenv("INFNOISE_LOCK_MEMORY", "true")
+	t.Setenv("INFNOISE_CHUNK_SIZE", "")
+
+	err := c.LoadEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Config{
+		Backend:       BackendSimulator,
+		Board:         "v1",
+		TargetEntropy: 0.85,
+		HealthWindow:  4096,
+		IOBatch:       256,
+		ChunkSize:     1024,
+		IdleTimeout:   30 * time.Second,
+		ReadMode:      ModeRawTap,
+		LockMemory:    true,
+	}
+
+	if c != want {
+		t.Fatalf("loaded %+v\nwant   %+v", c, want)
+	}
+
+	t.Setenv("INFNOISE_QUARANTINE", "three")
+
+	err = c.LoadEnv()
+	if err == nil || !strings.Contains(err.Error(), "INFNOISE_QUARANTINE") {
+		t.Fatalf("LoadEnv with an invalid value: %v", err)
+	}
+}
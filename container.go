@@ -0,0 +1,37 @@
+package infnoise
+
+import "fmt"
+
+// Diagnosis is the outcome of one check of DiagnosePassthrough.
+type Diagnosis struct {
+	// Check names the check: "container", "sysfs", "usbfs", "device-node", "open", or "ioctl".
+	Check  string `json:"check"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+
+	// Hint says how to fix a failed check.
+	Hint string `json:"hint,omitempty"`
+}
+
+// DiagnosePassthrough follows the path from the host's USB stack to an open board and reports where it breaks,
+// for containers that cannot find a board the host sees: the board in sysfs, the usbfs mount, the board's device
+// node, the device cgroup and permissions (by opening the node), and seccomp or LSM policy (by a harmless usbfs
+// ioctl). Checks stop at the first failure, whose Hint names the container option missing. The first Diagnosis
+// says which container runtime, if any, was detected. It is only supported on Linux.
+func DiagnosePassthrough() ([]Diagnosis, error) {
+	return diagnosePassthrough(VendorID, ProductID, detectContainer())
+}
+
+// notFoundErr reports that no board matching vid:pid could be opened, explained by the first failed passthrough
+// check if there is one.
+func notFoundErr(vid, pid uint16) error {
+	diagnoses, _ := diagnosePassthrough(vid, pid, detectContainer())
+
+	for _, d := range diagnoses {
+		if !d.OK {
+			return fmt.Errorf("0x%04x:0x%04x: %w: %s; %s", vid, pid, ErrDeviceNotFound, d.Detail, d.Hint)
+		}
+	}
+
+	return fmt.Errorf("0x%04x:0x%04x: %w", vid, pid, ErrDeviceNotFound)
+}
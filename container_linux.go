@@ -0,0 +1,190 @@
+//go:build linux
+// +build linux
+
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// usbfsRoot is where usbfs device nodes live.
+var usbfsRoot = "/dev/bus/usb"
+
+// usbdevfsGetCapabilities is USBDEVFS_GET_CAPABILITIES, _IOR('U', 26, __u32): a usbfs ioctl with no side effects.
+const usbdevfsGetCapabilities = 0x8004551a
+
+// detectContainer names the container runtime the process runs under, or returns "" on the host.
+func detectContainer() string {
+	// systemd-nspawn, podman, and LXC set $container.
+	if c := os.Getenv("container"); c != "" {
+		return c
+	}
+
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+
+	for file, name := range map[string]string{"/.dockerenv": "docker", "/run/.containerenv": "podman"} {
+		if _, err := os.Stat(file); err == nil {
+			return name
+		}
+	}
+
+	b, _ := os.ReadFile("/proc/1/cgroup")
+
+	for _, name := range []string{"kubepods", "docker", "libpod", "containerd", "lxc"} {
+		if strings.Contains(string(b), name) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func diagnosePassthrough(vid, pid uint16, runtime string) ([]Diagnosis, error) {
+	container := Diagnosis{Check: "container", OK: true, Detail: "not in a container"}
+	if runtime != "" {
+		container.Detail = "running in a " + runtime + " container"
+	}
+
+	out := []Diagnosis{container}
+
+	fail := func(check, detail, hint string) ([]Diagnosis, error) {
+		return append(out, Diagnosis{Check: check, Detail: detail, Hint: hint}), nil
+	}
+
+	pass := func(check, detail string) {
+		out = append(out, Diagnosis{Check: check, OK: true, Detail: detail})
+	}
+
+	if _, err := os.Stat(sysfsUSBDevices); err != nil {
+		// With sysfs mounted, a missing usb bus means the kernel has no USB host controller.
+		if _, err := os.Stat(filepath.Dir(filepath.Dir(sysfsUSBDevices))); err == nil {
+			return fail("sysfs", fmt.Sprintf("%s is missing", sysfsUSBDevices),
+				"the kernel has no USB host controller; load its driver on the host, or give the virtual machine a USB controller")
+		}
+
+		return fail("sysfs", fmt.Sprintf("%s is missing", sysfsUSBDevices),
+			"mount sysfs at /sys (read-only is enough), as container runtimes do by default")
+	}
+
+	dir, ok := sysfsDevice(vid, pid)
+	if !ok {
+		hint := "plug the board in and check the host's kernel log"
+		if runtime != "" {
+			hint = "plug the board into the host (containers see the host's USB buses in sysfs, so it is missing there too)"
+		}
+
+		return fail("sysfs", fmt.Sprintf("no %04x:%04x device on the USB buses", vid, pid),
+			hint+"; in a virtual machine, pass the USB device through to the guest first")
+	}
+
+	bus, dev := sysfsString(dir, "busnum"), sysfsString(dir, "devnum")
+	number := sysfsString(dir, "dev")
+
+	var busnum, devnum int
+
+	fmt.Sscan(bus, &busnum)
+	fmt.Sscan(dev, &devnum)
+
+	node := filepath.Join(usbfsRoot, fmt.Sprintf("%03d", busnum), fmt.Sprintf("%03d", devnum))
+
+	pass("sysfs", fmt.Sprintf("board at %s (usb %s, device %s)", filepath.Base(dir), node, number))
+
+	// A replug gives the board a new node, which is why passing single nodes breaks.
+	passAll := "pass the whole directory with -v /dev/bus/usb:/dev/bus/usb --device-cgroup-rule='c 189:* rmw', " +
+		"which keeps working after the board is replugged (in Kubernetes, mount /dev/bus/usb as a hostPath volume in a " +
+		"privileged pod or use a USB device plugin)"
+
+	if _, err := os.Stat(usbfsRoot); err != nil {
+		if runtime != "" {
+			return fail("usbfs", fmt.Sprintf("%s does not exist in the container", usbfsRoot),
+				fmt.Sprintf("the board was not passed through: add --device %s, or %s", node, passAll))
+		}
+
+		return fail("usbfs", fmt.Sprintf("%s does not exist", usbfsRoot), "mount devtmpfs at /dev; udev or the kernel creates the usbfs nodes")
+	}
+
+	var st unix.Stat_t
+
+	err := unix.Stat(node, &st)
+	if err != nil {
+		hint := "the node should appear when the board enumerates; check udev"
+		if runtime != "" {
+			hint = "only other nodes were passed through, perhaps from before the board was replugged: " + passAll
+		}
+
+		return fail("device-node", fmt.Sprintf("%s does not exist", node), hint)
+	}
+
+	actual := fmt.Sprintf("%d:%d", unix.Major(uint64(st.Rdev)), unix.Minor(uint64(st.Rdev)))
+
+	if st.Mode&unix.S_IFMT != unix.S_IFCHR || (number != "" && actual != number) {
+		return fail("device-node", fmt.Sprintf("%s is not the board's character device %s", node, number),
+			"the node is stale or was bind-mounted as a file; pass the device with --device, or "+passAll)
+	}
+
+	pass("device-node", fmt.Sprintf("%s is character device %s", node, actual))
+
+	f, err := os.OpenFile(node, os.O_RDWR, 0)
+	if err != nil {
+		switch {
+		case errors.Is(err, syscall.EPERM) && runtime != "":
+			return fail("open", fmt.Sprintf("opening %s: %v", node, err),
+				fmt.Sprintf("the container's device cgroup does not allow c %s; add --device-cgroup-rule='c 189:* rmw' "+
+					"(--device adds a rule for the node it passes)", actual))
+		case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+			hint := accessHint(node, vid, pid)
+			if runtime != "" {
+				hint = fmt.Sprintf("%s is mode %04o, uid %d, gid %d; run as root in the container or add its group with --group-add %d",
+					node, st.Mode&0o777, st.Uid, st.Gid, st.Gid)
+			}
+
+			return fail("open", fmt.Sprintf("opening %s: %v", node, err), hint)
+		default:
+			return fail("open", fmt.Sprintf("opening %s: %v", node, err), "check the host's kernel log for USB errors")
+		}
+	}
+
+	defer f.Close()
+
+	pass("open", fmt.Sprintf("%s opened for reading and writing", node))
+
+	_, err = unix.IoctlGetUint32(int(f.Fd()), usbdevfsGetCapabilities)
+	if err != nil && !errors.Is(err, unix.ENOTTY) && !errors.Is(err, unix.EINVAL) {
+		if seccompFiltered() {
+			return fail("ioctl", fmt.Sprintf("usbfs ioctl: %v", err),
+				"a seccomp filter blocks ioctl; allow it in the container's seccomp profile, or test with --security-opt seccomp=unconfined")
+		}
+
+		return fail("ioctl", fmt.Sprintf("usbfs ioctl: %v", err),
+			"an LSM denies usbfs ioctls; look for AppArmor or SELinux denials in the host's audit log")
+	}
+
+	pass("ioctl", "usbfs ioctls are allowed")
+
+	return out, nil
+}
+
+// seccompFiltered reports whether the process runs under a seccomp filter.
+func seccompFiltered() bool {
+	b, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+
+	for line := range strings.Lines(string(b)) {
+		if v, ok := strings.CutPrefix(line, "Seccomp:"); ok {
+			return strings.TrimSpace(v) == "2"
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+package infnoise
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosePassthrough(t *testing.T) {
+	sysfs, usbfs := t.TempDir(), filepath.Join(t.TempDir(), "usb")
+
+	defer func(sysfs, usbfs string) { sysfsUSBDevices, usbfsRoot = sysfs, usbfs }(sysfsUSBDevices, usbfsRoot)
+
+	sysfsUSBDevices, usbfsRoot = sysfs, usbfs
+
+	// last returns the failed check ending the diagnoses.
+	last := func(runtime string) Diagnosis {
+		t.Helper()
+
+		diagnoses, err := diagnosePassthrough(VendorID, ProductID, runtime)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diagnoses[0].Check != "container" || !strings.Contains(diagnoses[0].Detail, runtime) {
+			t.Fatalf("first diagnosis %+v", diagnoses[0])
+		}
+
+		d := diagnoses[len(diagnoses)-1]
+		if d.OK || d.Hint == "" {
+			t.Fatalf("last diagnosis %+v passed", d)
+		}
+
+		return d
+	}
+
+	if d := last("docker"); d.Check != "sysfs" || !strings.Contains(d.Detail, "0403:6015") {
+		t.Fatalf("without a board: %+v", d)
+	}
+
+	board := filepath.Join(sysfs, "1-1.4")
+
+	err := os.MkdirAll(board, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, v := range map[string]string{"idVendor": "0403", "idProduct": "6015", "busnum": "1", "devnum": "7", "dev": "189:6"} {
+		err = os.WriteFile(filepath.Join(board, name), []byte(v+"\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	node := filepath.Join(usbfs, "001", "007")
+
+	if d := last("docker"); d.Check != "usbfs" || !strings.Contains(d.Hint, "--device "+node) {
+		t.Fatalf("without usbfs: %+v", d)
+	}
+
+	err = os.MkdirAll(filepath.Dir(node), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := last("docker"); d.Check != "device-node" || !strings.Contains(d.Hint, "--device-cgroup-rule") {
+		t.Fatalf("without the node: %+v", d)
+	}
+
+	err = os.WriteFile(node, nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := last(""); d.Check != "device-node" || !strings.Contains(d.Detail, "189:6") {
+		t.Fatalf("with a file for a node: %+v", d)
+	}
+
+	err = notFoundErr(VendorID, ProductID)
+	if !errors.Is(err, ErrDeviceNotFound) || !strings.Contains(err.Error(), "is not the board's character device") {
+		t.Fatalf("notFoundErr: %v", err)
+	}
+}
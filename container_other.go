@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package infnoise
+
+import "errors"
+
+func detectContainer() string {
+	return ""
+}
+
+func diagnosePassthrough(vid, pid uint16, runtime string) ([]Diagnosis, error) {
+	return nil, errors.New("passthrough diagnostics are only supported on Linux")
+}
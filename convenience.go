@@ -0,0 +1,84 @@
+package infnoise
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+)
+
+// Bytes returns n bytes of whitened output (see ReadWhitened). Small requests are served from an internal buffer so
+// they do not each cost a USB round trip.
+func (d *Device) Bytes(n int) ([]byte, error) {
+	out := make([]byte, n)
+
+	err := d.readBuffered(out)
+	if err != nil {
+		clear(out)
+
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Uint32 returns a uniformly distributed 32-bit value from the device's whitened output, buffered like Bytes.
+func (d *Device) Uint32() (uint32, error) {
+	var b [4]byte
+
+	err := d.readBuffered(b[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+// Uint64 returns a uniformly distributed 64-bit value from the device's whitened output, buffered like Bytes.
+func (d *Device) Uint64() (uint64, error) {
+	var b [8]byte
+
+	err := d.readBuffered(b[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+// Chunks yields successive size-byte chunks of whitened output (see ReadWhitened) until the loop stops or a read
+// fails, in which case the error is yielded with a nil chunk and iteration ends. The chunk is reused and wiped after
+// each iteration; copy it to keep it.
+func (d *Device) Chunks(size int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if size <= 0 {
+			yield(nil, fmt.Errorf("invalid chunk size %d", size))
+
+			return
+		}
+
+		buf := make([]byte, size)
+
+		defer clear(buf)
+
+		for {
+			_, err := d.ReadWhitened(buf)
+			if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			if !yield(buf, nil) {
+				return
+			}
+
+			clear(buf)
+		}
+	}
+}
+
+func (d *Device) readBuffered(p []byte) error {
+	_, err := d.spare.Read(p)
+
+	return err
+}
@@ -0,0 +1,147 @@
+package infnoise
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// DefaultFalsePositiveRate is the default probability that a continuous test fails a healthy source, per sample
+	// (SP 800-90B recommends 2^-20 to 2^-40).
+	DefaultFalsePositiveRate = 0x1p-30
+
+	// DefaultMinEntropy is the default assessed min-entropy per raw bit. It is deliberately below the board's
+	// theoretical 0.864 bits of Shannon entropy, since min-entropy is the smaller measure.
+	DefaultMinEntropy = 0.5
+
+	// AdaptiveProportionWindow is the window of the adaptive proportion test for binary samples (SP 800-90B 4.4.2).
+	AdaptiveProportionWindow = 1024
+)
+
+// Cutoffs are the thresholds of the SP 800-90B continuous health tests, derived from a false positive rate and the
+// assessed min-entropy of the raw bits. The repetition count test fails when RepetitionCount identical bits occur
+// in a row; the adaptive proportion test fails when the first bit of a window of AdaptiveWindow bits occurs
+// AdaptiveProportion times in it.
+type Cutoffs struct {
+	FalsePositiveRate float64 `json:"falsePositiveRate"`
+	MinEntropy        float64 `json:"minEntropy"`
+
+	RepetitionCount    int `json:"repetitionCount"`
+	AdaptiveProportion int `json:"adaptiveProportion"`
+	AdaptiveWindow     int `json:"adaptiveWindow"`
+}
+
+// NewCutoffs derives the continuous test cutoffs for false positive rate alpha and min-entropy h bits per bit,
+// following SP 800-90B 4.4.1 and 4.4.2.
+func NewCutoffs(alpha, h float64) (Cutoffs, error) {
+	if !(alpha > 0 && alpha < 1) || !(h > 0 && h <= 1) {
+		return Cutoffs{}, fmt.Errorf("invalid false positive rate %g or min-entropy %g (need 0 < rate < 1 and 0 < min-entropy <= 1)", alpha, h)
+	}
+
+	return Cutoffs{
+		FalsePositiveRate:  alpha,
+		MinEntropy:         h,
+		RepetitionCount:    1 + int(math.Ceil(-math.Log2(alpha)/h)),
+		AdaptiveProportion: 1 + critBinom(AdaptiveProportionWindow, math.Exp2(-h), alpha),
+		AdaptiveWindow:     AdaptiveProportionWindow,
+	}, nil
+}
+
+// critBinom returns the smallest k for which a binomial(n, p) variable exceeds k with probability at most alpha,
+// i.e. CRITBINOM(n, p, 1-alpha). The tail is summed from the top so tiny alphas keep their precision.
+func critBinom(n int, p, alpha float64) int {
+	lnP, lnQ := math.Log(p), math.Log1p(-p)
+	lgN, _ := math.Lgamma(float64(n + 1))
+
+	var tail float64
+
+	for k := n; k > 0; k-- {
+		lgK, _ := math.Lgamma(float64(k + 1))
+		lgNK, _ := math.Lgamma(float64(n - k + 1))
+
+		pmf := math.Exp(lgN - lgK - lgNK + float64(k)*lnP + float64(n-k)*lnQ)
+
+		// tail is P(X > k-1) from here on.
+		if tail+pmf > alpha {
+			return k
+		}
+
+		tail += pmf
+	}
+
+	return 0
+}
+
+// continuousTests runs the repetition count and adaptive proportion tests over the raw bits.
+type continuousTests struct {
+	cutoffs Cutoffs
+
+	last byte
+	run  int
+
+	reference byte
+	seen      int
+	matches   int
+
+	// repetitionFailures and proportionFailures count failures since the last reset.
+	repetitionFailures uint64
+	proportionFailures uint64
+}
+
+// add feeds one bit to both tests and returns a description of the failure, if any.
+func (c *continuousTests) add(bit byte) string {
+	var failed string
+
+	if c.run > 0 && bit == c.last {
+		c.run++
+	} else {
+		c.last, c.run = bit, 1
+	}
+
+	if c.run == c.cutoffs.RepetitionCount {
+		c.repetitionFailures++
+		failed = fmt.Sprintf("repetition count test failed: %d identical bits", c.run)
+	}
+
+	if c.seen == 0 {
+		c.reference = bit
+	}
+
+	if bit == c.reference {
+		c.matches++
+	}
+
+	c.seen++
+
+	if c.matches == c.cutoffs.AdaptiveProportion {
+		c.proportionFailures++
+		failed = fmt.Sprintf("adaptive proportion test failed: %d of %d bits were %d", c.matches, c.seen, c.reference)
+	}
+
+	if c.seen == c.cutoffs.AdaptiveWindow {
+		c.seen, c.matches = 0, 0
+	}
+
+	return failed
+}
+
+// reset restarts both tests and clears their failure counts.
+func (c *continuousTests) reset() {
+	*c = continuousTests{cutoffs: c.cutoffs}
+}
+
+// results reports the tests for a Report.
+func (c *continuousTests) results() []TestResult {
+	return []TestResult{
+		{
+			Name:   "repetition-count",
+			Pass:   c.repetitionFailures == 0,
+			Detail: fmt.Sprintf("%d failures, cutoff %d identical bits", c.repetitionFailures, c.cutoffs.RepetitionCount),
+		},
+		{
+			Name:   "adaptive-proportion",
+			Pass:   c.proportionFailures == 0,
+			Detail: fmt.Sprintf("%d failures, cutoff %d of %d bits", c.proportionFailures, c.cutoffs.AdaptiveProportion, c.cutoffs.AdaptiveWindow),
+		},
+	}
+}
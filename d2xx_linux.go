@@ -0,0 +1,550 @@
+//go:build linux
+// +build linux
+
+package infnoise
+
+/*
+#cgo LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef unsigned int FT_STATUS;
+typedef unsigned int DWORD;
+typedef void *FT_HANDLE;
+
+static void *d2xx_open_lib(const char *name) {
+	return dlopen(name, RTLD_NOW | RTLD_LOCAL);
+}
+
+static void *d2xx_sym(void *lib, const char *name) {
+	return dlsym(lib, name);
+}
+
+static const char *d2xx_dlerror(void) {
+	return dlerror();
+}
+
+static FT_STATUS d2xx_create_device_info_list(void *fn, DWORD *n) {
+	return ((FT_STATUS (*)(DWORD *))fn)(n);
+}
+
+static FT_STATUS d2xx_get_device_info_detail(void *fn, DWORD i, DWORD *flags, DWORD *type, DWORD *id, DWORD *loc, void *serial, void *desc, FT_HANDLE *h) {
+	return ((FT_STATUS (*)(DWORD, DWORD *, DWORD *, DWORD *, DWORD *, void *, void *, FT_HANDLE *))fn)(i, flags, type, id, loc, serial, desc, h);
+}
+
+static FT_STATUS d2xx_set_vid_pid(void *fn, DWORD vid, DWORD pid) {
+	return ((FT_STATUS (*)(DWORD, DWORD))fn)(vid, pid);
+}
+
+static FT_STATUS d2xx_open_ex(void *fn, void *arg, DWORD flags, FT_HANDLE *h) {
+	return ((FT_STATUS (*)(void *, DWORD, FT_HANDLE *))fn)(arg, flags, h);
+}
+
+static FT_STATUS d2xx_handle(void *fn, FT_HANDLE h) {
+	return ((FT_STATUS (*)(FT_HANDLE))fn)(h);
+}
+
+static FT_STATUS d2xx_handle_u32(void *fn, FT_HANDLE h, DWORD a) {
+	return ((FT_STATUS (*)(FT_HANDLE, DWORD))fn)(h, a);
+}
+
+static FT_STATUS d2xx_handle_u32_u32(void *fn, FT_HANDLE h, DWORD a, DWORD b) {
+	return ((FT_STATUS (*)(FT_HANDLE, DWORD, DWORD))fn)(h, a, b);
+}
+
+static FT_STATUS d2xx_handle_u8(void *fn, FT_HANDLE h, unsigned char a) {
+	return ((FT_STATUS (*)(FT_HANDLE, unsigned char))fn)(h, a);
+}
+
+static FT_STATUS d2xx_handle_u8_u8(void *fn, FT_HANDLE h, unsigned char a, unsigned char b) {
+	return ((FT_STATUS (*)(FT_HANDLE, unsigned char, unsigned char))fn)(h, a, b);
+}
+
+static FT_STATUS d2xx_set_chars(void *fn, FT_HANDLE h, unsigned char a, unsigned char b, unsigned char c, unsigned char d) {
+	return ((FT_STATUS (*)(FT_HANDLE, unsigned char, unsigned char, unsigned char, unsigned char))fn)(h, a, b, c, d);
+}
+
+static FT_STATUS d2xx_set_flow_control(void *fn, FT_HANDLE h, unsigned short flow, unsigned char xon, unsigned char xoff) {
+	return ((FT_STATUS (*)(FT_HANDLE, unsigned short, unsigned char, unsigned char))fn)(h, flow, xon, xoff);
+}
+
+static FT_STATUS d2xx_handle_pu32(void *fn, FT_HANDLE h, DWORD *a) {
+	return ((FT_STATUS (*)(FT_HANDLE, DWORD *))fn)(h, a);
+}
+
+static FT_STATUS d2xx_handle_buf(void *fn, FT_HANDLE h, void *buf, DWORD n) {
+	return ((FT_STATUS (*)(FT_HANDLE, void *, DWORD))fn)(h, buf, n);
+}
+
+static FT_STATUS d2xx_io(void *fn, FT_HANDLE h, void *buf, DWORD n, DWORD *done) {
+	return ((FT_STATUS (*)(FT_HANDLE, void *, DWORD, DWORD *))fn)(h, buf, n, done);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const (
+	ftOK = 0
+
+	ftPurgeRx = 1
+	ftPurgeTx = 2
+
+	ftOpenBySerialNumber = 1
+
+	ftFlowNone = 0x0000
+)
+
+var d2xxLibNames = []string{"libftd2xx.so", "libftd2xx.so.1"}
+
+type d2xxLib struct {
+	createDeviceInfoList unsafe.Pointer
+	getDeviceInfoDetail  unsafe.Pointer
+	setVIDPID            unsafe.Pointer
+	openEx               unsafe.Pointer
+
+	close            unsafe.Pointer
+	resetDevice      unsafe.Pointer
+	purge            unsafe.Pointer
+	setUSBParameters unsafe.Pointer
+	setChars         unsafe.Pointer
+	setFlowControl   unsafe.Pointer
+	setTimeouts      unsafe.Pointer
+	setLatencyTimer  unsafe.Pointer
+	setBaudRate      unsafe.Pointer
+	setBitMode       unsafe.Pointer
+
+	write unsafe.Pointer
+	read  unsafe.Pointer
+
+	eeUASize  unsafe.Pointer
+	eeUARead  unsafe.Pointer
+	eeUAWrite unsafe.Pointer
+}
+
+var (
+	d2xxOnce sync.Once
+	d2xx     *d2xxLib
+	d2xxErr  error
+)
+
+type d2xxHandle struct {
+	lib      *d2xxLib
+	ftHandle C.FT_HANDLE
+}
+
+func init() {
+	RegisterBackend(BackendD2XX, func() Backend {
+		return &d2xxHandle{}
+	})
+}
+
+func loadD2XX() (*d2xxLib, error) {
+	d2xxOnce.Do(func() {
+		var handle unsafe.Pointer
+
+		for _, name := range d2xxLibNames {
+			cName := C.CString(name)
+
+			handle = C.d2xx_open_lib(cName)
+
+			C.free(unsafe.Pointer(cName))
+
+			if handle != nil {
+				break
+			}
+		}
+
+		if handle == nil {
+			d2xxErr = fmt.Errorf("libftd2xx.so not available: %w: %s", ErrDriverUnavailable, C.GoString(C.d2xx_dlerror()))
+
+			return
+		}
+
+		lib := &d2xxLib{}
+
+		syms := []struct {
+			name     string
+			dst      *unsafe.Pointer
+			optional bool
+		}{
+			{"FT_CreateDeviceInfoList", &lib.createDeviceInfoList, false},
+			{"FT_GetDeviceInfoDetail", &lib.getDeviceInfoDetail, false},
+			{"FT_SetVIDPID", &lib.setVIDPID, true},
+			{"FT_OpenEx", &lib.openEx, false},
+			{"FT_Close", &lib.close, false},
+			{"FT_ResetDevice", &lib.resetDevice, false},
+			{"FT_Purge", &lib.purge, false},
+			{"FT_SetUSBParameters", &lib.setUSBParameters, false},
+			{"FT_SetChars", &lib.setChars, false},
+			{"FT_SetFlowControl", &lib.setFlowControl, false},
+			{"FT_SetTimeouts", &lib.setTimeouts, false},
+			{"FT_SetLatencyTimer", &lib.setLatencyTimer, false},
+			{"FT_SetBaudRate", &lib.setBaudRate, false},
+			{"FT_SetBitMode", &lib.setBitMode, false},
+			{"FT_Write", &lib.write, false},
+			{"FT_Read", &lib.read, false},
+			{"FT_EE_UASize", &lib.eeUASize, true},
+			{"FT_EE_UARead", &lib.eeUARead, true},
+			{"FT_EE_UAWrite", &lib.eeUAWrite, true},
+		}
+
+		for _, sym := range syms {
+			cName := C.CString(sym.name)
+
+			*sym.dst = C.d2xx_sym(handle, cName)
+
+			C.free(unsafe.Pointer(cName))
+
+			if *sym.dst == nil && !sym.optional {
+				d2xxErr = fmt.Errorf("libftd2xx.so is missing symbol %s", sym.name)
+
+				return
+			}
+		}
+
+		d2xx = lib
+	})
+
+	return d2xx, d2xxErr
+}
+
+// Open connects to the first device matching vid:pid through libftd2xx.so.
+func (h *d2xxHandle) Open(vid, pid uint16) error {
+	lib, err := loadD2XX()
+	if err != nil {
+		return err
+	}
+
+	// libftd2xx on Linux only enumerates FTDI's default PIDs unless told otherwise.
+	if lib.setVIDPID != nil {
+		C.d2xx_set_vid_pid(lib.setVIDPID, C.DWORD(vid), C.DWORD(pid))
+	}
+
+	serial, err := lib.findFirstDeviceSerial(vid, pid)
+	if err != nil {
+		return err
+	}
+
+	serialZ := C.CString(serial)
+	defer C.free(unsafe.Pointer(serialZ))
+
+	var handle C.FT_HANDLE
+
+	st := C.d2xx_open_ex(lib.openEx, unsafe.Pointer(serialZ), ftOpenBySerialNumber, &handle)
+	if st != ftOK {
+		if err := diagnoseAccess(vid, pid); err != nil {
+			return err
+		}
+
+		// D2XX cannot detach kernel drivers itself.
+		if driver := kernelDriver(vid, pid); driver != "" && driver != "usbfs" {
+			return fmt.Errorf("FT_OpenEx(by serial=%q) failed: %w: %w (%s); unbind it or unload the module", serial, FTStatus(st), ErrKernelDriver, driver)
+		}
+
+		return fmt.Errorf("FT_OpenEx(by serial=%q) failed: %w (is the ftdi_sio kernel module unloaded?)", serial, FTStatus(st))
+	}
+
+	h.lib = lib
+	h.ftHandle = handle
+
+	st = C.d2xx_handle(lib.resetDevice, h.ftHandle)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_ResetDevice failed: %w", FTStatus(st))
+	}
+
+	st = C.d2xx_handle_u32(lib.purge, h.ftHandle, ftPurgeRx|ftPurgeTx)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_Purge failed: %w", FTStatus(st))
+	}
+
+	st = C.d2xx_handle_u32_u32(lib.setUSBParameters, h.ftHandle, 65536, 65536)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_SetUSBParameters failed: %w", FTStatus(st))
+	}
+
+	st = C.d2xx_set_chars(lib.setChars, h.ftHandle, 0, 0, 0, 0)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_SetChars failed: %w", FTStatus(st))
+	}
+
+	st = C.d2xx_set_flow_control(lib.setFlowControl, h.ftHandle, ftFlowNone, 0, 0)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_SetFlowControl failed: %w", FTStatus(st))
+	}
+
+	st = C.d2xx_handle_u8(lib.setLatencyTimer, h.ftHandle, 2)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_SetLatencyTimer failed: %w", FTStatus(st))
+	}
+
+	err = h.SetTimeout(0)
+	if err != nil {
+		h.Close()
+
+		return err
+	}
+
+	st = C.d2xx_handle_u8_u8(lib.setBitMode, h.ftHandle, 0, 0)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_SetBitMode(reset) failed: %w", FTStatus(st))
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	st = C.d2xx_handle_u32(lib.setBaudRate, h.ftHandle, 30000)
+	if st != ftOK {
+		h.Close()
+
+		return fmt.Errorf("FT_SetBaudRate failed: %w", FTStatus(st))
+	}
+
+	return nil
+}
+
+func (h *d2xxHandle) SetBitMode(mask byte, mode byte) error {
+	st := C.d2xx_handle_u8_u8(h.lib.setBitMode, h.ftHandle, C.uchar(mask), C.uchar(mode))
+	if st != ftOK {
+		return fmt.Errorf("FT_SetBitMode(mask=0x%02x, mode=0x%02x) failed: %w", mask, mode, FTStatus(st))
+	}
+
+	// Only bitbang mode echoes one byte per byte written; other modes would stall the prime read.
+	if mode == BitModeSyncBitbang {
+		buf := make([]byte, 64)
+
+		err := h.Write(buf)
+		if err != nil {
+			return fmt.Errorf("prime write failed: %w", err)
+		}
+
+		err = h.Read(buf)
+		if err != nil {
+			return fmt.Errorf("prime read failed: %w", err)
+		}
+	}
+
+	st = C.d2xx_handle_u32(h.lib.purge, h.ftHandle, ftPurgeRx|ftPurgeTx)
+	if st != ftOK {
+		return fmt.Errorf("FT_Purge(after bitmode) failed: %w", FTStatus(st))
+	}
+
+	return nil
+}
+
+// SetTimeout bounds subsequent Write and Read calls to d.
+func (h *d2xxHandle) SetTimeout(d time.Duration) error {
+	ms := C.DWORD(timeoutMillis(d))
+
+	st := C.d2xx_handle_u32_u32(h.lib.setTimeouts, h.ftHandle, ms, ms)
+	if st != ftOK {
+		return fmt.Errorf("FT_SetTimeouts failed: %w", FTStatus(st))
+	}
+
+	return nil
+}
+
+// SetLatencyTimer sets how long the chip holds back a partly filled bulk-in packet, in milliseconds.
+func (h *d2xxHandle) SetLatencyTimer(ms byte) error {
+	st := C.d2xx_handle_u8(h.lib.setLatencyTimer, h.ftHandle, C.uchar(ms))
+	if st != ftOK {
+		return fmt.Errorf("FT_SetLatencyTimer failed: %w", FTStatus(st))
+	}
+
+	return nil
+}
+
+func (h *d2xxHandle) Write(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var written C.DWORD
+
+	st := C.d2xx_io(h.lib.write, h.ftHandle, unsafe.Pointer(&data[0]), C.DWORD(len(data)), &written)
+	if st != ftOK {
+		return fmt.Errorf("FT_Write failed: %w", FTStatus(st))
+	}
+
+	if int(written) != len(data) {
+		return fmt.Errorf("FT_Write short write: wrote %d, want %d", written, len(data))
+	}
+
+	return nil
+}
+
+func (h *d2xxHandle) Read(data []byte) error {
+	var total int
+
+	for total < len(data) {
+		var got C.DWORD
+
+		st := C.d2xx_io(h.lib.read, h.ftHandle, unsafe.Pointer(&data[total]), C.DWORD(len(data)-total), &got)
+		if st != ftOK {
+			return fmt.Errorf("FT_Read failed: %w", FTStatus(st))
+		}
+
+		if got == 0 {
+			return fmt.Errorf("FT_Read timeout/stall: got %d, want %d: %w", total, len(data), os.ErrDeadlineExceeded)
+		}
+
+		total += int(got)
+	}
+
+	return nil
+}
+
+// ReadUserArea returns the EEPROM user area.
+func (h *d2xxHandle) ReadUserArea() ([]byte, error) {
+	if h.lib.eeUASize == nil || h.lib.eeUARead == nil || h.lib.eeUAWrite == nil {
+		return nil, ErrUserAreaUnsupported
+	}
+
+	var size C.DWORD
+
+	st := C.d2xx_handle_pu32(h.lib.eeUASize, h.ftHandle, &size)
+	if st != ftOK {
+		return nil, fmt.Errorf("FT_EE_UASize failed: %w", FTStatus(st))
+	}
+
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+
+	var got C.DWORD
+
+	st = C.d2xx_io(h.lib.eeUARead, h.ftHandle, unsafe.Pointer(&buf[0]), size, &got)
+	if st != ftOK {
+		return nil, fmt.Errorf("FT_EE_UARead failed: %w", FTStatus(st))
+	}
+
+	return buf[:got], nil
+}
+
+// WriteUserArea writes p to the start of the EEPROM user area.
+func (h *d2xxHandle) WriteUserArea(p []byte) error {
+	if h.lib.eeUAWrite == nil {
+		return ErrUserAreaUnsupported
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+
+	st := C.d2xx_handle_buf(h.lib.eeUAWrite, h.ftHandle, unsafe.Pointer(&p[0]), C.DWORD(len(p)))
+	if st != ftOK {
+		return fmt.Errorf("FT_EE_UAWrite failed: %w", FTStatus(st))
+	}
+
+	return nil
+}
+
+func (h *d2xxHandle) Close() error {
+	if h.ftHandle != nil {
+		C.d2xx_handle_u8_u8(h.lib.setBitMode, h.ftHandle, 0, 0)
+		C.d2xx_handle(h.lib.close, h.ftHandle)
+
+		h.ftHandle = nil
+	}
+
+	return nil
+}
+
+func (l *d2xxLib) findFirstDeviceSerial(vid, pid uint16) (string, error) {
+	var n C.DWORD
+
+	st := C.d2xx_create_device_info_list(l.createDeviceInfoList, &n)
+	if st != ftOK {
+		return "", fmt.Errorf("FT_CreateDeviceInfoList failed: %w", FTStatus(st))
+	}
+
+	if n == 0 {
+		return "", fmt.Errorf("no FTDI devices found: %w", ErrDeviceNotFound)
+	}
+
+	wantID := (uint32(vid) << 16) | uint32(pid)
+
+	serial := (*C.char)(C.malloc(16))
+	defer C.free(unsafe.Pointer(serial))
+
+	desc := (*C.char)(C.malloc(64))
+	defer C.free(unsafe.Pointer(desc))
+
+	for i := C.DWORD(0); i < n; i++ {
+		var (
+			flags   C.DWORD
+			devType C.DWORD
+			id      C.DWORD
+			locID   C.DWORD
+
+			dummyHandle C.FT_HANDLE
+		)
+
+		C.memset(unsafe.Pointer(serial), 0, 16)
+		C.memset(unsafe.Pointer(desc), 0, 64)
+
+		st = C.d2xx_get_device_info_detail(
+			l.getDeviceInfoDetail,
+			i,
+			&flags,
+			&devType,
+			&id,
+			&locID,
+			unsafe.Pointer(serial),
+			unsafe.Pointer(desc),
+			&dummyHandle,
+		)
+
+		if st != ftOK {
+			continue
+		}
+
+		if uint32(id) != wantID {
+			continue
+		}
+
+		s := C.GoStringN(serial, 16)
+		s = cString([]byte(s))
+
+		if s == "" {
+			continue
+		}
+
+		return s, nil
+	}
+
+	return "", fmt.Errorf("no matching FTDI device found for VID=0x%04x PID=0x%04x: %w", vid, pid, ErrDeviceNotFound)
+}
+
+func cString(b []byte) string {
+	var n int
+
+	for n < len(b) && b[n] != 0 {
+		n++
+	}
+
+	return string(b[:n])
+}
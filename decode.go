@@ -0,0 +1,38 @@
+package infnoise
+
+// DecodeRaw extracts the noise bitstream from pin samples captured in synchronous bitbang mode while the
+// output pattern alternates SWEN1/SWEN2: even samples carry a bit on COMP2 and odd samples on COMP1, and
+// every 8 samples form one output byte, most significant bit first.
+//
+// It decodes min(len(in)/8, len(out)) bytes into out and returns that count.
+func DecodeRaw(in, out []byte) int {
+	return decode(in, out, COMP2, COMP1)
+}
+
+// decode is DecodeRaw with the pins sampled on even and odd samples given explicitly.
+func decode(in, out []byte, evenPin, oddPin uint8) int {
+	n := min(len(in)/8, len(out))
+
+	for i := range n {
+		base := i * 8
+
+		var b uint8
+
+		for j := range 8 {
+			val := in[base+j]
+
+			evenBit := (val >> evenPin) & 1
+			oddBit := (val >> oddPin) & 1
+
+			if (j & 1) == 1 {
+				b = (b << 1) | oddBit
+			} else {
+				b = (b << 1) | evenBit
+			}
+		}
+
+		out[i] = b
+	}
+
+	return n
+}
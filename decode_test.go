@@ -0,0 +1,149 @@
+package infnoise
+
+import "testing"
+
+func TestDecodeRaw(t *testing.T) {
+	// 0xA5 = 1010 0101: bits on COMP2 for even samples and COMP1 for odd ones, with noise on the other pin.
+	in := []byte{
+		1 << COMP2, 1 << COMP2, 1<<COMP2 | 1<<COMP1, 0,
+		1 << COMP1, 1 << COMP1, 0, 1<<COMP1 | 1<<COMP2,
+		0, 0, // trailing partial byte is ignored
+	}
+
+	out := make([]byte, 4)
+
+	n := DecodeRaw(in, out)
+	if n != 1 || out[0] != 0xA5 {
+		t.Fatalf("got %d bytes, first %#x; want 1 byte 0xa5", n, out[0])
+	}
+
+	if n := DecodeRaw(in, out[:0]); n != 0 {
+		t.Fatalf("decoded %d bytes into empty output", n)
+	}
+}
+
+func FuzzDecodeRaw(f *testing.F) {
+	f.Add(simulatorSamples(f, 1024), 128)
+	f.Add([]byte{0xff, 0, 0xff, 0, 0xff, 0, 0xff}, 1)
+	f.Add([]byte{}, 0)
+
+	f.Fuzz(func(t *testing.T, in []byte, outLen int) {
+		if outLen < 0 || outLen > 1<<16 {
+			return
+		}
+
+		out := make([]byte, outLen)
+
+		n := DecodeRaw(in, out)
+		if n != min(len(in)/8, outLen) {
+			t.Fatalf("decoded %d bytes from %d samples into %d", n, len(in), outLen)
+		}
+
+		// Only the comparator pins may influence the output.
+		masked := make([]byte, len(in))
+
+		for i, v := range in {
+			masked[i] = v & (1<<COMP1 | 1<<COMP2)
+		}
+
+		again := make([]byte, outLen)
+
+		DecodeRaw(masked, again)
+
+		if string(out[:n]) != string(again[:n]) {
+			t.Fatal("output pins leaked into the decoded stream")
+		}
+	})
+}
+
+func TestPattern(t *testing.T) {
+	b := DefaultPattern().Bytes(32)
+
+	for i, v := range b {
+		swen := byte(1 << SWEN1)
+		if i&1 == 1 {
+			swen = 1 << SWEN2
+		}
+
+		if v != swen|makeAddress(uint8(i&0x0f)) {
+			t.Fatalf("default pattern byte %d = %#x", i, v)
+		}
+	}
+
+	p := Pattern{FirstAddress: 4, LastAddress: 5, Order: SwitchSWEN2First}
+
+	want := []byte{
+		1<<SWEN2 | makeAddress(4),
+		1<<SWEN1 | makeAddress(5),
+		1<<SWEN2 | makeAddress(4),
+	}
+
+	if got := p.Bytes(3); string(got) != string(want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	if (Pattern{FirstAddress: 3, LastAddress: 2}).Validate() == nil || (Pattern{LastAddress: 16}).Validate() == nil {
+		t.Fatal("invalid address range accepted")
+	}
+}
+
+// makeAddress is the original address encoding for the stock pin assignment.
+func makeAddress(addr uint8) uint8 {
+	var value uint8
+
+	if addr&1 != 0 {
+		value |= 1 << ADDR0
+	}
+
+	if addr&2 != 0 {
+		value |= 1 << ADDR1
+	}
+
+	if addr&4 != 0 {
+		value |= 1 << ADDR2
+	}
+
+	if addr&8 != 0 {
+		value |= 1 << ADDR3
+	}
+
+	return value
+}
+
+func TestProfile(t *testing.T) {
+	if ProfileV2.Mask() != Mask {
+		t.Fatalf("ProfileV2 mask %#x, want %#x", ProfileV2.Mask(), Mask)
+	}
+
+	for _, p := range []Profile{ProfileV1, ProfileV2} {
+		if err := p.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bad := ProfileV2
+	bad.SWEN1 = bad.COMP1
+
+	if bad.Validate() == nil {
+		t.Fatal("profile with a pin assigned twice accepted")
+	}
+
+	if ProfileV1.Bytes(2)[0] != 1<<ProfileV1.SWEN1 {
+		t.Fatal("ProfileV1 pattern does not drive its own switch pin")
+	}
+}
+
+// BenchmarkDecodeRaw replays a simulated capture of one IOBatch through the bit extraction. Profile it with
+//
+//	go test -run=NONE -bench=DecodeRaw -cpuprofile=cpu.out
+func BenchmarkDecodeRaw(b *testing.B) {
+	capture := simulatorSamples(b, IOBatch)
+	out := make([]byte, IOBatch/8)
+
+	b.ReportAllocs()
+	b.SetBytes(IOBatch)
+
+	for b.Loop() {
+		DecodeRaw(capture, out)
+	}
+}
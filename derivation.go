@@ -0,0 +1,79 @@
+package infnoise
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+const (
+	// derivationScheme versions the whitening construction: its labels, what each chunk absorbs, and the ratchet.
+	// It changes whenever the vectors of the conditioner KATs do.
+	derivationScheme = 1
+
+	// modulePath is the import path of this module, for finding its version in the build info.
+	modulePath = "github.com/coalaura/infnoise"
+)
+
+// DerivationHeader carries the DerivationID of the output in responses of Handler's /whitened.
+const DerivationHeader = "Infnoise-Derivation"
+
+// moduleVersion is the version of this module linked into the binary, or "devel" when built from a checkout.
+var moduleVersion = sync.OnceValue(func() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+
+	version := bi.Main.Version
+
+	if bi.Main.Path != modulePath {
+		version = ""
+
+		for _, dep := range bi.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+			}
+		}
+	}
+
+	if version == "" || version == "(devel)" {
+		return "devel"
+	}
+
+	return version
+})
+
+// DerivationID describes how ReadWhitened derives its output, for downstream systems to record with the entropy
+// they draw so it can be audited later. It is a list of key=value pairs separated by semicolons:
+//
+//	scheme=1;conditioner=cshake256;personalization=infnoise whitening;ratchet=on;multiplier=2;chunk=2048;reseed=off;version=v1.4.0
+//
+// scheme versions the construction as a whole, multiplier is the raw bytes absorbed per output byte, chunk the
+// WithChunkSize, reseed the WithReseedInterval, and version the module's version ("devel" when built from a
+// checkout). MixIn input is not recorded; it never weakens the output.
+func (d *Device) DerivationID() string {
+	ratchet, reseed := "off", "off"
+
+	if d.whitener.ratchet != nil {
+		ratchet = "on"
+	}
+
+	if d.reseedInterval > 0 {
+		reseed = d.reseedInterval.String()
+	}
+
+	fields := []string{
+		fmt.Sprintf("scheme=%d", derivationScheme),
+		"conditioner=cshake256",
+		"personalization=" + whitenLabel,
+		"ratchet=" + ratchet,
+		fmt.Sprintf("multiplier=%d", len(d.rawPool)/len(d.poolBuf)),
+		fmt.Sprintf("chunk=%d", len(d.poolBuf)),
+		"reseed=" + reseed,
+		"version=" + moduleVersion(),
+	}
+
+	return strings.Join(fields, ";")
+}
@@ -0,0 +1,24 @@
+package infnoise
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDerivationID(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	want := "scheme=1;conditioner=cshake256;personalization=infnoise whitening;ratchet=on;multiplier=2;chunk=2048;reseed=off;version="
+	if id := dv.DerivationID(); !strings.HasPrefix(id, want) || strings.HasSuffix(id, "=") {
+		t.Fatalf("DerivationID = %q, want %q and a version", id, want)
+	}
+
+	dv, _ = openSimulator(t, 2, DefaultSimulatorGain, WithRatchet(false), WithChunkSize(64), WithReseedInterval(time.Hour))
+
+	for _, field := range []string{";ratchet=off;", ";chunk=64;", ";reseed=1h0m0s;"} {
+		if id := dv.DerivationID(); !strings.Contains(id, field) {
+			t.Fatalf("DerivationID = %q, want %s", id, field)
+		}
+	}
+}
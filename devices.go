@@ -0,0 +1,31 @@
+package infnoise
+
+// USBDevice describes an attached board as listed by ListDevices, without opening it.
+type USBDevice struct {
+	// Path is the board's position in the USB tree (bus-port.port..., as in sysfs); Node is its usbfs device node.
+	Bus     int    `json:"bus"`
+	Address int    `json:"address"`
+	Path    string `json:"path"`
+	Node    string `json:"node"`
+
+	Serial       string `json:"serial"`
+	Manufacturer string `json:"manufacturer"`
+	Product      string `json:"product"`
+
+	// Firmware is the FT240X's device release number (bcdDevice) as major.minor.
+	Firmware string `json:"firmware"`
+
+	// Speed is the negotiated link speed in Mbit/s.
+	Speed string `json:"speed"`
+
+	// Driver is the kernel driver bound to the board's interface ("usbfs" if a program has claimed it), if any;
+	// USBIP is set if the board is attached through usbip.
+	Driver string `json:"driver,omitempty"`
+	USBIP  bool   `json:"usbip,omitempty"`
+}
+
+// ListDevices returns every attached device with VendorID and ProductID, in sysfs order. It reads sysfs and is
+// only supported on Linux.
+func ListDevices() ([]USBDevice, error) {
+	return listDevices(VendorID, ProductID)
+}
@@ -0,0 +1,59 @@
+//go:build linux
+// +build linux
+
+package infnoise
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysfsUSBDevices is where the kernel lists USB devices and their interfaces.
+var sysfsUSBDevices = "/sys/bus/usb/devices"
+
+func listDevices(vid, pid uint16) ([]USBDevice, error) {
+	dirs, err := filepath.Glob(filepath.Join(sysfsUSBDevices, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var out []USBDevice
+
+	for _, dir := range dirs {
+		// Interfaces (1-1.4:1.0) live next to the devices.
+		if strings.Contains(filepath.Base(dir), ":") || sysfsHex(dir, "idVendor") != vid || sysfsHex(dir, "idProduct") != pid {
+			continue
+		}
+
+		bus, _ := strconv.Atoi(sysfsString(dir, "busnum"))
+		addr, _ := strconv.Atoi(sysfsString(dir, "devnum"))
+		release := sysfsHex(dir, "bcdDevice")
+
+		d := USBDevice{
+			Bus:          bus,
+			Address:      addr,
+			Path:         filepath.Base(dir),
+			Node:         fmt.Sprintf("/dev/bus/usb/%03d/%03d", bus, addr),
+			Serial:       sysfsString(dir, "serial"),
+			Manufacturer: sysfsString(dir, "manufacturer"),
+			Product:      sysfsString(dir, "product"),
+			Firmware:     fmt.Sprintf("%x.%02x", release>>8, release&0xff),
+			Speed:        sysfsString(dir, "speed"),
+		}
+
+		if link, err := os.Readlink(filepath.Join(dir+":1.0", "driver")); err == nil {
+			d.Driver = filepath.Base(link)
+		}
+
+		if path, err := filepath.EvalSymlinks(dir); err == nil {
+			d.USBIP = strings.Contains(path, "/vhci_hcd")
+		}
+
+		out = append(out, d)
+	}
+
+	return out, nil
+}
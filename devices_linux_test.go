@@ -0,0 +1,62 @@
+//go:build linux
+// +build linux
+
+package infnoise
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListDevices(t *testing.T) {
+	root := t.TempDir()
+
+	write := func(dir string, attrs map[string]string) {
+		t.Helper()
+
+		err := os.MkdirAll(filepath.Join(root, dir), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, v := range attrs {
+			err = os.WriteFile(filepath.Join(root, dir, name), []byte(v+"\n"), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	write("1-1.4", map[string]string{
+		"idVendor": "0403", "idProduct": "6015", "busnum": "1", "devnum": "7", "bcdDevice": "1000",
+		"serial": "1337-0042", "manufacturer": "13-37.org", "product": "Infinite Noise TRNG", "speed": "12",
+	})
+	write("1-1.4:1.0", nil)
+	write("2-1", map[string]string{"idVendor": "046d", "idProduct": "c52b"})
+	write("drivers/ftdi_sio", nil)
+
+	err := os.Symlink(filepath.Join(root, "drivers/ftdi_sio"), filepath.Join(root, "1-1.4:1.0", "driver"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(dir string) { sysfsUSBDevices = dir }(sysfsUSBDevices)
+
+	sysfsUSBDevices = root
+
+	devices, err := ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := USBDevice{
+		Bus: 1, Address: 7, Path: "1-1.4", Node: "/dev/bus/usb/001/007",
+		Serial: "1337-0042", Manufacturer: "13-37.org", Product: "Infinite Noise TRNG",
+		Firmware: "10.00", Speed: "12", Driver: "ftdi_sio",
+	}
+
+	if len(devices) != 1 || devices[0] != want {
+		t.Fatalf("devices %+v, want %+v", devices, want)
+	}
+}
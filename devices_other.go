@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package infnoise
+
+import "errors"
+
+func listDevices(vid, pid uint16) ([]USBDevice, error) {
+	return nil, errors.New("listing devices is only supported on Linux")
+}
@@ -0,0 +1,119 @@
+package infnoise
+
+import (
+	"math"
+	"sync"
+)
+
+// driftTimeConstantBits is how many bits it takes the long-term estimate to move 63% of the way to a new level,
+// about half a minute at full rate: slow enough to ignore noise, fast enough to follow warm-up and temperature.
+const driftTimeConstantBits = 1 << 24
+
+// DriftStats reports slow changes in the noise source, which mostly follow board temperature.
+type DriftStats struct {
+	// LongTermEntropy is an exponentially weighted average of the entropy per bit.
+	LongTermEntropy float64
+
+	// Target is the expected entropy per bit (see WithTargetEntropy).
+	Target float64
+
+	// Recalibrate is set while the long-term estimate is more than half the health tolerance away from the
+	// target, well before the health check itself fails.
+	Recalibrate bool
+
+	// Temperature is the last reading passed to SetTemperature; HasTemperature reports whether there was one.
+	Temperature    float64
+	HasTemperature bool
+
+	// TemperatureCorrelation is the Pearson correlation between temperature readings and per-batch entropy
+	// estimates, or 0 until enough varied readings have been paired.
+	TemperatureCorrelation float64
+}
+
+// driftTracker follows the long-term entropy estimate and its relation to externally supplied temperatures.
+type driftTracker struct {
+	mu sync.Mutex
+
+	target    float64
+	tolerance float64
+
+	ewma    float64
+	started bool
+
+	temperature    float64
+	hasTemperature bool
+
+	// Running sums for the temperature/estimate correlation (Welford).
+	n                  float64
+	meanT, meanE       float64
+	m2T, m2E, coMoment float64
+}
+
+func (t *driftTracker) add(estimate float64, bits int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.started {
+		t.ewma = estimate
+		t.started = true
+	} else {
+		alpha := 1 - math.Exp(-float64(bits)/driftTimeConstantBits)
+
+		t.ewma += alpha * (estimate - t.ewma)
+	}
+
+	if !t.hasTemperature {
+		return
+	}
+
+	t.n++
+
+	dT := t.temperature - t.meanT
+	dE := estimate - t.meanE
+
+	t.meanT += dT / t.n
+	t.meanE += dE / t.n
+
+	t.m2T += dT * (t.temperature - t.meanT)
+	t.m2E += dE * (estimate - t.meanE)
+	t.coMoment += dT * (estimate - t.meanE)
+}
+
+func (t *driftTracker) setTemperature(celsius float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.temperature = celsius
+	t.hasTemperature = true
+}
+
+func (t *driftTracker) stats() DriftStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := DriftStats{
+		LongTermEntropy: t.ewma,
+		Target:          t.target,
+		Recalibrate:     t.started && math.Abs(t.ewma-t.target) > t.target*t.tolerance/2,
+		Temperature:     t.temperature,
+		HasTemperature:  t.hasTemperature,
+	}
+
+	if t.m2T > 0 && t.m2E > 0 {
+		stats.TemperatureCorrelation = t.coMoment / math.Sqrt(t.m2T*t.m2E)
+	}
+
+	return stats
+}
+
+// SetTemperature records an external board temperature reading in degrees Celsius. Subsequent batches are
+// paired with it to compute DriftStats.TemperatureCorrelation.
+func (d *Device) SetTemperature(celsius float64) {
+	d.drift.setTemperature(celsius)
+}
+
+// Drift returns the long-term entropy estimate, whether it calls for recalibration, and its correlation with
+// temperature readings.
+func (d *Device) Drift() DriftStats {
+	return d.drift.stats()
+}
@@ -0,0 +1,49 @@
+package infnoise
+
+import "testing"
+
+func TestDriftTracker(t *testing.T) {
+	tr := driftTracker{target: 0.864, tolerance: 0.05}
+
+	tr.setTemperature(20)
+
+	for range 100 {
+		tr.add(0.864, 1<<20)
+	}
+
+	if s := tr.stats(); s.Recalibrate {
+		t.Fatalf("on-target source flagged for recalibration: %+v", s)
+	}
+
+	// A warmer board with less entropy per bit.
+	tr.setTemperature(45)
+
+	for range 100 {
+		tr.add(0.83, 1<<20)
+	}
+
+	s := tr.stats()
+
+	if !s.Recalibrate {
+		t.Fatalf("drifted source not flagged: %+v", s)
+	}
+
+	if s.TemperatureCorrelation > -0.9 {
+		t.Fatalf("got temperature correlation %.3f, want strongly negative", s.TemperatureCorrelation)
+	}
+}
+
+func TestDeviceDrift(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	_, err := dv.Read(make([]byte, testBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := dv.Drift()
+
+	if s.Recalibrate || s.LongTermEntropy < 0.8 || s.LongTermEntropy > 0.95 {
+		t.Fatalf("unexpected drift stats for a healthy simulator: %+v", s)
+	}
+}
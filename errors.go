@@ -0,0 +1,122 @@
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrorClass tells daemons how to react to a failed read: reconnect, reset, alert a human, or stop.
+type ErrorClass int
+
+const (
+	// ClassUnknown is an error the package cannot attribute.
+	ClassUnknown ErrorClass = iota
+
+	// ClassTransport is a USB transfer failure, timeout, or disconnect. The noise source is not implicated;
+	// reopening the device usually recovers.
+	ClassTransport
+
+	// ClassProtocol is a misuse of the stream or an inconsistency in the data path, such as a read mode conflict, a
+	// repeated whitened chunk, or a failed bitbang loopback. Restarting the device resets it.
+	ClassProtocol
+
+	// ClassHealth is a failure of the entropy source itself: the health check or a noise source self-test failed.
+	// Output must not be trusted until a human has inspected the board.
+	ClassHealth
+
+	// ClassClosed means the device is closed, paused, or was never started.
+	ClassClosed
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassUnknown:
+		return "unknown"
+	case ClassTransport:
+		return "transport"
+	case ClassProtocol:
+		return "protocol"
+	case ClassHealth:
+		return "health"
+	case ClassClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("ErrorClass(%d)", int(c))
+	}
+}
+
+// Hint is the suggested remediation for errors of the class.
+func (c ErrorClass) Hint() string {
+	switch c {
+	case ClassTransport:
+		return "reconnect: close and restart the device, and check the cable or USB/IP link if it recurs"
+	case ClassProtocol:
+		return "reset: restart the device and check that raw and whitened reads are not mixed"
+	case ClassHealth:
+		return "alert: the noise source may be faulty; stop using its output and inspect the board"
+	case ClassClosed:
+		return "start or resume the device before reading"
+	default:
+		return "inspect the error"
+	}
+}
+
+// Error is the error returned by failed reads. It wraps the underlying failure, whose message it keeps, with the
+// failure's class.
+type Error struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Hint is the suggested remediation (see ErrorClass.Hint).
+func (e *Error) Hint() string {
+	return e.Class.Hint()
+}
+
+// Classify returns the class of err: the one recorded by an *Error in its chain, or else one inferred from the
+// package's errors it wraps. It returns ClassUnknown for nil.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+
+	var e *Error
+
+	if errors.As(err, &e) {
+		return e.Class
+	}
+
+	switch {
+	case errors.Is(err, ErrClosed), errors.Is(err, ErrPaused), errors.Is(err, ErrNotStarted):
+		return ClassClosed
+	case errors.Is(err, ErrHealthCheck), errors.Is(err, ErrQuarantined), errors.Is(err, ErrNoiseSource):
+		return ClassHealth
+	case errors.Is(err, ErrModeConflict), errors.Is(err, ErrDuplicateBlock), errors.Is(err, ErrBitbangPath):
+		return ClassProtocol
+	case errors.Is(err, ErrIO), errors.Is(err, ErrDisconnected), errors.Is(err, ErrDeviceNotFound),
+		errors.Is(err, ErrSimulatorDisconnected), errors.Is(err, os.ErrDeadlineExceeded):
+		return ClassTransport
+	}
+
+	return ClassUnknown
+}
+
+// classified wraps a read failure in an *Error, unless it already is one.
+func classified(err error) error {
+	var e *Error
+
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+
+	return &Error{Class: Classify(err), Err: err}
+}
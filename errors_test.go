@@ -0,0 +1,78 @@
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{nil, ClassUnknown},
+		{errors.New("other"), ClassUnknown},
+		{ErrClosed, ClassClosed},
+		{ErrNotStarted, ClassClosed},
+		{fmt.Errorf("%w: entropy 0.5", ErrHealthCheck), ClassHealth},
+		{ErrModeConflict, ClassProtocol},
+		{FTStatus(4), ClassTransport},
+		{fmt.Errorf("read: %w", os.ErrDeadlineExceeded), ClassTransport},
+		{&Error{Class: ClassHealth, Err: ErrIO}, ClassHealth},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+
+	buf := make([]byte, testBytes)
+
+	read := func(read func([]byte) (int, error)) *Error {
+		t.Helper()
+
+		_, err := read(buf)
+
+		var e *Error
+
+		if !errors.As(err, &e) {
+			t.Fatalf("got %v (%T), want *Error", err, err)
+		}
+
+		return e
+	}
+
+	dv, _ := openSimulator(t, 1, 1.5)
+
+	if e := read(dv.Read); e.Class != ClassHealth || !errors.Is(e, ErrHealthCheck) || e.Hint() == "" {
+		t.Fatalf("degraded simulator: %s error %v", e.Class, e)
+	}
+
+	if s := dv.Stats(); s.HealthErrors != 1 || s.TransportErrors != 0 || s.ProtocolErrors != 0 {
+		t.Fatalf("stats after a health failure: %+v", s)
+	}
+
+	dv, _ = openSimulator(t, 2, DefaultSimulatorGain)
+
+	_, err := dv.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := read(dv.ReadWhitened); e.Class != ClassProtocol || !errors.Is(e, ErrModeConflict) {
+		t.Fatalf("mode conflict: %s error %v", e.Class, e)
+	}
+
+	if s := dv.Stats(); s.ProtocolErrors != 1 {
+		t.Fatalf("%d protocol errors after a mode conflict", s.ProtocolErrors)
+	}
+
+	dv.Close()
+
+	if e := read(dv.Read); e.Class != ClassClosed || !errors.Is(e, ErrClosed) {
+		t.Fatalf("closed device: %s error %v", e.Class, e)
+	}
+}
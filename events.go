@@ -0,0 +1,113 @@
+package infnoise
+
+import (
+	"fmt"
+	"time"
+)
+
+// eventBuffer is the capacity of the Events channel; events beyond it are dropped rather than blocking reads.
+const eventBuffer = 64
+
+// EventKind identifies a device lifecycle event.
+type EventKind int
+
+const (
+	// EventStarted is sent when Start has opened the device.
+	EventStarted EventKind = iota
+
+	// EventStopped is sent when Close releases a started device.
+	EventStopped
+
+	// EventReconnecting and EventReconnected bracket an automatic re-open of the USB handle after a transport
+	// failure (see WithReconnect); Err holds the failure behind EventReconnecting.
+	EventReconnecting
+	EventReconnected
+
+	// EventHealthDegraded and EventHealthRecovered are sent on health check transitions (see WithHealthCallback).
+	EventHealthDegraded
+	EventHealthRecovered
+
+	// EventUSBError is sent when a bitbang batch fails in the backend; Err holds the error.
+	EventUSBError
+
+	// EventOverflowDropped is sent when the backend's ring buffer lost data; Dropped holds the bytes lost since
+	// the previous event.
+	EventOverflowDropped
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventStarted:
+		return "started"
+	case EventStopped:
+		return "stopped"
+	case EventReconnecting:
+		return "reconnecting"
+	case EventReconnected:
+		return "reconnected"
+	case EventHealthDegraded:
+		return "health degraded"
+	case EventHealthRecovered:
+		return "health recovered"
+	case EventUSBError:
+		return "usb error"
+	case EventOverflowDropped:
+		return "overflow dropped"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event describes something that happened to a Device.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Err is the failure behind EventUSBError and EventReconnecting.
+	Err error
+
+	// Estimate is the entropy estimate at a health transition.
+	Estimate float64
+
+	// Dropped is the number of bytes lost for EventOverflowDropped.
+	Dropped uint64
+}
+
+// Events returns the channel on which the device reports lifecycle events. The same channel is returned on every
+// call and is never closed, so it survives Close and a later Start. The device never blocks on it: events that
+// do not fit into its buffer are discarded.
+func (d *Device) Events() <-chan Event {
+	return d.events
+}
+
+// emit sends ev without blocking, and records it in the audit log.
+func (d *Device) emit(ev Event) {
+	ev.Time = time.Now()
+
+	d.audit(ev)
+
+	select {
+	case d.events <- ev:
+	default:
+	}
+}
+
+// checkOverflow reports data the ring buffer has lost since the last check.
+func (d *Device) checkOverflow() {
+	d.capMu.Lock()
+	ring := d.ring
+	d.capMu.Unlock()
+
+	if ring == nil {
+		return
+	}
+
+	dropped := ring.RingStats().Dropped
+	if dropped <= d.dropped {
+		return
+	}
+
+	d.emit(Event{Kind: EventOverflowDropped, Dropped: dropped - d.dropped})
+
+	d.dropped = dropped
+}
@@ -0,0 +1,104 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Export streams src to w in whole words, as binary, until the limit, the context is cancelled, or writing fails,
+// as it does once a reader such as PractRand's RNG_test exits. It returns the number of bytes written and the
+// first error, which is ctx.Err() after cancellation.
+//
+// Only whole blocks are read from src, so the output never ends in a partial word unless a write fails.
+func Export(ctx context.Context, w io.Writer, src io.Reader, opts ...option) (int64, error) {
+	o := options{word: 4, block: DefaultBlock}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch {
+	case o.word != 1 && o.word != 2 && o.word != 4 && o.word != 8:
+		return 0, fmt.Errorf("invalid word size %d (need 1, 2, 4, or 8)", o.word)
+	case o.block < o.word:
+		return 0, fmt.Errorf("invalid block size %d", o.block)
+	case o.rate < 0 || o.limit < 0:
+		return 0, fmt.Errorf("invalid rate %d or limit %d", o.rate, o.limit)
+	}
+
+	block := o.block
+
+	// Paced output is written in tenths of a second's worth, so it flows evenly.
+	if o.rate > 0 {
+		block = min(block, max(o.rate/10, o.word))
+	}
+
+	buf := make([]byte, block/o.word*o.word)
+
+	defer clear(buf)
+
+	limit := o.limit / int64(o.word) * int64(o.word)
+
+	var written int64
+
+	next := time.Now()
+
+	for o.limit == 0 || written < limit {
+		p := buf
+
+		if o.limit > 0 {
+			p = buf[:min(int64(len(buf)), limit-written)]
+		}
+
+		if o.rate > 0 {
+			select {
+			case <-ctx.Done():
+				return written, ctx.Err()
+			case <-time.After(time.Until(next)):
+			}
+
+			next = next.Add(time.Duration(len(p)) * time.Second / time.Duration(o.rate))
+
+			// Do not burst to catch up after falling behind.
+			if now := time.Now(); next.Before(now) {
+				next = now
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		_, err := io.ReadFull(src, p)
+		if err != nil {
+			return written, &readError{err}
+		}
+
+		n, err := w.Write(p)
+
+		written += int64(n)
+
+		clear(p)
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// readError is an Export failure reading the source rather than writing.
+type readError struct {
+	err error
+}
+
+func (e *readError) Error() string {
+	return "read: " + e.err.Error()
+}
+
+func (e *readError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,195 @@
+// Package external streams device output into third-party statistical test suites, PractRand and TestU01, and
+// parses their verdicts, so they can be recorded in the device's health report (see infnoise.Device.RecordTest).
+//
+// Export writes output to an io.Writer, such as standard output or a FIFO, in the binary words the suites read, at
+// a bounded rate if asked. Run starts a suite's program, feeds it on its standard input until it exits, and
+// returns the verdict parsed from what it printed.
+package external
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/coalaura/infnoise"
+)
+
+// Suite describes an external test program that reads binary output on its standard input.
+type Suite struct {
+	// Name identifies the suite in results, such as "practrand".
+	Name string
+
+	// Command and Args start the program.
+	Command string
+	Args    []string
+
+	// Word is the size in bytes of the words the program reads (see WithWord).
+	Word int
+
+	// Parse reads the program's standard output and returns its verdict.
+	Parse func(r io.Reader) (Result, error)
+}
+
+// Anomaly is a test a suite flagged.
+type Anomaly struct {
+	Test string `json:"test"`
+
+	// PValue is the p-value as the suite printed it, such as "1.2e-14", "1-3.4e-5", or "eps".
+	PValue string `json:"pValue"`
+
+	// Evaluation is the suite's verdict on the test, such as PractRand's "suspicious" or "FAIL !!".
+	Evaluation string `json:"evaluation"`
+	Fail       bool   `json:"fail"`
+}
+
+// Result is the verdict of a suite.
+type Result struct {
+	Suite string `json:"suite"`
+
+	// Bytes is the output Run streamed to the program, and Length how much of it the verdict covers, if the suite
+	// reports that.
+	Bytes  int64 `json:"bytes"`
+	Length int64 `json:"length,omitempty"`
+
+	// Tests is the number of test results the verdict is based on; Anomalies are the ones the suite flagged.
+	Tests     int       `json:"tests"`
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+	Pass      bool      `json:"pass"`
+}
+
+// TestResult summarizes r for infnoise.Device.RecordTest.
+func (r Result) TestResult() infnoise.TestResult {
+	var failed []string
+
+	for _, a := range r.Anomalies {
+		if a.Fail {
+			failed = append(failed, fmt.Sprintf("%s (p = %s)", a.Test, a.PValue))
+		}
+	}
+
+	detail := fmt.Sprintf("%d tests over %d bytes, %d anomalies", r.Tests, max(r.Length, r.Bytes), len(r.Anomalies))
+
+	if len(failed) > 0 {
+		detail += ", failed " + strings.Join(failed, ", ")
+	}
+
+	return infnoise.TestResult{Name: r.Suite, Pass: r.Pass, Detail: detail}
+}
+
+// Run starts the suite's program and exports src to it (see Export) until it exits, then parses its verdict. The
+// program is killed when ctx is cancelled. WithWord defaults to the suite's word size, and a limit, if set, should
+// leave the program enough output to finish.
+func Run(ctx context.Context, src io.Reader, s Suite, opts ...option) (Result, error) {
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		stdout bytes.Buffer
+		stderr tail
+	)
+
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if o.output != nil {
+		// exec serializes writes when both are the same writer, but not across two different ones.
+		out := &lockedWriter{w: o.output}
+
+		cmd.Stdout = io.MultiWriter(&stdout, out)
+		cmd.Stderr = io.MultiWriter(&stderr, out)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return Result{}, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return Result{}, fmt.Errorf("start %s: %w", s.Name, err)
+	}
+
+	type exported struct {
+		n   int64
+		err error
+	}
+
+	done := make(chan exported, 1)
+
+	go func() {
+		n, err := Export(ctx, stdin, src, append([]option{WithWord(s.Word)}, opts...)...)
+
+		stdin.Close()
+
+		done <- exported{n, err}
+	}()
+
+	waitErr := cmd.Wait()
+
+	// Wait closed the pipe, so the export has stopped.
+	exp := <-done
+
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	// Writes fail once the program exits; only failing to read the source matters.
+	var readErr *readError
+	if errors.As(exp.err, &readErr) {
+		return Result{}, exp.err
+	}
+
+	r, err := s.Parse(&stdout)
+	if err != nil {
+		if waitErr != nil {
+			return Result{}, fmt.Errorf("%s: %w: %s", s.Name, waitErr, stderr.String())
+		}
+
+		return Result{}, fmt.Errorf("%s: %w", s.Name, err)
+	}
+
+	r.Suite = s.Name
+	r.Bytes = exp.n
+
+	return r, nil
+}
+
+// tail keeps the end of a program's standard error for error messages.
+type tail struct {
+	buf []byte
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+
+	if over := len(t.buf) - 512; over > 0 {
+		t.buf = t.buf[over:]
+	}
+
+	return len(p), nil
+}
+
+func (t *tail) String() string {
+	return strings.TrimSpace(string(t.buf))
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
@@ -0,0 +1,253 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/coalaura/infnoise"
+)
+
+const practRandOutput = `RNG_test using PractRand version 0.95
+RNG = RNG_stdin8, seed = unknown
+test set = core, folding = standard (8 bit)
+
+rng=RNG_stdin8, seed=unknown
+length= 1 megabyte (2^20 bytes), time= 0.2 seconds
+  no anomalies in 67 test result(s)
+
+rng=RNG_stdin8, seed=unknown
+length= 2 megabytes (2^21 bytes), time= 0.6 seconds
+  Test Name                         Raw       Processed     Evaluation
+  BCFN(2+0,13-3,T)                  R= +29.0  p =  1.2e-14    FAIL !
+  [Low1/8]DC6-9x1Bytes-1            R=  +5.6  p =  4.7e-3   unusual
+  ...and 82 test result(s) without anomalies
+
+`
+
+const testU01Passed = `
+========= Summary results of SmallCrush =========
+
+ Version:          TestU01 1.2.3
+ Generator:        stdin
+ Number of statistics:  15
+ Total CPU time:   00:00:09.23
+
+ All tests were passed
+
+`
+
+const testU01Flagged = `
+========= Summary results of Crush =========
+
+ Version:          TestU01 1.2.3
+ Generator:        stdin
+ Number of statistics:  144
+ Total CPU time:   00:35:12.10
+ The following tests gave p-values outside [0.001, 0.9990]:
+ (eps  means a value < 1.0e-300):
+ (eps1 means a value < 1.0e-15):
+
+       Test                          p-value
+ ----------------------------------------------
+  1  SerialOver, t = 2                 eps
+ 11  BirthdaySpacings, t = 2         1 - eps1
+ 25  MaxOft AD                        4.5e-4
+ ----------------------------------------------
+ All other tests were passed
+
+`
+
+func TestParsePractRand(t *testing.T) {
+	r, err := ParsePractRand(strings.NewReader(practRandOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Pass || r.Length != 1<<21 || r.Tests != 84 || len(r.Anomalies) != 2 {
+		t.Fatalf("parsed %+v", r)
+	}
+
+	if a := r.Anomalies[0]; a.Test != "BCFN(2+0,13-3,T)" || a.PValue != "1.2e-14" || a.Evaluation != "FAIL !" || !a.Fail {
+		t.Fatalf("first anomaly %+v", a)
+	}
+
+	if a := r.Anomalies[1]; a.Evaluation != "unusual" || a.Fail {
+		t.Fatalf("second anomaly %+v", a)
+	}
+
+	// The first report alone passed.
+	first, _, _ := strings.Cut(practRandOutput, "rng=RNG_stdin8, seed=unknown\nlength= 2")
+
+	r, err = ParsePractRand(strings.NewReader(first))
+	if err != nil || !r.Pass || r.Tests != 67 || r.Length != 1<<20 {
+		t.Fatalf("first report: %+v, %v", r, err)
+	}
+
+	_, err = ParsePractRand(strings.NewReader("error reading standard input\n"))
+	if err == nil {
+		t.Fatal("parsed output without results")
+	}
+}
+
+func TestParseTestU01(t *testing.T) {
+	r, err := ParseTestU01(strings.NewReader(testU01Passed))
+	if err != nil || !r.Pass || r.Tests != 15 || len(r.Anomalies) != 0 {
+		t.Fatalf("passed battery: %+v, %v", r, err)
+	}
+
+	r, err = ParseTestU01(strings.NewReader(testU01Passed + testU01Flagged))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fails []bool
+
+	for _, a := range r.Anomalies {
+		fails = append(fails, a.Fail)
+	}
+
+	if r.Pass || r.Tests != 159 || !slices.Equal(fails, []bool{true, true, false}) {
+		t.Fatalf("failed battery: %+v", r)
+	}
+
+	if a := r.Anomalies[1]; a.Test != "BirthdaySpacings, t = 2" || a.PValue != "1 - eps1" {
+		t.Fatalf("second anomaly %+v", a)
+	}
+
+	if _, err := ParseTestU01(strings.NewReader("segmentation fault\n")); err == nil {
+		t.Fatal("parsed output without a summary")
+	}
+}
+
+// counter is an endless stream of SHA-256 blocks.
+type counter struct {
+	n   int
+	buf []byte
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	for len(c.buf) < len(p) {
+		sum := sha256.Sum256(fmt.Append(nil, c.n))
+
+		c.n++
+		c.buf = append(c.buf, sum[:]...)
+	}
+
+	n := copy(p, c.buf)
+
+	c.buf = c.buf[n:]
+
+	return n, nil
+}
+
+func TestExport(t *testing.T) {
+	var out bytes.Buffer
+
+	n, err := Export(context.Background(), &out, &counter{}, WithLimit(1003), WithWord(4), WithBlockSize(100))
+	if err != nil || n != 1000 || out.Len() != 1000 {
+		t.Fatalf("exported %d bytes (%d written), %v; want 1000 whole words", n, out.Len(), err)
+	}
+
+	want := make([]byte, 1000)
+
+	io.ReadFull(&counter{}, want)
+
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatal("exported output differs from the source")
+	}
+
+	start := time.Now()
+
+	_, err = Export(context.Background(), io.Discard, &counter{}, WithLimit(3000), WithRate(10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first tenth of a second goes out at once.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("3000 bytes at 10000 per second took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = Export(ctx, io.Discard, &counter{}, WithRate(1000))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Export after the deadline = %v", err)
+	}
+
+	_, err = Export(context.Background(), io.Discard, strings.NewReader("short"), WithLimit(64))
+	if err == nil || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Export of a short source = %v", err)
+	}
+
+	if _, err := Export(context.Background(), io.Discard, &counter{}, WithWord(3)); err == nil {
+		t.Fatal("accepted 3-byte words")
+	}
+}
+
+// TestHelperProcess stands in for RNG_test: it reads 1 MiB from standard input and prints the fixture.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("EXTERNAL_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+
+	io.CopyN(io.Discard, os.Stdin, 1<<20)
+
+	fmt.Print(practRandOutput)
+
+	os.Exit(0)
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("EXTERNAL_HELPER", "1")
+
+	s := PractRand()
+
+	s.Command, s.Args = os.Args[0], []string{"-test.run=^TestHelperProcess$"}
+
+	var log bytes.Buffer
+
+	r, err := Run(context.Background(), &counter{}, s, WithOutput(&log))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Suite != "practrand" || r.Pass || r.Bytes < 1<<20 || !strings.Contains(log.String(), "BCFN") {
+		t.Fatalf("ran %+v", r)
+	}
+
+	h := infnoise.NewHealthCheck(0.864, 0.05, 1000)
+
+	h.Record(infnoise.TestResult{Name: "practrand", Pass: true})
+	h.Record(r.TestResult())
+
+	report := h.Report()
+
+	if report.Status != infnoise.HealthFailed {
+		t.Fatal("a failed suite did not fail the report")
+	}
+
+	recorded := report.Tests[len(report.Tests)-1]
+
+	if recorded.Name != "practrand" || report.Tests[len(report.Tests)-2].Name == "practrand" || !strings.Contains(recorded.Detail, "BCFN(2+0,13-3,T) (p = 1.2e-14)") {
+		t.Fatalf("recorded %+v", report.Tests)
+	}
+
+	failing := errors.New("device unplugged")
+
+	_, err = Run(context.Background(), io.MultiReader(strings.NewReader("x"), iotest.ErrReader(failing)), s)
+	if !errors.Is(err, failing) {
+		t.Fatalf("Run with a failing source = %v", err)
+	}
+}
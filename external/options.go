@@ -0,0 +1,60 @@
+package external
+
+import "io"
+
+const (
+	// DefaultBlock is the size of the writes Export makes.
+	DefaultBlock = 64 << 10
+
+	// DefaultPractRand is the RNG_test command PractRand runs without arguments: 8-bit words on standard input,
+	// results from 1 MiB on, up to 64 MiB.
+	DefaultPractRand = "RNG_test stdin8 -tlmin 1MB -tlmax 64MB"
+)
+
+type options struct {
+	rate   int
+	limit  int64
+	word   int
+	block  int
+	output io.Writer
+}
+
+type option func(*options)
+
+// WithRate paces the output to bytesPerSec (default 0: as fast as the source and the reader allow), so a long run
+// leaves the device to other consumers.
+func WithRate(bytesPerSec int) option {
+	return func(o *options) {
+		o.rate = bytesPerSec
+	}
+}
+
+// WithLimit stops after n bytes (default 0: until the reader goes away or the context is cancelled).
+func WithLimit(n int64) option {
+	return func(o *options) {
+		o.limit = n
+	}
+}
+
+// WithWord sets the size in bytes of the words the reader consumes, 1, 2, 4, or 8 (default 4, as TestU01
+// harnesses read). Output is written in whole words only, so a limit is rounded down to a multiple of it.
+func WithWord(bytes int) option {
+	return func(o *options) {
+		o.word = bytes
+	}
+}
+
+// WithBlockSize sets the size of each write (default DefaultBlock), rounded down to a whole number of words.
+func WithBlockSize(bytes int) option {
+	return func(o *options) {
+		o.block = bytes
+	}
+}
+
+// WithOutput copies what Run's suite prints, on standard output and standard error, to w, for following a long run
+// or keeping its log.
+func WithOutput(w io.Writer) option {
+	return func(o *options) {
+		o.output = w
+	}
+}
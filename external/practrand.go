@@ -0,0 +1,92 @@
+package external
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// practRandLength matches the header of a checkpoint, "length= 2 megabytes (2^21 bytes), time= 0.6 seconds".
+	practRandLength = regexp.MustCompile(`^length=.*\(2\^(\d+) bytes\)`)
+
+	// practRandAnomaly matches a flagged test, "BCFN(2+0,13-3,T)   R= +29.0  p =  1.2e-14   FAIL".
+	practRandAnomaly = regexp.MustCompile(`^\s*(\S+)\s+R=\s*\S+\s+p\s*=\s*(.*?)\s+(unusual|(?:mildly |very )?suspicious|VERY SUSPICIOUS|FAIL.*?)\s*$`)
+
+	// practRandClean matches the count of unflagged tests, "no anomalies in 67 test result(s)" or "...and 82 test
+	// result(s) without anomalies".
+	practRandClean = regexp.MustCompile(`(\d+) test result\(s\)`)
+)
+
+// PractRand runs PractRand's RNG_test on 8-bit words from standard input, with args such as "-tlmax", "1GB" (see
+// DefaultPractRand for the command line used without any). RNG_test must be on the PATH.
+func PractRand(args ...string) Suite {
+	if len(args) == 0 {
+		args = strings.Fields(DefaultPractRand)[2:]
+	}
+
+	return Suite{
+		Name:    "practrand",
+		Command: "RNG_test",
+		Args:    append([]string{"stdin8"}, args...),
+		Word:    1,
+		Parse:   ParsePractRand,
+	}
+}
+
+// ParsePractRand parses the output of RNG_test. RNG_test reports at every power of two; the verdict is that of the
+// last report, which fails if a test was evaluated "FAIL".
+func ParsePractRand(r io.Reader) (Result, error) {
+	var (
+		res   Result
+		found bool
+	)
+
+	sc := bufio.NewScanner(r)
+
+	for sc.Scan() {
+		line := sc.Text()
+
+		if m := practRandLength.FindStringSubmatch(line); m != nil {
+			exp, _ := strconv.Atoi(m[1])
+
+			res = Result{Length: 1 << min(exp, 62), Pass: true}
+			found = true
+
+			continue
+		}
+
+		if !found {
+			continue
+		}
+
+		if m := practRandAnomaly.FindStringSubmatch(line); m != nil {
+			a := Anomaly{Test: m[1], PValue: m[2], Evaluation: m[3], Fail: strings.HasPrefix(m[3], "FAIL")}
+
+			res.Anomalies = append(res.Anomalies, a)
+			res.Tests++
+			res.Pass = res.Pass && !a.Fail
+
+			continue
+		}
+
+		if m := practRandClean.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+
+			res.Tests += n
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return Result{}, err
+	}
+
+	if !found {
+		return Result{}, errors.New("no results in PractRand output")
+	}
+
+	return res, nil
+}
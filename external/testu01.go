@@ -0,0 +1,110 @@
+package external
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FailPValue is the distance from 0 or 1 at which ParseTestU01 considers a flagged p-value a failure rather than a
+// suspicion, as the TestU01 guide suggests.
+const FailPValue = 1e-10
+
+var (
+	// testU01Statistics matches " Number of statistics:  15".
+	testU01Statistics = regexp.MustCompile(`^\s*Number of statistics:\s*(\d+)`)
+
+	// testU01Anomaly matches a flagged test, "  1  BirthdaySpacings, t = 2        1 - eps1".
+	testU01Anomaly = regexp.MustCompile(`^\s*\d+\s+(.+?)\s+((?:1 - )?(?:eps1?|[\d.]+(?:e[-+]?\d+)?))\s*$`)
+)
+
+// TestU01 runs command, a harness that reads 32-bit words from standard input and runs TestU01 batteries such as
+// SmallCrush, Crush, or Rabbit on them, printing their summaries. TestU01 is a C library without a program of its
+// own, so the harness is up to the user.
+func TestU01(command string, args ...string) Suite {
+	return Suite{
+		Name:    "testu01",
+		Command: command,
+		Args:    args,
+		Word:    4,
+		Parse:   ParseTestU01,
+	}
+}
+
+// ParseTestU01 parses the summaries TestU01 batteries print, combining them if there are several. A test is flagged
+// when its p-value lies outside [0.001, 0.999] and fails when it lies within FailPValue of 0 or 1.
+func ParseTestU01(r io.Reader) (Result, error) {
+	res := Result{Pass: true}
+
+	// Flagged tests are listed between the first two dashed lines of a summary.
+	var summaries, dashes int
+
+	sc := bufio.NewScanner(r)
+
+	for sc.Scan() {
+		line := sc.Text()
+
+		switch {
+		case strings.Contains(line, "Summary results of"):
+			summaries++
+
+			dashes = 0
+		case summaries == 0:
+		case testU01Statistics.MatchString(line):
+			n, _ := strconv.Atoi(testU01Statistics.FindStringSubmatch(line)[1])
+
+			res.Tests += n
+		case strings.HasPrefix(strings.TrimSpace(line), "---"):
+			dashes++
+		case dashes == 1:
+			m := testU01Anomaly.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+
+			a := Anomaly{Test: m[1], PValue: m[2], Fail: testU01Failed(m[2])}
+
+			a.Evaluation = "suspect"
+			if a.Fail {
+				a.Evaluation = "fail"
+			}
+
+			res.Anomalies = append(res.Anomalies, a)
+			res.Pass = res.Pass && !a.Fail
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return Result{}, err
+	}
+
+	if summaries == 0 {
+		return Result{}, errors.New("no battery summary in TestU01 output")
+	}
+
+	return res, nil
+}
+
+// testU01Failed reports whether a p-value as TestU01 prints it ("eps", "1 - eps1", "1 - 2.3e-12", "4.5e-4") is
+// within FailPValue of 0 or 1.
+func testU01Failed(p string) bool {
+	p, ok := strings.CutPrefix(p, "1 - ")
+
+	if strings.HasPrefix(p, "eps") {
+		return true
+	}
+
+	v, err := strconv.ParseFloat(p, 64)
+	if err != nil {
+		return false
+	}
+
+	if !ok {
+		v = min(v, 1-v)
+	}
+
+	return v < FailPValue
+}
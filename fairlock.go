@@ -0,0 +1,48 @@
+package infnoise
+
+import "sync"
+
+// fairLock is a mutex that hands ownership to waiters in arrival order.
+type fairLock struct {
+	mu      sync.Mutex
+	held    bool
+	waiters []chan struct{}
+}
+
+func (l *fairLock) lock() {
+	l.mu.Lock()
+
+	if !l.held {
+		l.held = true
+
+		l.mu.Unlock()
+
+		return
+	}
+
+	ch := make(chan struct{})
+
+	l.waiters = append(l.waiters, ch)
+
+	l.mu.Unlock()
+
+	<-ch
+}
+
+func (l *fairLock) unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.waiters) == 0 {
+		l.held = false
+
+		return
+	}
+
+	// Ownership passes directly to the oldest waiter; held stays set.
+	next := l.waiters[0]
+
+	l.waiters = l.waiters[1:]
+
+	close(next)
+}
@@ -0,0 +1,122 @@
+package infnoise
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// MaxFoldFactor is the largest number of raw bits ReadFolded folds into one output bit.
+	MaxFoldFactor = 64
+
+	// foldEntropy is the estimated raw entropy FoldFactor gathers into each output bit, half a bit more than the
+	// output claims. At the nominal 0.864 bits per raw bit, it folds two raw bits, as the whitener absorbs.
+	foldEntropy = 1.5
+
+	// foldBatch bounds the output ReadFolded folds per raw read, so the raw buffer stays small.
+	foldBatch = BufLen
+)
+
+// FoldFactor returns how many raw bits ReadFolded folds into each output bit for an estimated estimate bits of
+// entropy per raw bit: enough to gather 1.5 bits, from 1 up to MaxFoldFactor.
+func FoldFactor(estimate float64) int {
+	if !(estimate > 0) {
+		return MaxFoldFactor
+	}
+
+	return int(min(max(math.Ceil(foldEntropy/estimate), 1), MaxFoldFactor))
+}
+
+// FoldFactor returns the number of raw bits the next ReadFolded folds into each output bit: the WithFoldFactor or,
+// by default, FoldFactor of the health check's entropy estimate (of the target entropy before any was measured).
+func (d *Device) FoldFactor() int {
+	if d.fold > 0 {
+		return d.fold
+	}
+
+	_, estimate := d.Health()
+	if estimate == 0 {
+		estimate = d.drift.target
+	}
+
+	return FoldFactor(estimate)
+}
+
+// ReadFolded fills p with raw output conditioned without cryptography, for certification schemes that disallow
+// cryptographic post-processing: each output bit is the XOR of K consecutive raw bits (most significant first) that
+// passed the health check, K being the device's FoldFactor when the call starts. It needs K times len(p) raw bytes.
+// ReadFolded shares the stream like Read under ModeExclusive and fails with ErrModeConflict under ModeRawTap.
+// Failures are *Error values, as for Read.
+//
+// Folding reduces bias but, unlike ReadWhitened, does not remove correlation between bits; use it only where the
+// scheme requires it.
+func (d *Device) ReadFolded(p []byte) (n int, err error) {
+	ctx, span := d.tracer.Start(context.Background(), "infnoise.ReadFolded", trace.WithAttributes(attrRequested.Int(len(p))))
+
+	start := time.Now()
+
+	defer func() {
+		endSpan(span, n, err)
+
+		err = classified(err)
+
+		d.readStats.record(len(p), time.Since(start), err)
+	}()
+
+	if d.mode == ModeRawTap {
+		return 0, ErrModeConflict
+	}
+
+	err = d.claim(ownerFolded)
+	if err != nil {
+		return 0, err
+	}
+
+	k := d.FoldFactor()
+
+	raw := make([]byte, k*min(len(p), foldBatch))
+
+	defer clear(raw)
+
+	for n < len(p) {
+		m := min(len(p)-n, foldBatch)
+
+		_, err = d.readRaw(ctx, raw[:k*m])
+		if err != nil {
+			return n, err
+		}
+
+		xorFold(raw[:k*m], p[n:n+m], k)
+
+		n += m
+	}
+
+	return n, nil
+}
+
+// xorFold sets each bit of out, most significant first, to the XOR of the next k bits of raw, which must hold
+// k*len(out) bytes.
+func xorFold(raw, out []byte, k int) {
+	bit := 0
+
+	for i := range out {
+		var b byte
+
+		for range 8 {
+			var x byte
+
+			for range k {
+				x ^= raw[bit/8] >> (7 - bit%8)
+
+				bit++
+			}
+
+			b = b<<1 | x&1
+		}
+
+		out[i] = b
+	}
+}
@@ -0,0 +1,120 @@
+package infnoise
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/bits"
+	"testing"
+)
+
+func TestXorFold(t *testing.T) {
+	out := make([]byte, 1)
+
+	xorFold([]byte{0xa5, 0x3c}, out, 2)
+
+	if out[0] != 0xf0 {
+		t.Fatalf("folded 0xa53c by 2 into %#02x, want 0xf0", out[0])
+	}
+
+	raw := []byte("folding eight raw bytes per byte")
+
+	out = make([]byte, len(raw)/8)
+
+	xorFold(raw, out, 8)
+
+	for i, b := range out {
+		var want byte
+
+		for j, r := range raw[8*i : 8*i+8] {
+			want |= byte(bits.OnesCount8(r)&1) << (7 - j)
+		}
+
+		if b != want {
+			t.Fatalf("byte %d: %08b, want the parities %08b", i, b, want)
+		}
+	}
+
+	xorFold(raw[:4], out[:4], 1)
+
+	if !bytes.Equal(out[:4], raw[:4]) {
+		t.Fatal("a factor of 1 changed the output")
+	}
+}
+
+func TestFoldFactor(t *testing.T) {
+	for _, c := range []struct {
+		estimate float64
+		want     int
+	}{
+		{0.864, 2},
+		{0.8, 2},
+		{1, 2},
+		{0.5, 3},
+		{0.1, 15},
+		{0.01, MaxFoldFactor},
+		{0, MaxFoldFactor},
+		{math.NaN(), MaxFoldFactor},
+	} {
+		if got := FoldFactor(c.estimate); got != c.want {
+			t.Errorf("FoldFactor(%v) = %d, want %d", c.estimate, got, c.want)
+		}
+	}
+}
+
+func TestReadFolded(t *testing.T) {
+	folded, _ := openSimulator(t, 3, DefaultSimulatorGain, WithFoldFactor(3))
+
+	got := make([]byte, 2*foldBatch+10)
+
+	_, err := folded.ReadFolded(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := openSimulator(t, 3, DefaultSimulatorGain)
+
+	stream := make([]byte, 3*len(got))
+
+	_, err = raw.Read(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := make([]byte, len(got))
+
+	xorFold(stream, want, 3)
+
+	if !bytes.Equal(got, want) {
+		t.Fatal("ReadFolded differs from the folded raw stream")
+	}
+
+	if _, err := folded.Read(stream); !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("Read after ReadFolded = %v, want ErrModeConflict", err)
+	}
+
+	auto, _ := openSimulator(t, 4, DefaultSimulatorGain)
+
+	if k := auto.FoldFactor(); k != 2 {
+		t.Fatalf("FoldFactor before reading = %d, want 2", k)
+	}
+
+	_, err = auto.Folded().Read(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k := auto.FoldFactor(); k != 2 {
+		t.Fatalf("FoldFactor at the nominal entropy = %d, want 2", k)
+	}
+
+	tap, _ := openSimulator(t, 5, DefaultSimulatorGain, WithReadMode(ModeRawTap))
+
+	if _, err := tap.ReadFolded(got); !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("ReadFolded in ModeRawTap = %v, want ErrModeConflict", err)
+	}
+
+	if err := New(WithFoldFactor(MaxFoldFactor + 1)).Start(); err == nil {
+		t.Fatal("Start accepted a fold factor above MaxFoldFactor")
+	}
+}
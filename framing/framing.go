@@ -0,0 +1,37 @@
+// Package framing wraps a byte stream into self-checking records so entropy can be moved over lossy or multiplexed
+// transports (serial links, message queues, sneakernet) and validated on the other side.
+//
+// Each record is laid out as
+//
+//	[len uint32][seq uint32][crc32 uint32][payload]
+//
+// with all integers big-endian. The CRC (IEEE) covers len, seq and the payload.
+package framing
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+const (
+	// HeaderSize is the size of the record header in bytes.
+	HeaderSize = 12
+
+	// DefaultChunkSize is the payload size used by NewWriter when chunkSize is not positive.
+	DefaultChunkSize = 4096
+
+	// DefaultMaxPayload is the largest payload a Reader accepts unless configured otherwise.
+	DefaultMaxPayload = 64 * 1024
+)
+
+func putHeader(hdr []byte, seq uint32, payload []byte) {
+	binary.BigEndian.PutUint32(hdr[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(hdr[4:8], seq)
+	binary.BigEndian.PutUint32(hdr[8:12], checksum(hdr[0:8], payload))
+}
+
+func checksum(lenSeq, payload []byte) uint32 {
+	crc := crc32.ChecksumIEEE(lenSeq)
+
+	return crc32.Update(crc, crc32.IEEETable, payload)
+}
@@ -0,0 +1,99 @@
+package framing
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"testing"
+)
+
+func frame(t *testing.T, data []byte, chunk int) [][]byte {
+	t.Helper()
+
+	var (
+		buf     bytes.Buffer
+		records [][]byte
+	)
+
+	w := NewWriter(&buf, chunk)
+
+	for off := 0; off < len(data); off += chunk {
+		buf.Reset()
+
+		_, err := w.Write(data[off:min(off+chunk, len(data))])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, bytes.Clone(buf.Bytes()))
+	}
+
+	return records
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := make([]byte, 10_000)
+
+	rand.NewChaCha8([32]byte{1}).Read(data)
+
+	var buf bytes.Buffer
+
+	_, err := NewWriter(&buf, 333).Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(&buf)
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Fatal("payload mismatch")
+	}
+
+	if st := r.Stats(); st.Records != 31 || st.Corrupt != 0 || st.Lost != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	data := make([]byte, 800)
+
+	for i := range data {
+		data[i] = byte(i / 100)
+	}
+
+	recs := frame(t, data, 100)
+
+	corrupt := bytes.Clone(recs[2])
+	corrupt[HeaderSize+5] ^= 0xFF
+
+	// 0, 2 (corrupt), 1, 1 (duplicate), 3, 5, 6, 7; record 4 is lost.
+	var stream bytes.Buffer
+
+	for _, rec := range [][]byte{recs[0], corrupt, recs[2], recs[1], recs[1], recs[3], recs[5], recs[6], recs[7]} {
+		stream.Write(rec)
+	}
+
+	r := NewReader(&stream, WithReorderWindow(2))
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(bytes.Clone(data[:400]), data[500:]...)
+
+	if !bytes.Equal(got, want) {
+		t.Fatalf("payload mismatch: got %d bytes, want %d", len(got), len(want))
+	}
+
+	st := r.Stats()
+
+	if st.Records != 7 || st.Corrupt != 1 || st.Duplicates != 1 || st.Lost != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
@@ -0,0 +1,22 @@
+package framing
+
+type options struct {
+	maxPayload int
+	window     int
+}
+
+type option func(*options)
+
+// WithMaxPayload sets the largest payload the Reader accepts (default 64 KiB); larger length fields are treated as corruption.
+func WithMaxPayload(bytes int) option {
+	return func(o *options) {
+		o.maxPayload = bytes
+	}
+}
+
+// WithReorderWindow sets how many out-of-order records the Reader holds back while waiting for a missing one (default 16).
+func WithReorderWindow(records int) option {
+	return func(o *options) {
+		o.window = records
+	}
+}
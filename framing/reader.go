@@ -0,0 +1,207 @@
+package framing
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+)
+
+// ReaderStats counts what a Reader had to repair while decoding.
+type ReaderStats struct {
+	// Records is the number of valid records delivered.
+	Records uint64
+
+	// Corrupt is the number of times an invalid header or checksum forced a resync.
+	Corrupt uint64
+
+	// SkippedBytes is the number of bytes discarded while resyncing.
+	SkippedBytes uint64
+
+	// Duplicates is the number of valid records dropped because their sequence number was already delivered.
+	Duplicates uint64
+
+	// Lost is the number of sequence numbers that never arrived and were skipped.
+	Lost uint64
+}
+
+// Reader validates framed records and returns their payloads in sequence order.
+//
+// Corrupted bytes are skipped until the next valid record, duplicates are dropped, and records arriving
+// out of order are held back until the gap is filled or more than window later records have arrived.
+type Reader struct {
+	r          *bufio.Reader
+	maxPayload int
+	window     int
+
+	started bool
+	next    uint32
+	pending map[uint32][]byte
+	cur     []byte
+
+	stats ReaderStats
+}
+
+// NewReader returns a Reader decoding records from r.
+func NewReader(r io.Reader, opts ...option) *Reader {
+	conf := &options{
+		maxPayload: DefaultMaxPayload,
+		window:     16,
+	}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	return &Reader{
+		r:          bufio.NewReaderSize(r, HeaderSize+conf.maxPayload),
+		maxPayload: conf.maxPayload,
+		window:     max(conf.window, 0),
+		pending:    make(map[uint32][]byte),
+	}
+}
+
+// Read fills p with payload bytes in sequence order.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.cur) == 0 {
+		payload, err := r.nextPayload()
+		if err != nil {
+			return 0, err
+		}
+
+		r.cur = payload
+	}
+
+	n := copy(p, r.cur)
+
+	r.cur = r.cur[n:]
+
+	return n, nil
+}
+
+// Stats returns the repair counters accumulated so far.
+func (r *Reader) Stats() ReaderStats {
+	return r.stats
+}
+
+func (r *Reader) nextPayload() ([]byte, error) {
+	for {
+		if payload, ok := r.pending[r.next]; ok && r.started {
+			delete(r.pending, r.next)
+
+			return r.deliver(payload), nil
+		}
+
+		seq, payload, err := r.readRecord()
+		if errors.Is(err, io.EOF) && len(r.pending) > 0 {
+			r.skipGap()
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !r.started {
+			r.started = true
+			r.next = seq
+		}
+
+		diff := int32(seq - r.next)
+
+		switch {
+		case diff == 0:
+			return r.deliver(payload), nil
+		case diff < 0:
+			r.stats.Duplicates++
+		default:
+			if _, ok := r.pending[seq]; ok {
+				r.stats.Duplicates++
+
+				continue
+			}
+
+			r.pending[seq] = payload
+
+			if len(r.pending) > r.window {
+				r.skipGap()
+			}
+		}
+	}
+}
+
+func (r *Reader) deliver(payload []byte) []byte {
+	r.next++
+	r.stats.Records++
+
+	return payload
+}
+
+// skipGap gives up on the missing records before the oldest held-back one.
+func (r *Reader) skipGap() {
+	seqs := make([]uint32, 0, len(r.pending))
+
+	for seq := range r.pending {
+		seqs = append(seqs, seq)
+	}
+
+	oldest := slices.MinFunc(seqs, func(a, b uint32) int {
+		return cmp.Compare(int32(a-r.next), int32(b-r.next))
+	})
+
+	r.stats.Lost += uint64(oldest - r.next)
+	r.next = oldest
+}
+
+func (r *Reader) readRecord() (uint32, []byte, error) {
+	resyncing := false
+
+	for {
+		hdr, err := r.r.Peek(HeaderSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(hdr) > 0 {
+				if !resyncing {
+					r.stats.Corrupt++
+				}
+
+				r.stats.SkippedBytes += uint64(len(hdr))
+
+				r.r.Discard(len(hdr))
+			}
+
+			return 0, nil, err
+		}
+
+		size := int(binary.BigEndian.Uint32(hdr[0:4]))
+
+		if size <= r.maxPayload {
+			rec, err := r.r.Peek(HeaderSize + size)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return 0, nil, err
+			}
+
+			// A record cut short by EOF can only be garbage; resync past it.
+			if err == nil && checksum(rec[0:8], rec[HeaderSize:]) == binary.BigEndian.Uint32(rec[8:12]) {
+				seq := binary.BigEndian.Uint32(rec[4:8])
+				payload := slices.Clone(rec[HeaderSize:])
+
+				r.r.Discard(HeaderSize + size)
+
+				return seq, payload, nil
+			}
+		}
+
+		if !resyncing {
+			resyncing = true
+
+			r.stats.Corrupt++
+		}
+
+		r.r.Discard(1)
+
+		r.stats.SkippedBytes++
+	}
+}
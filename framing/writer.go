@@ -0,0 +1,52 @@
+package framing
+
+import "io"
+
+// Writer splits everything written to it into framed records.
+type Writer struct {
+	w     io.Writer
+	chunk int
+	seq   uint32
+	buf   []byte
+}
+
+// NewWriter returns a Writer emitting records of at most chunkSize payload bytes to w.
+func NewWriter(w io.Writer, chunkSize int) *Writer {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return &Writer{
+		w:     w,
+		chunk: chunkSize,
+		buf:   make([]byte, HeaderSize+chunkSize),
+	}
+}
+
+// Write frames p into one or more records, issuing one write on the underlying writer per record.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	for n < len(p) {
+		size := min(len(p)-n, w.chunk)
+
+		payload := p[n : n+size]
+
+		putHeader(w.buf[:HeaderSize], w.seq, payload)
+		copy(w.buf[HeaderSize:], payload)
+
+		_, err := w.w.Write(w.buf[:HeaderSize+size])
+		if err != nil {
+			return n, err
+		}
+
+		w.seq++
+
+		n += size
+	}
+
+	return n, nil
+}
+
+// Seq returns the sequence number the next record will carry.
+func (w *Writer) Seq() uint32 {
+	return w.seq
+}
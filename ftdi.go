@@ -0,0 +1,92 @@
+package infnoise
+
+import "fmt"
+
+const (
+	// ftdiStatusLen is the number of modem status bytes an FTDI chip prepends to every bulk-in packet.
+	ftdiStatusLen = 2
+
+	// ringBufferSize is the capacity of the ring buffer the libusb reader loops fill.
+	ringBufferSize = 64 * 1024
+
+	// bulkReadSize is the size of each bulk-in transfer issued by the reader loops.
+	bulkReadSize = 4096
+)
+
+// FTDI vendor requests, for backends that talk to the chip without the D2XX driver.
+const (
+	sioReset       = 0x00
+	sioSetBaudRate = 0x03
+	sioSetBitMode  = 0x0B
+	sioSetLatency  = 0x09
+	sioResetSio    = 0x0000
+	sioPurgeRx     = 0x0001
+	sioPurgeTx     = 0x0002
+
+	reqOutVendor = 0x40
+
+	epInAddr  = 0x81
+	epOutAddr = 0x02
+)
+
+// RingStats describes the ring buffer a background reader loop fills from the bulk-in endpoint.
+type RingStats struct {
+	// Capacity and Buffered are the ring size and the bytes currently waiting to be read.
+	Capacity, Buffered int
+
+	// HighWater and LowWater are the levels at which the reader loop pauses and resumes.
+	HighWater, LowWater int
+
+	// Pauses is the number of times the reader loop stopped polling because the buffer reached HighWater.
+	Pauses uint64
+
+	// Purged is the number of buffered bytes discarded when the bit mode was (re)set.
+	Purged uint64
+
+	// Dropped is the number of bytes lost to overflow. The reader loop pauses instead of overwriting
+	// data, so this stays zero unless a backend cannot apply backpressure.
+	Dropped uint64
+}
+
+// RingBackend is implemented by backends that buffer bulk-in data in a ring filled by a background loop.
+type RingBackend interface {
+	Backend
+
+	// RingStats returns the current buffer level and counters.
+	RingStats() RingStats
+
+	// SetWatermarks makes the reader loop pause once high bytes are buffered and resume at or below low.
+	SetWatermarks(high, low int) error
+}
+
+// defaultHighWater leaves room for one more transfer, the most the ring can take without overflowing.
+const defaultHighWater = ringBufferSize - bulkReadSize
+
+func checkWatermarks(high, low int) error {
+	if high <= 0 || high > defaultHighWater || low < 0 || low > high {
+		return fmt.Errorf("invalid watermarks high=%d low=%d (need 0 <= low <= high <= %d)", high, low, defaultHighWater)
+	}
+
+	return nil
+}
+
+// stripModemStatus appends the payload of the bulk-in packets in buf to dst. A transfer is a sequence of
+// maxPacket-sized packets, the last of which may be short; each starts with two status bytes, and a packet
+// of only status bytes carries no data.
+func stripModemStatus(dst, buf []byte, maxPacket int) []byte {
+	if maxPacket <= ftdiStatusLen {
+		return dst
+	}
+
+	for len(buf) > 0 {
+		pkt := buf[:min(maxPacket, len(buf))]
+
+		buf = buf[len(pkt):]
+
+		if len(pkt) > ftdiStatusLen {
+			dst = append(dst, pkt[ftdiStatusLen:]...)
+		}
+	}
+
+	return dst
+}
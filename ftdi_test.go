@@ -0,0 +1,161 @@
+package infnoise
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStripModemStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"empty", nil, nil},
+		{"status only", []byte{0x01, 0x60}, nil},
+		{"short final packet", []byte{0x01, 0x60, 1, 2, 0x01, 0x60, 3, 4, 0x01, 0x60, 5}, []byte{1, 2, 3, 4, 5}},
+		{"status-only final packet", []byte{0x01, 0x60, 1, 2, 0x01, 0x60}, []byte{1, 2}},
+		{"truncated status", []byte{0x01, 0x60, 1, 2, 0x01}, []byte{1, 2}},
+	}
+
+	for _, tt := range tests {
+		got := stripModemStatus(nil, tt.in, 4)
+
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := stripModemStatus(nil, []byte{1, 2, 3}, 2); len(got) != 0 {
+		t.Errorf("packets without room for payload: got %v", got)
+	}
+}
+
+func TestCheckWatermarks(t *testing.T) {
+	valid := [][2]int{{defaultHighWater, defaultHighWater}, {32 * 1024, 8 * 1024}, {1, 0}}
+	invalid := [][2]int{{0, 0}, {defaultHighWater + 1, 0}, {1024, 2048}, {1024, -1}}
+
+	for _, w := range valid {
+		if err := checkWatermarks(w[0], w[1]); err != nil {
+			t.Errorf("high=%d low=%d: %v", w[0], w[1], err)
+		}
+	}
+
+	for _, w := range invalid {
+		if checkWatermarks(w[0], w[1]) == nil {
+			t.Errorf("high=%d low=%d accepted", w[0], w[1])
+		}
+	}
+}
+
+// simulatorSamples returns n pin samples from a simulated board driven with the default pattern.
+func simulatorSamples(tb testing.TB, n int) []byte {
+	tb.Helper()
+
+	sim := NewSimulator(1)
+
+	err := sim.Open(0x0403, 0x6015)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	defer sim.Close()
+
+	err = sim.SetBitMode(Mask, BitModeSyncBitbang)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	out := ProfileV2.Bytes(n)
+	in := make([]byte, n)
+
+	err = sim.Write(out)
+	if err == nil {
+		err = sim.Read(in)
+	}
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return in
+}
+
+func FuzzReaderLoopFraming(f *testing.F) {
+	f.Add(simulatorSamples(f, 1000), byte(0x01), byte(0x60), 64)
+	f.Add([]byte{}, byte(0x01), byte(0x60), 64)
+	f.Add([]byte{1, 2, 3}, byte(0xff), byte(0xff), 3)
+	f.Add([]byte{1, 2, 3, 4}, byte(0), byte(0), 512)
+
+	f.Fuzz(func(t *testing.T, payload []byte, s0, s1 byte, maxPacket int) {
+		// Anything a reader loop could see: raw garbage, including sizes without room for payload, must not panic.
+		stripModemStatus(nil, payload, maxPacket)
+
+		if maxPacket <= ftdiStatusLen || maxPacket > 1024 {
+			return
+		}
+
+		var transfer []byte
+
+		for off := 0; off < len(payload); off += maxPacket - ftdiStatusLen {
+			transfer = append(transfer, s0, s1)
+			transfer = append(transfer, payload[off:min(off+maxPacket-ftdiStatusLen, len(payload))]...)
+		}
+
+		got := stripModemStatus(nil, transfer, maxPacket)
+		if !bytes.Equal(got, payload) {
+			t.Fatalf("framed %d bytes into %d-byte packets, got back %d", len(payload), maxPacket, len(got))
+		}
+
+		// A status-only packet after full packets carries nothing.
+		if len(transfer)%maxPacket == 0 {
+			got = stripModemStatus(nil, append(transfer, s0, s1), maxPacket)
+			if !bytes.Equal(got, payload) {
+				t.Fatal("status-only packet added data")
+			}
+		}
+	})
+}
+
+func TestFTStatus(t *testing.T) {
+	err := fmt.Errorf("FT_Write failed: %w", FTStatus(4))
+
+	if !errors.Is(err, ErrIO) {
+		t.Fatalf("%v does not match ErrIO", err)
+	}
+
+	if err.Error() != "FT_Write failed: FT_IO_ERROR (4)" {
+		t.Fatalf("got message %q", err)
+	}
+
+	if !errors.Is(FTStatus(2), ErrDeviceNotFound) || !errors.Is(FTStatus(1), ErrDisconnected) {
+		t.Fatal("device codes not mapped")
+	}
+
+	if errors.Is(FTStatus(6), ErrIO) {
+		t.Fatal("FT_INVALID_PARAMETER matched ErrIO")
+	}
+
+	if s := FTStatus(99).String(); s != "FT_STATUS(99)" {
+		t.Fatalf("unknown code formatted as %q", s)
+	}
+}
+
+func BenchmarkStripModemStatus(b *testing.B) {
+	buf := make([]byte, bulkReadSize)
+
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+
+	dst := make([]byte, 0, len(buf))
+
+	b.ReportAllocs()
+	b.SetBytes(bulkReadSize)
+
+	for b.Loop() {
+		dst = stripModemStatus(dst[:0], buf, 64)
+	}
+}
@@ -0,0 +1,70 @@
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDeviceNotFound is returned by Start when no matching board is attached (or none can be opened).
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrDisconnected is wrapped by failures on a handle that no longer refers to an attached device.
+	ErrDisconnected = errors.New("device disconnected")
+
+	// ErrIO is wrapped by transfer failures reported by the USB stack.
+	ErrIO = errors.New("usb i/o error")
+)
+
+// FTStatus is an FT_STATUS code returned by the FTDI D2XX driver. It is an error whose message names the
+// code and which matches the package's errors for the codes that have one (errors.Is).
+type FTStatus uint32
+
+var ftStatusNames = [...]string{
+	"FT_OK",
+	"FT_INVALID_HANDLE",
+	"FT_DEVICE_NOT_FOUND",
+	"FT_DEVICE_NOT_OPENED",
+	"FT_IO_ERROR",
+	"FT_INSUFFICIENT_RESOURCES",
+	"FT_INVALID_PARAMETER",
+	"FT_INVALID_BAUD_RATE",
+	"FT_DEVICE_NOT_OPENED_FOR_ERASE",
+	"FT_DEVICE_NOT_OPENED_FOR_WRITE",
+	"FT_FAILED_TO_WRITE_DEVICE",
+	"FT_EEPROM_READ_FAILED",
+	"FT_EEPROM_WRITE_FAILED",
+	"FT_EEPROM_ERASE_FAILED",
+	"FT_EEPROM_NOT_PRESENT",
+	"FT_EEPROM_NOT_PROGRAMMED",
+	"FT_INVALID_ARGS",
+	"FT_NOT_SUPPORTED",
+	"FT_OTHER_ERROR",
+	"FT_DEVICE_LIST_NOT_READY",
+}
+
+func (s FTStatus) String() string {
+	if int(s) < len(ftStatusNames) {
+		return ftStatusNames[s]
+	}
+
+	return fmt.Sprintf("FT_STATUS(%d)", uint32(s))
+}
+
+func (s FTStatus) Error() string {
+	return fmt.Sprintf("%s (%d)", s.String(), uint32(s))
+}
+
+// Is reports whether target is the package error s corresponds to.
+func (s FTStatus) Is(target error) bool {
+	switch s {
+	case 1:
+		return target == ErrDisconnected
+	case 2, 3:
+		return target == ErrDeviceNotFound
+	case 4, 10:
+		return target == ErrIO
+	}
+
+	return false
+}
@@ -1,3 +1,10 @@
 module github.com/coalaura/infnoise
 
 go 1.25.5
+
+require (
+	github.com/google/gousb v1.1.3
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+	golang.org/x/sys v0.40.0
+)
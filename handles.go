@@ -0,0 +1,158 @@
+package infnoise
+
+import "sync"
+
+// RawReader reads the raw bitstream of a Device through its own buffer (see Device.Raw).
+type RawReader struct {
+	buf bufReader
+}
+
+// WhitenedReader reads the whitened output of a Device through its own buffer (see Device.Whitened).
+type WhitenedReader struct {
+	buf bufReader
+}
+
+// FoldedReader reads the XOR-folded output of a Device through its own buffer (see Device.Folded).
+type FoldedReader struct {
+	buf bufReader
+}
+
+// Raw returns a handle that only ever reads the raw bitstream, for passing to code that expects an io.Reader
+// without giving it the whole Device. Each handle buffers small reads independently of other handles.
+func (d *Device) Raw() *RawReader {
+	return &RawReader{buf: newBufReader(d.Read, BufLen)}
+}
+
+// Whitened returns a handle that only ever reads whitened output (see ReadWhitened), buffered like Raw.
+func (d *Device) Whitened() *WhitenedReader {
+	return &WhitenedReader{buf: newBufReader(d.ReadWhitened, BufLen)}
+}
+
+// Folded returns a handle that only ever reads XOR-folded output (see ReadFolded), buffered like Raw.
+func (d *Device) Folded() *FoldedReader {
+	return &FoldedReader{buf: newBufReader(d.ReadFolded, BufLen)}
+}
+
+// Read fills p with raw output.
+func (r *RawReader) Read(p []byte) (int, error) {
+	return r.buf.Read(p)
+}
+
+// Read fills p with whitened output.
+func (w *WhitenedReader) Read(p []byte) (int, error) {
+	return w.buf.Read(p)
+}
+
+// Read fills p with XOR-folded output.
+func (f *FoldedReader) Read(p []byte) (int, error) {
+	return f.buf.Read(p)
+}
+
+// ReadByte returns one raw byte, implementing io.ByteReader.
+func (r *RawReader) ReadByte() (byte, error) {
+	return r.buf.readByte()
+}
+
+// ReadRune returns one raw byte as a rune of size 1, implementing io.RuneReader for byte-at-a-time consumers; the
+// stream is not decoded as UTF-8.
+func (r *RawReader) ReadRune() (rune, int, error) {
+	return r.buf.readRune()
+}
+
+// ReadByte returns one whitened byte, implementing io.ByteReader.
+func (w *WhitenedReader) ReadByte() (byte, error) {
+	return w.buf.readByte()
+}
+
+// ReadRune returns one whitened byte as a rune of size 1, like RawReader.ReadRune.
+func (w *WhitenedReader) ReadRune() (rune, int, error) {
+	return w.buf.readRune()
+}
+
+// ReadByte returns one XOR-folded byte, implementing io.ByteReader.
+func (f *FoldedReader) ReadByte() (byte, error) {
+	return f.buf.readByte()
+}
+
+// ReadRune returns one XOR-folded byte as a rune of size 1, like RawReader.ReadRune.
+func (f *FoldedReader) ReadRune() (rune, int, error) {
+	return f.buf.readRune()
+}
+
+// bufReader serves small reads from a buffer refilled in one call to read, which must fill what it is given
+// unless it fails. Buffered bytes are wiped as they are handed out.
+type bufReader struct {
+	mu      sync.Mutex
+	read    func([]byte) (int, error)
+	buf     []byte
+	pending []byte
+}
+
+func newBufReader(read func([]byte) (int, error), size int) bufReader {
+	return bufReader{
+		read: read,
+		buf:  make([]byte, size),
+	}
+}
+
+// Read fills all of p unless the underlying read fails.
+func (b *bufReader) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var n int
+
+	for n < len(p) {
+		if len(b.pending) == 0 {
+			// Requests at least as large as the buffer gain nothing from it.
+			if len(p)-n >= len(b.buf) {
+				m, err := b.read(p[n:])
+
+				return n + m, err
+			}
+
+			_, err := b.read(b.buf)
+			if err != nil {
+				return n, err
+			}
+
+			b.pending = b.buf
+		}
+
+		m := copy(p[n:], b.pending)
+
+		clear(b.pending[:m])
+
+		b.pending = b.pending[m:]
+		n += m
+	}
+
+	return n, nil
+}
+
+func (b *bufReader) readByte() (byte, error) {
+	var p [1]byte
+
+	_, err := b.Read(p[:])
+
+	return p[0], err
+}
+
+func (b *bufReader) readRune() (rune, int, error) {
+	c, err := b.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return rune(c), 1, nil
+}
+
+// reset discards and wipes the buffered bytes.
+func (b *bufReader) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clear(b.buf)
+
+	b.pending = nil
+}
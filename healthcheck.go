@@ -1,35 +1,231 @@
 package infnoise
 
 import (
+	"fmt"
 	"math"
+	"slices"
 	"sync"
 )
 
+const (
+	// DefaultHistoryBits is the number of preceding bits the health check predicts each bit from, as in the
+	// reference implementation; MaxHistoryBits bounds WithHealthHistoryBits.
+	DefaultHistoryBits = 7
+	MaxHistoryBits     = 20
+)
+
+// HealthStatus is the outcome of the health check.
+type HealthStatus int
+
+const (
+	HealthOK HealthStatus = iota
+	HealthFailed
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthOK:
+		return "ok"
+	case HealthFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("HealthStatus(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the status as its name, so reports read "ok" or "failed".
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses a name produced by MarshalText.
+func (s *HealthStatus) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "ok":
+		*s = HealthOK
+	case "failed":
+		*s = HealthFailed
+	default:
+		return fmt.Errorf("unknown health status %q", b)
+	}
+
+	return nil
+}
+
 // HealthCheck implements the official Infinite Noise health monitoring algorithm.
 type HealthCheck struct {
 	mu sync.Mutex
 
-	counts [128][2]uint32
+	// counts holds the zero and one counts following each history of historyBits bits.
+	counts      [][2]uint32
+	historyBits int
 
 	totalBits  uint64
 	window     uint64
 	entropySum float64
 
+	continuous continuousTests
+	autocorr   autocorrelation
+	predict    *predictors
+
+	// Only every sampling-th Add call is analyzed (see WithHealthSampling); calls counts them all, and skipped the
+	// bits of the skipped ones, which only the continuous tests see.
+	sampling uint64
+	calls    uint64
+	skipped  uint64
+
+	// failed describes the latest failure of the continuous tests or the autocorrelation monitor during the current
+	// Add call.
+	failed string
+
+	// bias breaks the ones down by bit position and, if addresses holds the switch address of each sample of a
+	// batch, by address.
+	bias      BiasMap
+	addresses []uint8
+
+	// recorded holds the results of tests run outside the health check (see Record), in the order first recorded.
+	recorded []TestResult
+
 	TargetEntropy float64
 	Tolerance     float64
 }
 
-// Add processes raw bytes and updates the entropy estimate.
+// NewHealthCheck returns a health check that enforces targetEntropy ± tolerance (a fraction of the target)
+// once window bits have been observed.
+// The continuous tests use the cutoffs for DefaultFalsePositiveRate and DefaultMinEntropy until SetCutoffs.
+func NewHealthCheck(targetEntropy, tolerance float64, window uint64) *HealthCheck {
+	cutoffs, _ := NewCutoffs(DefaultFalsePositiveRate, DefaultMinEntropy)
+
+	return &HealthCheck{
+		TargetEntropy: targetEntropy,
+		Tolerance:     tolerance,
+		window:        window,
+		counts:        make([][2]uint32, 1<<DefaultHistoryBits),
+		historyBits:   DefaultHistoryBits,
+		continuous:    continuousTests{cutoffs: cutoffs},
+		autocorr:      autocorrelation{limit: DefaultAutocorrelationLimit},
+		predict:       newPredictors(DefaultPredictorFraction),
+		sampling:      1,
+	}
+}
+
+// SetHistoryBits sets how many preceding bits predict each bit (default DefaultHistoryBits) and discards all
+// accumulated statistics. Longer histories split the bits over more contexts, so they need a longer window before
+// the estimate settles.
+func (h *HealthCheck) SetHistoryBits(k int) error {
+	if k < 1 || k > MaxHistoryBits {
+		return fmt.Errorf("invalid history length %d bits (need 1 to %d)", k, MaxHistoryBits)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.historyBits = k
+	h.counts = make([][2]uint32, 1<<k)
+
+	h.resetLocked()
+
+	return nil
+}
+
+// SetCutoffs replaces the cutoffs of the continuous tests and restarts them.
+func (h *HealthCheck) SetCutoffs(c Cutoffs) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.continuous = continuousTests{cutoffs: c}
+}
+
+// SetAutocorrelationLimit sets the largest autocorrelation magnitude allowed at any lag (see
+// WithAutocorrelationLimit) and restarts the monitor; 0 disables the check but keeps reporting coefficients.
+func (h *HealthCheck) SetAutocorrelationLimit(limit float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.autocorr = autocorrelation{limit: limit}
+}
+
+// SetPredictors makes the prediction estimators analyze the given fraction of the raw bits (see WithPredictors)
+// and restarts them; 0 disables them.
+func (h *HealthCheck) SetPredictors(fraction float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.predict = newPredictors(fraction)
+}
+
+// Record adds the result of a test run outside the health check, such as an external test suite over the output,
+// to the Tests of its Report, replacing an earlier result of the same name. A failed result fails the report's
+// status. Recorded results are kept when the statistics are discarded.
+func (h *HealthCheck) Record(r TestResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := slices.IndexFunc(h.recorded, func(t TestResult) bool {
+		return t.Name == r.Name
+	})
+
+	if i < 0 {
+		h.recorded = append(h.recorded, r)
+	} else {
+		h.recorded[i] = r
+	}
+}
+
+// SetSampling makes the health check analyze the given fraction of Add calls (see WithHealthSampling), starting
+// with the next one; fractions outside (0, 1] analyze every call.
+func (h *HealthCheck) SetSampling(fraction float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sampling, h.calls = 1, 0
+
+	if fraction > 0 && fraction < 1 {
+		h.sampling = uint64(math.Round(1 / fraction))
+	}
+}
+
+// Add processes raw bytes and updates the entropy estimate. It reports false if the estimate is out of tolerance or
+// a continuous test (see Cutoffs) or the autocorrelation monitor failed on data.
 func (h *HealthCheck) Add(data []byte) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	var history uint8
+	sampled := h.calls%h.sampling == 0
+
+	h.calls++
 
-	for _, b := range data {
+	return h.addLocked(data, sampled)
+}
+
+// addLocked is Add with h.mu held. Unless analyze is set, data only passes through the continuous tests.
+func (h *HealthCheck) addLocked(data []byte, analyze bool) bool {
+	h.failed = ""
+
+	if !analyze {
+		for _, b := range data {
+			for i := range 8 {
+				if failed := h.continuous.add((b >> (7 - i)) & 1); failed != "" {
+					h.failed = failed
+				}
+			}
+		}
+
+		h.skipped += 8 * uint64(len(data))
+
+		return h.IsHealthy() && h.failed == ""
+	}
+
+	var history uint32
+
+	mask := uint32(len(h.counts) - 1)
+
+	for k, b := range data {
 		for i := range 8 {
 			bit := (b >> (7 - i)) & 1
 
+			h.bias.add(8*k+i, bit, h.addresses)
+
 			c0 := float64(h.counts[history][0])
 			c1 := float64(h.counts[history][1])
 
@@ -51,13 +247,25 @@ func (h *HealthCheck) Add(data []byte) bool {
 
 			h.counts[history][bit]++
 
-			history = ((history << 1) | bit) & 0x7F
+			if failed := h.continuous.add(bit); failed != "" {
+				h.failed = failed
+			}
+
+			if failed := h.autocorr.add(bit); failed != "" {
+				h.failed = failed
+			}
+
+			if h.predict != nil {
+				h.predict.add(bit)
+			}
+
+			history = (history<<1 | uint32(bit)) & mask
 
 			h.totalBits++
 		}
 	}
 
-	return h.IsHealthy()
+	return h.IsHealthy() && h.failed == ""
 }
 
 // IsHealthy determines if the hardware is performing within expected physical parameters.
@@ -66,10 +274,73 @@ func (h *HealthCheck) IsHealthy() bool {
 		return true
 	}
 
-	actual := h.entropySum / float64(h.totalBits)
-	diff := math.Abs(actual - h.TargetEntropy)
+	return h.withinTolerance(h.entropySum / float64(h.totalBits))
+}
+
+func (h *HealthCheck) withinTolerance(estimate float64) bool {
+	return math.Abs(estimate-h.TargetEntropy) <= h.TargetEntropy*h.Tolerance
+}
+
+// windowEstimate adds data and returns the entropy per bit estimated for data alone, whether data was analyzed
+// rather than skipped by sampling, and the overall result Add would have returned. If all is set, data is
+// analyzed regardless of sampling.
+func (h *HealthCheck) windowEstimate(data []byte, all bool) (estimate float64, analyzed, healthy bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sum, bits := h.entropySum, h.totalBits
+
+	if !all {
+		all = h.calls%h.sampling == 0
+
+		h.calls++
+	}
+
+	healthy = h.addLocked(data, all)
+
+	if h.totalBits == bits {
+		return 0, false, healthy
+	}
+
+	return (h.entropySum - sum) / float64(h.totalBits-bits), true, healthy
+}
+
+// reset discards all accumulated statistics.
+func (h *HealthCheck) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.resetLocked()
+}
+
+// resetLocked is reset with h.mu held.
+func (h *HealthCheck) resetLocked() {
+	clear(h.counts)
+	h.totalBits = 0
+	h.skipped = 0
+	h.entropySum = 0
 
-	return diff <= (h.TargetEntropy * h.Tolerance)
+	h.continuous.reset()
+	h.autocorr.reset()
+
+	if h.predict != nil {
+		h.predict.reset()
+	}
+
+	h.bias = BiasMap{}
+}
+
+// Err describes why the last Add failed, wrapping ErrHealthCheck, so sources built on a HealthCheck (see package
+// trng) report failures as Device does.
+func (h *HealthCheck) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failed != "" {
+		return fmt.Errorf("%w: %s", ErrHealthCheck, h.failed)
+	}
+
+	return fmt.Errorf("%w: entropy %0.4f outside tolerance", ErrHealthCheck, h.entropySum/float64(max(h.totalBits, 1)))
 }
 
 // EstimatedEntropy returns the current calculated Shannon entropy per bit.
@@ -83,3 +354,35 @@ func (h *HealthCheck) EstimatedEntropy() float64 {
 
 	return h.entropySum / float64(h.totalBits)
 }
+
+// Health returns the outcome of the health check and the current entropy estimate. It reports HealthFailed while
+// the estimate over the bits seen so far is out of tolerance, after a continuous test or the autocorrelation monitor
+// failed, while the last read failed the check, and while the device is quarantined. Only reads move it: a failing
+// device recovers when reads pass again.
+func (d *Device) Health() (HealthStatus, float64) {
+	h := d.health
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var estimate float64
+
+	if h.totalBits > 0 {
+		estimate = h.entropySum / float64(h.totalBits)
+	}
+
+	switch {
+	case d.quarantined.Load(), d.failing.Load(), h.failed != "":
+		return HealthFailed, estimate
+	case h.totalBits >= h.window && !h.withinTolerance(estimate):
+		return HealthFailed, estimate
+	}
+
+	return HealthOK, estimate
+}
+
+// Quarantined reports whether the device withholds output after a health failure (see WithQuarantine). Reads lift
+// the quarantine once enough health windows pass, so servers gating on it must keep letting some reads through.
+func (d *Device) Quarantined() bool {
+	return d.quarantined.Load()
+}
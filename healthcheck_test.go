@@ -0,0 +1,394 @@
+package infnoise
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func FuzzHealthAdd(f *testing.F) {
+	raw := make([]byte, 1024)
+
+	DecodeRaw(simulatorSamples(f, 8*len(raw)), raw)
+
+	f.Add(raw)
+	f.Add([]byte{})
+	f.Add([]byte{0x00, 0x00, 0x00, 0x00})
+	f.Add([]byte{0xff, 0x00, 0xaa, 0x55})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h := NewHealthCheck(0.864, 0.05, 80000)
+
+		h.Add(data)
+		h.Add(data)
+
+		if h.totalBits != uint64(16*len(data)) {
+			t.Fatalf("counted %d bits, want %d", h.totalBits, 16*len(data))
+		}
+
+		est := h.EstimatedEntropy()
+		if math.IsNaN(est) || est < 0 {
+			t.Fatalf("estimate %v for %d bytes", est, len(data))
+		}
+
+		estimate, _, _ := h.windowEstimate(data, false)
+		if math.IsNaN(estimate) || estimate < 0 {
+			t.Fatalf("window estimate %v for %d bytes", estimate, len(data))
+		}
+	})
+}
+
+func TestHealthReport(t *testing.T) {
+	raw := make([]byte, 20000)
+
+	DecodeRaw(simulatorSamples(t, 8*len(raw)), raw)
+
+	h := NewHealthCheck(0.864, 0.05, 80000)
+
+	h.Add(raw[:1000])
+
+	r := h.Report()
+	if r.WindowFilled || r.Status != HealthOK || !r.Tests[0].Pass {
+		t.Fatalf("report before the window filled: %+v", r)
+	}
+
+	h.Add(raw[1000:])
+
+	b, err := json.Marshal(h.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Report
+
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.WindowFilled || got.Status != HealthOK || got.TotalBits != 8*uint64(len(raw)) || !slices.Equal(got.Contexts, h.counts) {
+		t.Fatalf("round-tripped report: %s", b)
+	}
+
+	for _, e := range got.Estimates {
+		if e.BitsPerBit <= 0 || e.BitsPerBit > 1 {
+			t.Fatalf("estimate %s = %f", e.Name, e.BitsPerBit)
+		}
+	}
+
+	h = NewHealthCheck(0.864, 0.05, 80)
+
+	h.Add(make([]byte, 100))
+
+	if r := h.Report(); r.Status != HealthFailed || r.Tests[0].Pass {
+		t.Fatalf("report for constant input: %+v", r.Tests)
+	}
+}
+
+func TestCutoffs(t *testing.T) {
+	// SP 800-90B's examples for full-entropy binary samples at alpha = 2^-20.
+	c, err := NewCutoffs(0x1p-20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.RepetitionCount != 21 || c.AdaptiveProportion != 589 || c.AdaptiveWindow != 1024 {
+		t.Fatalf("cutoffs %+v", c)
+	}
+
+	for _, bad := range [][2]float64{{0, 1}, {1, 1}, {0x1p-30, 0}, {0x1p-30, 1.5}} {
+		if _, err := NewCutoffs(bad[0], bad[1]); err == nil {
+			t.Fatalf("accepted rate %g and min-entropy %g", bad[0], bad[1])
+		}
+	}
+
+	h := NewHealthCheck(0.864, 0.05, 1<<30)
+
+	h.SetCutoffs(c)
+
+	if h.Add([]byte{0x55, 0xff, 0xff, 0xff}) {
+		t.Fatal("a run of 25 ones passed the repetition count test")
+	}
+
+	if !h.Add([]byte{0x55, 0xaa}) {
+		t.Fatal("alternating bits failed")
+	}
+
+	r := h.Report()
+
+	if r.Cutoffs != c || r.Status != HealthFailed || r.Tests[1].Pass || !r.Tests[2].Pass {
+		t.Fatalf("report %+v", r)
+	}
+
+	if err := New(WithContinuousTests(0.5, 2)).Start(); err == nil {
+		t.Fatal("Start accepted invalid continuous test settings")
+	}
+}
+
+func TestBiasMap(t *testing.T) {
+	h := NewHealthCheck(0.864, 0.05, 80000)
+
+	data := make([]byte, 64)
+	for i := range data {
+		data[i] = 0x80
+	}
+
+	h.Add(data)
+
+	m := h.Report().Bias
+
+	if m.Positions[0] != (Density{Ones: 64, Bits: 64}) || m.Positions[1] != (Density{Bits: 64}) || m.Addresses[0].Bits != 0 {
+		t.Fatalf("bias map without addresses: %+v", m)
+	}
+
+	if o := m.Overall(); o.Ratio() != 0.125 || m.Positions[0].Deviation(o) < 5 {
+		t.Fatalf("overall %+v, deviation %f", o, m.Positions[0].Deviation(o))
+	}
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithPattern(Pattern{FirstAddress: 2, LastAddress: 5}))
+
+	_, err := dv.Read(make([]byte, 4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m = dv.HealthReport().Bias
+
+	var addressed uint64
+
+	for a, d := range m.Addresses {
+		if (a < 2 || a > 5) != (d.Bits == 0) {
+			t.Fatalf("address %d counted %d bits", a, d.Bits)
+		}
+
+		addressed += d.Bits
+	}
+
+	if addressed != m.Overall().Bits || addressed != 8*4096 {
+		t.Fatalf("addresses counted %d bits, positions %d", addressed, m.Overall().Bits)
+	}
+}
+
+func TestAutocorrelation(t *testing.T) {
+	h := NewHealthCheck(0.864, 0.05, 1<<40)
+
+	alternating := make([]byte, AutocorrelationWindow/8)
+	for i := range alternating {
+		alternating[i] = 0x55
+	}
+
+	if !h.Add(alternating[:1]) || len(h.Report().Autocorrelation.Coefficients) != 0 {
+		t.Fatal("autocorrelation reported before the window was complete")
+	}
+
+	if h.Add(alternating[1:]) {
+		t.Fatal("alternating bits passed the autocorrelation check")
+	}
+
+	r := h.Report().Autocorrelation
+
+	if len(r.Coefficients) != MaxAutocorrelationLag || math.Abs(r.Coefficients[0]+1) > 1e-3 || math.Abs(r.Coefficients[1]-1) > 1e-3 {
+		t.Fatalf("coefficients %v", r.Coefficients)
+	}
+
+	if r.Windows != 1 || r.Failures != 1 || r.Limit != DefaultAutocorrelationLimit {
+		t.Fatalf("report %+v", r)
+	}
+
+	raw := make([]byte, AutocorrelationWindow/8)
+
+	DecodeRaw(simulatorSamples(t, 8*len(raw)), raw)
+
+	h.SetAutocorrelationLimit(0)
+
+	if !h.Add(alternating) {
+		t.Fatal("disabled autocorrelation check failed")
+	}
+
+	h.SetAutocorrelationLimit(DefaultAutocorrelationLimit)
+
+	if !h.Add(raw) {
+		t.Fatalf("simulator output failed: %s", h.Err())
+	}
+
+	if r := h.Report().Autocorrelation; r.Coefficients[0] > -0.1 || r.Failures != 0 {
+		t.Fatalf("simulator coefficients %v", r.Coefficients)
+	}
+}
+
+func TestHealthHistoryBits(t *testing.T) {
+	raw := make([]byte, 20000)
+
+	DecodeRaw(simulatorSamples(t, 8*len(raw)), raw)
+
+	h := NewHealthCheck(0.864, 0.05, 80000)
+
+	err := h.SetHistoryBits(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Add(raw)
+
+	r := h.Report()
+
+	var total uint64
+
+	for _, c := range r.Contexts {
+		total += uint64(c[0]) + uint64(c[1])
+	}
+
+	if r.HistoryBits != 12 || len(r.Contexts) != 1<<12 || total != r.TotalBits {
+		t.Fatalf("%d-bit report with %d contexts counting %d of %d bits", r.HistoryBits, len(r.Contexts), total, r.TotalBits)
+	}
+
+	var buf strings.Builder
+
+	err = r.WriteContexts(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	want := fmt.Sprintf("000000000011,%d,%d", r.Contexts[3][0], r.Contexts[3][1])
+
+	if len(lines) != 1+1<<12 || lines[0] != "history,zeros,ones" || lines[4] != want {
+		t.Fatalf("CSV has %d lines, line 4 %q, want %q", len(lines), lines[4], want)
+	}
+
+	if h.SetHistoryBits(MaxHistoryBits+1) == nil || New(WithHealthHistoryBits(0)).Start() == nil {
+		t.Fatal("accepted an invalid history length")
+	}
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithHealthHistoryBits(9))
+
+	if r := dv.HealthReport(); r.HistoryBits != 9 || len(r.Contexts) != 512 {
+		t.Fatalf("device report with %d history bits and %d contexts", r.HistoryBits, len(r.Contexts))
+	}
+}
+
+func TestPredictors(t *testing.T) {
+	h := NewHealthCheck(0.864, 0.05, 1<<40)
+
+	h.SetAutocorrelationLimit(0)
+	h.SetPredictors(1)
+
+	alternating := make([]byte, PredictorBlock/8)
+	for i := range alternating {
+		alternating[i] = 0x55
+	}
+
+	h.Add(alternating)
+
+	r := h.Report()
+
+	if len(r.Predictions) != 4 || r.Estimates[len(r.Estimates)-1].Name != "prediction-bound" {
+		t.Fatalf("predictions %+v, estimates %+v", r.Predictions, r.Estimates)
+	}
+
+	for _, p := range r.Predictions {
+		if p.MinEntropy > 0.1 && p.Name != "multi-mcw" {
+			t.Errorf("%s bounds alternating bits at %.4f bits per bit", p.Name, p.MinEntropy)
+		}
+	}
+
+	raw := make([]byte, 8*PredictorBlock/8)
+
+	DecodeRaw(simulatorSamples(t, 8*len(raw)), raw)
+
+	h.SetPredictors(1)
+	h.Add(raw)
+
+	bound := h.Report().Estimates
+
+	if b := bound[len(bound)-1]; b.BitsPerBit < 0.5 || b.BitsPerBit > 1 {
+		t.Fatalf("simulator output bounded at %.4f bits per bit", b.BitsPerBit)
+	}
+
+	h.SetPredictors(0.25)
+	h.Add(raw)
+
+	// Blocks 0 and 4 of the 8 are analyzed.
+	for _, p := range h.Report().Predictions {
+		if p.Predictions > 2*PredictorBlock || p.Predictions < 2*PredictorBlock-64 {
+			t.Errorf("%s made %d predictions on a quarter of %d bits", p.Name, p.Predictions, 8*len(raw))
+		}
+	}
+
+	h.SetPredictors(0)
+	h.Add(raw)
+
+	if r := h.Report(); r.Predictions != nil || r.Estimates[len(r.Estimates)-1].Name == "prediction-bound" {
+		t.Fatal("disabled predictors reported")
+	}
+
+	if New(WithPredictors(1.5)).Start() == nil {
+		t.Fatal("accepted an invalid predictor fraction")
+	}
+}
+
+func TestHealthSampling(t *testing.T) {
+	raw := make([]byte, 7*1024)
+
+	DecodeRaw(simulatorSamples(t, 8*len(raw)), raw)
+
+	h := NewHealthCheck(0.864, 0.05, 1<<40)
+
+	h.SetSampling(0.25)
+
+	for batch := range slices.Chunk(raw, 1024) {
+		if !h.Add(batch) {
+			t.Fatalf("simulator output failed: %s", h.Err())
+		}
+	}
+
+	r := h.Report()
+
+	if r.Sampling != 0.25 || r.TotalBits != 2*8192 || r.SkippedBits != 5*8192 {
+		t.Fatalf("sampling %g analyzed %d bits and skipped %d", r.Sampling, r.TotalBits, r.SkippedBits)
+	}
+
+	// The eighth call is skipped, but a stuck source still fails the repetition count test.
+	stuck := make([]byte, 64)
+
+	if h.Add(stuck) || h.Report().TotalBits != 2*8192 {
+		t.Fatal("stuck bits passed a skipped batch")
+	}
+
+	if New(WithHealthSampling(0)).Start() == nil {
+		t.Fatal("accepted an invalid sampling fraction")
+	}
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithHealthSampling(0.5))
+
+	buf := make([]byte, 4*IOBatch/8)
+
+	_, err := io.ReadFull(dv, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := dv.HealthReport(); r.SkippedBits == 0 || r.TotalBits == 0 {
+		t.Fatalf("device analyzed %d bits and skipped %d", r.TotalBits, r.SkippedBits)
+	}
+}
+
+func BenchmarkHealthAdd(b *testing.B) {
+	raw := make([]byte, IOBatch/8)
+
+	DecodeRaw(simulatorSamples(b, IOBatch), raw)
+
+	h := NewHealthCheck(0.864, 0.05, 80000)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+
+	for b.Loop() {
+		h.Add(raw)
+	}
+}
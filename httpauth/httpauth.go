@@ -0,0 +1,221 @@
+// Package httpauth authenticates the clients of the HTTP entropy services and signs their responses.
+//
+// Wrap admits a request that carries one of the configured API keys as a bearer token or, with WithClientCerts,
+// presents a TLS client certificate the server verified (mTLS; configure the server's tls.Config with ClientCAs).
+// With WithResponseMAC, every response carries in MACHeader the hex-encoded HMAC-SHA256, under a key shared with
+// the clients, of
+//
+//	"infnoise-mac-v1\n" method " " request-target "\n" nonce "\n" status "\n" body
+//
+// where nonce is the client's NonceHeader, so a client can check that a payload came from the holder of the key and
+// answers its own request rather than a replayed one. Transport is the client side.
+package httpauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// NonceHeader carries the client's nonce, which the response MAC covers.
+	NonceHeader = "Infnoise-Nonce"
+
+	// MACHeader carries the response MAC.
+	MACHeader = "Infnoise-MAC"
+)
+
+// macLabel separates response MACs from any other use of the key.
+const macLabel = "infnoise-mac-v1\n"
+
+// ErrBadMAC is returned by Transport when a response's MAC is missing or does not verify.
+var ErrBadMAC = errors.New("response MAC does not verify")
+
+type options struct {
+	keys        [][sha256.Size]byte
+	clientCerts bool
+	macKey      []byte
+}
+
+// Option configures Wrap.
+type Option func(*options)
+
+// WithAPIKeys admits requests sending one of keys as "Authorization: Bearer <key>".
+func WithAPIKeys(keys ...string) Option {
+	return func(o *options) {
+		for _, key := range keys {
+			o.keys = append(o.keys, sha256.Sum256([]byte(key)))
+		}
+	}
+}
+
+// WithClientCerts admits requests over TLS connections whose client certificate the server verified.
+func WithClientCerts() Option {
+	return func(o *options) {
+		o.clientCerts = true
+	}
+}
+
+// WithResponseMAC signs every response with key (see the package documentation).
+func WithResponseMAC(key []byte) Option {
+	return func(o *options) {
+		o.macKey = bytes.Clone(key)
+	}
+}
+
+// Wrap authenticates requests to next and signs its responses as configured. Without WithAPIKeys or
+// WithClientCerts, every request is admitted.
+func Wrap(next http.Handler, opts ...Option) http.Handler {
+	o := &options{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.macKey != nil {
+			mw := &macWriter{ResponseWriter: w, status: http.StatusOK}
+
+			defer mw.finish(o.macKey, r)
+
+			w = mw
+		}
+
+		if !o.admit(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// admit reports whether r authenticated itself by one of the configured means.
+func (o *options) admit(r *http.Request) bool {
+	if len(o.keys) == 0 && !o.clientCerts {
+		return true
+	}
+
+	if o.clientCerts && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(token))
+
+	var match int
+
+	for _, key := range o.keys {
+		match |= subtle.ConstantTimeCompare(sum[:], key[:])
+	}
+
+	return match == 1
+}
+
+// macWriter holds back a response until its MAC is known.
+type macWriter struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+}
+
+func (m *macWriter) WriteHeader(status int) {
+	m.status = status
+}
+
+func (m *macWriter) Write(p []byte) (int, error) {
+	return m.body.Write(p)
+}
+
+// finish signs the response and sends it.
+func (m *macWriter) finish(key []byte, r *http.Request) {
+	sum := mac(key, r.Method, r.RequestURI, r.Header.Get(NonceHeader), m.status, m.body.Bytes())
+
+	m.Header().Set(MACHeader, hex.EncodeToString(sum))
+
+	m.ResponseWriter.WriteHeader(m.status)
+	m.ResponseWriter.Write(m.body.Bytes())
+
+	clear(m.body.Bytes())
+}
+
+func mac(key []byte, method, target, nonce string, status int, body []byte) []byte {
+	h := hmac.New(sha256.New, key)
+
+	fmt.Fprintf(h, "%s%s %s\n%s\n%d\n", macLabel, method, target, nonce, status)
+
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+// Transport sends the API key with every request and, with a MAC key, sends a fresh nonce and checks the response
+// MAC, failing with ErrBadMAC when it does not verify. Use it as the Transport of the http.Client passed to
+// infnoise.NewRemoteDevice; client certificates go in Base's TLS configuration.
+type Transport struct {
+	// Base sends the requests (default http.DefaultTransport).
+	Base http.RoundTripper
+
+	APIKey string
+	MACKey []byte
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context())
+
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+
+	if t.MACKey == nil {
+		return base.RoundTrip(req)
+	}
+
+	nonce := rand.Text()
+
+	req.Header.Set(NonceHeader, nonce)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	got, err := hex.DecodeString(resp.Header.Get(MACHeader))
+	if err != nil || !hmac.Equal(got, mac(t.MACKey, req.Method, req.URL.RequestURI(), nonce, resp.StatusCode, body)) {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Redacted(), ErrBadMAC)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
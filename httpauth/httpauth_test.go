@@ -0,0 +1,137 @@
+package httpauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var hello = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("hello"))
+})
+
+func TestAPIKeys(t *testing.T) {
+	h := Wrap(hello, WithAPIKeys("k1", "k2"))
+
+	for auth, want := range map[string]int{
+		"":          http.StatusUnauthorized,
+		"Bearer k3": http.StatusUnauthorized,
+		"Basic k1":  http.StatusUnauthorized,
+		"Bearer k1": http.StatusOK,
+		"Bearer k2": http.StatusOK,
+	} {
+		r := httptest.NewRequest("GET", "/raw?n=1", nil)
+
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if w.Code != want {
+			t.Errorf("Authorization %q: got status %d, want %d", auth, w.Code, want)
+		}
+	}
+}
+
+func TestClientCerts(t *testing.T) {
+	h := Wrap(hello, WithAPIKeys("k1"), WithClientCerts())
+
+	r := httptest.NewRequest("GET", "/raw?n=1", nil)
+	w := httptest.NewRecorder()
+
+	// A TLS connection without a verified client certificate does not count.
+	r.TLS = &tls.ConnectionState{}
+
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("no client certificate: got status %d", w.Code)
+	}
+
+	r.TLS.VerifiedChains = [][]*x509.Certificate{{{}}}
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("verified client certificate: got status %d", w.Code)
+	}
+}
+
+func TestResponseMAC(t *testing.T) {
+	key := []byte("device key")
+
+	srv := httptest.NewServer(Wrap(hello, WithAPIKeys("k1"), WithResponseMAC(key)))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{APIKey: "k1", MACKey: key}}
+
+	resp, err := client.Get(srv.URL + "/whitened?n=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("got %d %q", resp.StatusCode, body)
+	}
+
+	// Errors are signed too, so a client can trust a refusal.
+	client.Transport = &Transport{APIKey: "k2", MACKey: key}
+
+	resp, err = client.Get(srv.URL + "/whitened?n=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong key: got status %d", resp.StatusCode)
+	}
+
+	// A server without the key cannot produce responses the client accepts.
+	client.Transport = &Transport{APIKey: "k1", MACKey: []byte("other key")}
+
+	_, err = client.Get(srv.URL + "/whitened?n=5")
+	if !errors.Is(err, ErrBadMAC) {
+		t.Fatalf("response under another key: %v", err)
+	}
+}
+
+func TestResponseMACBindsNonce(t *testing.T) {
+	key := []byte("device key")
+
+	h := Wrap(hello, WithResponseMAC(key))
+
+	r := httptest.NewRequest("GET", "/whitened?n=5", nil)
+
+	r.Header.Set(NonceHeader, "n1")
+
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	got := w.Header().Get(MACHeader)
+
+	r.Header.Set(NonceHeader, "n2")
+
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	// Same request and body, different nonce: a recorded response cannot be replayed to another request.
+	if got == "" || got == w.Header().Get(MACHeader) {
+		t.Fatalf("MAC %q does not depend on the nonce", got)
+	}
+}
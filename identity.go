@@ -0,0 +1,161 @@
+package infnoise
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+var (
+	// ErrUserAreaUnsupported is returned by Info and Provision when the backend cannot access the EEPROM user area.
+	ErrUserAreaUnsupported = errors.New("backend cannot access the EEPROM user area")
+
+	// ErrBadBoardID is returned when the EEPROM user area holds an identity record whose checksum does not match.
+	ErrBadBoardID = errors.New("corrupt board ID record")
+)
+
+// UserAreaBackend is implemented by backends that can access the EEPROM user area, the part of the FT240X's
+// configuration memory left free for applications.
+type UserAreaBackend interface {
+	Backend
+
+	// ReadUserArea returns the whole user area.
+	ReadUserArea() ([]byte, error)
+
+	// WriteUserArea writes p to the start of the user area.
+	WriteUserArea(p []byte) error
+}
+
+// BoardID is a random per-board identifier written to the EEPROM user area by Provision. Unlike the USB serial
+// string, it cannot be cloned by reprogramming the descriptors with FTDI's tools.
+type BoardID [16]byte
+
+func (id BoardID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// DeviceInfo describes the board behind a started Device.
+type DeviceInfo struct {
+	// Backend is the backend the device was opened with.
+	Backend BackendName
+
+	// ID is the board's identifier; HasID is false if the board was never provisioned.
+	ID    BoardID
+	HasID bool
+}
+
+// boardIDMagic starts the identity record: magic, a version byte, the ID, and a CRC-32 over everything before it.
+var boardIDMagic = [4]byte{'I', 'N', 'I', 'D'}
+
+const (
+	boardIDVersion = 1
+
+	boardIDRecordLen = len(boardIDMagic) + 1 + len(BoardID{}) + 4
+)
+
+func encodeBoardID(id BoardID) []byte {
+	rec := make([]byte, 0, boardIDRecordLen)
+
+	rec = append(rec, boardIDMagic[:]...)
+	rec = append(rec, boardIDVersion)
+	rec = append(rec, id[:]...)
+
+	return binary.LittleEndian.AppendUint32(rec, crc32.ChecksumIEEE(rec))
+}
+
+// decodeBoardID parses the record at the start of area; ok is false if there is none.
+func decodeBoardID(area []byte) (id BoardID, ok bool, err error) {
+	if len(area) < boardIDRecordLen || !bytes.Equal(area[:len(boardIDMagic)], boardIDMagic[:]) {
+		return id, false, nil
+	}
+
+	rec := area[:boardIDRecordLen]
+	body := rec[:len(rec)-4]
+
+	if rec[len(boardIDMagic)] != boardIDVersion || crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(rec[len(body):]) {
+		return id, false, ErrBadBoardID
+	}
+
+	copy(id[:], body[len(boardIDMagic)+1:])
+
+	return id, true, nil
+}
+
+// Info reads the board's identity from the EEPROM user area. The device must be started.
+func (d *Device) Info() (DeviceInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	info := DeviceInfo{Backend: d.backend}
+
+	area, err := d.readUserArea()
+	if err != nil {
+		return info, err
+	}
+
+	info.ID, info.HasID, err = decodeBoardID(area)
+
+	return info, err
+}
+
+// Provision writes a random BoardID to the EEPROM user area unless the board already has one, and returns the
+// board's ID. The record is read back to verify the write. A corrupt record is not overwritten; Provision fails with
+// ErrBadBoardID instead, so a board is never silently given a new identity.
+func (d *Device) Provision() (BoardID, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	area, err := d.readUserArea()
+	if err != nil {
+		return BoardID{}, err
+	}
+
+	id, ok, err := decodeBoardID(area)
+	if err != nil || ok {
+		return id, err
+	}
+
+	if len(area) < boardIDRecordLen {
+		return BoardID{}, fmt.Errorf("EEPROM user area holds %d bytes, need %d", len(area), boardIDRecordLen)
+	}
+
+	rand.Read(id[:])
+
+	err = d.usbDev.(UserAreaBackend).WriteUserArea(encodeBoardID(id))
+	if err != nil {
+		return BoardID{}, err
+	}
+
+	area, err = d.readUserArea()
+	if err != nil {
+		return BoardID{}, err
+	}
+
+	got, ok, err := decodeBoardID(area)
+	if err == nil && (!ok || got != id) {
+		err = errors.New("board ID did not read back")
+	}
+
+	if err != nil {
+		return BoardID{}, fmt.Errorf("verifying board ID: %w", err)
+	}
+
+	return id, nil
+}
+
+func (d *Device) readUserArea() ([]byte, error) {
+	if !d.running {
+		return nil, ErrNotStarted
+	}
+
+	ua, ok := d.usbDev.(UserAreaBackend)
+	if !ok {
+		return nil, ErrUserAreaUnsupported
+	}
+
+	return ua.ReadUserArea()
+}
@@ -0,0 +1,55 @@
+package infnoise
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProvision(t *testing.T) {
+	dv, sim := openSimulator(t, 1, DefaultSimulatorGain)
+
+	info, err := dv.Info()
+	if err != nil || info.HasID {
+		t.Fatalf("Info before provisioning: %+v, %v", info, err)
+	}
+
+	id, err := dv.Provision()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := dv.Provision()
+	if err != nil || again != id {
+		t.Fatalf("second Provision: %s, %v (want %s)", again, err, id)
+	}
+
+	dv.Close()
+
+	// The ID survives a reconnect, like the EEPROM it lives in.
+	err = dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dv.Close()
+
+	info, err = dv.Info()
+	if err != nil || !info.HasID || info.ID != id || info.Backend != dv.backend {
+		t.Fatalf("Info after reconnect: %+v, %v (want ID %s)", info, err, id)
+	}
+
+	rec := encodeBoardID(id)
+	rec[len(boardIDMagic)+1] ^= 1
+
+	sim.WriteUserArea(rec)
+
+	_, err = dv.Info()
+	if !errors.Is(err, ErrBadBoardID) {
+		t.Fatalf("Info with a corrupt record: %v", err)
+	}
+
+	_, err = dv.Provision()
+	if !errors.Is(err, ErrBadBoardID) {
+		t.Fatalf("Provision over a corrupt record: %v", err)
+	}
+}
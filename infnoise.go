@@ -1,42 +1,233 @@
 package infnoise
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coalaura/infnoise/trng"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// VendorID and ProductID identify the board's FT240X.
+	VendorID  = 0x0403
+	ProductID = 0x6015
 )
 
+// Bitbang pin assignments of current boards (ProfileV2).
 const (
+	// COMP1 and COMP2 are the comparator outputs, the only inputs.
 	COMP1 = 1
 	COMP2 = 4
+
+	// SWEN1 and SWEN2 enable the multiplier switches.
 	SWEN1 = 2
 	SWEN2 = 0
 
+	// ADDR0..ADDR3 drive the address sweep.
 	ADDR0 = 3
 	ADDR1 = 5
 	ADDR2 = 6
 	ADDR3 = 7
 
-	// All bits are outputs except COMP1 and COMP2.
+	// Mask is the bitbang direction mask of current boards: all bits are outputs except COMP1 and COMP2.
 	// 0xFF &~(1<<1) &~(1<<4) == 0xED
 	Mask = 0xED
+)
 
-	BufLen  = 512
+const (
+	// BufLen is the length of the repeating output pattern.
+	BufLen = 512
+
+	// IOBatch and WhitenedChunkSize are the defaults of WithIOBatch and WithChunkSize.
 	IOBatch = BufLen * 64
 
 	WhitenedChunkSize = 2048
+
+	// MaxIOBatch and MaxChunkSize bound WithIOBatch and WithChunkSize; MaxRetries bounds WithRetry, so retries
+	// cannot hide a device that keeps failing.
+	MaxIOBatch   = 1 << 20
+	MaxChunkSize = 1 << 20
+	MaxRetries   = 10
+)
+
+const (
+	// HighLatencyTimeout is the transfer timeout set by WithHighLatencyLink and for boards attached through usbip.
+	HighLatencyTimeout = 30 * time.Second
+
+	// highLatencyTimer is the latency timer, in milliseconds, used on high-latency links.
+	highLatencyTimer = 16
 )
 
+const (
+	// paceSlices is how many batches per second WithTargetRate aims for.
+	paceSlices = 10
+
+	// paceStep bounds each sleep of the rate governor.
+	paceStep = 50 * time.Millisecond
+)
+
+// ErrClosed is returned by reads that were pending or issued after Close.
+var ErrClosed = errors.New("device closed")
+
+// ErrPaused is returned by reads issued while the device is paused (see Pause).
+var ErrPaused = errors.New("device paused")
+
+// ErrNotStarted is returned by operations that need a started device.
+var ErrNotStarted = errors.New("device not started")
+
+// ErrHealthCheck is wrapped by reads that fail the health check, and ErrQuarantined by reads while the device
+// stays quarantined afterwards (see WithQuarantine).
+var (
+	ErrHealthCheck = errors.New("hardware health check failed")
+	ErrQuarantined = errors.New("hardware quarantined")
+)
+
+var _ trng.Source = (*Device)(nil)
+
 // Device represents a connection to an Infinite Noise TRNG hardware unit.
 type Device struct {
 	mu      sync.Mutex
-	usbDev  *usbHandle
+	turn    fairLock
+	backend BackendName
+	usbDev  Backend
 	health  *HealthCheck
 	running bool
+	paused  bool
+
+	// idle is set after idleTimeout without reads; suspended records whether the backend's reader is stopped,
+	// which it is while the device is paused or idle.
+	idle        bool
+	suspended   bool
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+	lastRead    time.Time
+
+	// closed is set by Close before it waits for in-flight reads, which then fail with ErrClosed.
+	closed atomic.Bool
+
+	// capMu guards the optional backend capabilities that are used without holding mu.
+	capMu     sync.Mutex
+	interrupt func()
+	ring      RingBackend
 
+	// deadline is the read deadline in Unix nanoseconds, or 0 for none.
+	deadline atomic.Int64
+
+	// resync is set when a batch was interrupted, leaving unread samples in the FTDI FIFO.
+	resync bool
+
+	// timeoutSet records that the backend timeout was shortened for a deadline and has to be restored.
+	timeoutSet bool
+
+	// timeout and latency are the configured link settings (0 for the backend default); linkTimeout is the
+	// timeout in effect since Start, restored after deadline-bounded reads.
+	timeout     time.Duration
+	latency     byte
+	linkTimeout time.Duration
+
+	watermarks [2]int
+
+	// tracer records spans (see WithTracerProvider); batchCtx carries the current batch's span to fill and is
+	// guarded by mu.
+	tracer   trace.Tracer
+	batchCtx context.Context
+
+	// labels carries the base pprof labels the hot path adds its stage labels to, or is nil if WithProfileLabels is
+	// off.
+	labels context.Context
+
+	// trend is the recorder set up by WithTrend, or nil.
+	trend *trendRecorder
+
+	// detach is passed to backends implementing KernelDriverBackend.
+	detach bool
+
+	warmupBytes int
+	warmupTime  time.Duration
+
+	// quarantined is written under mu; it and failing, which mirrors status, are also read by Health without mu.
+	quarantineWindows int
+	quarantined       atomic.Bool
+	failing           atomic.Bool
+
+	// rewhiten is set when a quarantine begins and again when recover ends it; the next holder of poolMu then
+	// restarts the whitener and drops the pooled output (see dropQuarantined).
+	rewhiten atomic.Bool
+
+	// rate is the WithTargetRate limit; nextBatch, guarded by turn, is when the governor admits the next batch.
+	rate      int
+	nextBatch time.Time
+
+	drift driftTracker
+
+	status   HealthStatus
+	onHealth func(old, new HealthStatus, estimate float64)
+
+	// confErr is an invalid option found by New and reported by Start.
+	confErr error
+
+	// events receives lifecycle events (see Events); dropped is the ring's overflow counter at the last check.
+	events  chan Event
+	dropped uint64
+
+	profile    Profile
 	outPattern []byte
 	outBulk    []byte
 	inBulk     []byte
+
+	// spare buffers the whitened output behind Bytes, Uint32 and Uint64.
+	spare bufReader
+
+	// mode and owner arbitrate between Read, ReadWhitened, and ReadFolded (see ReadMode).
+	mode  ReadMode
+	owner atomic.Int32
+
+	// fold is the WithFoldFactor, or 0 to derive it from the entropy estimate.
+	fold int
+
+	// poolMu guards the whitened output buffered in pool, the raw tap, and the conditioner.
+	poolMu   sync.Mutex
+	whitener *whitener
+	rawPool  []byte
+	poolBuf  []byte
+	pool     []byte
+	tap      []byte
+
+	// reseedInterval is the WithReseedInterval period; reseeds and lastReseed (Unix nanoseconds) back Stats.
+	reseedInterval time.Duration
+	reseeds        atomic.Uint64
+	lastReseed     atomic.Int64
+
+	// readStats records every Read and ReadWhitened for Stats.
+	readStats readStats
+
+	// retries and retryBackoff are the WithRetry policy; retried counts the retried batches, for Stats.
+	retries      int
+	retryBackoff time.Duration
+	retried      atomic.Uint64
+
+	// reconnects and reconnectBackoff are the WithReconnect policy; reconnected counts the re-opened handles, for
+	// Stats.
+	reconnects       int
+	reconnectBackoff time.Duration
+	reconnected      atomic.Uint64
+
+	// poolTime is when pool was harvested (guarded by poolMu). Output older than maxPoolAge is handled according to
+	// stalePolicy; staleDiscarded and staleServed count the bytes affected, for Stats.
+	poolTime       time.Time
+	maxPoolAge     time.Duration
+	stalePolicy    StalePolicy
+	staleDiscarded atomic.Uint64
+	staleServed    atomic.Uint64
+
+	// auditLog is the WithAuditLog record, or nil.
+	auditLog *auditLog
 }
 
 // New initializes a new Infinite Noise device with default internal buffers.
@@ -45,36 +236,113 @@ func New(opts ...option) *Device {
 		targetEntropy: 0.864,
 		tolerance:     0.05,
 		window:        80000,
+		profile:       ProfileV2,
+		ioBatch:       IOBatch,
+		chunkSize:     WhitenedChunkSize,
+		detach:        true,
+		ratchet:       true,
+		falsePositive: DefaultFalsePositiveRate,
+		minEntropy:    DefaultMinEntropy,
+		autocorrLimit: DefaultAutocorrelationLimit,
+		historyBits:   DefaultHistoryBits,
+		predictors:    DefaultPredictorFraction,
+		sampling:      1,
 	}
 
 	for _, opt := range opts {
 		opt(conf)
 	}
 
+	if conf.pattern != nil {
+		conf.profile.Pattern = *conf.pattern
+	}
+
+	// Fall back to the defaults so the buffers can be allocated; Start reports the error.
+	confErr := conf.validate()
+	if confErr != nil {
+		conf.ioBatch = IOBatch
+		conf.chunkSize = WhitenedChunkSize
+	}
+
 	d := &Device{
-		health: &HealthCheck{
-			TargetEntropy: conf.targetEntropy,
-			Tolerance:     conf.tolerance,
-			window:        conf.window,
-		},
+		backend: conf.backend,
+
+		health:   NewHealthCheck(conf.targetEntropy, conf.tolerance, conf.window),
+		drift:    driftTracker{target: conf.targetEntropy, tolerance: conf.tolerance},
+		onHealth: conf.onHealth,
+
+		quarantineWindows: conf.quarantine,
+		watermarks:        conf.watermarks,
+		warmupBytes:       conf.warmupBytes,
+		warmupTime:        conf.warmupTime,
+		rate:              conf.rate,
+		idleTimeout:       conf.idleTimeout,
+		detach:            conf.detach,
+		tracer:            newTracer(conf.tracerProvider),
+		labels:            conf.labels,
+		timeout:           conf.timeout,
+		latency:           byte(conf.latency),
+
+		events: make(chan Event, eventBuffer),
+
+		profile:    conf.profile,
+		outPattern: conf.profile.Bytes(BufLen),
+		outBulk:    make([]byte, conf.ioBatch),
+		inBulk:     make([]byte, conf.ioBatch),
+
+		mode:     conf.mode,
+		fold:     conf.fold,
+		whitener: newWhitener(conf.ratchet),
+		rawPool:  make([]byte, 2*conf.chunkSize),
+		poolBuf:  make([]byte, conf.chunkSize),
+
+		reseedInterval: conf.reseed,
+		maxPoolAge:     conf.maxPoolAge,
+		stalePolicy:    conf.stalePolicy,
+		retries:        conf.retries,
+		retryBackoff:   conf.retryBackoff,
+
+		reconnects:       conf.reconnects,
+		reconnectBackoff: conf.reconnectWait,
 
-		outPattern: make([]byte, BufLen),
-		outBulk:    make([]byte, IOBatch),
-		inBulk:     make([]byte, IOBatch),
+		confErr: confErr,
 	}
 
-	for i := range BufLen {
-		if i&1 == 1 {
-			d.outPattern[i] = (1 << SWEN2)
-		} else {
-			d.outPattern[i] = (1 << SWEN1)
-		}
+	d.spare = newBufReader(d.ReadWhitened, BufLen)
+
+	if conf.auditLog != nil {
+		d.auditLog = &auditLog{w: conf.auditLog}
+	}
+
+	// Batches start at the beginning of the output pattern, so samples map to addresses through its first period.
+	d.health.addresses = make([]uint8, BufLen)
+
+	for i := range d.health.addresses {
+		d.health.addresses[i] = conf.profile.Pattern.address(i)
+	}
+
+	if cutoffs, err := NewCutoffs(conf.falsePositive, conf.minEntropy); err == nil {
+		d.health.SetCutoffs(cutoffs)
+	}
+
+	d.health.SetAutocorrelationLimit(max(conf.autocorrLimit, 0))
+	d.health.SetPredictors(conf.predictors)
+	d.health.SetSampling(conf.sampling)
+
+	if confErr == nil {
+		d.health.SetHistoryBits(conf.historyBits)
+	}
+
+	if confErr == nil && (conf.lockMemory || conf.noDump) {
+		d.confErr = d.protectBuffers(conf.lockMemory, conf.noDump)
+	}
 
-		d.outPattern[i] |= makeAddress(uint8(i & 0x0f))
+	if confErr == nil && conf.trendInterval > 0 {
+		d.trend = newTrendRecorder(conf.trendInterval, conf.trendPoints, conf.trendSink, conf.trendFormat)
 	}
 
 	for off := 0; off < len(d.outBulk); off += BufLen {
-		copy(d.outBulk[off:off+BufLen], d.outPattern)
+		copy(d.outBulk[off:], d.outPattern)
 	}
 
 	return d
@@ -85,98 +353,763 @@ func (d *Device) Start() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	handle, err := openUSB(0x0403, 0x6015)
+	if d.confErr != nil {
+		return d.confErr
+	}
+
+	err := d.profile.Validate()
+	if err != nil {
+		return err
+	}
+
+	d.paused = false
+	d.idle = false
+
+	err = d.openHandle()
+	if err != nil {
+		return err
+	}
+
+	d.running = true
+
+	d.closed.Store(false)
+
+	d.owner.Store(ownerNone)
+
+	if d.idleTimeout > 0 {
+		d.lastRead = time.Now()
+		d.idleTimer = time.AfterFunc(d.idleTimeout, d.enterIdle)
+	}
+
+	if d.trend != nil {
+		d.trend.start(d.trendPoint)
+	}
+
+	d.openAudit()
+	d.emit(Event{Kind: EventStarted})
+
+	return nil
+}
+
+// openHandle opens the backend, puts it into synchronous bitbang mode, and runs the warm-up. It is shared by Start
+// and reconnect; d.mu must be held.
+func (d *Device) openHandle() error {
+	handle, err := openBackend(d.backend, VendorID, ProductID, func(b Backend) {
+		if kd, ok := b.(KernelDriverBackend); ok {
+			kd.SetDetachKernelDriver(d.detach)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = d.configureLink(handle)
 	if err != nil {
+		handle.Close()
+
 		return err
 	}
 
-	err = handle.setBitMode(Mask, 0x04)
+	if ring, ok := handle.(RingBackend); ok && d.watermarks != [2]int{} {
+		err = ring.SetWatermarks(d.watermarks[0], d.watermarks[1])
+		if err != nil {
+			handle.Close()
+
+			return err
+		}
+	}
+
+	err = handle.SetBitMode(d.profile.Mask(), BitModeSyncBitbang)
 	if err != nil {
-		handle.close()
+		handle.Close()
 
 		return err
 	}
 
 	d.usbDev = handle
-	d.running = true
+	d.resync = false
+	d.suspended = false
+
+	err = d.warmup()
+	if err != nil {
+		handle.Close()
+
+		d.usbDev = nil
+
+		return fmt.Errorf("warm-up failed: %w", err)
+	}
+
+	d.capMu.Lock()
+
+	d.interrupt = nil
+
+	if intr, ok := handle.(Interrupter); ok {
+		d.interrupt = intr.Interrupt
+	}
+
+	d.ring, _ = handle.(RingBackend)
+
+	d.capMu.Unlock()
+
+	d.dropped = 0
 
 	return nil
 }
 
-// Read fills p with the direct bitstream from the hardware.
+// reconnect replaces the USB handle after cause, a transport failure, under WithReconnect. It closes the old handle
+// and opens a new one up to d.reconnects times, bracketed by EventReconnecting and EventReconnected. If every
+// attempt fails the device is stopped, as if closed, and cause is returned along with the last failure.
+func (d *Device) reconnect(cause error) error {
+	d.emit(Event{Kind: EventReconnecting, Err: cause})
+
+	// Close must not interrupt a handle that is already released.
+	d.capMu.Lock()
+
+	d.interrupt = nil
+	d.ring = nil
+
+	d.capMu.Unlock()
+
+	d.usbDev.Close()
+
+	d.usbDev = nil
+
+	var err error
+
+	for attempt := 0; attempt < d.reconnects; attempt++ {
+		time.Sleep(d.reconnectBackoff << attempt)
+
+		if d.closed.Load() {
+			return ErrClosed
+		}
+
+		err = d.openHandle()
+		if err == nil {
+			d.reconnected.Add(1)
+
+			d.emit(Event{Kind: EventReconnected})
+
+			return nil
+		}
+	}
+
+	d.running = false
+
+	d.emit(Event{Kind: EventStopped})
+
+	return fmt.Errorf("%w (reconnect failed: %w)", cause, err)
+}
+
+// reconnectable reports whether a batch that failed after its retries may reconnect under WithReconnect: transport
+// failures qualify, Close and expired read deadlines do not.
+func (d *Device) reconnectable(err error) bool {
+	if d.reconnects == 0 || d.closed.Load() {
+		return false
+	}
+
+	if ns := d.deadline.Load(); ns != 0 && time.Now().UnixNano() >= ns {
+		return false
+	}
+
+	return Classify(err) == ClassTransport
+}
+
+// Read fills p with the direct bitstream from the hardware. How it shares the stream with ReadWhitened is set by
+// WithReadMode. Failures are *Error values classifying the cause (see Classify).
+//
+// Read is safe for concurrent use. Concurrent calls are served round-robin one batch (at most a WithIOBatch
+// eighth, by default IOBatch/8 bytes) at a time, so a large read cannot starve small ones; each call receives distinct bytes from the stream.
 func (d *Device) Read(p []byte) (n int, err error) {
+	ctx, span := d.tracer.Start(context.Background(), "infnoise.Read",
+		trace.WithAttributes(attrRequested.Int(len(p)), attrMode.String(d.mode.String())))
+
+	start := time.Now()
+
+	defer func() {
+		endSpan(span, n, err)
+
+		err = classified(err)
+
+		d.readStats.record(len(p), time.Since(start), err)
+	}()
+
+	if d.mode == ModeRawTap {
+		return d.readTap(ctx, p)
+	}
+
+	err = d.claim(ownerRaw)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.readRaw(ctx, p)
+}
+
+// readRaw fills p with raw output, batch by batch.
+func (d *Device) readRaw(ctx context.Context, p []byte) (n int, err error) {
+	var transitions []func()
+
+	// Registered first so callbacks run after every lock has been released.
+	defer func() {
+		for _, fn := range transitions {
+			fn()
+		}
+	}()
+
+	for {
+		m, done, err := d.readBatch(ctx, p[n:], &transitions)
+
+		n += m
+
+		if err != nil || done {
+			return n, err
+		}
+	}
+}
+
+// readBatch waits for its turn and fills the start of p with one batch, reporting whether p is now full.
+func (d *Device) readBatch(ctx context.Context, p []byte, transitions *[]func()) (n int, done bool, err error) {
+	ctx, span := d.tracer.Start(ctx, "infnoise.batch")
+
+	defer func() {
+		endSpan(span, n, err)
+	}()
+
+	queued := time.Now()
+
+	d.turn.lock()
+	defer d.turn.unlock()
+
+	if d.closed.Load() {
+		return 0, false, ErrClosed
+	}
+
+	outCount := min(len(p), len(d.inBulk)/8)
+	if outCount == 0 {
+		return 0, true, nil
+	}
+
+	span.SetAttributes(attrBatch.Int(outCount))
+
+	if d.rate > 0 {
+		outCount = min(outCount, max(d.rate/paceSlices, 1))
+
+		err := d.pace(outCount)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	span.SetAttributes(attrQueued.Float64(float64(time.Since(queued).Microseconds()) / 1000))
+
+	d.batchCtx = ctx
+
+	defer func() {
+		d.batchCtx = nil
+	}()
+
+	if !d.running {
+		if d.closed.Load() {
+			return 0, false, ErrClosed
+		}
+
+		return 0, false, ErrNotStarted
+	}
+
+	if d.paused {
+		return 0, false, ErrPaused
+	}
+
+	// Deferred so the idle period counts from the end of the batch, however long it took.
+	if d.idleTimer != nil {
+		defer func() {
+			d.lastRead = time.Now()
+
+			d.idleTimer.Reset(d.idleTimeout)
+		}()
+	}
+
+	if d.idle {
+		d.idle = false
+
+		d.syncSuspend()
+	}
+
+	if d.quarantined.Load() {
+		span.SetAttributes(attrRecovery.Bool(true))
+
+		err := d.recover(transitions)
+		if err != nil {
+			if d.closed.Load() {
+				return 0, false, ErrClosed
+			}
+
+			return 0, false, err
+		}
+	}
+
+	out := p[:outCount]
+
+	err = d.fill(out)
+
+	for attempt := 0; err != nil && attempt < d.retries && d.retryable(err); attempt++ {
+		d.retried.Add(1)
+
+		time.Sleep(d.retryBackoff << attempt)
+
+		// fill purges the FIFOs before the next attempt (see resync).
+		err = d.fill(out)
+	}
+
+	if err != nil && d.reconnectable(err) {
+		err = d.reconnect(err)
+		if err == nil {
+			err = d.fill(out)
+		}
+	}
+
+	d.checkOverflow()
+
+	if err != nil {
+		if d.closed.Load() {
+			return 0, false, ErrClosed
+		}
+
+		return 0, false, err
+	}
+
+	var (
+		estimate          float64
+		analyzed, healthy bool
+	)
+
+	d.labeled(labelHealth, func() {
+		estimate, analyzed, healthy = d.health.windowEstimate(out, false)
+	})
+
+	if analyzed {
+		d.drift.add(estimate, len(out)*8)
+	}
+
+	if fn := d.setStatus(healthy); fn != nil {
+		*transitions = append(*transitions, fn)
+	}
+
+	if !healthy {
+		clear(out)
+
+		if d.quarantineWindows > 0 {
+			d.quarantined.Store(true)
+
+			d.rewhiten.Store(true)
+		}
+
+		return 0, false, d.health.Err()
+	}
+
+	return outCount, outCount == len(p), nil
+}
+
+// Pause stops issuing bitbang batches without closing the USB handle, so other USB traffic on the host can run
+// undisturbed. It waits for an in-flight batch and also stops the background reader of backends implementing
+// Suspender. Until Resume, reads fail with ErrPaused.
+func (d *Device) Pause() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running {
+		return ErrNotStarted
+	}
+
+	if d.paused {
+		return nil
+	}
+
+	d.paused = true
+
+	// Samples still in the FTDI FIFO predate the pause; the first batch after Resume purges them.
+	d.resync = true
+
+	d.syncSuspend()
+
+	return nil
+}
+
+// Resume restarts a device stopped with Pause.
+func (d *Device) Resume() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	if !d.running {
-		return 0, errors.New("device not started")
+		return ErrNotStarted
+	}
+
+	if !d.paused {
+		return nil
+	}
+
+	d.paused = false
+
+	d.syncSuspend()
+
+	return nil
+}
+
+// pace waits until the target rate admits another n bytes and reserves them. It must be called under turn.
+func (d *Device) pace(n int) error {
+	now := time.Now()
+
+	// Idle time does not accumulate into a burst.
+	if d.nextBatch.Before(now) {
+		d.nextBatch = now
+	}
+
+	for wait := time.Until(d.nextBatch); wait > 0; wait = time.Until(d.nextBatch) {
+		if d.closed.Load() {
+			return ErrClosed
+		}
+
+		if ns := d.deadline.Load(); ns != 0 && time.Now().UnixNano() >= ns {
+			return os.ErrDeadlineExceeded
+		}
+
+		// Sleep in short steps so Close and deadlines are noticed while waiting.
+		time.Sleep(min(wait, paceStep))
+	}
+
+	d.nextBatch = d.nextBatch.Add(time.Duration(n) * time.Second / time.Duration(d.rate))
+
+	return nil
+}
+
+// enterIdle stops background polling once no read has happened for idleTimeout and purges the samples left
+// in the FIFOs. The next read re-arms the device.
+func (d *Device) enterIdle() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running || d.idle {
+		return
+	}
+
+	// A read may have run while this callback waited for the lock.
+	if remaining := d.idleTimeout - time.Since(d.lastRead); remaining > 0 {
+		d.idleTimer.Reset(remaining)
+
+		return
+	}
+
+	d.idle = true
+
+	d.syncSuspend()
+
+	d.resync = d.usbDev.SetBitMode(d.profile.Mask(), BitModeSyncBitbang) != nil
+}
+
+// syncSuspend stops or restarts the backend's background reader to match the paused and idle states.
+func (d *Device) syncSuspend() {
+	s, ok := d.usbDev.(Suspender)
+	if !ok {
+		return
+	}
+
+	want := d.paused || d.idle
+	if want == d.suspended {
+		return
+	}
+
+	d.suspended = want
+
+	if want {
+		s.Suspend()
+	} else {
+		s.Resume()
+	}
+}
+
+// RingStats returns the backend's ring buffer level and counters; ok is false if the backend does not buffer
+// through a ring (see RingBackend) or the device is not started.
+func (d *Device) RingStats() (stats RingStats, ok bool) {
+	d.capMu.Lock()
+	ring := d.ring
+	d.capMu.Unlock()
+
+	if ring == nil {
+		return RingStats{}, false
+	}
+
+	return ring.RingStats(), true
+}
+
+// SetReadDeadline bounds how long Read (and the helpers built on it) may block; a zero t removes the deadline.
+// Once the deadline passes, reads fail with an error wrapping os.ErrDeadlineExceeded. Backends implementing
+// TimeoutBackend also have each USB transfer limited to the time remaining.
+func (d *Device) SetReadDeadline(t time.Time) error {
+	var ns int64
+
+	if !t.IsZero() {
+		ns = t.UnixNano()
+	}
+
+	d.deadline.Store(ns)
+
+	return nil
+}
+
+// fill runs one bitbang batch producing len(out) extracted bytes (at most len(d.inBulk)/8).
+func (d *Device) fill(out []byte) error {
+	err := d.applyDeadline()
+	if err != nil {
+		return err
+	}
+
+	// Samples left over from an interrupted batch would shift the COMP1/COMP2 interleaving; purge them.
+	if d.resync {
+		span := d.usbSpan("infnoise.usb.resync", 0)
+
+		err = d.usbDev.SetBitMode(d.profile.Mask(), BitModeSyncBitbang)
+
+		finishSpan(span, err)
+
+		if err != nil {
+			return err
+		}
+
+		d.resync = false
 	}
 
-	for n < len(p) {
-		needOut := len(p) - n
+	needIn := len(out) * 8
+
+	d.labeled(labelUSB, func() {
+		span := d.usbSpan("infnoise.usb.write", needIn)
+
+		err = d.usbDev.Write(d.outBulk[:needIn])
+
+		finishSpan(span, err)
 
-		needIn := min(needOut*8, len(d.inBulk))
+		if err == nil {
+			span = d.usbSpan("infnoise.usb.read", needIn)
 
-		needIn &= ^7
-		if needIn == 0 {
-			return n, nil
+			err = d.usbDev.Read(d.inBulk[:needIn])
+
+			finishSpan(span, err)
 		}
+	})
+
+	if err != nil {
+		d.resync = true
 
-		err := d.usbDev.write(d.outBulk[:needIn])
+		ns := d.deadline.Load()
+		expired := ns != 0 && time.Now().UnixNano() >= ns
+
+		if expired && !errors.Is(err, os.ErrDeadlineExceeded) {
+			err = fmt.Errorf("%w: %w", os.ErrDeadlineExceeded, err)
+		}
+
+		// Expired read deadlines and Close are requested by the caller, not transport failures.
+		if !d.closed.Load() && !expired {
+			d.emit(Event{Kind: EventUSBError, Err: err})
+		}
+
+		return err
+	}
+
+	evenPin, oddPin := d.profile.comparators()
+
+	d.labeled(labelExtract, func() {
+		decode(d.inBulk[:needIn], out, evenPin, oddPin)
+	})
+
+	return nil
+}
+
+// retryable reports whether a failed batch may be retried under WithRetry: transfer errors and backend timeouts
+// qualify, Close and expired read deadlines do not.
+func (d *Device) retryable(err error) bool {
+	if d.closed.Load() {
+		return false
+	}
+
+	if ns := d.deadline.Load(); ns != 0 && time.Now().UnixNano() >= ns {
+		return false
+	}
+
+	return errors.Is(err, ErrIO) || errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// warmup discards output while the analog loop settles after bitbang mode is enabled. The samples bypass the
+// health check, whose estimate they would otherwise skew.
+func (d *Device) warmup() error {
+	if d.warmupBytes <= 0 && d.warmupTime <= 0 {
+		return nil
+	}
+
+	buf := make([]byte, len(d.inBulk)/8)
+
+	defer clear(buf)
+
+	start := time.Now()
+
+	for discarded := 0; discarded < d.warmupBytes || time.Since(start) < d.warmupTime; {
+		n := len(buf)
+		if discarded < d.warmupBytes {
+			n = min(n, d.warmupBytes-discarded)
+		}
+
+		err := d.fill(buf[:n])
 		if err != nil {
-			return n, err
+			return err
+		}
+
+		discarded += n
+	}
+
+	return nil
+}
+
+// applyDeadline fails once the read deadline has passed and otherwise limits the backend to the time remaining.
+func (d *Device) applyDeadline() error {
+	var remaining time.Duration
+
+	if ns := d.deadline.Load(); ns != 0 {
+		remaining = time.Until(time.Unix(0, ns))
+
+		if remaining <= 0 {
+			return os.ErrDeadlineExceeded
 		}
+	}
+
+	tb, ok := d.usbDev.(TimeoutBackend)
+	if !ok || (remaining == 0 && !d.timeoutSet) {
+		return nil
+	}
+
+	d.timeoutSet = remaining > 0
+
+	if remaining == 0 {
+		return tb.SetTimeout(d.linkTimeout)
+	}
+
+	return tb.SetTimeout(remaining)
+}
 
-		err = d.usbDev.read(d.inBulk[:needIn])
+// configureLink applies the transfer timeout and latency timer, defaulting to the high-latency settings for boards
+// attached through usbip.
+func (d *Device) configureLink(handle Backend) error {
+	timeout, latency := d.timeout, d.latency
+
+	if timeout == 0 && latency == 0 && usbipAttached(VendorID, ProductID) {
+		timeout, latency = HighLatencyTimeout, highLatencyTimer
+	}
+
+	d.linkTimeout = timeout
+	d.timeoutSet = false
+
+	if tb, ok := handle.(TimeoutBackend); ok && timeout > 0 {
+		err := tb.SetTimeout(timeout)
 		if err != nil {
-			return n, err
+			return err
 		}
+	}
 
-		outCount := min(needIn/8, needOut)
+	if lb, ok := handle.(LatencyBackend); ok && latency > 0 {
+		err := lb.SetLatencyTimer(latency)
+		if err != nil {
+			return err
+		}
+	}
 
-		in := d.inBulk[:needIn]
-		out := p[n : n+outCount]
+	return nil
+}
 
-		for i := range outCount {
-			base := i * 8
+// recover discards output until quarantineWindows consecutive health windows pass on their own. The estimate is
+// restarted from scratch first so statistics from before the failure neither mask nor prolong it, and on release the
+// whitener starts over too.
+func (d *Device) recover(transitions *[]func()) error {
+	size := int(max(d.health.window/8, 1))
+	buf := make([]byte, size)
 
-			var b uint8
+	defer clear(buf)
 
-			for j := range 8 {
-				val := in[base+j]
+	d.health.reset()
 
-				evenBit := (val >> COMP2) & 1
-				oddBit := (val >> COMP1) & 1
+	for good := 0; good < d.quarantineWindows; good++ {
+		for off := 0; off < size; {
+			chunk := min(size-off, len(d.inBulk)/8)
 
-				if (j & 1) == 1 {
-					b = (b << 1) | oddBit
-				} else {
-					b = (b << 1) | evenBit
-				}
+			err := d.fill(buf[off : off+chunk])
+			if err != nil {
+				return err
 			}
 
-			out[i] = b
+			off += chunk
 		}
 
-		if !d.health.Add(p[n : n+outCount]) {
-			return n, fmt.Errorf("hardware health check failed: entropy %0.4f outside tolerance", d.health.EstimatedEntropy())
+		estimate, _, _ := d.health.windowEstimate(buf, true)
+
+		if !d.health.withinTolerance(estimate) {
+			return fmt.Errorf("%w: window entropy %0.4f outside tolerance (%d/%d healthy windows)", ErrQuarantined, estimate, good, d.quarantineWindows)
 		}
+	}
 
-		n += outCount
+	d.quarantined.Store(false)
+
+	d.rewhiten.Store(true)
+
+	if fn := d.setStatus(true); fn != nil {
+		*transitions = append(*transitions, fn)
 	}
 
-	return n, nil
+	return nil
 }
 
 // Close stops the device and releases the underlying USB handle.
+//
+// Reads blocked in the backend are woken where the backend supports it (see Interrupter) and, like reads issued
+// afterwards, fail with ErrClosed.
 func (d *Device) Close() error {
+	d.closed.Store(true)
+
+	d.capMu.Lock()
+
+	if d.interrupt != nil {
+		d.interrupt()
+	}
+
+	d.ring = nil
+
+	d.capMu.Unlock()
+
+	d.spare.reset()
+
+	d.clearPool()
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if d.running {
+		d.emit(Event{Kind: EventStopped})
+	}
+
 	d.running = false
 
+	clear(d.inBulk)
+
+	if d.idleTimer != nil {
+		d.idleTimer.Stop()
+
+		d.idleTimer = nil
+	}
+
+	if d.trend != nil {
+		d.trend.stop()
+	}
+
 	if d.usbDev != nil {
-		err := d.usbDev.close()
+		err := d.usbDev.Close()
 
 		d.usbDev = nil
 
@@ -186,24 +1119,36 @@ func (d *Device) Close() error {
 	return nil
 }
 
-func makeAddress(addr uint8) uint8 {
-	var value uint8
-
-	if addr&1 != 0 {
-		value |= 1 << ADDR0
+// setStatus records the health outcome and returns the callback invocation for a transition, if any.
+func (d *Device) setStatus(healthy bool) func() {
+	status := HealthOK
+	if !healthy {
+		status = HealthFailed
 	}
 
-	if addr&2 != 0 {
-		value |= 1 << ADDR1
+	old := d.status
+	if old == status {
+		return nil
 	}
 
-	if addr&4 != 0 {
-		value |= 1 << ADDR2
+	d.status = status
+
+	d.failing.Store(status == HealthFailed)
+
+	estimate := d.health.EstimatedEntropy()
+
+	kind := EventHealthRecovered
+	if status == HealthFailed {
+		kind = EventHealthDegraded
 	}
 
-	if addr&8 != 0 {
-		value |= 1 << ADDR3
+	d.emit(Event{Kind: kind, Estimate: estimate})
+
+	if d.onHealth == nil {
+		return nil
 	}
 
-	return value
+	return func() {
+		d.onHealth(old, status, estimate)
+	}
 }
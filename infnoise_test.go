@@ -1,8 +1,15 @@
 package infnoise
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"math/bits"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 const (
@@ -29,6 +36,689 @@ func openDevice(t testing.TB) *Device {
 	return dv
 }
 
+// simulators numbers the backends registered by registerSimulator, so that every device of a test opens its own.
+var simulators atomic.Uint64
+
+// registerSimulator registers b, a Simulator or a wrapper of one, as a backend named after the test and returns
+// the name.
+func registerSimulator(t testing.TB, b Backend) BackendName {
+	name := BackendName(fmt.Sprintf("test-simulator-%s-%d", t.Name(), simulators.Add(1)))
+
+	RegisterBackend(name, func() Backend {
+		return b
+	})
+
+	return name
+}
+
+// openSimulator starts a device with opts on a new simulator with the given seed and gain, and closes it when the
+// test ends. The simulator is returned for tests that inject faults or change the gain.
+func openSimulator(t testing.TB, seed uint64, gain float64, opts ...option) (*Device, *Simulator) {
+	t.Helper()
+
+	sim := NewSimulator(seed)
+	sim.SetGain(gain)
+
+	dv := New(append([]option{WithBackend(registerSimulator(t, sim))}, opts...)...)
+
+	err := dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		dv.Close()
+	})
+
+	return dv, sim
+}
+
+func TestSimulatorHealth(t *testing.T) {
+	buf := make([]byte, testBytes)
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	_, err := dv.Read(buf)
+	if err != nil {
+		t.Fatalf("healthy simulator: %v", err)
+	}
+
+	dv, _ = openSimulator(t, 1, 1.5)
+
+	_, err = dv.Read(buf)
+	if err == nil {
+		t.Fatal("degraded simulator passed the health check")
+	}
+}
+
+func TestHealthCallback(t *testing.T) {
+	type transition struct {
+		old, new HealthStatus
+	}
+
+	var got []transition
+
+	dv, _ := openSimulator(t, 1, 1.5, WithHealthCallback(func(old, new HealthStatus, estimate float64) {
+		got = append(got, transition{old, new})
+	}))
+
+	buf := make([]byte, testBytes)
+
+	for range 2 {
+		dv.Read(buf)
+	}
+
+	if len(got) != 1 || got[0] != (transition{HealthOK, HealthFailed}) {
+		t.Fatalf("got transitions %v, want a single ok -> failed", got)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	dv, _ := openSimulator(t, 1, 1.5)
+
+	dv.Read(make([]byte, testBytes))
+	dv.Close()
+
+	var got []EventKind
+
+	for len(dv.Events()) > 0 {
+		got = append(got, (<-dv.Events()).Kind)
+	}
+
+	want := []EventKind{EventStarted, EventHealthDegraded, EventStopped}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	var recovered bool
+
+	dv, sim := openSimulator(t, 1, 1.5, WithQuarantine(3), WithHealthCallback(func(old, new HealthStatus, estimate float64) {
+		recovered = new == HealthOK
+	}))
+
+	buf := make([]byte, testBytes)
+
+	_, err := dv.Read(buf)
+	if err == nil {
+		t.Fatal("degraded simulator passed the health check")
+	}
+
+	_, err = dv.Read(buf)
+	if err == nil || !dv.Quarantined() {
+		t.Fatalf("read from degraded quarantined device: got %v, want quarantine error", err)
+	}
+
+	if status, _ := dv.Health(); status != HealthFailed {
+		t.Fatalf("quarantined device reports %v", status)
+	}
+
+	sim.SetGain(DefaultSimulatorGain)
+
+	_, err = dv.Read(buf)
+	if err != nil {
+		t.Fatalf("repaired device still quarantined: %v", err)
+	}
+
+	if status, _ := dv.Health(); status != HealthOK {
+		t.Fatalf("recovered device reports %v", status)
+	}
+
+	if !recovered {
+		t.Fatal("health callback not notified of recovery")
+	}
+}
+
+func TestBytes(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	seen := make(map[uint64]struct{})
+
+	for range 200 {
+		v, err := dv.Uint64()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen[v] = struct{}{}
+	}
+
+	if len(seen) != 200 {
+		t.Fatalf("got %d distinct values out of 200", len(seen))
+	}
+
+	for _, n := range []int{0, 1, 3, BufLen, 3*BufLen + 5} {
+		b, err := dv.Bytes(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(b) != n {
+			t.Fatalf("Bytes(%d) returned %d bytes", n, len(b))
+		}
+	}
+}
+
+func TestBytesWhitened(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	// Under ModeExclusive, the helpers must not claim the raw stream from whitened readers.
+	_, err := dv.Whitened().Read(make([]byte, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dv.Uint32()
+	if err != nil {
+		t.Fatalf("Uint32 after a whitened read: %v", err)
+	}
+
+	_, err = dv.Bytes(BufLen + 1)
+	if err != nil {
+		t.Fatalf("Bytes after a whitened read: %v", err)
+	}
+
+	_, err = dv.ReadWhitened(make([]byte, 100))
+	if err != nil {
+		t.Fatalf("ReadWhitened after Uint32: %v", err)
+	}
+
+	if _, err := dv.Read(make([]byte, 100)); !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("Read after the helpers = %v, want ErrModeConflict", err)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	var chunks [][]byte
+
+	for chunk, err := range dv.Chunks(256) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(chunk) != 256 {
+			t.Fatalf("chunk of %d bytes, want 256", len(chunk))
+		}
+
+		chunks = append(chunks, bytes.Clone(chunk))
+
+		if len(chunks) == 3 {
+			break
+		}
+	}
+
+	if bytes.Equal(chunks[0], chunks[1]) || bytes.Equal(chunks[1], chunks[2]) {
+		t.Fatal("Chunks repeated a chunk")
+	}
+
+	dv.Close()
+
+	for chunk, err := range dv.Chunks(256) {
+		if chunk != nil || !errors.Is(err, ErrClosed) {
+			t.Fatalf("Chunks on a closed device yielded %d bytes, %v", len(chunk), err)
+		}
+	}
+}
+
+func TestReadDeadline(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	buf := make([]byte, 64)
+
+	dv.SetReadDeadline(time.Now().Add(-time.Second))
+
+	_, err := dv.Read(buf)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	dv.SetReadDeadline(time.Time{})
+
+	_, err = dv.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPause(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	buf := make([]byte, 64)
+
+	err := dv.Pause()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dv.Read(buf)
+	if !errors.Is(err, ErrPaused) {
+		t.Fatalf("read while paused: got %v, want ErrPaused", err)
+	}
+
+	err = dv.Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dv.Read(buf)
+	if err != nil {
+		t.Fatalf("read after resume: %v", err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithIdleTimeout(20*time.Millisecond))
+
+	buf := make([]byte, 64)
+
+	_, err := dv.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	dv.mu.Lock()
+	idle := dv.idle
+	dv.mu.Unlock()
+
+	if !idle {
+		t.Fatal("device not idle after the timeout")
+	}
+
+	_, err = dv.Read(buf)
+	if err != nil {
+		t.Fatalf("read after idle: %v", err)
+	}
+
+	dv.mu.Lock()
+	idle = dv.idle
+	dv.mu.Unlock()
+
+	if idle {
+		t.Fatal("read did not re-arm the device")
+	}
+}
+
+func TestBatchOptions(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithIOBatch(BufLen), WithChunkSize(100))
+
+	_, err := dv.Read(make([]byte, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dv, _ = openSimulator(t, 2, DefaultSimulatorGain, WithChunkSize(100))
+
+	_, err = dv.ReadWhitened(make([]byte, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opt := range []option{WithIOBatch(0), WithIOBatch(100), WithIOBatch(MaxIOBatch + 8), WithChunkSize(0)} {
+		dv := New(WithBackend(BackendSimulator), opt)
+
+		err := dv.Start()
+		if err == nil {
+			dv.Close()
+
+			t.Fatal("Start accepted an invalid batch option")
+		}
+	}
+}
+
+// linkSimulator records the link settings Start applies.
+type linkSimulator struct {
+	*Simulator
+
+	timeouts []time.Duration
+	latency  byte
+}
+
+func (s *linkSimulator) SetTimeout(d time.Duration) error {
+	s.timeouts = append(s.timeouts, d)
+
+	return nil
+}
+
+func (s *linkSimulator) SetLatencyTimer(ms byte) error {
+	s.latency = ms
+
+	return nil
+}
+
+func TestHighLatencyLink(t *testing.T) {
+	sim := &linkSimulator{Simulator: NewSimulator(1)}
+
+	dv := New(WithBackend(registerSimulator(t, sim)), WithHighLatencyLink())
+
+	err := dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dv.Close()
+
+	if sim.latency != highLatencyTimer || len(sim.timeouts) == 0 || sim.timeouts[0] != HighLatencyTimeout {
+		t.Fatalf("link settings: latency %d, timeouts %v", sim.latency, sim.timeouts)
+	}
+
+	// A deadline-bounded read shortens the timeout and then restores the link timeout, not the backend default.
+	dv.SetReadDeadline(time.Now().Add(time.Second))
+
+	_, err = dv.Read(make([]byte, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dv.SetReadDeadline(time.Time{})
+
+	_, err = dv.Read(make([]byte, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last := sim.timeouts[len(sim.timeouts)-1]; last != HighLatencyTimeout {
+		t.Fatalf("timeout after deadline cleared: %v", last)
+	}
+
+	dv = New(WithBackend(BackendSimulator), WithLatencyTimer(256))
+
+	err = dv.Start()
+	if err == nil {
+		dv.Close()
+
+		t.Fatal("Start accepted an invalid latency timer")
+	}
+}
+
+func TestTargetRate(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithTargetRate(20000))
+
+	start := time.Now()
+
+	// Three batches of 2000 bytes: the first is admitted at once, the others 100 ms apart.
+	_, err := dv.Read(make([]byte, 6000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Fatalf("6000 bytes at 20000 B/s took only %v", elapsed)
+	}
+}
+
+func TestConcurrentReadFairness(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	large := make(chan error, 1)
+	small := make(chan error, 1)
+
+	go func() {
+		_, err := dv.Read(make([]byte, 64*IOBatch/8))
+
+		large <- err
+	}()
+
+	go func() {
+		buf := make([]byte, 64)
+
+		for range 8 {
+			_, err := dv.Read(buf)
+			if err != nil {
+				small <- err
+
+				return
+			}
+		}
+
+		small <- nil
+	}()
+
+	select {
+	case err := <-small:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case err := <-large:
+		t.Fatalf("large read finished before small reads were served (err %v)", err)
+	}
+
+	if err := <-large; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseUnblocksRead(t *testing.T) {
+	dv, sim := openSimulator(t, 1, DefaultSimulatorGain)
+
+	sim.SetStalled(true)
+
+	done := make(chan error, 2)
+
+	for range 2 {
+		go func() {
+			_, err := dv.Read(make([]byte, 64))
+
+			done <- err
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	dv.Close()
+
+	for range 2 {
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrClosed) {
+				t.Fatalf("got %v, want ErrClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Read still blocked after Close")
+		}
+	}
+
+	_, err := dv.Read(make([]byte, 64))
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("read after Close: got %v, want ErrClosed", err)
+	}
+}
+
+// TestConcurrentStress exercises every entry point at once; it is mostly useful under -race.
+func TestConcurrentStress(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithReadMode(ModeRawTap), WithIdleTimeout(time.Millisecond))
+
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	run := func(fn func()) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					fn()
+				}
+			}
+		}()
+	}
+
+	handle := dv.Whitened()
+
+	run(func() { dv.Read(make([]byte, 100)) })
+	run(func() { dv.ReadWhitened(make([]byte, 3000)) })
+	run(func() { handle.Read(make([]byte, 7)) })
+	run(func() { dv.Uint64() })
+	run(func() { dv.RingStats(); dv.Drift(); dv.SetTemperature(25) })
+	run(func() { dv.Pause(); dv.Resume() })
+	run(func() { dv.SetReadDeadline(time.Now().Add(time.Millisecond)); dv.SetReadDeadline(time.Time{}) })
+	run(func() {
+		for len(dv.Events()) > 0 {
+			<-dv.Events()
+		}
+	})
+
+	// Reconnect repeatedly while the readers run.
+	run(func() {
+		time.Sleep(10 * time.Millisecond)
+
+		dv.Close()
+
+		err := dv.Start()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	time.Sleep(300 * time.Millisecond)
+
+	close(stop)
+
+	wg.Wait()
+
+	_, err := dv.ReadWhitened(make([]byte, 64))
+	if err != nil {
+		t.Fatalf("read after stress: %v", err)
+	}
+}
+
+func TestInjectedFaults(t *testing.T) {
+	dv, sim := openSimulator(t, 1, DefaultSimulatorGain)
+
+	buf := make([]byte, 1000)
+
+	sim.InjectFault(8*1500, FaultTimeout)
+	sim.InjectFault(8*2500, FaultShortRead)
+	sim.InjectFault(8*5000, FaultDisconnect)
+
+	usbErrors := func() (n int) {
+		for len(dv.Events()) > 0 {
+			if (<-dv.Events()).Kind == EventUSBError {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	usbErrors()
+
+	// Each transient fault fails one read, which resyncs the stream for the next.
+	steps := []struct {
+		fails bool
+		is    error
+	}{
+		{false, nil},
+		{true, os.ErrDeadlineExceeded},
+		{false, nil},
+		{true, nil},
+		{false, nil},
+	}
+
+	for i, step := range steps {
+		_, err := dv.Read(buf)
+
+		if (err != nil) != step.fails || (step.is != nil && !errors.Is(err, step.is)) {
+			t.Fatalf("read %d: got %v", i, err)
+		}
+	}
+
+	if n := usbErrors(); n != 2 {
+		t.Fatalf("got %d USB error events, want 2", n)
+	}
+
+	_, err := dv.Read(make([]byte, 4000))
+	if !errors.Is(err, ErrSimulatorDisconnected) {
+		t.Fatalf("got %v, want ErrSimulatorDisconnected", err)
+	}
+
+	_, err = dv.Read(buf)
+	if !errors.Is(err, ErrSimulatorDisconnected) {
+		t.Fatalf("read after disconnect: got %v, want ErrSimulatorDisconnected", err)
+	}
+
+	// Reconnecting reopens the simulated device.
+	dv.Close()
+
+	err = dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim.InjectFault(0, FaultStall)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+
+		sim.SetStalled(false)
+	}()
+
+	_, err = dv.Read(buf)
+	if err != nil {
+		t.Fatalf("read after stall: %v", err)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	cold, _ := openSimulator(t, 7, DefaultSimulatorGain)
+	warm, _ := openSimulator(t, 7, DefaultSimulatorGain, WithWarmup(1000))
+
+	want := make([]byte, 1064)
+
+	_, err := cold.Read(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]byte, 64)
+
+	_, err = warm.Read(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want[1000:]) {
+		t.Fatal("warm-up did not discard exactly the first 1000 bytes")
+	}
+
+	if est := warm.health.EstimatedEntropy(); warm.health.totalBits != 64*8 {
+		t.Fatalf("health check saw %d bits (estimate %.3f), want only the 512 returned", warm.health.totalBits, est)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	err := dv.SelfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dv.Read(make([]byte, testBytes))
+	if err != nil {
+		t.Fatalf("read after self-test: %v", err)
+	}
+
+	// A gain below 1 collapses the multiplier to zero, so the comparators stop toggling.
+	dv, _ = openSimulator(t, 2, 0.5)
+
+	err = dv.SelfTest()
+	if !errors.Is(err, ErrNoiseSource) {
+		t.Fatalf("got %v, want ErrNoiseSource", err)
+	}
+}
+
 func TestRead(t *testing.T) {
 	dv := openDevice(t)
 
@@ -122,6 +812,48 @@ func BenchmarkReadThroughput(b *testing.B) {
 		}
 	}
 
+	reportThroughput(b, testChunk)
+}
+
+// BenchmarkSimulatorRead runs the raw pipeline (bitbang batches, extraction, health check) over the simulator,
+// so regressions show up without hardware.
+func BenchmarkSimulatorRead(b *testing.B) {
+	benchmarkSimulator(b, func(dv *Device, p []byte) (int, error) {
+		return dv.Read(p)
+	})
+}
+
+// BenchmarkSimulatorReadWhitened adds the whitening layer to BenchmarkSimulatorRead.
+func BenchmarkSimulatorReadWhitened(b *testing.B) {
+	benchmarkSimulator(b, func(dv *Device, p []byte) (int, error) {
+		return dv.ReadWhitened(p)
+	})
+}
+
+func benchmarkSimulator(b *testing.B, read func(*Device, []byte) (int, error)) {
+	dv, _ := openSimulator(b, 1, DefaultSimulatorGain)
+
+	buf := make([]byte, testBytes)
+
+	b.ReportAllocs()
+	b.SetBytes(testBytes)
+
+	for b.Loop() {
+		n, err := read(dv, buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if n != len(buf) {
+			b.Fatalf("short read: %d < %d", n, len(buf))
+		}
+	}
+
+	reportThroughput(b, testBytes)
+}
+
+// reportThroughput reports KB/s and Kbps for a benchmark that read bytesPerOp per iteration.
+func reportThroughput(b *testing.B, bytesPerOp int64) {
 	b.StopTimer()
 
 	sec := b.Elapsed().Seconds()
@@ -129,7 +861,7 @@ func BenchmarkReadThroughput(b *testing.B) {
 		return
 	}
 
-	totalBytes := float64(int64(b.N) * testChunk)
+	totalBytes := float64(int64(b.N) * bytesPerOp)
 
 	kBps := (totalBytes / 1000.0) / sec
 	kbps := (totalBytes * 8.0 / 1000.0) / sec
@@ -137,3 +869,146 @@ func BenchmarkReadThroughput(b *testing.B) {
 	b.ReportMetric(kBps, "KB/s")
 	b.ReportMetric(kbps, "Kbps")
 }
+
+func TestReadStats(t *testing.T) {
+	var s readStats
+
+	for _, size := range []int{0, 1, 2, 3, 4, 5, 4096} {
+		s.record(size, 3*time.Microsecond, nil)
+	}
+
+	s.record(64, 100*time.Millisecond, ErrIO)
+
+	want := []SizeBucket{{0, 1}, {1, 1}, {2, 1}, {4, 2}, {8, 1}, {64, 1}, {4096, 1}}
+
+	if got := s.sizeStats(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("sizes %v, want %v", got, want)
+	}
+
+	l := s.latencyStats()
+
+	if l.P50 != 4*time.Microsecond || l.P90 != 100*time.Millisecond || l.Max != 100*time.Millisecond {
+		t.Fatalf("latency %+v", l)
+	}
+
+	if s.failed[ClassTransport].Load() != 1 {
+		t.Fatal("transport error not counted")
+	}
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	buf := make([]byte, 100)
+
+	for range 3 {
+		_, err := dv.ReadWhitened(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := dv.Stats()
+
+	if st.Reads != 3 || len(st.ReadSizes) != 1 || st.ReadSizes[0] != (SizeBucket{128, 3}) || st.ReadLatency.Max <= 0 {
+		t.Fatalf("stats %+v", st)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	buf := make([]byte, 1000)
+
+	dv, sim := openSimulator(t, 1, DefaultSimulatorGain, WithRetry(2, time.Millisecond))
+
+	sim.InjectFault(0, FaultTimeout)
+	sim.InjectFault(8*500, FaultShortRead)
+
+	_, err := dv.Read(buf)
+	if err != nil {
+		t.Fatalf("read with transient faults: %v", err)
+	}
+
+	if s := dv.Stats(); s.Retries != 2 {
+		t.Fatalf("got %d retries, want 2", s.Retries)
+	}
+
+	// Three faults in a row exhaust two attempts.
+	for range 3 {
+		sim.InjectFault(0, FaultTimeout)
+	}
+
+	_, err = dv.Read(buf)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v after exhausting retries", err)
+	}
+
+	dv, sim = openSimulator(t, 1, DefaultSimulatorGain, WithRetry(MaxRetries, 0))
+
+	sim.InjectFault(0, FaultDisconnect)
+
+	_, err = dv.Read(buf)
+	if !errors.Is(err, ErrSimulatorDisconnected) || dv.Stats().Retries != 0 {
+		t.Fatalf("disconnect: got %v after %d retries", err, dv.Stats().Retries)
+	}
+
+	if err := New(WithRetry(MaxRetries+1, 0)).Start(); err == nil {
+		t.Fatal("accepted too many retries")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	sim := NewSimulator(1)
+
+	dv := New(WithBackend(registerSimulator(t, &boundSimulator{Simulator: sim})), WithReconnect(2, time.Millisecond))
+
+	err := dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dv.Close()
+
+	buf := make([]byte, 1000)
+
+	sim.InjectFault(0, FaultDisconnect)
+
+	_, err = dv.Read(buf)
+	if err != nil {
+		t.Fatalf("read across a disconnect: %v", err)
+	}
+
+	if s := dv.Stats(); s.Reconnects != 1 {
+		t.Fatalf("got %d reconnects, want 1", s.Reconnects)
+	}
+
+	// From now on the re-opens find the interface held by a kernel driver.
+	dv.detach = false
+
+	sim.InjectFault(0, FaultDisconnect)
+
+	_, err = dv.Read(buf)
+	if !errors.Is(err, ErrSimulatorDisconnected) || !errors.Is(err, ErrKernelDriver) {
+		t.Fatalf("got %v after failed reconnects", err)
+	}
+
+	_, err = dv.Read(buf)
+	if !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("read after failed reconnects: got %v, want ErrNotStarted", err)
+	}
+
+	var got []EventKind
+
+	for len(dv.Events()) > 0 {
+		if ev := <-dv.Events(); ev.Kind != EventUSBError {
+			got = append(got, ev.Kind)
+		}
+	}
+
+	want := []EventKind{EventStarted, EventReconnecting, EventReconnected, EventReconnecting, EventStopped}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+
+	if err := New(WithReconnect(MaxRetries+1, 0)).Start(); err == nil {
+		t.Fatal("accepted too many reconnects")
+	}
+}
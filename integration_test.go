@@ -0,0 +1,46 @@
+package infnoise
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The whole pipeline (pattern, bitbang round trip, extraction, health check, whitening) is deterministic for a
+// given simulator seed, so its output can be pinned. A change in any of these values changes the bytes users
+// get from a board and must be deliberate.
+func TestPipelineKnownAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		whitened bool
+		opts     []option
+		want     string
+	}{
+		{"raw", false, nil, "59a31b34a6256ea52ecc948ea9494b476449d58b5ca98b2898b49946263ae634"},
+		{"raw after warm-up", false, []option{WithWarmup(1000)}, "cad6b2d922cd92eb135997676ca699c6dcd591663b53136b4a5ba8ccd52d38da"},
+		{"whitened", true, nil, "900ffce25b467e67aebc85ea0a83ecde74ab4e7abe0cef4dba626326553ec15f"},
+		{"whitened small chunks", true, []option{WithChunkSize(16)}, "f882e0fe5eefbffea17a3e9f3592d3e67b885dfa85748dc577a0aa425065d41d"},
+		{"whitened small chunks without ratchet", true, []option{WithChunkSize(16), WithRatchet(false)}, "f882e0fe5eefbffea17a3e9f3592d3e698948ce4ce01a61d272d6fbf6b376f03"},
+	}
+
+	for _, tt := range tests {
+		dv, _ := openSimulator(t, 42, DefaultSimulatorGain, tt.opts...)
+
+		got := make([]byte, 32)
+
+		var err error
+
+		if tt.whitened {
+			_, err = dv.ReadWhitened(got)
+		} else {
+			_, err = dv.Read(got)
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("%s: got %x, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package ratelimit implements the token buckets with which the named pipe and HTTP servers bound how fast they
+// hand out entropy, overall and per client.
+package ratelimit
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pruneAt is the number of client buckets above which Clients drops those that have refilled.
+const pruneAt = 1024
+
+// Bucket limits throughput to rate bytes per second with bursts of up to one second's worth. Requests larger than
+// the available tokens are admitted and paid back by delaying the caller. A nil Bucket admits everything.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Bucket for rate bytes per second, or nil if rate is not positive.
+func New(rate int) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &Bucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+	}
+}
+
+// Reserve takes n tokens at now and returns how long the caller has to wait before using them.
+func (b *Bucket) Reserve(n int, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+
+	b.tokens -= float64(n)
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// full reports whether the bucket has refilled by now, so a fresh one would behave the same.
+func (b *Bucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+
+	return b.tokens >= b.rate
+}
+
+// refill credits the tokens accrued since the last call. mu must be held.
+func (b *Bucket) refill(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.rate)
+	}
+
+	b.last = now
+}
+
+// Reserve takes n tokens from each of buckets at now and returns the longest wait.
+func Reserve(n int, now time.Time, buckets ...*Bucket) time.Duration {
+	var wait time.Duration
+
+	for _, b := range buckets {
+		wait = max(wait, b.Reserve(n, now))
+	}
+
+	return wait
+}
+
+// Clients keeps a Bucket per client key. A nil Clients hands out nil buckets.
+type Clients struct {
+	rate int
+
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewClients returns Clients limiting each key to rate bytes per second, or nil if rate is not positive.
+func NewClients(rate int) *Clients {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &Clients{
+		rate:    rate,
+		buckets: make(map[string]*Bucket),
+	}
+}
+
+// Get returns the bucket of key, creating it if needed. Buckets that have refilled are forgotten once there are
+// many, so clients that come and go do not grow the set without bound.
+func (c *Clients) Get(key string, now time.Time) *Bucket {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if b, ok := c.buckets[key]; ok {
+		return b
+	}
+
+	if len(c.buckets) >= pruneAt {
+		for k, b := range c.buckets {
+			if b.full(now) {
+				delete(c.buckets, k)
+			}
+		}
+	}
+
+	b := New(c.rate)
+
+	c.buckets[key] = b
+
+	return b
+}
+
+// Reader charges each of Buckets for the bytes read from R, waiting before it returns them. Charging after the
+// read keeps short reads, which R is free to return, from paying for the whole buffer. Throttled, if set, counts
+// the reads that had to wait.
+type Reader struct {
+	R         io.Reader
+	Buckets   []*Bucket
+	Throttled *atomic.Uint64
+}
+
+func (t Reader) Read(p []byte) (int, error) {
+	n, err := t.R.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	if d := Reserve(n, time.Now(), t.Buckets...); d > 0 {
+		time.Sleep(d)
+
+		if t.Throttled != nil {
+			t.Throttled.Add(1)
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := New(1000)
+	now := time.Unix(0, 0)
+
+	if d := b.Reserve(1000, now); d != 0 {
+		t.Fatalf("initial burst delayed by %v", d)
+	}
+
+	if d := b.Reserve(500, now); d != 500*time.Millisecond {
+		t.Fatalf("got delay %v, want 500ms for an empty bucket", d)
+	}
+
+	// 1.5s later the debt is repaid and a full second's worth has accumulated again.
+	if d := b.Reserve(1000, now.Add(1500*time.Millisecond)); d != 0 {
+		t.Fatalf("refilled bucket delayed by %v", d)
+	}
+
+	if New(0) != nil {
+		t.Fatal("zero rate should disable limiting")
+	}
+
+	if d := Reserve(1<<20, now, nil, New(1000)); d != time.Duration(1<<20-1000)*time.Millisecond {
+		t.Fatalf("got delay %v from a nil and a fresh bucket", d)
+	}
+}
+
+func TestReaderShortReads(t *testing.T) {
+	b := New(1000)
+
+	r := Reader{
+		R:       iotest.OneByteReader(zeros{}),
+		Buckets: []*Bucket{b, nil},
+	}
+
+	for range 10 {
+		n, err := r.Read(make([]byte, 4096))
+		if n != 1 || err != nil {
+			t.Fatalf("read %d bytes, %v", n, err)
+		}
+	}
+
+	// Only the ten bytes returned are charged, not ten buffers' worth; a refill of a few tokens may have crept in.
+	if b.tokens < 990 || b.tokens > 1000 {
+		t.Fatalf("bucket holds %v tokens after 10 one-byte reads, want about 990", b.tokens)
+	}
+}
+
+func TestClients(t *testing.T) {
+	c := NewClients(1000)
+	now := time.Unix(0, 0)
+
+	a := c.Get("a", now)
+
+	if a.Reserve(1000, now) != 0 || c.Get("a", now) != a {
+		t.Fatal("client did not keep its bucket")
+	}
+
+	if c.Get("b", now).Reserve(1000, now) != 0 {
+		t.Fatal("one client's burst was charged to another")
+	}
+
+	// Buckets still paying off a read are kept however many there are.
+	for i := range pruneAt {
+		c.Get(strconv.Itoa(i), now).Reserve(1, now)
+	}
+
+	if len(c.buckets) != pruneAt+2 {
+		t.Fatalf("%d buckets kept, want all %d in use", len(c.buckets), pruneAt+2)
+	}
+
+	// Two seconds later every bucket has refilled, so the next new client clears them out.
+	c.Get("late", now.Add(2*time.Second))
+
+	if len(c.buckets) != 1 {
+		t.Fatalf("%d buckets kept after all refilled", len(c.buckets))
+	}
+
+	if NewClients(0).Get("a", now) != nil {
+		t.Fatal("zero rate should disable limiting")
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+
+	return len(p), nil
+}
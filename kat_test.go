@@ -0,0 +1,151 @@
+package infnoise
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The vectors below pin how output is derived. A change that breaks them changes the output for a given input,
+// which is a compatibility break for anyone reproducing output from captured raw data, not a test to update; a
+// deliberate change updates them together with derivationScheme.
+
+// katRaw is the synthetic raw input of the conditioner KATs: n bytes of a fixed affine sequence.
+func katRaw(n int) []byte {
+	raw := make([]byte, n)
+
+	for i := range raw {
+		raw[i] = byte(37*i + 11)
+	}
+
+	return raw
+}
+
+func TestConditionerKAT(t *testing.T) {
+	raw := katRaw(128)
+
+	var seed [32]byte
+
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	// Each configuration whitens two 32-byte chunks from the same 128 raw bytes; the second shows what the chaining
+	// value carries forward.
+	tests := []struct {
+		name    string
+		ratchet bool
+		setup   func(w *whitener)
+		want    [2]string
+	}{
+		{
+			name:    "ratchet",
+			ratchet: true,
+			want: [2]string{
+				"1020c7720d00cc5e4445399f64a82fddba4d0a177c7e0e4c075cf2b3fe452f6e",
+				"5b9d8bc6a611d83d710f466ea12fab2571bb2f8751bcf0e455e0092f841a633a",
+			},
+		},
+		{
+			name: "no ratchet",
+			want: [2]string{
+				"1020c7720d00cc5e4445399f64a82fddba4d0a177c7e0e4c075cf2b3fe452f6e",
+				"d74b9cde985b7776b49c97890e1db428edf67221181b6ab27403eb33d2d0a4e9",
+			},
+		},
+		{
+			name:    "mix-in",
+			ratchet: true,
+			setup: func(w *whitener) {
+				w.mixIn([]byte("sensor reading"))
+			},
+			want: [2]string{
+				"ed95cbdedef1a7e580043e42b1d04d8cd1d1977e473c32096a349952206e90af",
+				"7d96cb9eb00daf50baa0d24bc9132167ee8b2e7a6d44837f6955fdbc25da3ffc",
+			},
+		},
+		{
+			name:    "reseed",
+			ratchet: true,
+			setup: func(w *whitener) {
+				w.reseed(1, seed[:])
+			},
+			want: [2]string{
+				"6da32fb37065bb639c5156965a704e16faa8380f92aeb980faa6726a0f08fec3",
+				"783d1ea09c1fe359dbaf86d4a394b94f1e3e9bbbc69cfb240aac00c14e9629b1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		w := newWhitener(tt.ratchet)
+
+		if tt.setup != nil {
+			tt.setup(w)
+		}
+
+		for i, want := range tt.want {
+			out := make([]byte, 32)
+
+			w.whiten(raw[64*i:64*(i+1)], out)
+
+			if got := hex.EncodeToString(out); got != want {
+				t.Errorf("%s, chunk %d:\n got %s\nwant %s", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+// TestDeviceKAT pins the output of a simulated board end to end: decoding the bitbang samples, whitening, and the
+// derivation of labeled broker subscriptions.
+func TestDeviceKAT(t *testing.T) {
+	check := func(name string, got []byte, want string) {
+		t.Helper()
+
+		if hex.EncodeToString(got) != want {
+			t.Errorf("%s:\n got %x\nwant %s", name, got, want)
+		}
+	}
+
+	raw := make([]byte, 32)
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	_, err := dv.Read(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check("raw", raw, "347254c4ae55dada645b37473529ae537666348b1d916b94aec944aa96c5b723")
+
+	whitened := make([]byte, 32)
+
+	dv, _ = openSimulator(t, 1, DefaultSimulatorGain, WithChunkSize(64))
+
+	_, err = dv.ReadWhitened(whitened)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check("whitened", whitened, "48a045492a0d9af0ef57ccea0e41717a1d101e14d823b1268aec48dcc6d1ac8f")
+
+	dv, _ = openSimulator(t, 1, DefaultSimulatorGain, WithChunkSize(64))
+
+	b := NewBroker(dv)
+	defer b.Close()
+
+	s, err := b.SubscribeLabeled("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	labeled := make([]byte, 32)
+
+	_, err = s.Read(labeled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check("labeled", labeled, "382a7f99ce06230b80018874ae87b5a508993ba7f95dc81bef4f71a7b1c6e5f0")
+}
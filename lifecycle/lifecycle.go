@@ -0,0 +1,239 @@
+// Package lifecycle shuts a daemon's subsystems down in a defined order within a deadline, so connections are
+// drained before the device they read from is closed, and the device's buffers are wiped even when something hangs.
+//
+// Subsystems register a stop function in one of the phases, which run in order: listeners stop accepting requests
+// and drain their connections, producers such as the kernel feeder stop drawing output, sinks flush and close the
+// files they were fed, and finally the device wipes its buffers and closes the USB handle. The stages of a phase
+// stop concurrently.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// lateGrace is how long each phase may take once the shutdown's deadline has passed.
+const lateGrace = time.Second
+
+// Phase orders the stages of a shutdown.
+type Phase int
+
+const (
+	// PhaseListeners stop accepting requests and drain open connections.
+	PhaseListeners Phase = iota
+
+	// PhaseProducers stop background work drawing device output, such as feeding the kernel.
+	PhaseProducers
+
+	// PhaseSinks flush and close what the earlier phases wrote to, such as capture files.
+	PhaseSinks
+
+	// PhaseDevice wipes buffered output and closes the device.
+	PhaseDevice
+
+	phases
+)
+
+var phaseNames = [phases]string{"listeners", "producers", "sinks", "device"}
+
+func (p Phase) String() string {
+	if p < 0 || p >= phases {
+		return fmt.Sprintf("Phase(%d)", int(p))
+	}
+
+	return phaseNames[p]
+}
+
+type stage struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager runs the registered subsystems until the daemon is asked to stop or one of them fails, then stops them
+// phase by phase. It is safe for concurrent use.
+type Manager struct {
+	opts options
+
+	mu     sync.Mutex
+	stages [phases][]stage
+
+	// failed receives the first error of a subsystem started with Go or Serve.
+	failed   chan error
+	stopOnce sync.Once
+	err      error
+}
+
+// New returns a Manager without stages.
+func New(opts ...option) *Manager {
+	o := options{
+		timeout: DefaultTimeout,
+		logf:    func(string, ...any) {},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Manager{opts: o, failed: make(chan error, 1)}
+}
+
+// Add registers stop to run in phase during the shutdown. ctx carries the shutdown's deadline; a stop still
+// running when it passes is abandoned, and each later phase still runs, with a second to finish.
+func (m *Manager) Add(phase Phase, name string, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stages[phase] = append(m.stages[phase], stage{name: name, stop: stop})
+}
+
+// Go runs fn in a goroutine until the shutdown reaches phase, which cancels its ctx and waits for it to return.
+// An error returned before that starts the shutdown and is reported by Run.
+func (m *Manager) Go(phase Phase, name string, fn func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+
+	go func() {
+		err := fn(ctx)
+
+		// Run reports the failure; the stage need not report it again.
+		if err != nil && ctx.Err() == nil {
+			m.fail(fmt.Errorf("%s: %w", name, err))
+
+			err = nil
+		}
+
+		done <- err
+	}()
+
+	m.Add(phase, name, func(stopCtx context.Context) error {
+		cancel()
+
+		select {
+		case err := <-done:
+			if errors.Is(err, context.Canceled) {
+				err = nil
+			}
+
+			return err
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	})
+}
+
+// Serve runs serve, such as srv.ListenAndServe, in a PhaseListeners stage that shuts srv down gracefully.
+func (m *Manager) Serve(name string, srv *http.Server, serve func() error) {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		err := serve()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+
+	m.Add(PhaseListeners, name, func(ctx context.Context) error {
+		err := srv.Shutdown(ctx)
+
+		<-done
+
+		return err
+	})
+}
+
+func (m *Manager) fail(err error) {
+	select {
+	case m.failed <- err:
+	default:
+	}
+}
+
+// Run waits until ctx is done or a subsystem started with Go or Serve fails, then shuts down. It returns that
+// failure joined with the errors of the shutdown, or nil.
+func (m *Manager) Run(ctx context.Context) error {
+	var err error
+
+	select {
+	case <-ctx.Done():
+	case err = <-m.failed:
+	}
+
+	return errors.Join(err, m.Shutdown())
+}
+
+// Shutdown stops every stage, phase by phase, within the timeout, and returns their errors joined. Only the first
+// call stops anything; later ones return its result.
+func (m *Manager) Shutdown() error {
+	m.stopOnce.Do(func() {
+		m.mu.Lock()
+		stages := m.stages
+		m.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), m.opts.timeout)
+		defer cancel()
+
+		var errs []error
+
+		for phase := range phases {
+			// A hung stage must not keep the device from being closed, so the phases after the deadline get a
+			// short grace period of their own.
+			if ctx.Err() != nil {
+				ctx, cancel = context.WithTimeout(context.Background(), lateGrace)
+				defer cancel()
+			}
+
+			errs = append(errs, m.stopPhase(ctx, phase, stages[phase])...)
+		}
+
+		m.err = errors.Join(errs...)
+	})
+
+	return m.err
+}
+
+// stopPhase runs the stages of a phase concurrently and waits for them, or for ctx to expire.
+func (m *Manager) stopPhase(ctx context.Context, phase Phase, stages []stage) []error {
+	errs := make([]error, len(stages))
+
+	var wg sync.WaitGroup
+
+	for i, s := range stages {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+
+			err := s.stop(ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("stopping %s: %w", s.name, err)
+			}
+
+			m.opts.logf("stopped %s (%s) in %s", s.name, phase, time.Since(start).Round(time.Millisecond))
+		}()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return errs
+	case <-ctx.Done():
+	}
+
+	return []error{fmt.Errorf("%s phase: %w", phase, ctx.Err())}
+}
@@ -0,0 +1,131 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownOrder(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			order = append(order, name)
+
+			return nil
+		}
+	}
+
+	m := New()
+
+	// Registered out of order; the phases decide.
+	m.Add(PhaseDevice, "device", record("device"))
+	m.Add(PhaseSinks, "capture", record("capture"))
+
+	m.Go(PhaseProducers, "feeder", func(ctx context.Context) error {
+		<-ctx.Done()
+
+		record("feeder")(ctx)
+
+		return ctx.Err()
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: http.NotFoundHandler()}
+
+	m.Serve("http", srv, func() error {
+		defer record("http")(nil)
+
+		return srv.Serve(ln)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = m.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"http", "feeder", "capture", "device"}; !slices.Equal(order, want) {
+		t.Fatalf("stopped in order %v, want %v", order, want)
+	}
+
+	if m.Shutdown() != nil || len(order) != 4 {
+		t.Fatal("a second Shutdown stopped stages again")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	m := New(WithTimeout(20 * time.Millisecond))
+
+	hung := make(chan struct{})
+	defer close(hung)
+
+	var closed bool
+
+	m.Add(PhaseListeners, "hung", func(context.Context) error {
+		<-hung
+
+		return nil
+	})
+
+	m.Add(PhaseDevice, "device", func(context.Context) error {
+		closed = true
+
+		return nil
+	})
+
+	err := m.Shutdown()
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "listeners") {
+		t.Fatalf("Shutdown = %v, want the listeners phase timing out", err)
+	}
+
+	if !closed {
+		t.Fatal("the device was not closed after an earlier phase hung")
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	m := New()
+
+	failure := errors.New("kernel refused")
+
+	m.Go(PhaseProducers, "feeder", func(context.Context) error {
+		return failure
+	})
+
+	var stopped bool
+
+	m.Add(PhaseDevice, "device", func(context.Context) error {
+		stopped = true
+
+		return nil
+	})
+
+	err := m.Run(context.Background())
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "feeder") || strings.Count(err.Error(), "kernel refused") != 1 {
+		t.Fatalf("Run = %v", err)
+	}
+
+	if !stopped {
+		t.Fatal("a failing subsystem did not shut the others down")
+	}
+}
@@ -0,0 +1,27 @@
+package lifecycle
+
+import "time"
+
+// DefaultTimeout bounds a shutdown unless WithTimeout says otherwise.
+const DefaultTimeout = 10 * time.Second
+
+type options struct {
+	timeout time.Duration
+	logf    func(format string, args ...any)
+}
+
+type option func(*options)
+
+// WithTimeout bounds the whole shutdown (default DefaultTimeout).
+func WithTimeout(d time.Duration) option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithLogf reports each stage as it is stopped, and how long it took, through logf (such as log.Printf).
+func WithLogf(logf func(format string, args ...any)) option {
+	return func(o *options) {
+		o.logf = logf
+	}
+}
@@ -0,0 +1,47 @@
+package infnoise
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// secretSize is the size of the buffers protectBuffers moves into locked memory.
+func (d *Device) secretSize(tapCap int) int {
+	return len(d.rawPool) + len(d.poolBuf) + len(d.inBulk) + len(d.spare.buf) + tapCap
+}
+
+// protectBuffers moves the buffers that hold raw samples and whitened output out of the Go heap, into memory that is
+// locked against swapping and excluded from core dumps as requested. The memory is wiped and released once the
+// Device is garbage collected.
+func (d *Device) protectBuffers(lock, noDump bool) error {
+	var tapCap int
+
+	if d.mode == ModeRawTap {
+		tapCap = max(tapLimit, 2*len(d.rawPool))
+	}
+
+	mem, err := allocSecret(d.secretSize(tapCap), lock, noDump)
+	if err != nil {
+		return fmt.Errorf("protecting buffers: %w", err)
+	}
+
+	rest := mem
+
+	take := func(n int) []byte {
+		b := rest[:n:n]
+
+		rest = rest[n:]
+
+		return b
+	}
+
+	d.rawPool = take(len(d.rawPool))
+	d.poolBuf = take(len(d.poolBuf))
+	d.inBulk = take(len(d.inBulk))
+	d.spare.buf = take(len(d.spare.buf))
+	d.tap = take(tapCap)[:0]
+
+	runtime.AddCleanup(d, freeSecret, mem)
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build darwin
+// +build darwin
+
+package infnoise
+
+import "errors"
+
+// excludeFromDumps fails on macOS, which cannot exclude memory from core dumps.
+func excludeFromDumps(mem []byte) error {
+	return errors.New("excluding memory from core dumps is not supported on macOS")
+}
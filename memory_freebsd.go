@@ -0,0 +1,10 @@
+//go:build freebsd
+// +build freebsd
+
+package infnoise
+
+import "golang.org/x/sys/unix"
+
+func excludeFromDumps(mem []byte) error {
+	return unix.Madvise(mem, unix.MADV_NOCORE)
+}
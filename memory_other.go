@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package infnoise
+
+import "errors"
+
+func allocSecret(size int, lock, noDump bool) ([]byte, error) {
+	return nil, errors.New("locked and dump-excluded memory are not supported on this platform")
+}
+
+func freeSecret(mem []byte) {}
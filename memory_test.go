@@ -0,0 +1,88 @@
+package infnoise
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestLockedMemory(t *testing.T) {
+	dv := New(WithBackend(registerSimulator(t, NewSimulator(1))), WithReadMode(ModeRawTap), WithLockedMemory())
+
+	err := dv.Start()
+	if err != nil {
+		t.Skipf("cannot lock memory here: %v", err)
+	}
+
+	_, err = dv.ReadWhitened(make([]byte, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dv.Read(make([]byte, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cap(dv.tap) != max(tapLimit, 2*len(dv.rawPool)) {
+		t.Fatalf("tap capacity %d, want it preallocated", cap(dv.tap))
+	}
+
+	dv.Close()
+
+	for name, buf := range map[string][]byte{"raw pool": dv.rawPool, "pool": dv.poolBuf, "bulk in": dv.inBulk, "tap": dv.tap[:cap(dv.tap)]} {
+		if !bytes.Equal(buf, make([]byte, len(buf))) {
+			t.Fatalf("%s not wiped by Close", name)
+		}
+	}
+}
+
+func TestWithoutCoreDumps(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("checks /proc/self/smaps")
+	}
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithoutCoreDumps())
+
+	smaps, err := os.ReadFile("/proc/self/smaps")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	addr := uint64(uintptr(unsafe.Pointer(unsafe.SliceData(dv.rawPool))))
+
+	var inside bool
+
+	for line := range strings.Lines(string(smaps)) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if strings.Contains(fields[0], "-") && !strings.HasSuffix(fields[0], ":") {
+			start, end, _ := strings.Cut(fields[0], "-")
+
+			lo, err1 := strconv.ParseUint(start, 16, 64)
+			hi, err2 := strconv.ParseUint(end, 16, 64)
+
+			inside = err1 == nil && err2 == nil && addr >= lo && addr < hi
+
+			continue
+		}
+
+		if inside && fields[0] == "VmFlags:" {
+			if !slices.Contains(fields[1:], "dd") {
+				t.Fatalf("pool mapping lacks the dd flag: %s", line)
+			}
+
+			return
+		}
+	}
+
+	t.Fatal("pool mapping not found in smaps")
+}
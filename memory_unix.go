@@ -0,0 +1,37 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package infnoise
+
+import "golang.org/x/sys/unix"
+
+// allocSecret maps size bytes of anonymous memory, locked into RAM and excluded from core dumps as requested.
+func allocSecret(size int, lock, noDump bool) ([]byte, error) {
+	mem, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+
+	if noDump {
+		err = excludeFromDumps(mem)
+	}
+
+	if err == nil && lock {
+		err = unix.Mlock(mem)
+	}
+
+	if err != nil {
+		unix.Munmap(mem)
+
+		return nil, err
+	}
+
+	return mem, nil
+}
+
+func freeSecret(mem []byte) {
+	clear(mem)
+
+	unix.Munlock(mem)
+	unix.Munmap(mem)
+}
@@ -0,0 +1,86 @@
+//go:build windows
+// +build windows
+
+package infnoise
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	// WerRegisterExcludedMemoryBlock keeps memory out of Windows Error Reporting dumps (Windows 10 1709 and later).
+	pWerRegisterExcludedMemoryBlock   = kernel32.NewProc("WerRegisterExcludedMemoryBlock")
+	pWerUnregisterExcludedMemoryBlock = kernel32.NewProc("WerUnregisterExcludedMemoryBlock")
+)
+
+// allocSecret allocates size bytes of committed memory, locked into the working set and excluded from Windows
+// Error Reporting dumps as requested. Locking more than the process's minimum working set fails; see
+// SetProcessWorkingSetSize.
+func allocSecret(size int, lock, noDump bool) ([]byte, error) {
+	addr, err := windows.VirtualAlloc(0, uintptr(size), windows.MEM_COMMIT|windows.MEM_RESERVE, windows.PAGE_READWRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	if noDump {
+		err = werExclude(addr, size)
+	}
+
+	if err == nil && lock {
+		err = windows.VirtualLock(addr, uintptr(size))
+	}
+
+	if err != nil {
+		if noDump {
+			werInclude(addr)
+		}
+
+		windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+
+		return nil, err
+	}
+
+	// vet's unsafeptr check flags this conversion (vet windows builds with -unsafeptr=false). It is sound: addr is
+	// memory VirtualAlloc committed outside the Go heap, which the garbage collector neither moves nor frees.
+	return unsafe.Slice((*byte)(unsafe.Pointer(addr)), size), nil
+}
+
+func freeSecret(mem []byte) {
+	clear(mem)
+
+	addr := uintptr(unsafe.Pointer(unsafe.SliceData(mem)))
+
+	windows.VirtualUnlock(addr, uintptr(len(mem)))
+
+	werInclude(addr)
+
+	windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+}
+
+func werExclude(addr uintptr, size int) error {
+	err := pWerRegisterExcludedMemoryBlock.Find()
+	if err != nil {
+		return err
+	}
+
+	hr, _, _ := pWerRegisterExcludedMemoryBlock.Call(addr, uintptr(size))
+	if hr != 0 {
+		return fmt.Errorf("WerRegisterExcludedMemoryBlock failed: HRESULT %#x", uint32(hr))
+	}
+
+	return nil
+}
+
+// werInclude undoes werExclude; unregistering a block that was never registered is harmless.
+func werInclude(addr uintptr) {
+	if pWerUnregisterExcludedMemoryBlock.Find() != nil {
+		return
+	}
+
+	pWerUnregisterExcludedMemoryBlock.Call(addr)
+}
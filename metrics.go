@@ -0,0 +1,65 @@
+package infnoise
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MetricsHandler serves the device's health, drift, reseed, and ring buffer figures in the Prometheus text
+// exposition format, for scraping at /metrics.
+func (d *Device) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		d.writeMetrics(w)
+	})
+}
+
+func (d *Device) writeMetrics(w io.Writer) {
+	status, estimate := d.Health()
+	drift := d.Drift()
+	stats := d.Stats()
+
+	metric(w, "infnoise_health_ok", "gauge", "Whether the health check passes.", boolMetric(status == HealthOK))
+	metric(w, "infnoise_entropy_estimate_bits", "gauge", "Entropy per raw bit estimated by the health check.", estimate)
+	metric(w, "infnoise_entropy_long_term_bits", "gauge", "Long-term average of the entropy per raw bit.", drift.LongTermEntropy)
+	metric(w, "infnoise_entropy_target_bits", "gauge", "Expected entropy per raw bit.", drift.Target)
+	metric(w, "infnoise_recalibrate", "gauge", "Whether the long-term estimate has drifted from the target.", boolMetric(drift.Recalibrate))
+	metric(w, "infnoise_reseeds_total", "counter", "Reseeds of the whitener with OS entropy.", float64(stats.Reseeds))
+	metric(w, "infnoise_retries_total", "counter", "Batches retried after a transient transport error.", float64(stats.Retries))
+	metric(w, "infnoise_reconnects_total", "counter", "Re-opens of the USB handle after a transport failure.", float64(stats.Reconnects))
+	metric(w, "infnoise_stale_discarded_bytes_total", "counter", "Whitened bytes discarded for exceeding the maximum pool age.", float64(stats.StaleDiscarded))
+	metric(w, "infnoise_stale_served_bytes_total", "counter", "Whitened bytes served after exceeding the maximum pool age.", float64(stats.StaleServed))
+	metric(w, "infnoise_reads_total", "counter", "Calls to Read and ReadWhitened.", float64(stats.Reads))
+	metric(w, "infnoise_read_latency_p50_seconds", "gauge", "Median read latency, rounded up to a power of two.", stats.ReadLatency.P50.Seconds())
+	metric(w, "infnoise_read_latency_p99_seconds", "gauge", "99th percentile read latency, rounded up to a power of two.", stats.ReadLatency.P99.Seconds())
+	metric(w, "infnoise_read_latency_max_seconds", "gauge", "Slowest read.", stats.ReadLatency.Max.Seconds())
+	metric(w, "infnoise_transport_errors_total", "counter", "Reads failed by a USB transfer error, timeout, or disconnect.", float64(stats.TransportErrors))
+	metric(w, "infnoise_protocol_errors_total", "counter", "Reads failed by a misuse of the stream or a data path inconsistency.", float64(stats.ProtocolErrors))
+	metric(w, "infnoise_health_errors_total", "counter", "Reads failed by the health check or a noise source self-test.", float64(stats.HealthErrors))
+	metric(w, "infnoise_panics_total", "counter", "Panics recovered in supervised goroutines of the process.", float64(Panics()))
+
+	ring, ok := d.RingStats()
+	if !ok {
+		return
+	}
+
+	metric(w, "infnoise_ring_capacity_bytes", "gauge", "Size of the backend's ring buffer.", float64(ring.Capacity))
+	metric(w, "infnoise_ring_buffered_bytes", "gauge", "Bytes waiting in the backend's ring buffer.", float64(ring.Buffered))
+	metric(w, "infnoise_ring_pauses_total", "counter", "Times the reader loop paused at the high watermark.", float64(ring.Pauses))
+	metric(w, "infnoise_ring_purged_bytes_total", "counter", "Buffered bytes discarded when the bit mode was set.", float64(ring.Purged))
+	metric(w, "infnoise_ring_dropped_bytes_total", "counter", "Bytes lost to ring buffer overflow.", float64(ring.Dropped))
+}
+
+func metric(w io.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,30 @@
+package infnoise
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	_, err := dv.ReadWhitened(make([]byte, testBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dv.Reseed()
+
+	rec := httptest.NewRecorder()
+
+	dv.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+
+	for _, want := range []string{"# TYPE infnoise_health_ok gauge\ninfnoise_health_ok 1\n", "infnoise_reseeds_total 1\n", "infnoise_entropy_estimate_bits 0.8"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
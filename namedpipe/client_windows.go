@@ -0,0 +1,66 @@
+//go:build windows
+// +build windows
+
+package namedpipe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// Client reads entropy from a named pipe server.
+type Client struct {
+	mu   sync.Mutex
+	conn *os.File
+}
+
+// Dial connects to the server listening on name, waiting up to 5 seconds while all pipe instances are busy.
+func Dial(name string) (*Client, error) {
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		h, err := windows.CreateFile(
+			path,
+			windows.GENERIC_READ|windows.GENERIC_WRITE,
+			0,
+			nil,
+			windows.OPEN_EXISTING,
+			0,
+			0,
+		)
+		if err == nil {
+			return &Client{
+				conn: os.NewFile(uintptr(h), name),
+			}, nil
+		}
+
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) || time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Read fills p with entropy from the server, splitting large reads into multiple requests.
+func (c *Client) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return readChunked(c.conn, p)
+}
+
+// Close disconnects from the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,39 @@
+package namedpipe
+
+type options struct {
+	name string
+	sddl string
+
+	clientRate int
+	globalRate int
+}
+
+type option func(*options)
+
+// WithName sets the pipe path the server listens on (default DefaultName).
+func WithName(name string) option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithSDDL sets the security descriptor applied to the pipe, in SDDL form (default DefaultSDDL).
+func WithSDDL(sddl string) option {
+	return func(o *options) {
+		o.sddl = sddl
+	}
+}
+
+// WithClientRate limits each connected client to bytesPerSecond (default 0, unlimited) so one greedy client cannot starve the others.
+func WithClientRate(bytesPerSecond int) option {
+	return func(o *options) {
+		o.clientRate = bytesPerSecond
+	}
+}
+
+// WithRateLimit caps the combined throughput of all clients at bytesPerSecond (default 0, unlimited).
+func WithRateLimit(bytesPerSecond int) option {
+	return func(o *options) {
+		o.globalRate = bytesPerSecond
+	}
+}
@@ -0,0 +1,146 @@
+// Package namedpipe serves entropy to local processes over a Windows named pipe, where Unix sockets and
+// /dev/random feeding are not available, and provides the matching client.
+//
+// The wire protocol is request/response: the client sends the number of bytes it wants as a big-endian
+// uint32 (1..MaxRequest); the server answers with a status byte followed by either the requested bytes
+// (statusOK) or a big-endian uint16 length and an error message (statusError).
+package namedpipe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultName is the pipe the service listens on unless configured otherwise.
+	DefaultName = `\\.\pipe\infnoise`
+
+	// DefaultSDDL grants SYSTEM and Administrators full control and authenticated users read/write access.
+	DefaultSDDL = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GRGW;;;AU)"
+
+	// MaxRequest is the largest number of bytes a single request may ask for.
+	MaxRequest = 64 * 1024
+
+	statusOK    = 0
+	statusError = 1
+)
+
+// ErrServerClosed is returned by Serve after Close has been called.
+var ErrServerClosed = errors.New("namedpipe: server closed")
+
+// serveConn answers requests on conn from src until the client disconnects.
+func serveConn(conn io.ReadWriter, src io.Reader) error {
+	var (
+		hdr [4]byte
+		buf = make([]byte, MaxRequest+1)
+	)
+
+	defer clear(buf)
+
+	for {
+		_, err := io.ReadFull(conn, hdr[:])
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		n := binary.BigEndian.Uint32(hdr[:])
+		if n == 0 || n > MaxRequest {
+			return writeError(conn, fmt.Errorf("invalid request size %d", n))
+		}
+
+		out := buf[:n+1]
+
+		_, err = io.ReadFull(src, out[1:])
+		if err != nil {
+			return writeError(conn, err)
+		}
+
+		out[0] = statusOK
+
+		_, err = conn.Write(out)
+
+		clear(out)
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeError(conn io.Writer, cause error) error {
+	msg := cause.Error()
+	if len(msg) > 0xFFFF {
+		msg = msg[:0xFFFF]
+	}
+
+	out := make([]byte, 3+len(msg))
+
+	out[0] = statusError
+
+	binary.BigEndian.PutUint16(out[1:3], uint16(len(msg)))
+	copy(out[3:], msg)
+
+	conn.Write(out)
+
+	return cause
+}
+
+// request asks the server for len(p) bytes (at most MaxRequest) and reads them into p.
+func request(conn io.ReadWriter, p []byte) (int, error) {
+	var hdr [4]byte
+
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(p)))
+
+	_, err := conn.Write(hdr[:])
+	if err != nil {
+		return 0, err
+	}
+
+	var status [1]byte
+
+	_, err = io.ReadFull(conn, status[:])
+	if err != nil {
+		return 0, err
+	}
+
+	if status[0] != statusOK {
+		var size [2]byte
+
+		_, err = io.ReadFull(conn, size[:])
+		if err != nil {
+			return 0, err
+		}
+
+		msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+
+		_, err = io.ReadFull(conn, msg)
+		if err != nil {
+			return 0, err
+		}
+
+		return 0, fmt.Errorf("server: %s", msg)
+	}
+
+	return io.ReadFull(conn, p)
+}
+
+// readChunked fills p using as many requests as needed.
+func readChunked(conn io.ReadWriter, p []byte) (n int, err error) {
+	for n < len(p) {
+		m, err := request(conn, p[n:n+min(len(p)-n, MaxRequest)])
+
+		n += m
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
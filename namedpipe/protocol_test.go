@@ -0,0 +1,47 @@
+package namedpipe
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestProtocol(t *testing.T) {
+	server, client := net.Pipe()
+
+	defer client.Close()
+
+	want := make([]byte, 3*MaxRequest+100)
+
+	rand.NewChaCha8([32]byte{1}).Read(want)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- serveConn(server, bytes.NewReader(want))
+
+		server.Close()
+	}()
+
+	got := make([]byte, len(want))
+
+	n, err := readChunked(client, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(want) || !bytes.Equal(got, want) {
+		t.Fatal("received bytes differ from source")
+	}
+
+	_, err = request(client, make([]byte, 1))
+	if err == nil || !strings.Contains(err.Error(), "EOF") {
+		t.Fatalf("got %v, want server-side EOF error once the source is drained", err)
+	}
+
+	if err := <-done; err == nil {
+		t.Fatal("serveConn returned nil after source failure")
+	}
+}
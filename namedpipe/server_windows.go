@@ -0,0 +1,240 @@
+//go:build windows
+// +build windows
+
+package namedpipe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/coalaura/infnoise/internal/ratelimit"
+	"golang.org/x/sys/windows"
+)
+
+const pipeBufferSize = MaxRequest + 16
+
+// ServerStats reports a server's activity.
+type ServerStats struct {
+	// Clients is the number of currently connected clients.
+	Clients int
+
+	// Bytes is the total number of entropy bytes handed out.
+	Bytes uint64
+
+	// Throttled is the number of requests delayed by the client or global rate limit.
+	Throttled uint64
+
+	// ClientRate and GlobalRate are the configured limits in bytes per second (0 means unlimited).
+	ClientRate, GlobalRate int
+}
+
+// Server serves entropy from a source to named pipe clients.
+type Server struct {
+	src  io.Reader
+	name string
+	sddl string
+
+	clientRate int
+	globalRate int
+	global     *ratelimit.Bucket
+
+	bytes     atomic.Uint64
+	throttled atomic.Uint64
+
+	mu      sync.Mutex
+	srcMu   sync.Mutex
+	closed  bool
+	clients map[*os.File]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewServer creates a server handing out bytes read from src. Reads from src are serialized.
+func NewServer(src io.Reader, opts ...option) *Server {
+	conf := &options{
+		name: DefaultName,
+		sddl: DefaultSDDL,
+	}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	return &Server{
+		src:     src,
+		name:    conf.name,
+		sddl:    conf.sddl,
+		clients: make(map[*os.File]struct{}),
+
+		clientRate: conf.clientRate,
+		globalRate: conf.globalRate,
+		global:     ratelimit.New(conf.globalRate),
+	}
+}
+
+// Serve accepts clients until Close is called. It always returns a non-nil error; after Close it returns ErrServerClosed.
+func (s *Server) Serve() error {
+	sd, err := windows.SecurityDescriptorFromString(s.sddl)
+	if err != nil {
+		return fmt.Errorf("invalid SDDL %q: %w", s.sddl, err)
+	}
+
+	sa := &windows.SecurityAttributes{
+		SecurityDescriptor: sd,
+	}
+
+	sa.Length = uint32(unsafe.Sizeof(*sa))
+
+	name, err := windows.UTF16PtrFromString(s.name)
+	if err != nil {
+		return err
+	}
+
+	for {
+		h, err := windows.CreateNamedPipe(
+			name,
+			windows.PIPE_ACCESS_DUPLEX,
+			windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+			windows.PIPE_UNLIMITED_INSTANCES,
+			pipeBufferSize,
+			pipeBufferSize,
+			0,
+			sa,
+		)
+		if err != nil {
+			return fmt.Errorf("CreateNamedPipe(%s): %w", s.name, err)
+		}
+
+		err = windows.ConnectNamedPipe(h, nil)
+		if err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+			windows.CloseHandle(h)
+
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+
+			return fmt.Errorf("ConnectNamedPipe: %w", err)
+		}
+
+		conn := os.NewFile(uintptr(h), s.name)
+
+		s.mu.Lock()
+
+		if s.closed {
+			s.mu.Unlock()
+
+			conn.Close()
+
+			return ErrServerClosed
+		}
+
+		s.clients[conn] = struct{}{}
+		s.wg.Add(1)
+
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting clients, disconnects existing ones and waits for their handlers to return.
+func (s *Server) Close() error {
+	s.mu.Lock()
+
+	if s.closed {
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	s.closed = true
+
+	for conn := range s.clients {
+		conn.Close()
+	}
+
+	s.mu.Unlock()
+
+	// Wake the pending ConnectNamedPipe so Serve can observe the closed flag.
+	if wake, err := Dial(s.name); err == nil {
+		wake.Close()
+	}
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *Server) handle(conn *os.File) {
+	defer s.wg.Done()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, conn)
+		s.mu.Unlock()
+
+		windows.FlushFileBuffers(windows.Handle(conn.Fd()))
+		windows.DisconnectNamedPipe(windows.Handle(conn.Fd()))
+
+		conn.Close()
+	}()
+
+	src := ratelimit.Reader{
+		R:         countingReader{r: lockedReader{mu: &s.srcMu, r: s.src}, n: &s.bytes},
+		Buckets:   []*ratelimit.Bucket{ratelimit.New(s.clientRate), s.global},
+		Throttled: &s.throttled,
+	}
+
+	serveConn(conn, src)
+}
+
+// Stats returns the server's current activity counters and configured limits.
+func (s *Server) Stats() ServerStats {
+	s.mu.Lock()
+	clients := len(s.clients)
+	s.mu.Unlock()
+
+	return ServerStats{
+		Clients:    clients,
+		Bytes:      s.bytes.Load(),
+		Throttled:  s.throttled.Load(),
+		ClientRate: s.clientRate,
+		GlobalRate: s.globalRate,
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+type lockedReader struct {
+	mu *sync.Mutex
+	r  io.Reader
+}
+
+func (l lockedReader) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.r.Read(p)
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Uint64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+
+	c.n.Add(uint64(n))
+
+	return n, err
+}
@@ -1,9 +1,62 @@
 package infnoise
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
 type options struct {
 	targetEntropy float64
 	tolerance     float64
 	window        uint64
+	backend       BackendName
+	onHealth      func(old, new HealthStatus, estimate float64)
+	quarantine    int
+	watermarks    [2]int
+	warmupBytes   int
+	warmupTime    time.Duration
+	profile       Profile
+	pattern       *Pattern
+	rate          int
+	idleTimeout   time.Duration
+	mode          ReadMode
+	fold          int
+	ioBatch       int
+	chunkSize     int
+	detach        bool
+	timeout       time.Duration
+	latency       int
+	trendInterval time.Duration
+	trendPoints   int
+	trendSink     io.Writer
+	auditLog      io.Writer
+	trendFormat   TrendFormat
+	ratchet       bool
+	reseed        time.Duration
+	lockMemory    bool
+	noDump        bool
+	maxPoolAge    time.Duration
+	stalePolicy   StalePolicy
+	retries       int
+	retryBackoff  time.Duration
+	reconnects    int
+	reconnectWait time.Duration
+	falsePositive float64
+	minEntropy    float64
+	autocorrLimit float64
+	historyBits   int
+	predictors    float64
+	sampling      float64
+
+	tracerProvider trace.TracerProvider
+	labels         context.Context
+
+	// err is an invalid setting found while applying a Config.
+	err error
 }
 
 type option func(*options)
@@ -15,6 +68,56 @@ func WithTargetEntropy(bits float64) option {
 	}
 }
 
+// WithContinuousTests sets the false positive rate per raw bit the SP 800-90B continuous health tests may have
+// (default DefaultFalsePositiveRate) and the assessed min-entropy per raw bit they assume (default
+// DefaultMinEntropy); the cutoffs are derived from both (see Cutoffs) and listed in HealthReport. A batch that fails
+// either test is rejected like one failing the tolerance check.
+func WithContinuousTests(falsePositiveRate, minEntropy float64) option {
+	return func(o *options) {
+		o.falsePositive = falsePositiveRate
+		o.minEntropy = minEntropy
+	}
+}
+
+// WithAutocorrelationLimit sets the largest magnitude the autocorrelation of the raw bits may reach at any lag from
+// 1 to MaxAutocorrelationLag (default DefaultAutocorrelationLimit). Coefficients are computed over windows of
+// AutocorrelationWindow bits, and the batch completing a window that exceeds the limit is rejected like one failing
+// the tolerance check. A negative limit disables the check; HealthReport still lists the coefficients.
+func WithAutocorrelationLimit(limit float64) option {
+	return func(o *options) {
+		o.autocorrLimit = limit
+	}
+}
+
+// WithHealthHistoryBits sets how many preceding bits the health check predicts each bit from (default
+// DefaultHistoryBits, up to MaxHistoryBits). HealthReport exports one pair of counts per history, so longer
+// histories give researchers higher-order Markov contexts to fit; they also need a longer WithHealthWindow before
+// the estimate settles, and each doubles the memory of the counts.
+func WithHealthHistoryBits(k int) option {
+	return func(o *options) {
+		o.historyBits = k
+	}
+}
+
+// WithPredictors runs the SP 800-90B MultiMCW, lag, MultiMMC, and LZ78Y prediction estimators on the given fraction
+// of the raw bits (default DefaultPredictorFraction; 0 disables them, 1 analyzes everything). Every n-th block of
+// PredictorBlock consecutive bits is analyzed, n being the inverse of the fraction rounded, so the models learn from
+// unbroken runs. HealthReport reports each estimator and the lowest bound as the prediction-bound estimate; they
+// cost about a microsecond per analyzed bit, which is why the default samples.
+func WithPredictors(fraction float64) option {
+	return func(o *options) {
+		o.predictors = fraction
+	}
+}
+
+// WithHealthSampling makes the health check's estimators analyze only every n-th batch (n = 1/fraction, default 1); the
+// repetition count and adaptive proportion tests still see every bit. See the README for what sampling gives up.
+func WithHealthSampling(fraction float64) option {
+	return func(o *options) {
+		o.sampling = fraction
+	}
+}
+
 // WithTolerance sets the allowed deviation from the target (default 0.05).
 func WithTolerance(percent float64) option {
 	return func(o *options) {
@@ -28,3 +131,343 @@ func WithHealthWindow(bits uint64) option {
 		o.window = bits
 	}
 }
+
+// WithBackend selects the USB transport used by Start (default: the platform's native backend).
+func WithBackend(name BackendName) option {
+	return func(o *options) {
+		o.backend = name
+	}
+}
+
+// WithHealthCallback registers fn to be called whenever the health check transitions between passing and failing.
+// fn runs on the goroutine calling Read after the device lock has been released, so it may call back into the Device.
+func WithHealthCallback(fn func(old, new HealthStatus, estimate float64)) option {
+	return func(o *options) {
+		o.onHealth = fn
+	}
+}
+
+// WithQuarantine withholds all output after a health failure until the given number of consecutive health windows
+// pass on their own (default 0, reads resume as soon as the overall estimate recovers).
+func WithQuarantine(windows int) option {
+	return func(o *options) {
+		o.quarantine = windows
+	}
+}
+
+// WithRingWatermarks makes a ring-buffered backend's reader loop stop polling USB once high bytes are buffered and
+// resume at or below low (default: pause only when one more transfer would not fit). Ignored by other backends.
+func WithRingWatermarks(high, low int) option {
+	return func(o *options) {
+		o.watermarks = [2]int{high, low}
+	}
+}
+
+// WithWarmup makes Start read and discard this many bytes before returning, while the analog loop settles (default 0).
+func WithWarmup(bytes int) option {
+	return func(o *options) {
+		o.warmupBytes = bytes
+	}
+}
+
+// WithWarmupDuration makes Start keep discarding output for at least d before returning (default 0).
+func WithWarmupDuration(d time.Duration) option {
+	return func(o *options) {
+		o.warmupTime = d
+	}
+}
+
+// WithPattern replaces the board profile's output pattern (default DefaultPattern()), for boards whose switching or
+// address wiring was modified. Start fails if the pattern is invalid.
+func WithPattern(p Pattern) option {
+	return func(o *options) {
+		o.pattern = &p
+	}
+}
+
+// WithBoardProfile selects the board's pin mapping and pattern (default ProfileV2); custom boards can pass their own
+// Profile. Start fails if the profile is invalid.
+func WithBoardProfile(p Profile) option {
+	return func(o *options) {
+		o.profile = p
+	}
+}
+
+// WithTargetRate limits harvesting to about bytesPerSec by sleeping between batches, which are also shrunk to a
+// tenth of a second's worth so output arrives steadily (default 0, full speed). Use it to reduce USB bus load and
+// CPU wake-ups when full-speed entropy is not needed.
+func WithTargetRate(bytesPerSec int) option {
+	return func(o *options) {
+		o.rate = bytesPerSec
+	}
+}
+
+// WithIdleTimeout stops the background USB polling of backends implementing Suspender and purges the FIFOs once no
+// read has happened for d (default 0, never). The next read re-arms the device, at the cost of one extra purge.
+func WithIdleTimeout(d time.Duration) option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithReadMode sets how Read and ReadWhitened share the bitstream (default ModeExclusive).
+func WithReadMode(m ReadMode) option {
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+// WithIOBatch sets the number of bitbang samples per USB round trip, which yields an eighth as many raw bytes
+// (default IOBatch). It must be a multiple of 8 up to MaxIOBatch. Smaller batches return the first bytes of a
+// read sooner and hold the device lock for less time; larger ones spend less time on per-transfer overhead and
+// reach the full ~60 KB/s more reliably.
+func WithIOBatch(samples int) option {
+	return func(o *options) {
+		o.ioBatch = samples
+	}
+}
+
+// WithChunkSize sets the number of whitened bytes squeezed per conditioning step, each of which absorbs twice as
+// many raw bytes (default WhitenedChunkSize, at most MaxChunkSize). Small chunks keep less whitened output
+// buffered and make small reads cheaper; large ones amortize the hashing over more input.
+func WithChunkSize(bytes int) option {
+	return func(o *options) {
+		o.chunkSize = bytes
+	}
+}
+
+// WithKernelDriverDetach sets whether Start may detach a kernel driver (ftdi_sio on Linux) bound to the board
+// (default true). Disable it on shared machines where the port may be in use as a serial device: Start then fails
+// with ErrKernelDriver instead of taking the board away from its driver.
+func WithKernelDriverDetach(allow bool) option {
+	return func(o *options) {
+		o.detach = allow
+	}
+}
+
+// WithTransferTimeout sets how long a single USB transfer may block while no read deadline is set (default 5s).
+func WithTransferTimeout(d time.Duration) option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithLatencyTimer sets the FTDI latency timer to 1-255 ms on backends implementing LatencyBackend (default 2).
+// Longer timers send fewer, fuller packets.
+func WithLatencyTimer(ms int) option {
+	return func(o *options) {
+		o.latency = ms
+	}
+}
+
+// WithHighLatencyLink tunes the device for links that add tens of milliseconds per round trip, such as USB/IP:
+// batches of 4*IOBatch samples, HighLatencyTimeout per transfer, and a 16 ms latency timer. Without it, Start
+// still applies the timeout and latency timer when it finds the board attached through usbip (Linux vhci_hcd)
+// and neither was set explicitly.
+func WithHighLatencyLink() option {
+	return func(o *options) {
+		o.ioBatch = 4 * IOBatch
+		o.timeout = HighLatencyTimeout
+		o.latency = highLatencyTimer
+	}
+}
+
+// WithTrend samples the entropy estimate every interval while the device is running and keeps the most recent
+// points for Trend (default off). The series survives Close, so a long-running service can show a board slowly
+// degrading over weeks; 2016 points at 5 minutes cover one week.
+func WithTrend(interval time.Duration, points int) option {
+	return func(o *options) {
+		o.trendInterval = interval
+		o.trendPoints = points
+	}
+}
+
+// WithTrendSink also writes each trend point to w, for example an append-only log file. A sink that fails a write
+// is dropped; the in-memory series is unaffected.
+func WithTrendSink(w io.Writer, format TrendFormat) option {
+	return func(o *options) {
+		o.trendSink = w
+		o.trendFormat = format
+	}
+}
+
+// WithAuditLog writes a tamper-evident record of the session to w, for example an append-only file: one JSON
+// AuditEntry per line for the configuration, Start and Close, health transitions, USB errors, reseeds, and the
+// SHA-256 of every whitened chunk (never the chunk itself), each line carrying the hash of the one before it. Check
+// a log with VerifyAuditLog. It needs chunks of at least 32 bytes (see WithChunkSize). A log that fails a write is
+// dropped; raw reads are not recorded.
+func WithAuditLog(w io.Writer) option {
+	return func(o *options) {
+		o.auditLog = w
+	}
+}
+
+// WithFoldFactor sets how many raw bits ReadFolded folds into each output bit, from 1 to MaxFoldFactor (default 0:
+// derived from the measured entropy, see FoldFactor). Fix it where the certification records the factor.
+func WithFoldFactor(k int) option {
+	return func(o *options) {
+		o.fold = k
+	}
+}
+
+// WithTracerProvider records OpenTelemetry spans for Read and ReadWhitened, each harvest and bitbang batch, and the
+// USB transfers within it (default off). Batches carry their size and how long they queued for the device, which
+// shows contention on shared USB buses; transfers carry their size.
+func WithTracerProvider(tp trace.TracerProvider) option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithProfileLabels tags the hot path with the pprof label "infnoise" so CPU profiles of entropy daemons attribute
+// time to its stages: usb-wait (USB transfers), extract (bit extraction), health (the health check), and absorb
+// (whitening). The labels are added to those of base, which the reading goroutine is left with after each stage
+// (default off).
+func WithProfileLabels(base context.Context) option {
+	return func(o *options) {
+		if base == nil {
+			base = context.Background()
+		}
+
+		o.labels = base
+	}
+}
+
+// WithRatchet controls forward secrecy of the whitener (default true): the sponge is wiped after every chunk and the
+// chaining value is ratcheted through a one-way step, so memory captured later cannot reconstruct earlier output.
+// Disabling it reproduces the output of earlier versions for comparison against recorded test vectors.
+func WithRatchet(enabled bool) option {
+	return func(o *options) {
+		o.ratchet = enabled
+	}
+}
+
+// WithReseedInterval makes the whitener fold fresh OS entropy into its state (see Device.Reseed) before the first
+// chunk and then whenever d has passed since the last reseed (default 0, never).
+func WithReseedInterval(d time.Duration) option {
+	return func(o *options) {
+		o.reseed = d
+	}
+}
+
+// WithLockedMemory allocates the buffers holding raw samples and whitened output outside the Go heap and locks them
+// into RAM (mlock, or VirtualLock on Windows), so they are never written to swap. Start fails if the memory cannot
+// be locked, e.g. because RLIMIT_MEMLOCK or the minimum working set is too small. Buffers of handles returned by Raw
+// and Whitened are not covered.
+func WithLockedMemory() option {
+	return func(o *options) {
+		o.lockMemory = true
+	}
+}
+
+// WithoutCoreDumps excludes the buffers covered by WithLockedMemory from core dumps (MADV_DONTDUMP on Linux,
+// MADV_NOCORE on FreeBSD) and, on Windows, from Windows Error Reporting dumps (WerRegisterExcludedMemoryBlock), so
+// crash dumps of entropy daemons do not leak their contents. It can be combined with WithLockedMemory or used on
+// its own. Start fails where this is unsupported, including macOS.
+func WithoutCoreDumps() option {
+	return func(o *options) {
+		o.noDump = true
+	}
+}
+
+// WithMaxPoolAge limits how long whitened output may wait in the device's buffer between being harvested and being
+// returned by ReadWhitened (default 0, no limit). Older output is discarded and replaced with StaleDiscard, or
+// served and counted in Stats with StaleFlag. Buffers of handles and subscriptions are not covered.
+func WithMaxPoolAge(age time.Duration, policy StalePolicy) option {
+	return func(o *options) {
+		o.maxPoolAge = age
+		o.stalePolicy = policy
+	}
+}
+
+// WithRetry retries a batch up to attempts times (at most MaxRetries) when it fails with a transient transport
+// error, such as a stalled endpoint (LIBUSB_ERROR_PIPE), a transfer timeout, or FT_IO_ERROR, instead of returning
+// the error (default 0, no retries). Each retry purges the FIFOs and waits backoff, doubled after every attempt.
+// Retries are counted in Stats; Close, expired read deadlines, and disconnects are never retried (see WithReconnect).
+func WithRetry(attempts int, backoff time.Duration) option {
+	return func(o *options) {
+		o.retries = attempts
+		o.retryBackoff = backoff
+	}
+}
+
+// WithReconnect reopens the USB handle up to attempts times (at most MaxRetries) when a batch still fails with a
+// transport error after WithRetry, such as a disconnect or a reset USB/IP link (default 0, no reconnects). Each
+// attempt waits backoff first, doubled after every attempt, and the batch is run again on the new handle.
+// EventReconnecting and EventReconnected bracket the re-open; if every attempt fails, the device stops with
+// EventStopped and must be started again. Close and expired read deadlines never reconnect.
+func WithReconnect(attempts int, backoff time.Duration) option {
+	return func(o *options) {
+		o.reconnects = attempts
+		o.reconnectWait = backoff
+	}
+}
+
+func (o *options) validate() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	if o.ioBatch < 8 || o.ioBatch%8 != 0 || o.ioBatch > MaxIOBatch {
+		return fmt.Errorf("invalid I/O batch %d (need a positive multiple of 8 up to %d)", o.ioBatch, MaxIOBatch)
+	}
+
+	if o.timeout < 0 {
+		return fmt.Errorf("invalid transfer timeout %s", o.timeout)
+	}
+
+	if o.latency < 0 || o.latency > 255 {
+		return fmt.Errorf("invalid latency timer %d ms (need 1 to 255)", o.latency)
+	}
+
+	if o.trendInterval < 0 || (o.trendInterval > 0 && o.trendPoints <= 0) {
+		return fmt.Errorf("invalid trend interval %s with %d points", o.trendInterval, o.trendPoints)
+	}
+
+	if o.reseed < 0 {
+		return fmt.Errorf("invalid reseed interval %s", o.reseed)
+	}
+
+	if o.maxPoolAge < 0 || (o.stalePolicy != StaleDiscard && o.stalePolicy != StaleFlag) {
+		return fmt.Errorf("invalid maximum pool age %s or stale policy %s", o.maxPoolAge, o.stalePolicy)
+	}
+
+	if o.retries < 0 || o.retries > MaxRetries || o.retryBackoff < 0 {
+		return fmt.Errorf("invalid retry policy of %d attempts with backoff %s (need 0 to %d attempts)", o.retries, o.retryBackoff, MaxRetries)
+	}
+
+	if o.reconnects < 0 || o.reconnects > MaxRetries || o.reconnectWait < 0 {
+		return fmt.Errorf("invalid reconnect policy of %d attempts with backoff %s (need 0 to %d attempts)", o.reconnects, o.reconnectWait, MaxRetries)
+	}
+
+	if !(o.sampling > 0 && o.sampling <= 1) {
+		return fmt.Errorf("invalid health sampling fraction %g (need more than 0, at most 1)", o.sampling)
+	}
+
+	if !(o.predictors >= 0 && o.predictors <= 1) {
+		return fmt.Errorf("invalid predictor fraction %g (need 0 to 1)", o.predictors)
+	}
+
+	if o.historyBits < 1 || o.historyBits > MaxHistoryBits {
+		return fmt.Errorf("invalid history length %d bits (need 1 to %d)", o.historyBits, MaxHistoryBits)
+	}
+
+	_, err := NewCutoffs(o.falsePositive, o.minEntropy)
+	if err != nil {
+		return err
+	}
+
+	if o.chunkSize <= 0 || o.chunkSize > MaxChunkSize {
+		return fmt.Errorf("invalid chunk size %d (need 1 to %d)", o.chunkSize, MaxChunkSize)
+	}
+
+	if o.fold < 0 || o.fold > MaxFoldFactor {
+		return fmt.Errorf("invalid fold factor %d (need 1 to %d, or 0 to derive it)", o.fold, MaxFoldFactor)
+	}
+
+	if o.auditLog != nil && o.chunkSize < minAuditChunk {
+		return fmt.Errorf("chunk size %d too small for an audit log (need at least %d)", o.chunkSize, minAuditChunk)
+	}
+
+	return nil
+}
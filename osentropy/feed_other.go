@@ -0,0 +1,10 @@
+//go:build !freebsd && !linux
+// +build !freebsd,!linux
+
+package osentropy
+
+import "io"
+
+func openKernel(path string) (io.WriteCloser, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,51 @@
+package osentropy
+
+import "time"
+
+const (
+	// DefaultRate is the default feeding rate in bytes per second.
+	DefaultRate = 1024
+
+	// DefaultChunk is the default size of each write.
+	DefaultChunk = 64
+
+	// DefaultPath is the device written to.
+	DefaultPath = "/dev/random"
+)
+
+type feedOptions struct {
+	rate  int
+	chunk int
+	path  string
+	retry time.Duration
+}
+
+type feedOption func(*feedOptions)
+
+// WithRate limits feeding to bytesPerSec (default DefaultRate).
+func WithRate(bytesPerSec int) feedOption {
+	return func(o *feedOptions) {
+		o.rate = bytesPerSec
+	}
+}
+
+// WithChunkSize sets the size of each write (default DefaultChunk).
+func WithChunkSize(bytes int) feedOption {
+	return func(o *feedOptions) {
+		o.chunk = bytes
+	}
+}
+
+// WithPath overrides the device written to (default DefaultPath).
+func WithPath(path string) feedOption {
+	return func(o *feedOptions) {
+		o.path = path
+	}
+}
+
+// WithRetryInterval sets how often a failing source is read again to see whether it recovered (default 5s).
+func WithRetryInterval(d time.Duration) feedOption {
+	return func(o *feedOptions) {
+		o.retry = d
+	}
+}
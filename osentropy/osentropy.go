@@ -0,0 +1,120 @@
+// Package osentropy feeds the whitened output of an Infinite Noise TRNG to the operating system's random number
+// generator, so every program on the host benefits from the board.
+//
+// Feeding is implemented for FreeBSD, whose random(4) device harvests anything written to /dev/random into its
+// Fortuna pools, and for Linux, where output is added with the RNDADDENTROPY ioctl so it is credited (this needs
+// CAP_SYS_ADMIN). Output is only fed while the board's health check passes, at a bounded rate.
+package osentropy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/coalaura/infnoise"
+)
+
+// ErrUnsupported is returned by Run on platforms without a feeding implementation.
+var ErrUnsupported = errors.New("feeding the kernel is not supported on this platform")
+
+// Source is the part of an infnoise.Source the feeder uses.
+type Source interface {
+	ReadWhitened(p []byte) (int, error)
+	Health() (infnoise.HealthStatus, float64)
+}
+
+// Feeder writes whitened output to the kernel at a bounded rate while the source is healthy.
+type Feeder struct {
+	src  Source
+	opts feedOptions
+}
+
+// New returns a Feeder reading from src.
+func New(src Source, opts ...feedOption) *Feeder {
+	o := feedOptions{
+		rate:  DefaultRate,
+		chunk: DefaultChunk,
+		path:  DefaultPath,
+		retry: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Feeder{src: src, opts: o}
+}
+
+// Run feeds the kernel until ctx is cancelled, returning nil then. Read errors other than health failures end the
+// run. While the health check fails, the source is still read every retry interval so it can recover, but nothing
+// is fed until it passes.
+func (f *Feeder) Run(ctx context.Context) error {
+	if f.opts.rate <= 0 || f.opts.chunk <= 0 {
+		return fmt.Errorf("invalid rate %d or chunk size %d", f.opts.rate, f.opts.chunk)
+	}
+
+	w, err := openKernel(f.opts.path)
+	if err != nil {
+		return err
+	}
+
+	defer w.Close()
+
+	return f.run(ctx, w)
+}
+
+func (f *Feeder) run(ctx context.Context, w io.Writer) error {
+	buf := make([]byte, f.opts.chunk)
+
+	defer clear(buf)
+
+	// One chunk every interval keeps the rate at opts.rate bytes per second.
+	interval := time.Duration(f.opts.chunk) * time.Second / time.Duration(f.opts.rate)
+
+	next := time.Now()
+
+	for {
+		wait := time.Until(next)
+
+		if wait <= 0 {
+			// Reads go on while the health check fails, since only reads let the device recover; their output is
+			// discarded until it passes again.
+			_, err := f.src.ReadWhitened(buf)
+			if err != nil && infnoise.Classify(err) != infnoise.ClassHealth {
+				return fmt.Errorf("read: %w", err)
+			}
+
+			if status, _ := f.src.Health(); err != nil || status != infnoise.HealthOK {
+				clear(buf)
+
+				next = time.Now().Add(f.opts.retry)
+
+				continue
+			}
+
+			_, err = w.Write(buf)
+			if err != nil {
+				return fmt.Errorf("feed kernel: %w", err)
+			}
+
+			clear(buf)
+
+			next = next.Add(interval)
+
+			// Do not burst to catch up after falling behind.
+			if now := time.Now(); next.Before(now) {
+				next = now
+			}
+
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
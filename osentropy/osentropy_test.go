@@ -0,0 +1,112 @@
+package osentropy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coalaura/infnoise"
+)
+
+// fakeSource fails its next failures reads with a health error, and reports a failed health check while any are
+// left or healthy is unset.
+type fakeSource struct {
+	healthy  atomic.Bool
+	failures atomic.Int64
+	reads    atomic.Int64
+}
+
+func (s *fakeSource) ReadWhitened(p []byte) (int, error) {
+	s.reads.Add(1)
+
+	if s.failures.Add(-1) >= 0 {
+		return 0, fmt.Errorf("%w: window entropy 0.5000 outside tolerance", infnoise.ErrQuarantined)
+	}
+
+	s.failures.Store(0)
+
+	for i := range p {
+		p[i] = 0xa5
+	}
+
+	return len(p), nil
+}
+
+func (s *fakeSource) Health() (infnoise.HealthStatus, float64) {
+	if s.healthy.Load() && s.failures.Load() == 0 {
+		return infnoise.HealthOK, 0.86
+	}
+
+	return infnoise.HealthFailed, 0.5
+}
+
+func TestFeeder(t *testing.T) {
+	src := &fakeSource{}
+
+	src.healthy.Store(true)
+
+	f := New(src, WithRate(64*100), WithChunkSize(64), WithRetryInterval(10*time.Millisecond))
+
+	var out bytes.Buffer
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := f.run(ctx, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 100 chunks per second for 0.2 s, plus the first one fed immediately.
+	if n := out.Len() / 64; n < 15 || n > 25 {
+		t.Fatalf("fed %d chunks in 200ms at 100 per second", n)
+	}
+
+	src.healthy.Store(false)
+	src.reads.Store(0)
+
+	fed := out.Len()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = f.run(ctx, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Len() != fed {
+		t.Fatal("fed the kernel while the health check failed")
+	}
+
+	// Reading goes on, or the device could never recover.
+	if src.reads.Load() == 0 {
+		t.Fatal("stopped reading while the health check failed")
+	}
+}
+
+func TestFeederRecovers(t *testing.T) {
+	src := &fakeSource{}
+
+	src.healthy.Store(true)
+	src.failures.Store(3)
+
+	f := New(src, WithRate(64*100), WithChunkSize(64), WithRetryInterval(10*time.Millisecond))
+
+	var out bytes.Buffer
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := f.run(ctx, &out)
+	if err != nil {
+		t.Fatalf("quarantined reads ended the run: %v", err)
+	}
+
+	if src.failures.Load() != 0 || out.Len() == 0 {
+		t.Fatalf("fed %d bytes after %d failed reads, want the feed to resume", out.Len(), 3-src.failures.Load())
+	}
+}
@@ -0,0 +1,97 @@
+package packaging
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"path"
+	"text/template"
+
+	"github.com/coalaura/infnoise/seedfile"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// Layout is where the assets are installed and what they refer to.
+type Layout struct {
+	// Bin is the infnoise binary; UnitDir and RuleDir hold the systemd units and the udev rule.
+	Bin     string
+	UnitDir string
+	RuleDir string
+
+	// Config holds the appliance's options and Token its bearer token, generated on installation.
+	Config string
+	Token  string
+
+	// Group is granted access to the board by the udev rule.
+	Group string
+}
+
+// DefaultLayout is the layout of the packages.
+var DefaultLayout = Layout{
+	Bin:     "/usr/bin/infnoise",
+	UnitDir: "/usr/lib/systemd/system",
+	RuleDir: "/usr/lib/udev/rules.d",
+	Config:  "/etc/infnoise/infnoise.conf",
+	Token:   "/etc/infnoise/token",
+	Group:   "dialout",
+}
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{"dir": path.Dir}).ParseFS(assets, "assets/*"))
+
+func render(name string, l Layout) ([]byte, error) {
+	var b bytes.Buffer
+
+	err := templates.ExecuteTemplate(&b, name, l)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Assets returns the files installed alongside the binary: the appliance unit, the boot seed unit, the udev rule,
+// and the default configuration with its directory.
+func Assets(l Layout) ([]File, error) {
+	var files []File
+
+	for _, a := range []struct {
+		name, path string
+		mode       fs.FileMode
+		config     bool
+	}{
+		{"infnoise.service", path.Join(l.UnitDir, "infnoise.service"), 0o644, false},
+		{"75-infnoise.rules", path.Join(l.RuleDir, "75-infnoise.rules"), 0o644, false},
+		{"infnoise.conf", l.Config, 0o644, true},
+	} {
+		data, err := render(a.name, l)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, File{Path: a.path, Mode: a.mode, Data: data, Config: a.config})
+	}
+
+	files = append(files,
+		File{Path: path.Join(l.UnitDir, seedfile.SystemdUnitName), Mode: 0o644, Data: []byte(seedfile.SystemdUnit(l.Bin))},
+		File{Path: path.Dir(l.Config), Mode: fs.ModeDir | 0o755},
+	)
+
+	return files, nil
+}
+
+// Scripts returns the maintainer scripts: after installation, they generate the token and reload udev and systemd;
+// before removal, they stop and disable the units.
+func Scripts(l Layout) (postInstall, preRemove, postRemove string, err error) {
+	var out [3][]byte
+
+	for i, name := range []string{"postinst", "prerm", "postrm"} {
+		out[i], err = render(name, l)
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+
+	return string(out[0]), string(out[1]), string(out[2]), nil
+}
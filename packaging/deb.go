@@ -0,0 +1,228 @@
+package packaging
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// WriteDeb writes p as a Debian binary package: an ar archive of debian-binary, control.tar.gz, and data.tar.gz.
+func WriteDeb(w io.Writer, p *Package) error {
+	err := p.validate()
+	if err != nil {
+		return err
+	}
+
+	control, err := debControl(p)
+	if err != nil {
+		return err
+	}
+
+	data, err := debData(p)
+	if err != nil {
+		return err
+	}
+
+	mtime := p.Time.Unix()
+
+	_, err = io.WriteString(w, "!<arch>\n")
+	if err != nil {
+		return err
+	}
+
+	for _, m := range []struct {
+		name string
+		data []byte
+	}{
+		{"debian-binary", []byte("2.0\n")},
+		{"control.tar.gz", control},
+		{"data.tar.gz", data},
+	} {
+		err = writeArMember(w, m.name, m.data, mtime)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeArMember(w io.Writer, name string, data []byte, mtime int64) error {
+	_, err := fmt.Fprintf(w, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, mtime, 0, 0, 0o100644, len(data))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	// Members start at even offsets.
+	if len(data)%2 == 1 {
+		_, err = io.WriteString(w, "\n")
+	}
+
+	return err
+}
+
+func debControl(p *Package) ([]byte, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Package: %s\n", p.Name)
+	fmt.Fprintf(&b, "Version: %s-%s\n", p.version(), p.release())
+	fmt.Fprintf(&b, "Architecture: %s\n", debArches[p.Arch])
+
+	if p.Maintainer != "" {
+		fmt.Fprintf(&b, "Maintainer: %s\n", p.Maintainer)
+	}
+
+	fmt.Fprintf(&b, "Installed-Size: %d\n", (p.installedSize()+1023)/1024)
+	b.WriteString("Section: utils\nPriority: optional\n")
+
+	if p.Homepage != "" {
+		fmt.Fprintf(&b, "Homepage: %s\n", p.Homepage)
+	}
+
+	fmt.Fprintf(&b, "Description: %s\n", p.Summary)
+
+	// Continuation lines start with a space and are wrapped; a lone dot separates paragraphs.
+	for i, para := range strings.Split(strings.TrimSpace(p.Description), "\n\n") {
+		if i > 0 {
+			b.WriteString(" .\n")
+		}
+
+		line := ""
+
+		for _, word := range strings.Fields(para) {
+			if line != "" && len(line)+1+len(word) > 79 {
+				fmt.Fprintf(&b, "%s\n", line)
+
+				line = ""
+			}
+
+			line += " " + word
+		}
+
+		if line != "" {
+			fmt.Fprintf(&b, "%s\n", line)
+		}
+	}
+
+	var conffiles, sums strings.Builder
+
+	for _, f := range p.files() {
+		if f.Mode.IsDir() {
+			continue
+		}
+
+		if f.Config {
+			fmt.Fprintf(&conffiles, "%s\n", f.Path)
+		}
+
+		fmt.Fprintf(&sums, "%x  %s\n", md5.Sum(f.Data), f.Path[1:])
+	}
+
+	files := []File{
+		{Path: "control", Mode: 0o644, Data: []byte(b.String())},
+		{Path: "md5sums", Mode: 0o644, Data: []byte(sums.String())},
+	}
+
+	if conffiles.Len() > 0 {
+		files = append(files, File{Path: "conffiles", Mode: 0o644, Data: []byte(conffiles.String())})
+	}
+
+	for _, s := range []struct{ name, script string }{
+		{"postinst", p.PostInstall},
+		{"prerm", p.PreRemove},
+		{"postrm", p.PostRemove},
+	} {
+		if s.script != "" {
+			files = append(files, File{Path: s.name, Mode: 0o755, Data: []byte(s.script)})
+		}
+	}
+
+	return tarGz(files, nil, p.Time)
+}
+
+func debData(p *Package) ([]byte, error) {
+	files := p.files()
+
+	for i := range files {
+		files[i].Path = files[i].Path[1:]
+	}
+
+	var dirs []string
+
+	for _, dir := range p.parents() {
+		dirs = append(dirs, dir[1:])
+	}
+
+	return tarGz(files, dirs, p.Time)
+}
+
+// tarGz archives files, relative to ".", after creating dirs. Everything belongs to root.
+func tarGz(files []File, dirs []string, mtime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	tw := tar.NewWriter(gz)
+
+	entries := []File{{Path: "", Mode: fs.ModeDir | 0o755}}
+
+	for _, dir := range dirs {
+		entries = append(entries, File{Path: dir, Mode: fs.ModeDir | 0o755})
+	}
+
+	for _, f := range append(entries, files...) {
+		hdr := &tar.Header{
+			Name:    "./" + f.Path,
+			Mode:    int64(f.Mode.Perm()),
+			ModTime: mtime,
+			Uname:   "root",
+			Gname:   "root",
+			Format:  tar.FormatGNU,
+		}
+
+		if f.Mode.IsDir() {
+			hdr.Typeflag = tar.TypeDir
+
+			if f.Path != "" {
+				hdr.Name += "/"
+			}
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(f.Data))
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err == nil {
+			_, err = tw.Write(f.Data)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tw.Close()
+	if err == nil {
+		err = gz.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,178 @@
+// Package packaging builds the Debian and RPM packages of the infnoise command: the binary, its systemd units,
+// the udev rule, and a default configuration, written directly from Go without dpkg-deb or rpmbuild.
+//
+// The unit, rule, configuration, and maintainer scripts are templates embedded from the assets directory, so the
+// packages and "infnoise install" ship the same files.
+package packaging
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// File is one file or directory in a package.
+type File struct {
+	// Path is absolute; Mode holds the permission bits, and fs.ModeDir for a directory.
+	Path string
+	Mode fs.FileMode
+	Data []byte
+
+	// Config marks a configuration file, which package upgrades do not overwrite once the administrator edited it.
+	Config bool
+}
+
+// Package describes a package. Version is the upstream version (a leading "v" is dropped) and Release the
+// packaging revision (default "1"); Arch is a GOARCH.
+type Package struct {
+	Name    string
+	Version string
+	Release string
+	Arch    string
+
+	Maintainer  string
+	Summary     string
+	Description string
+	Homepage    string
+	License     string
+
+	Files []File
+
+	// PostInstall, PreRemove, and PostRemove are shell scripts. They are called with dpkg's arguments in Debian
+	// packages ("configure", "remove", "purge", ...) and with the count of remaining installs in RPM packages.
+	PostInstall string
+	PreRemove   string
+	PostRemove  string
+
+	// Time is the modification time of every file and the build time; set it from SOURCE_DATE_EPOCH for
+	// reproducible packages.
+	Time time.Time
+}
+
+// debArches and rpmArches name GOARCH values in each format's terms.
+var (
+	debArches = map[string]string{
+		"amd64":   "amd64",
+		"arm64":   "arm64",
+		"arm":     "armhf",
+		"386":     "i386",
+		"riscv64": "riscv64",
+		"ppc64le": "ppc64el",
+		"s390x":   "s390x",
+	}
+
+	rpmArches = map[string]string{
+		"amd64":   "x86_64",
+		"arm64":   "aarch64",
+		"arm":     "armv7hl",
+		"386":     "i686",
+		"riscv64": "riscv64",
+		"ppc64le": "ppc64le",
+		"s390x":   "s390x",
+	}
+)
+
+func (p *Package) validate() error {
+	if p.Name == "" || strings.ContainsAny(p.Name, " /_") {
+		return fmt.Errorf("invalid package name %q", p.Name)
+	}
+
+	if p.version() == "" || strings.ContainsAny(p.version(), " -") {
+		return fmt.Errorf("invalid version %q", p.Version)
+	}
+
+	if strings.ContainsAny(p.release(), " -") {
+		return fmt.Errorf("invalid release %q", p.Release)
+	}
+
+	if p.Summary == "" || strings.Contains(p.Summary, "\n") {
+		return fmt.Errorf("invalid summary %q", p.Summary)
+	}
+
+	if _, ok := debArches[p.Arch]; !ok {
+		return fmt.Errorf("unsupported architecture %q", p.Arch)
+	}
+
+	seen := map[string]bool{}
+
+	for _, f := range p.Files {
+		if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path || f.Path == "/" {
+			return fmt.Errorf("invalid file path %q", f.Path)
+		}
+
+		if seen[f.Path] {
+			return fmt.Errorf("duplicate file %s", f.Path)
+		}
+
+		seen[f.Path] = true
+	}
+
+	return nil
+}
+
+func (p *Package) version() string {
+	return strings.TrimPrefix(p.Version, "v")
+}
+
+func (p *Package) release() string {
+	return cmp.Or(p.Release, "1")
+}
+
+// files returns p.Files sorted by path.
+func (p *Package) files() []File {
+	files := slices.Clone(p.Files)
+
+	slices.SortFunc(files, func(a, b File) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return files
+}
+
+// installedSize is the total size of p's regular files.
+func (p *Package) installedSize() int64 {
+	var n int64
+
+	for _, f := range p.Files {
+		if !f.Mode.IsDir() {
+			n += int64(len(f.Data))
+		}
+	}
+
+	return n
+}
+
+// parents returns the directories above p's files that p does not list itself, sorted.
+func (p *Package) parents() []string {
+	var dirs []string
+
+	for _, f := range p.Files {
+		for dir := path.Dir(f.Path); dir != "/"; dir = path.Dir(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	slices.Sort(dirs)
+
+	dirs = slices.Compact(dirs)
+
+	return slices.DeleteFunc(dirs, func(dir string) bool {
+		return slices.ContainsFunc(p.Files, func(f File) bool {
+			return f.Path == dir
+		})
+	})
+}
+
+// DebName returns the conventional file name of p's Debian package.
+func (p *Package) DebName() string {
+	return fmt.Sprintf("%s_%s-%s_%s.deb", p.Name, p.version(), p.release(), debArches[p.Arch])
+}
+
+// RPMName returns the conventional file name of p's RPM package.
+func (p *Package) RPMName() string {
+	return fmt.Sprintf("%s-%s-%s.%s.rpm", p.Name, p.version(), p.release(), rpmArches[p.Arch])
+}
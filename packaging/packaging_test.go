@@ -0,0 +1,452 @@
+package packaging
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coalaura/infnoise"
+)
+
+func testPackage(t *testing.T) *Package {
+	files, err := Assets(DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	postInstall, preRemove, postRemove, err := Scripts(DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Package{
+		Name:        "infnoise",
+		Version:     "v1.2.3",
+		Arch:        "arm64",
+		Maintainer:  "Test <test@example.com>",
+		Summary:     "test package",
+		Description: strings.Repeat("A long description. ", 10) + "\n\nA second paragraph.",
+		Files:       append(files, File{Path: DefaultLayout.Bin, Mode: 0o755, Data: []byte("\x7fELF binary")}),
+		PostInstall: postInstall,
+		PreRemove:   preRemove,
+		PostRemove:  postRemove,
+		Time:        time.Unix(1700000000, 0),
+	}
+}
+
+func TestAssets(t *testing.T) {
+	if DefaultLayout.Group != infnoise.DefaultUdevGroup {
+		t.Errorf("layout group %q, udev group %q", DefaultLayout.Group, infnoise.DefaultUdevGroup)
+	}
+
+	files, err := Assets(DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := map[string]string{}
+
+	for _, f := range files {
+		contents[f.Path] = string(f.Data)
+	}
+
+	// The packaged rule must grant what "infnoise setup-udev" grants.
+	var rule []string
+
+	for line := range strings.Lines(contents["/usr/lib/udev/rules.d/75-infnoise.rules"]) {
+		if !strings.HasPrefix(line, "#") {
+			rule = append(rule, line)
+		}
+	}
+
+	if got, want := strings.Join(rule, ""), infnoise.UdevRule(DefaultLayout.Group); got != want {
+		t.Errorf("rule %q, want %q", got, want)
+	}
+
+	unit := contents["/usr/lib/systemd/system/infnoise.service"]
+
+	for _, want := range []string{"EnvironmentFile=-/etc/infnoise/infnoise.conf", "ExecStart=/usr/bin/infnoise appliance $INFNOISE_OPTS"} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit lacks %q:\n%s", want, unit)
+		}
+	}
+
+	if !strings.Contains(contents["/usr/lib/systemd/system/infnoise-seed.service"], "ExecStart=/usr/bin/infnoise seed") {
+		t.Error("seed unit does not run the packaged binary")
+	}
+
+	if !strings.Contains(contents["/etc/infnoise/infnoise.conf"], "\nINFNOISE_TOKEN_FILE=/etc/infnoise/token\n") {
+		t.Errorf("config lacks the token file:\n%s", contents["/etc/infnoise/infnoise.conf"])
+	}
+
+	postInstall, _, _, err := Scripts(DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(postInstall, "mkdir -p /etc/infnoise\n") {
+		t.Errorf("postinst does not create the token directory:\n%s", postInstall)
+	}
+}
+
+func TestDeb(t *testing.T) {
+	p := testPackage(t)
+
+	if got, want := p.DebName(), "infnoise_1.2.3-1_arm64.deb"; got != want {
+		t.Errorf("name %q, want %q", got, want)
+	}
+
+	var b bytes.Buffer
+
+	err := WriteDeb(&b, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members := readAr(t, b.Bytes())
+
+	if got := string(members["debian-binary"]); got != "2.0\n" {
+		t.Errorf("debian-binary %q", got)
+	}
+
+	control := readTarGz(t, members["control.tar.gz"])
+
+	for _, want := range []string{"Package: infnoise\n", "Version: 1.2.3-1\n", "Architecture: arm64\n", "Description: test package\n", "\n .\n A second paragraph.\n"} {
+		if !strings.Contains(control["./control"], want) {
+			t.Errorf("control lacks %q:\n%s", want, control["./control"])
+		}
+	}
+
+	for line := range strings.Lines(control["./control"]) {
+		if len(line) > 81 {
+			t.Errorf("control line too long: %q", line)
+		}
+	}
+
+	if got := control["./conffiles"]; got != "/etc/infnoise/infnoise.conf\n" {
+		t.Errorf("conffiles %q", got)
+	}
+
+	if !strings.HasPrefix(control["./postinst"], "#!/bin/sh\n") {
+		t.Errorf("postinst %q", control["./postinst"])
+	}
+
+	data := readTarGz(t, members["data.tar.gz"])
+
+	for _, dir := range []string{"./", "./usr/", "./usr/bin/", "./etc/infnoise/"} {
+		if _, ok := data[dir]; !ok {
+			t.Errorf("data lacks directory %s", dir)
+		}
+	}
+
+	for _, f := range p.Files {
+		if f.Mode.IsDir() {
+			continue
+		}
+
+		if got := data["."+f.Path]; got != string(f.Data) {
+			t.Errorf("%s: got %q", f.Path, got)
+		}
+
+		sum := fmt.Sprintf("%x  %s\n", md5.Sum(f.Data), f.Path[1:])
+
+		if !strings.Contains(control["./md5sums"], sum) {
+			t.Errorf("md5sums lacks %q", sum)
+		}
+	}
+}
+
+func TestRPM(t *testing.T) {
+	p := testPackage(t)
+
+	if got, want := p.RPMName(), "infnoise-1.2.3-1.aarch64.rpm"; got != want {
+		t.Errorf("name %q, want %q", got, want)
+	}
+
+	var b bytes.Buffer
+
+	err := WriteRPM(&b, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpm := b.Bytes()
+
+	if !bytes.HasPrefix(rpm, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0}) {
+		t.Fatalf("bad lead % x", rpm[:8])
+	}
+
+	sig, sigEnd := readRPMHeader(t, rpm, 96, rpmTagHeaderSignatures)
+
+	headerStart := (sigEnd + 7) &^ 7
+
+	main, headerEnd := readRPMHeader(t, rpm, headerStart, rpmTagHeaderImmutable)
+
+	header, payload := rpm[headerStart:headerEnd], rpm[headerEnd:]
+
+	headerSum := sha256.Sum256(header)
+
+	if got := sig[rpmSigTagSHA256].strings[0]; got != hex.EncodeToString(headerSum[:]) {
+		t.Errorf("header digest %s", got)
+	}
+
+	if got := sig[rpmSigTagSize].int32s[0]; int(got) != len(header)+len(payload) {
+		t.Errorf("size %d, want %d", got, len(header)+len(payload))
+	}
+
+	if got, want := sig[rpmSigTagMD5].bin, md5.Sum(rpm[headerStart:]); !bytes.Equal(got, want[:]) {
+		t.Errorf("md5 %x, want %x", got, want)
+	}
+
+	for tag, want := range map[int32]string{rpmTagName: "infnoise", rpmTagVersion: "1.2.3", rpmTagRelease: "1", rpmTagArch: "aarch64", rpmTagOS: "linux"} {
+		if got := main[tag].strings[0]; got != want {
+			t.Errorf("tag %d: %q, want %q", tag, got, want)
+		}
+	}
+
+	if !strings.Contains(main[rpmTagPostIn].strings[0], "udevadm") {
+		t.Error("missing post-install script")
+	}
+
+	// Each payload entry is a file of the header, in the same order.
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sig[rpmSigTagPayloadSize].int32s[0]; int(got) != len(archive) {
+		t.Errorf("payload size %d, want %d", got, len(archive))
+	}
+
+	files := p.files()
+
+	for i := 0; ; i++ {
+		if len(archive) < 110 || string(archive[:6]) != "070701" {
+			t.Fatalf("bad cpio header at entry %d", i)
+		}
+
+		field := func(n int) int {
+			v, err := strconv.ParseUint(string(archive[6+8*n:14+8*n]), 16, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return int(v)
+		}
+
+		mode, size, nameSize := field(1), field(6), field(11)
+
+		name := string(archive[110 : 110+nameSize-1])
+		dataStart := (110 + nameSize + 3) &^ 3
+		data := archive[dataStart : dataStart+size]
+
+		archive = archive[(dataStart+size+3)&^3:]
+
+		if name == "TRAILER!!!" {
+			if i != len(files) {
+				t.Errorf("%d entries, want %d", i, len(files))
+			}
+
+			break
+		}
+
+		f := files[i]
+
+		dir := main[rpmTagDirNames].strings[main[rpmTagDirIndexes].int32s[i]]
+
+		if name != "."+f.Path || dir+main[rpmTagBaseNames].strings[i] != f.Path {
+			t.Errorf("entry %d: %s (header %s), want %s", i, name, dir+main[rpmTagBaseNames].strings[i], f.Path)
+		}
+
+		if fs.FileMode(mode).Perm() != f.Mode.Perm() || !bytes.Equal(data, f.Data) {
+			t.Errorf("%s: mode %o, %d bytes", f.Path, mode, len(data))
+		}
+
+		sum := sha256.Sum256(f.Data)
+
+		if !f.Mode.IsDir() && main[rpmTagFileDigests].strings[i] != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: digest %s", f.Path, main[rpmTagFileDigests].strings[i])
+		}
+
+		if (main[rpmTagFileFlags].int32s[i]&rpmFileConfig != 0) != f.Config {
+			t.Errorf("%s: flags %#x", f.Path, main[rpmTagFileFlags].int32s[i])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, change := range map[string]func(p *Package){
+		"no version":    func(p *Package) { p.Version = "" },
+		"dash":          func(p *Package) { p.Version = "1.0-rc1" },
+		"arch":          func(p *Package) { p.Arch = "mips" },
+		"relative path": func(p *Package) { p.Files[0].Path = "usr/bin/x" },
+		"duplicate":     func(p *Package) { p.Files = append(p.Files, p.Files[0]) },
+	} {
+		p := testPackage(t)
+
+		change(p)
+
+		if WriteDeb(io.Discard, p) == nil || WriteRPM(io.Discard, p) == nil {
+			t.Errorf("%s: package accepted", name)
+		}
+	}
+}
+
+func readAr(t *testing.T, b []byte) map[string][]byte {
+	t.Helper()
+
+	if !bytes.HasPrefix(b, []byte("!<arch>\n")) {
+		t.Fatal("not an ar archive")
+	}
+
+	members := map[string][]byte{}
+
+	for b = b[8:]; len(b) > 0; {
+		if len(b) < 60 || string(b[58:60]) != "`\n" {
+			t.Fatal("bad ar member header")
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(string(b[48:58])))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		members[strings.TrimSpace(string(b[:16]))] = b[60 : 60+size]
+
+		b = b[min(len(b), 60+size+size%2):]
+	}
+
+	return members
+}
+
+func readTarGz(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(zr)
+
+	files := map[string]string{}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hdr.Uname != "root" {
+			t.Errorf("%s owned by %s", hdr.Name, hdr.Uname)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[hdr.Name] = string(data)
+	}
+}
+
+// rpmValue is a decoded header entry.
+type rpmValue struct {
+	strings []string
+	int32s  []int32
+	bin     []byte
+}
+
+// readRPMHeader decodes the header at off, checking its region tag, and returns its entries and end.
+func readRPMHeader(t *testing.T, b []byte, off int, region int32) (map[int32]rpmValue, int) {
+	t.Helper()
+
+	if !bytes.Equal(b[off:off+4], []byte{0x8e, 0xad, 0xe8, 0x01}) {
+		t.Fatalf("no header magic at %d", off)
+	}
+
+	n := int(binary.BigEndian.Uint32(b[off+8:]))
+	size := int(binary.BigEndian.Uint32(b[off+12:]))
+	index := b[off+16 : off+16+16*n]
+	store := b[off+16+16*n : off+16+16*n+size]
+
+	entry := func(i int) (tag, typ int32, offset, count int) {
+		e := index[16*i:]
+
+		return int32(binary.BigEndian.Uint32(e)), int32(binary.BigEndian.Uint32(e[4:])), int(int32(binary.BigEndian.Uint32(e[8:]))), int(binary.BigEndian.Uint32(e[12:]))
+	}
+
+	if tag, typ, offset, count := entry(0); tag != region || typ != rpmBin || count != 16 || offset != size-16 {
+		t.Fatalf("bad region entry %d %d %d %d", tag, typ, offset, count)
+	}
+
+	trailer := store[size-16:]
+
+	if int32(binary.BigEndian.Uint32(trailer)) != region || int32(binary.BigEndian.Uint32(trailer[8:])) != int32(-16*n) {
+		t.Fatalf("bad region trailer % x", trailer)
+	}
+
+	values := map[int32]rpmValue{}
+
+	end := 0
+
+	for i := 1; i < n; i++ {
+		tag, typ, offset, count := entry(i)
+
+		if offset < end {
+			t.Fatalf("tag %d overlaps its predecessor", tag)
+		}
+
+		var v rpmValue
+
+		data := store[offset:]
+
+		switch typ {
+		case rpmString, rpmStringArray, rpmI18NString:
+			for range count {
+				s, _, _ := bytes.Cut(data, []byte{0})
+
+				v.strings = append(v.strings, string(s))
+				data = data[len(s)+1:]
+			}
+		case rpmInt32:
+			for j := range count {
+				v.int32s = append(v.int32s, int32(binary.BigEndian.Uint32(data[4*j:])))
+			}
+
+			data = data[4*count:]
+		case rpmInt16:
+			data = data[2*count:]
+		case rpmBin:
+			v.bin = data[:count]
+			data = data[count:]
+		default:
+			t.Fatalf("tag %d has unexpected type %d", tag, typ)
+		}
+
+		end = len(store) - len(data)
+		values[tag] = v
+	}
+
+	return values, off + 16 + 16*n + size
+}
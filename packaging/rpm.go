@@ -0,0 +1,446 @@
+package packaging
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+)
+
+// RPM header tags, from rpmtag.h.
+const (
+	rpmTagHeaderSignatures = 62
+	rpmTagHeaderImmutable  = 63
+	rpmTagI18NTable        = 100
+
+	rpmSigTagSHA1        = 269
+	rpmSigTagSHA256      = 273
+	rpmSigTagSize        = 1000
+	rpmSigTagMD5         = 1004
+	rpmSigTagPayloadSize = 1007
+
+	rpmTagName              = 1000
+	rpmTagVersion           = 1001
+	rpmTagRelease           = 1002
+	rpmTagSummary           = 1004
+	rpmTagDescription       = 1005
+	rpmTagBuildTime         = 1006
+	rpmTagBuildHost         = 1007
+	rpmTagSize              = 1009
+	rpmTagLicense           = 1014
+	rpmTagGroup             = 1016
+	rpmTagURL               = 1020
+	rpmTagOS                = 1021
+	rpmTagArch              = 1022
+	rpmTagPostIn            = 1024
+	rpmTagPreUn             = 1025
+	rpmTagPostUn            = 1026
+	rpmTagFileSizes         = 1028
+	rpmTagFileModes         = 1030
+	rpmTagFileRdevs         = 1033
+	rpmTagFileMtimes        = 1034
+	rpmTagFileDigests       = 1035
+	rpmTagFileLinkTos       = 1036
+	rpmTagFileFlags         = 1037
+	rpmTagFileUserName      = 1039
+	rpmTagFileGroupName     = 1040
+	rpmTagProvideName       = 1047
+	rpmTagRequireFlags      = 1048
+	rpmTagRequireName       = 1049
+	rpmTagRequireVersion    = 1050
+	rpmTagFileDevices       = 1095
+	rpmTagFileInodes        = 1096
+	rpmTagFileLangs         = 1097
+	rpmTagProvideFlags      = 1112
+	rpmTagProvideVersion    = 1113
+	rpmTagDirIndexes        = 1116
+	rpmTagBaseNames         = 1117
+	rpmTagDirNames          = 1118
+	rpmTagPayloadFormat     = 1124
+	rpmTagPayloadCompressor = 1125
+	rpmTagPayloadFlags      = 1126
+	rpmTagFileDigestAlgo    = 5011
+	rpmTagPayloadDigest     = 5092
+	rpmTagPayloadDigestAlgo = 5093
+)
+
+// RPM header entry types.
+const (
+	rpmInt16       = 3
+	rpmInt32       = 4
+	rpmString      = 6
+	rpmBin         = 7
+	rpmStringArray = 8
+	rpmI18NString  = 9
+)
+
+const (
+	rpmFileConfig    = 1 << 0
+	rpmFileNoReplace = 1 << 4
+
+	rpmSenseLess   = 1 << 1
+	rpmSenseEqual  = 1 << 3
+	rpmSenseRPMLib = 1 << 24
+
+	// rpmDigestSHA256 is PGPHASHALGO_SHA256.
+	rpmDigestSHA256 = 8
+)
+
+// rpmEntry is one tag of an RPM header, its value already encoded big-endian.
+type rpmEntry struct {
+	tag, typ int32
+	count    int
+	data     []byte
+}
+
+type rpmHeader []rpmEntry
+
+func (h *rpmHeader) add(tag, typ int32, count int, data []byte) {
+	*h = append(*h, rpmEntry{tag: tag, typ: typ, count: count, data: data})
+}
+
+func (h *rpmHeader) string(tag int32, s string) {
+	h.add(tag, rpmString, 1, append([]byte(s), 0))
+}
+
+// i18n adds a string for the single locale "C" listed in the I18N table.
+func (h *rpmHeader) i18n(tag int32, s string) {
+	h.add(tag, rpmI18NString, 1, append([]byte(s), 0))
+}
+
+func (h *rpmHeader) strings(tag int32, ss []string) {
+	var data []byte
+
+	for _, s := range ss {
+		data = append(append(data, s...), 0)
+	}
+
+	h.add(tag, rpmStringArray, len(ss), data)
+}
+
+func (h *rpmHeader) int32s(tag int32, vs ...int32) {
+	var data []byte
+
+	for _, v := range vs {
+		data = binary.BigEndian.AppendUint32(data, uint32(v))
+	}
+
+	h.add(tag, rpmInt32, len(vs), data)
+}
+
+func (h *rpmHeader) int16s(tag int32, vs ...uint16) {
+	var data []byte
+
+	for _, v := range vs {
+		data = binary.BigEndian.AppendUint16(data, v)
+	}
+
+	h.add(tag, rpmInt16, len(vs), data)
+}
+
+func (h *rpmHeader) bin(tag int32, b []byte) {
+	h.add(tag, rpmBin, len(b), b)
+}
+
+// marshal encodes the header behind a region tag covering all of it, with the entries sorted by tag and their data
+// in the same order, as rpm requires.
+func (h rpmHeader) marshal(region int32) []byte {
+	entries := slices.Clone(h)
+
+	slices.SortStableFunc(entries, func(a, b rpmEntry) int {
+		return cmp.Compare(a.tag, b.tag)
+	})
+
+	var index, store []byte
+
+	entry := func(tag, typ int32, offset, count int) {
+		for _, v := range []int32{tag, typ, int32(offset), int32(count)} {
+			index = binary.BigEndian.AppendUint32(index, uint32(v))
+		}
+	}
+
+	for _, e := range entries {
+		align := map[int32]int{rpmInt16: 2, rpmInt32: 4}[e.typ]
+
+		for align > 0 && len(store)%align != 0 {
+			store = append(store, 0)
+		}
+
+		entry(e.tag, e.typ, len(store), e.count)
+
+		store = append(store, e.data...)
+	}
+
+	// The region trailer is an index entry pointing back over the whole index.
+	n := len(entries) + 1
+
+	var trailer []byte
+
+	for _, v := range []int32{region, rpmBin, int32(-16 * n), 16} {
+		trailer = binary.BigEndian.AppendUint32(trailer, uint32(v))
+	}
+
+	// The region entry comes first in the index and its trailer last in the store.
+	rest := index
+	index = nil
+
+	entry(region, rpmBin, len(store), 16)
+
+	index = append(index, rest...)
+	store = append(store, trailer...)
+
+	out := []byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0}
+	out = binary.BigEndian.AppendUint32(out, uint32(n))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(store)))
+	out = append(out, index...)
+
+	return append(out, store...)
+}
+
+// WriteRPM writes p as an RPM package (format 3, as written by rpm 4): the lead, a signature header with the
+// digests but no signature, the header, and a gzip-compressed cpio payload.
+func WriteRPM(w io.Writer, p *Package) error {
+	err := p.validate()
+	if err != nil {
+		return err
+	}
+
+	files := p.files()
+
+	payload, payloadSize, err := rpmPayload(files, p.Time.Unix())
+	if err != nil {
+		return err
+	}
+
+	header := rpmMainHeader(p, files, payload).marshal(rpmTagHeaderImmutable)
+
+	sha1Sum, sha256Sum := sha1.Sum(header), sha256.Sum256(header)
+	md5Sum := md5.New()
+
+	md5Sum.Write(header)
+	md5Sum.Write(payload)
+
+	var sig rpmHeader
+
+	sig.string(rpmSigTagSHA1, hex.EncodeToString(sha1Sum[:]))
+	sig.string(rpmSigTagSHA256, hex.EncodeToString(sha256Sum[:]))
+	sig.int32s(rpmSigTagSize, int32(len(header)+len(payload)))
+	sig.bin(rpmSigTagMD5, md5Sum.Sum(nil))
+	sig.int32s(rpmSigTagPayloadSize, int32(payloadSize))
+
+	signature := sig.marshal(rpmTagHeaderSignatures)
+
+	// The signature header is padded to a multiple of 8 bytes.
+	signature = append(signature, make([]byte, (8-len(signature)%8)%8)...)
+
+	lead := make([]byte, 96)
+
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0})
+	copy(lead[10:75], fmt.Sprintf("%s-%s-%s", p.Name, p.version(), p.release()))
+
+	binary.BigEndian.PutUint16(lead[76:], 1) // Linux
+	binary.BigEndian.PutUint16(lead[78:], 5) // header-style signature
+
+	for _, b := range [][]byte{lead, signature, header, payload} {
+		_, err = w.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rpmMainHeader(p *Package, files []File, payload []byte) rpmHeader {
+	var h rpmHeader
+
+	h.strings(rpmTagI18NTable, []string{"C"})
+	h.string(rpmTagName, p.Name)
+	h.string(rpmTagVersion, p.version())
+	h.string(rpmTagRelease, p.release())
+	h.i18n(rpmTagSummary, p.Summary)
+	h.i18n(rpmTagDescription, cmp.Or(p.Description, p.Summary))
+	h.int32s(rpmTagBuildTime, int32(p.Time.Unix()))
+	h.string(rpmTagBuildHost, "localhost")
+	h.int32s(rpmTagSize, int32(p.installedSize()))
+	h.string(rpmTagLicense, cmp.Or(p.License, "Unspecified"))
+	h.i18n(rpmTagGroup, "Unspecified")
+
+	if p.Homepage != "" {
+		h.string(rpmTagURL, p.Homepage)
+	}
+
+	h.string(rpmTagOS, "linux")
+	h.string(rpmTagArch, rpmArches[p.Arch])
+
+	for _, s := range []struct {
+		tag    int32
+		script string
+	}{
+		{rpmTagPostIn, p.PostInstall},
+		{rpmTagPreUn, p.PreRemove},
+		{rpmTagPostUn, p.PostRemove},
+	} {
+		if s.script != "" {
+			h.string(s.tag, s.script)
+		}
+	}
+
+	var (
+		sizes, mtimes, flags, devices, inodes, dirIndexes []int32
+		modes, rdevs                                      []uint16
+		digests, links, users, langs, baseNames, dirNames []string
+	)
+
+	for i, f := range files {
+		mode, size, digest := uint16(0o100000), int32(len(f.Data)), sha256.Sum256(f.Data)
+
+		hexDigest := hex.EncodeToString(digest[:])
+
+		if f.Mode.IsDir() {
+			mode, size, hexDigest = 0o040000, 4096, ""
+		}
+
+		var flag int32
+		if f.Config {
+			flag = rpmFileConfig | rpmFileNoReplace
+		}
+
+		dir := path.Dir(f.Path) + "/"
+		if dir == "//" {
+			dir = "/"
+		}
+
+		if !slices.Contains(dirNames, dir) {
+			dirNames = append(dirNames, dir)
+		}
+
+		sizes = append(sizes, size)
+		modes = append(modes, mode|uint16(f.Mode.Perm()))
+		rdevs = append(rdevs, 0)
+		mtimes = append(mtimes, int32(p.Time.Unix()))
+		digests = append(digests, hexDigest)
+		links = append(links, "")
+		flags = append(flags, flag)
+		users = append(users, "root")
+		devices = append(devices, 1)
+		inodes = append(inodes, int32(i+1))
+		langs = append(langs, "")
+		dirIndexes = append(dirIndexes, int32(slices.Index(dirNames, dir)))
+		baseNames = append(baseNames, path.Base(f.Path))
+	}
+
+	if len(files) > 0 {
+		h.int32s(rpmTagFileSizes, sizes...)
+		h.int16s(rpmTagFileModes, modes...)
+		h.int16s(rpmTagFileRdevs, rdevs...)
+		h.int32s(rpmTagFileMtimes, mtimes...)
+		h.strings(rpmTagFileDigests, digests)
+		h.strings(rpmTagFileLinkTos, links)
+		h.int32s(rpmTagFileFlags, flags...)
+		h.strings(rpmTagFileUserName, users)
+		h.strings(rpmTagFileGroupName, users)
+		h.int32s(rpmTagFileDevices, devices...)
+		h.int32s(rpmTagFileInodes, inodes...)
+		h.strings(rpmTagFileLangs, langs)
+		h.int32s(rpmTagDirIndexes, dirIndexes...)
+		h.strings(rpmTagBaseNames, baseNames)
+		h.strings(rpmTagDirNames, dirNames)
+		h.int32s(rpmTagFileDigestAlgo, rpmDigestSHA256)
+	}
+
+	evr := p.version() + "-" + p.release()
+
+	h.strings(rpmTagProvideName, []string{p.Name})
+	h.int32s(rpmTagProvideFlags, rpmSenseEqual)
+	h.strings(rpmTagProvideVersion, []string{evr})
+
+	// The rpmlib features this package relies on.
+	requires := [][2]string{
+		{"rpmlib(CompressedFileNames)", "3.0.4-1"},
+		{"rpmlib(FileDigests)", "4.6.0-1"},
+		{"rpmlib(PayloadFilesHavePrefix)", "4.0-1"},
+	}
+
+	var names, versions []string
+	var requireFlags []int32
+
+	for _, r := range requires {
+		names = append(names, r[0])
+		versions = append(versions, r[1])
+		requireFlags = append(requireFlags, rpmSenseRPMLib|rpmSenseLess|rpmSenseEqual)
+	}
+
+	h.strings(rpmTagRequireName, names)
+	h.int32s(rpmTagRequireFlags, requireFlags...)
+	h.strings(rpmTagRequireVersion, versions)
+
+	h.string(rpmTagPayloadFormat, "cpio")
+	h.string(rpmTagPayloadCompressor, "gzip")
+	h.string(rpmTagPayloadFlags, "9")
+
+	payloadDigest := sha256.Sum256(payload)
+
+	h.strings(rpmTagPayloadDigest, []string{hex.EncodeToString(payloadDigest[:])})
+	h.int32s(rpmTagPayloadDigestAlgo, rpmDigestSHA256)
+
+	return h
+}
+
+// rpmPayload archives files as gzip-compressed cpio (the "new ASCII" format), returning the uncompressed size too.
+func rpmPayload(files []File, mtime int64) ([]byte, int, error) {
+	var archive bytes.Buffer
+
+	entry := func(ino int, mode uint32, name string, data []byte) {
+		nlink := 1
+		if mode&0o040000 != 0 {
+			nlink = 2
+		}
+
+		fmt.Fprintf(&archive, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+			ino, mode, 0, 0, nlink, mtime, len(data), 0, 0, 0, 0, len(name)+1, 0)
+
+		archive.WriteString(name)
+		archive.WriteByte(0)
+		archive.Write(make([]byte, (4-archive.Len()%4)%4))
+		archive.Write(data)
+		archive.Write(make([]byte, (4-archive.Len()%4)%4))
+	}
+
+	for i, f := range files {
+		mode := uint32(0o100000)
+		if f.Mode.IsDir() {
+			mode = 0o040000
+		}
+
+		entry(i+1, mode|uint32(f.Mode.Perm()), "."+f.Path, f.Data)
+	}
+
+	entry(0, 0, "TRAILER!!!", nil)
+
+	var buf bytes.Buffer
+
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = gz.Write(archive.Bytes())
+	if err == nil {
+		err = gz.Close()
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return buf.Bytes(), archive.Len(), nil
+}
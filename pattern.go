@@ -0,0 +1,62 @@
+package infnoise
+
+import "fmt"
+
+// SwitchOrder selects which of the two multiplier switches is enabled on even output samples.
+type SwitchOrder int
+
+const (
+	// SwitchSWEN1First enables SWEN1 on even samples and SWEN2 on odd ones, as the stock firmware does.
+	SwitchSWEN1First SwitchOrder = iota
+
+	// SwitchSWEN2First enables SWEN2 on even samples and SWEN1 on odd ones.
+	SwitchSWEN2First
+)
+
+// Pattern describes the output sequence clocked out in bitbang mode: the multiplier switches alternate every
+// sample while the address pins sweep a range, one step per sample.
+type Pattern struct {
+	// FirstAddress and LastAddress bound the sweep driven on ADDR0..ADDR3 (both 0..15).
+	FirstAddress, LastAddress uint8
+
+	// Order selects which switch is enabled on even samples.
+	Order SwitchOrder
+}
+
+// DefaultPattern returns the pattern used by the stock board: SWEN1 first and a full 0..15 address sweep.
+func DefaultPattern() Pattern {
+	return Pattern{
+		FirstAddress: 0,
+		LastAddress:  15,
+		Order:        SwitchSWEN1First,
+	}
+}
+
+// Validate reports whether the pattern can be driven.
+func (p Pattern) Validate() error {
+	if p.LastAddress > 15 || p.FirstAddress > p.LastAddress {
+		return fmt.Errorf("invalid address range %d..%d (need 0 <= first <= last <= 15)", p.FirstAddress, p.LastAddress)
+	}
+
+	if p.Order != SwitchSWEN1First && p.Order != SwitchSWEN2First {
+		return fmt.Errorf("invalid switch order %d", p.Order)
+	}
+
+	return nil
+}
+
+// address returns the address driven for sample i.
+func (p Pattern) address(i int) uint8 {
+	span := int(p.LastAddress-p.FirstAddress) + 1
+
+	return p.FirstAddress + uint8(i%span)
+}
+
+// Bytes returns the first n output bytes of the pattern on the pins of ProfileV2.
+func (p Pattern) Bytes(n int) []byte {
+	prof := ProfileV2
+
+	prof.Pattern = p
+
+	return prof.Bytes(n)
+}
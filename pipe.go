@@ -0,0 +1,152 @@
+package infnoise
+
+import "sync"
+
+// pipeDepth is the number of whitening chunks a PipeReader harvests ahead of its consumer.
+const pipeDepth = 2
+
+// PipeReader reads whitened output that a goroutine harvests ahead of the consumer, at most pipeDepth chunks (see
+// WithChunkSize) at a time (see Device.Pipe).
+type PipeReader struct {
+	// full carries harvested chunks to Read, which hands their buffers back through free once consumed.
+	full chan pipeChunk
+	free chan []byte
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	buf     []byte
+	pending []byte
+	err     error
+}
+
+type pipeChunk struct {
+	buf []byte
+	n   int
+	err error
+}
+
+// Pipe returns a reader of whitened output (see ReadWhitened) for streaming into consumers of any speed, such as a
+// slow network peer. A goroutine harvests the next chunks while the consumer works through the current one, so reads
+// rarely wait for the device, but it only harvests while the pipe has room: production follows consumption, and
+// however slow the consumer, no more than two chunks of output are buffered. The pipe stops at the first error,
+// which Read returns once the output harvested before it is consumed. Close the pipe to stop the goroutine.
+func (d *Device) Pipe() *PipeReader {
+	p := &PipeReader{
+		full: make(chan pipeChunk, pipeDepth),
+		free: make(chan []byte, pipeDepth),
+		done: make(chan struct{}),
+	}
+
+	for range pipeDepth {
+		p.free <- make([]byte, len(d.poolBuf))
+	}
+
+	p.wg.Add(1)
+
+	go p.run(d)
+
+	return p
+}
+
+func (p *PipeReader) run(d *Device) {
+	defer p.wg.Done()
+
+	for {
+		var buf []byte
+
+		// A free buffer is the pipe's room for another chunk.
+		select {
+		case buf = <-p.free:
+		case <-p.done:
+			return
+		}
+
+		var n int
+
+		err := Supervise("pipe", func() (err error) {
+			n, err = d.ReadWhitened(buf)
+
+			return err
+		})
+
+		// There are only pipeDepth buffers, so full has room for this one.
+		p.full <- pipeChunk{buf: buf, n: n, err: err}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read fills b with whitened output unless the pipe has failed, returning its error, or is closed, returning
+// ErrClosed.
+func (p *PipeReader) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var n int
+
+	for n < len(b) {
+		if len(p.pending) == 0 {
+			if p.err != nil {
+				return n, p.err
+			}
+
+			if p.buf != nil {
+				p.free <- p.buf
+
+				p.buf = nil
+			}
+
+			var c pipeChunk
+
+			select {
+			case c = <-p.full:
+			case <-p.done:
+				return n, ErrClosed
+			}
+
+			p.buf, p.pending, p.err = c.buf, c.buf[:c.n], c.err
+		}
+
+		m := copy(b[n:], p.pending)
+
+		clear(p.pending[:m])
+
+		p.pending = p.pending[m:]
+		n += m
+	}
+
+	return n, nil
+}
+
+// Close stops the pipe once the harvest in progress, if any, has finished, and wipes the output it buffered. The
+// device is left open.
+func (p *PipeReader) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	clear(p.buf)
+
+	p.buf, p.pending, p.err = nil, nil, ErrClosed
+
+	for {
+		select {
+		case c := <-p.full:
+			clear(c.buf)
+		case buf := <-p.free:
+			clear(buf)
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package infnoise
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	dev, _ := openSimulator(t, 7, DefaultSimulatorGain, WithChunkSize(64))
+
+	p := dev.Pipe()
+
+	// However long the consumer takes, the pipe harvests no more than it has room for.
+	time.Sleep(50 * time.Millisecond)
+
+	if reads := dev.Stats().Reads; reads > pipeDepth {
+		t.Fatalf("an idle pipe made %d reads, want at most %d", reads, pipeDepth)
+	}
+
+	got := make([]byte, 1000)
+
+	_, err := io.ReadFull(p, got[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = io.ReadFull(p, got[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := make([]byte, len(got))
+
+	ref, _ := openSimulator(t, 7, DefaultSimulatorGain, WithChunkSize(64))
+
+	_, err = ref.ReadWhitened(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Fatal("piped output differs from ReadWhitened")
+	}
+
+	p.Close()
+
+	if _, err := p.Read(got); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Read after Close = %v, want ErrClosed", err)
+	}
+
+	// Closing the device fails the pipe.
+	p = dev.Pipe()
+
+	dev.Close()
+
+	if _, err := io.ReadAll(p); err == nil {
+		t.Fatal("a pipe on a closed device did not fail")
+	}
+
+	p.Close()
+}
@@ -0,0 +1,370 @@
+package infnoise
+
+import (
+	"math"
+)
+
+const (
+	// PredictorBlock is the number of consecutive raw bits the prediction estimators analyze at a time; see
+	// WithPredictors for how blocks are sampled.
+	PredictorBlock = 4096
+
+	// DefaultPredictorFraction is the default fraction of blocks the prediction estimators analyze.
+	DefaultPredictorFraction = 1.0 / 64
+
+	// lagDepth, mmcDepth, and lz78yDepth are the SP 800-90B parameters D and B of the estimators.
+	lagDepth   = 128
+	mmcDepth   = 16
+	lz78yDepth = 16
+
+	// lz78yMaxEntries bounds the LZ78Y dictionary (maxDictionarySize).
+	lz78yMaxEntries = 65536
+)
+
+// mcwWindows are the window sizes of the MultiMCW subpredictors.
+var mcwWindows = [...]int{63, 255, 1023, 4095}
+
+// Prediction is the outcome of one SP 800-90B prediction estimator: how often it predicted the next raw bit
+// correctly, and the min-entropy per bit that bounds (6.3.7 to 6.3.10).
+type Prediction struct {
+	Name        string  `json:"name"`
+	Predictions uint64  `json:"predictions"`
+	Correct     uint64  `json:"correct"`
+	LongestRun  uint64  `json:"longestRun"`
+	MinEntropy  float64 `json:"minEntropy"`
+}
+
+// predictionScore tallies an estimator's predictions.
+type predictionScore struct {
+	n, correct, run, longest uint64
+}
+
+func (s *predictionScore) record(ok bool) {
+	s.n++
+
+	if !ok {
+		s.run = 0
+
+		return
+	}
+
+	s.correct++
+	s.run++
+	s.longest = max(s.longest, s.run)
+}
+
+// minEntropy is the min-entropy per bit bounded by the global and local prediction rates (SP 800-90B 6.3.7).
+func (s *predictionScore) minEntropy() float64 {
+	n := float64(s.n)
+
+	var global float64
+
+	if s.correct == 0 {
+		global = 1 - math.Pow(0.01, 1/n)
+	} else {
+		p := float64(s.correct) / n
+
+		global = min(1, p+2.576*math.Sqrt(p*(1-p)/max(n-1, 1)))
+	}
+
+	p := max(global, localPrediction(n, float64(s.longest+1)), 0.5)
+
+	return -math.Log2(p)
+}
+
+// localPrediction returns the success probability p at which a longest run of correct predictions shorter than r
+// among n predictions occurs with probability 0.99, found by bisection.
+func localPrediction(n, r float64) float64 {
+	// logProb is the log probability of no run of r successes, with x the root near 1 of 1 - x + q p^r x^(r+1).
+	logProb := func(p float64) float64 {
+		q := 1 - p
+
+		x := 1.0
+
+		for range 64 {
+			x = 1 + q*math.Pow(p, r)*math.Pow(x, r+1)
+		}
+
+		return math.Log(1-p*x) - math.Log((r+1-r*x)*q) - (n+1)*math.Log(x)
+	}
+
+	lo, hi := 0.0, 1-1e-9
+
+	for range 60 {
+		mid := (lo + hi) / 2
+
+		// NaN means x diverged, which happens for large p, where long runs are likely.
+		if lp := logProb(mid); lp > math.Log(0.99) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo
+}
+
+// predictors runs the MultiMCW, lag, MultiMMC, and LZ78Y estimators on sampled blocks of the raw bits.
+type predictors struct {
+	// period selects every period-th block; pos counts all bits seen.
+	period uint64
+	pos    uint64
+
+	// n counts the bits analyzed; hist holds the most recent 128 of them, the latest in bit 0 of hist[0].
+	n    uint64
+	hist [2]uint64
+
+	// ring holds the last 4095 analyzed bits for MultiMCW, whose windows count their ones.
+	ring    [4096]byte
+	mcwOnes [len(mcwWindows)]int
+
+	mcw, lag, mmc, lz predictionScore
+
+	mcwScores [len(mcwWindows)]uint64
+	mcwWinner int
+	lagScores [lagDepth]uint64
+	lagWinner int
+	mmcCounts [mmcDepth][][2]uint32
+	mmcScores [mmcDepth]uint64
+	mmcWinner int
+	lzCounts  [lz78yDepth][][2]uint32
+	lzEntries int
+}
+
+func newPredictors(fraction float64) *predictors {
+	if !(fraction > 0) {
+		return nil
+	}
+
+	return &predictors{period: uint64(math.Round(1 / min(fraction, 1)))}
+}
+
+// reset discards everything analyzed, keeping the sampling period.
+func (p *predictors) reset() {
+	*p = predictors{period: p.period}
+}
+
+// add feeds one raw bit, analyzing it if its block is sampled.
+func (p *predictors) add(bit byte) {
+	block := p.pos / PredictorBlock
+
+	p.pos++
+
+	if block%p.period != 0 {
+		return
+	}
+
+	if p.mmcCounts[0] == nil {
+		for d := range p.mmcCounts {
+			p.mmcCounts[d] = make([][2]uint32, 1<<(d+1))
+			p.lzCounts[d] = make([][2]uint32, 1<<(d+1))
+		}
+	}
+
+	p.scoreMCW(bit)
+	p.scoreLag(bit)
+	p.scoreMMC(bit)
+	p.scoreLZ78Y(bit)
+
+	p.learn(bit)
+}
+
+// recent returns the last k analyzed bits (k <= 64), the latest in bit 0.
+func (p *predictors) recent(k int) uint32 {
+	return uint32(p.hist[0] & (1<<k - 1))
+}
+
+// majority predicts the bit with the larger count, ties going to 1.
+func majority(c [2]uint32) byte {
+	if c[1] >= c[0] {
+		return 1
+	}
+
+	return 0
+}
+
+func (p *predictors) scoreMCW(bit byte) {
+	// The winner predicts before this bit's scores move it.
+	winner := p.mcwWinner
+
+	predicted, ok := byte(0), false
+
+	for j, w := range mcwWindows {
+		if p.n < uint64(w) {
+			continue
+		}
+
+		ones := p.mcwOnes[j]
+
+		guess := byte(0)
+
+		switch {
+		case 2*ones > w:
+			guess = 1
+		case 2*ones == w:
+			guess = byte(p.hist[0] & 1)
+		}
+
+		if j == winner {
+			predicted, ok = guess, true
+		}
+
+		if guess == bit {
+			p.mcwScores[j]++
+
+			if p.mcwScores[j] >= p.mcwScores[p.mcwWinner] {
+				p.mcwWinner = j
+			}
+		}
+	}
+
+	if ok {
+		p.mcw.record(predicted == bit)
+	}
+}
+
+func (p *predictors) scoreLag(bit byte) {
+	// The winner predicts before this bit's scores move it.
+	winner := p.lagWinner
+
+	predicted, ok := byte(0), false
+
+	for d := range min(p.n, lagDepth) {
+		guess := byte(p.hist[d/64]>>(d%64)) & 1
+
+		if int(d) == winner {
+			predicted, ok = guess, true
+		}
+
+		if guess == bit {
+			p.lagScores[d]++
+
+			if p.lagScores[d] >= p.lagScores[p.lagWinner] {
+				p.lagWinner = int(d)
+			}
+		}
+	}
+
+	if ok {
+		p.lag.record(predicted == bit)
+	}
+}
+
+func (p *predictors) scoreMMC(bit byte) {
+	// The winner predicts before this bit's scores move it.
+	winner := p.mmcWinner
+
+	predicted, ok := byte(0), false
+
+	for d := range mmcDepth {
+		if p.n <= uint64(d) {
+			break
+		}
+
+		c := p.mmcCounts[d][p.recent(d+1)]
+		if c[0] == 0 && c[1] == 0 {
+			continue
+		}
+
+		guess := majority(c)
+
+		if d == winner {
+			predicted, ok = guess, true
+		}
+
+		if guess == bit {
+			p.mmcScores[d]++
+
+			if p.mmcScores[d] >= p.mmcScores[p.mmcWinner] {
+				p.mmcWinner = d
+			}
+		}
+	}
+
+	if ok {
+		p.mmc.record(predicted == bit)
+	}
+}
+
+func (p *predictors) scoreLZ78Y(bit byte) {
+	// Dictionary entries are the contexts with counts; every entry has been counted at least once.
+	for j := min(int(p.n), lz78yDepth); j > 0; j-- {
+		c := p.lzCounts[j-1][p.recent(j)]
+		if c[0] == 0 && c[1] == 0 {
+			continue
+		}
+
+		p.lz.record(majority(c) == bit)
+
+		return
+	}
+}
+
+// learn updates the models and the history with bit.
+func (p *predictors) learn(bit byte) {
+	for d := range mmcDepth {
+		if p.n <= uint64(d) {
+			break
+		}
+
+		p.mmcCounts[d][p.recent(d+1)][bit]++
+	}
+
+	for j := min(int(p.n), lz78yDepth); j > 0; j-- {
+		c := &p.lzCounts[j-1][p.recent(j)]
+
+		if c[0] == 0 && c[1] == 0 {
+			if p.lzEntries >= lz78yMaxEntries {
+				continue
+			}
+
+			p.lzEntries++
+		}
+
+		c[bit]++
+	}
+
+	for j, w := range mcwWindows {
+		p.mcwOnes[j] += int(bit)
+
+		if p.n >= uint64(w) {
+			p.mcwOnes[j] -= int(p.ring[(p.n-uint64(w))%uint64(len(p.ring))])
+		}
+	}
+
+	p.ring[p.n%uint64(len(p.ring))] = bit
+
+	p.hist[1] = p.hist[1]<<1 | p.hist[0]>>63
+	p.hist[0] = p.hist[0]<<1 | uint64(bit)
+
+	p.n++
+}
+
+// results returns the estimators that have made predictions.
+func (p *predictors) results() []Prediction {
+	var out []Prediction
+
+	for _, e := range []struct {
+		name  string
+		score *predictionScore
+	}{
+		{"multi-mcw", &p.mcw},
+		{"lag", &p.lag},
+		{"multi-mmc", &p.mmc},
+		{"lz78y", &p.lz},
+	} {
+		if e.score.n == 0 {
+			continue
+		}
+
+		out = append(out, Prediction{
+			Name:        e.name,
+			Predictions: e.score.n,
+			Correct:     e.score.correct,
+			LongestRun:  e.score.longest,
+			MinEntropy:  e.score.minEntropy(),
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,112 @@
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// drainGrace is how recently a read must have returned output for an empty ring to count as drained by consumers
+// rather than starved.
+const drainGrace = time.Second
+
+// Ready reports whether the device can serve output: it is started and not paused, its health check passes, and a
+// backend buffering through a ring (see RingBackend) holds output or has just handed it out. It returns the first
+// reason it cannot, or nil.
+func (d *Device) Ready() error {
+	d.mu.Lock()
+	running, paused := d.running, d.paused
+	d.mu.Unlock()
+
+	switch {
+	case d.closed.Load():
+		return ErrClosed
+	case !running:
+		return ErrNotStarted
+	case paused:
+		return ErrPaused
+	}
+
+	if status, estimate := d.Health(); status != HealthOK {
+		return fmt.Errorf("health check failing (entropy estimate %.3f)", estimate)
+	}
+
+	stats, ok := d.RingStats()
+	if ok && stats.Buffered == 0 && time.Since(time.Unix(0, d.readStats.served.Load())) > drainGrace {
+		return errors.New("no output buffered")
+	}
+
+	return nil
+}
+
+// Probes configures ProbeHandler. Readiness has to fail for the thresholds before the probes report it, so a brief
+// health dip or a drained buffer neither de-routes nor restarts the process.
+type Probes struct {
+	// ReadyThreshold is how long Ready must fail before /readyz does; 0 reports failures at once.
+	ReadyThreshold time.Duration `json:"readyThreshold,omitempty"`
+
+	// LiveThreshold, if positive, fails /healthz too once Ready has failed that long, so the orchestrator restarts a
+	// process whose device does not recover. At 0, /healthz only reports that the process is up.
+	LiveThreshold time.Duration `json:"liveThreshold,omitempty"`
+}
+
+// ProbeHandler serves orchestrator probes:
+//
+//	GET /healthz   200 while the process is up (see Probes.LiveThreshold)
+//	GET /readyz    200 while Ready passes, 503 with the reason once it has failed for Probes.ReadyThreshold
+//
+// Both evaluate Ready on every request, so the thresholds take effect at the orchestrator's probe period.
+func (d *Device) ProbeHandler(p Probes) http.Handler {
+	var (
+		mu      sync.Mutex
+		failing time.Time
+	)
+
+	// check returns how long Ready has been failing, and its error.
+	check := func() (time.Duration, error) {
+		err := d.Ready()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err == nil {
+			failing = time.Time{}
+
+			return 0, nil
+		}
+
+		if failing.IsZero() {
+			failing = time.Now()
+		}
+
+		return time.Since(failing), err
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if p.LiveThreshold > 0 {
+			if since, err := check(); err != nil && since >= p.LiveThreshold {
+				http.Error(w, fmt.Sprintf("not ready for %s: %v", since.Round(time.Second), err), http.StatusServiceUnavailable)
+
+				return
+			}
+		}
+
+		w.Write([]byte("ok\n"))
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if since, err := check(); err != nil && since >= p.ReadyThreshold {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.Write([]byte("ok\n"))
+	})
+
+	return mux
+}
@@ -0,0 +1,70 @@
+package infnoise
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeHandler(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	probe := func(h http.Handler, path string) (int, string) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec.Code, rec.Body.String()
+	}
+
+	strict := dv.ProbeHandler(Probes{})
+	lenient := dv.ProbeHandler(Probes{ReadyThreshold: time.Hour, LiveThreshold: 20 * time.Millisecond})
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code, body := probe(strict, path); code != http.StatusOK {
+			t.Fatalf("%s on a started device: %d %s", path, code, body)
+		}
+	}
+
+	err := dv.Pause()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, body := probe(strict, "/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "paused") {
+		t.Fatalf("/readyz while paused: %d %s", code, body)
+	}
+
+	if code, _ := probe(strict, "/healthz"); code != http.StatusOK {
+		t.Fatalf("/healthz without a live threshold failed while paused: %d", code)
+	}
+
+	if code, _ := probe(lenient, "/readyz"); code != http.StatusOK {
+		t.Fatalf("/readyz failed within its threshold: %d", code)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if code, body := probe(lenient, "/healthz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "paused") {
+		t.Fatalf("/healthz past its live threshold: %d %s", code, body)
+	}
+
+	err = dv.Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := probe(lenient, "/healthz"); code != http.StatusOK {
+		t.Fatalf("/healthz after recovering: %d", code)
+	}
+
+	dv.Close()
+
+	if code, body := probe(strict, "/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "closed") {
+		t.Fatalf("/readyz after Close: %d %s", code, body)
+	}
+}
@@ -0,0 +1,111 @@
+package infnoise
+
+import "fmt"
+
+// Profile describes how a board wires the noise source to the FTDI bitbang pins and how it is driven.
+type Profile struct {
+	// Name identifies the profile in error messages.
+	Name string
+
+	// COMP1 and COMP2 are the comparator output pins; they are the only inputs.
+	COMP1, COMP2 uint8
+
+	// SWEN1 and SWEN2 are the multiplier switch enable pins.
+	SWEN1, SWEN2 uint8
+
+	// Address holds the pins for address bits 0..3.
+	Address [4]uint8
+
+	// Pattern is the output sequence clocked out in bitbang mode.
+	Pattern Pattern
+}
+
+var (
+	// ProfileV2 matches current Infinite Noise boards (the package-level pin constants).
+	ProfileV2 = Profile{
+		Name:    "v2",
+		COMP1:   COMP1,
+		COMP2:   COMP2,
+		SWEN1:   SWEN1,
+		SWEN2:   SWEN2,
+		Address: [4]uint8{ADDR0, ADDR1, ADDR2, ADDR3},
+		Pattern: DefaultPattern(),
+	}
+
+	// ProfileV1 matches the original board revision, which has the comparator and switch pins on other bits
+	// (the upstream driver's VERSION1 definitions).
+	ProfileV1 = Profile{
+		Name:    "v1",
+		COMP1:   2,
+		COMP2:   0,
+		SWEN1:   4,
+		SWEN2:   1,
+		Address: [4]uint8{ADDR0, ADDR1, ADDR2, ADDR3},
+		Pattern: DefaultPattern(),
+	}
+)
+
+// Mask returns the bitbang direction mask: every pin is an output except the two comparators.
+func (p Profile) Mask() byte {
+	return 0xFF &^ (1<<p.COMP1 | 1<<p.COMP2)
+}
+
+// Validate reports whether the profile's pins are distinct bitbang pins and its pattern can be driven.
+func (p Profile) Validate() error {
+	pins := append([]uint8{p.COMP1, p.COMP2, p.SWEN1, p.SWEN2}, p.Address[:]...)
+
+	var used uint16
+
+	for _, pin := range pins {
+		if pin > 7 {
+			return fmt.Errorf("profile %q: pin %d out of range", p.Name, pin)
+		}
+
+		if used&(1<<pin) != 0 {
+			return fmt.Errorf("profile %q: pin %d assigned twice", p.Name, pin)
+		}
+
+		used |= 1 << pin
+	}
+
+	err := p.Pattern.Validate()
+	if err != nil {
+		return fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+
+	return nil
+}
+
+// Bytes returns the first n output bytes of the profile's pattern on the profile's pins.
+func (p Profile) Bytes(n int) []byte {
+	out := make([]byte, n)
+
+	for i := range out {
+		even := i&1 == 0
+
+		if even == (p.Pattern.Order == SwitchSWEN1First) {
+			out[i] = 1 << p.SWEN1
+		} else {
+			out[i] = 1 << p.SWEN2
+		}
+
+		addr := p.Pattern.address(i)
+
+		for bit, pin := range p.Address {
+			if addr&(1<<bit) != 0 {
+				out[i] |= 1 << pin
+			}
+		}
+	}
+
+	return out
+}
+
+// comparators returns the input pins carrying the noise bit on even and odd samples.
+func (p Profile) comparators() (even, odd uint8) {
+	if p.Pattern.Order == SwitchSWEN2First {
+		return p.COMP1, p.COMP2
+	}
+
+	return p.COMP2, p.COMP1
+}
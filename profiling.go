@@ -0,0 +1,29 @@
+package infnoise
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// profileLabelKey is the pprof label key under which WithProfileLabels names the stage of the hot path.
+const profileLabelKey = "infnoise"
+
+var (
+	labelUSB     = pprof.Labels(profileLabelKey, "usb-wait")
+	labelExtract = pprof.Labels(profileLabelKey, "extract")
+	labelHealth  = pprof.Labels(profileLabelKey, "health")
+	labelAbsorb  = pprof.Labels(profileLabelKey, "absorb")
+)
+
+// labeled runs fn with labels added to d.labels, or directly if profile labels are off.
+func (d *Device) labeled(labels pprof.LabelSet, fn func()) {
+	if d.labels == nil {
+		fn()
+
+		return
+	}
+
+	pprof.Do(d.labels, labels, func(context.Context) {
+		fn()
+	})
+}
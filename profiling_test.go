@@ -0,0 +1,32 @@
+package infnoise
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestProfileLabels(t *testing.T) {
+	base := pprof.WithLabels(context.Background(), pprof.Labels("daemon", "test"))
+
+	plain, _ := openSimulator(t, 1, DefaultSimulatorGain)
+	labeled, _ := openSimulator(t, 1, DefaultSimulatorGain, WithProfileLabels(base))
+
+	want := make([]byte, 4096)
+	got := make([]byte, 4096)
+
+	_, err := plain.ReadWhitened(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = labeled.ReadWhitened(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Fatal("profile labels changed the output")
+	}
+}
@@ -0,0 +1,426 @@
+package infnoise
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/coalaura/infnoise/internal/ratelimit"
+	"github.com/coalaura/infnoise/trng"
+)
+
+// MaxRemoteRead bounds the bytes served by a single /raw or /whitened request.
+const MaxRemoteRead = 1 << 20
+
+// Source is the API shared by Device and RemoteDevice, so application code is the same whether the board is
+// attached locally or served by an entropy appliance.
+type Source interface {
+	trng.Source
+
+	ReadWhitened(p []byte) (int, error)
+	Health() (HealthStatus, float64)
+	HealthReport() Report
+	Drift() DriftStats
+	Trend() []TrendPoint
+	RingStats() (RingStats, bool)
+	DerivationID() string
+}
+
+var (
+	_ Source = (*Device)(nil)
+	_ Source = (*RemoteDevice)(nil)
+)
+
+type jsonHealth struct {
+	Healthy  bool    `json:"healthy"`
+	Estimate float64 `json:"estimate"`
+}
+
+// Handler serves the device over HTTP for RemoteDevice:
+//
+//	GET /raw?n=N        N bytes from Read
+//	GET /whitened?n=N   N bytes from ReadWhitened, with the DerivationID in the DerivationHeader
+//	GET /health         {"healthy": bool, "estimate": float}
+//	GET /report         the health check's Report
+//	GET /drift          DriftStats
+//	GET /trend          the points recorded by WithTrend
+//	GET /ring           RingStats, 404 if the backend does not buffer
+//	GET /derivation     the DerivationID as text
+//
+// N is at most MaxRemoteRead. The device must be started; reads follow its ReadMode like local ones.
+// WithHandlerRate and WithHandlerClientRate bound how fast /raw and /whitened hand out output, so one client
+// cannot drain the device.
+// Handler does not authenticate its clients: wrap it with httpauth.Wrap to require an API key or a client
+// certificate and to sign its responses.
+func (d *Device) Handler(opts ...handlerOption) http.Handler {
+	conf := &handlerOptions{}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	l := &readLimit{
+		global:  ratelimit.New(conf.rate),
+		clients: ratelimit.NewClients(conf.clientRate),
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /raw", func(w http.ResponseWriter, r *http.Request) {
+		serveRead(w, r, d.Read, l)
+	})
+
+	mux.HandleFunc("GET /whitened", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(DerivationHeader, d.DerivationID())
+
+		serveRead(w, r, d.ReadWhitened, l)
+	})
+
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		status, estimate := d.Health()
+
+		writeJSON(w, jsonHealth{Healthy: status == HealthOK, Estimate: estimate})
+	})
+
+	mux.HandleFunc("GET /report", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.HealthReport())
+	})
+
+	mux.HandleFunc("GET /drift", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.Drift())
+	})
+
+	mux.HandleFunc("GET /trend", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.Trend())
+	})
+
+	mux.HandleFunc("GET /ring", func(w http.ResponseWriter, r *http.Request) {
+		stats, ok := d.RingStats()
+		if !ok {
+			http.Error(w, "backend does not buffer", http.StatusNotFound)
+
+			return
+		}
+
+		writeJSON(w, stats)
+	})
+
+	mux.HandleFunc("GET /derivation", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+
+		w.Write([]byte(d.DerivationID()))
+	})
+
+	return mux
+}
+
+type handlerOptions struct {
+	rate       int
+	clientRate int
+}
+
+type handlerOption func(*handlerOptions)
+
+// WithHandlerRate caps the combined output of /raw and /whitened at bytesPerSecond (default 0, unlimited).
+func WithHandlerRate(bytesPerSecond int) handlerOption {
+	return func(o *handlerOptions) {
+		o.rate = bytesPerSecond
+	}
+}
+
+// WithHandlerClientRate limits each client, told apart by its IP address, to bytesPerSecond of /raw and /whitened
+// output (default 0, unlimited).
+func WithHandlerClientRate(bytesPerSecond int) handlerOption {
+	return func(o *handlerOptions) {
+		o.clientRate = bytesPerSecond
+	}
+}
+
+// readLimit holds the buckets Handler charges for output. Like the named pipe server, it charges after reading and
+// delays the response until the output is paid for.
+type readLimit struct {
+	global  *ratelimit.Bucket
+	clients *ratelimit.Clients
+}
+
+// wait charges n bytes to the global bucket and to that of r's client and waits until they are paid for. It fails
+// if the client goes away first.
+func (l *readLimit) wait(r *http.Request, n int) error {
+	now := time.Now()
+
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	d := ratelimit.Reserve(n, now, l.global, l.clients.Get(client, now))
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
+
+func serveRead(w http.ResponseWriter, r *http.Request, read func([]byte) (int, error), l *readLimit) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 || n > MaxRemoteRead {
+		http.Error(w, fmt.Sprintf("invalid or missing n (need 1 to %d)", MaxRemoteRead), http.StatusBadRequest)
+
+		return
+	}
+
+	buf := make([]byte, n)
+
+	_, err = io.ReadFull(readerFunc(read), buf)
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(err, ErrModeConflict):
+			status = http.StatusConflict
+		case errors.Is(err, ErrPaused), errors.Is(err, ErrClosed), Classify(err) == ClassHealth:
+			status = http.StatusServiceUnavailable
+		}
+
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	err = l.wait(r, n)
+	if err != nil {
+		clear(buf)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(n))
+
+	w.Write(buf)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(v)
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+// RemoteDevice reads from a Device served by Handler on another machine.
+//
+// Health, HealthReport, Drift, Trend, and RingStats cannot report transport errors through the Device API: while
+// the server is unreachable Health returns HealthFailed, the others return zero values, and RingStats returns
+// ok=false.
+type RemoteDevice struct {
+	base   string
+	client *http.Client
+	closed atomic.Bool
+}
+
+// NewRemoteDevice returns a RemoteDevice for the Handler at baseURL, such as "http://appliance:8080/infnoise".
+// A nil client uses http.DefaultClient; set its Timeout to bound reads, and its Transport to an httpauth.Transport
+// for servers requiring an API key or signing their responses.
+func NewRemoteDevice(baseURL string, client *http.Client) *RemoteDevice {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &RemoteDevice{
+		base:   strings.TrimSuffix(baseURL, "/"),
+		client: client,
+	}
+}
+
+// UnixSocketClient returns a client sending every request to the Handler served on the Unix socket at path, such
+// as the one "infnoise sidecar" creates in a volume shared with the application's container. The host in the URLs
+// passed to NewRemoteDevice is ignored: NewRemoteDevice("http://infnoise", UnixSocketClient(path)).
+func UnixSocketClient(path string) *http.Client {
+	var dialer net.Dialer
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+// Start checks that the server is reachable.
+func (d *RemoteDevice) Start() error {
+	d.closed.Store(false)
+
+	var h jsonHealth
+
+	return d.getJSON("/health", &h)
+}
+
+// Read fills p with raw samples from the remote device.
+func (d *RemoteDevice) Read(p []byte) (int, error) {
+	return d.read("/raw", p)
+}
+
+// ReadWhitened fills p with whitened output from the remote device.
+func (d *RemoteDevice) ReadWhitened(p []byte) (int, error) {
+	return d.read("/whitened", p)
+}
+
+// Health returns the remote health status and entropy estimate.
+func (d *RemoteDevice) Health() (HealthStatus, float64) {
+	var h jsonHealth
+
+	err := d.getJSON("/health", &h)
+	if err != nil || !h.Healthy {
+		return HealthFailed, h.Estimate
+	}
+
+	return HealthOK, h.Estimate
+}
+
+// HealthReport returns the remote health check's Report, or a zero Report if the server is unreachable.
+func (d *RemoteDevice) HealthReport() Report {
+	var r Report
+
+	d.getJSON("/report", &r)
+
+	return r
+}
+
+// Drift returns the remote device's drift statistics.
+func (d *RemoteDevice) Drift() DriftStats {
+	var stats DriftStats
+
+	d.getJSON("/drift", &stats)
+
+	return stats
+}
+
+// Trend returns the remote device's trend points, or nil if the server is unreachable.
+func (d *RemoteDevice) Trend() []TrendPoint {
+	var points []TrendPoint
+
+	d.getJSON("/trend", &points)
+
+	return points
+}
+
+// RingStats returns the remote backend's ring buffer statistics; ok is false if it does not buffer.
+func (d *RemoteDevice) RingStats() (stats RingStats, ok bool) {
+	err := d.getJSON("/ring", &stats)
+
+	return stats, err == nil
+}
+
+// DerivationID returns the remote device's DerivationID, or "" if the server is unreachable.
+func (d *RemoteDevice) DerivationID() string {
+	resp, err := d.get("/derivation")
+	if err != nil {
+		return ""
+	}
+
+	defer resp.Body.Close()
+
+	id, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	return string(id)
+}
+
+// Close makes subsequent reads fail with ErrClosed and drops idle connections.
+func (d *RemoteDevice) Close() error {
+	d.closed.Store(true)
+
+	d.client.CloseIdleConnections()
+
+	return nil
+}
+
+func (d *RemoteDevice) read(path string, p []byte) (int, error) {
+	var n int
+
+	for n < len(p) {
+		if d.closed.Load() {
+			return n, ErrClosed
+		}
+
+		chunk := p[n:min(len(p), n+MaxRemoteRead)]
+
+		resp, err := d.get(path + "?n=" + strconv.Itoa(len(chunk)))
+		if err != nil {
+			return n, err
+		}
+
+		m, err := io.ReadFull(resp.Body, chunk)
+
+		resp.Body.Close()
+
+		n += m
+
+		if err != nil {
+			return n, fmt.Errorf("remote read: %w", err)
+		}
+	}
+
+	return n, nil
+}
+
+func (d *RemoteDevice) getJSON(path string, v any) error {
+	resp, err := d.get(path)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// remoteErrors are the errors RemoteDevice recognizes in server responses, so errors.Is works as it does locally.
+var remoteErrors = []error{ErrModeConflict, ErrPaused, ErrClosed, ErrDuplicateBlock, ErrQuarantined, ErrHealthCheck}
+
+// get issues a request and turns error responses back into the errors the server reported.
+func (d *RemoteDevice) get(path string) (*http.Response, error) {
+	resp, err := d.client.Get(d.base + path)
+	if err != nil {
+		return nil, fmt.Errorf("remote: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	text := strings.TrimSpace(string(msg))
+
+	for _, known := range remoteErrors {
+		if strings.Contains(text, known.Error()) {
+			return nil, fmt.Errorf("remote: %s: %w", text, known)
+		}
+	}
+
+	return nil, fmt.Errorf("remote: %s: %s", resp.Status, text)
+}
@@ -0,0 +1,175 @@
+package infnoise
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coalaura/infnoise/httpauth"
+)
+
+func TestRemoteDevice(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	srv := httptest.NewServer(dv.Handler())
+	defer srv.Close()
+
+	var src Source = NewRemoteDevice(srv.URL, srv.Client())
+
+	err := src.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer src.Close()
+
+	buf := make([]byte, testBytes)
+
+	n, err := src.ReadWhitened(buf)
+	if err != nil || n != len(buf) {
+		t.Fatalf("ReadWhitened: %d, %v", n, err)
+	}
+
+	// The whitened reader claimed the stream, so raw reads conflict just as they do locally.
+	_, err = src.Read(buf)
+	if !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("Read after ReadWhitened: %v", err)
+	}
+
+	status, estimate := src.Health()
+	if status != HealthOK || estimate <= 0 {
+		t.Fatalf("Health: %v, %f", status, estimate)
+	}
+
+	if local := dv.Drift(); src.Drift() != local {
+		t.Fatalf("Drift: got %+v, want %+v", src.Drift(), local)
+	}
+
+	if id := src.DerivationID(); id != dv.DerivationID() {
+		t.Fatalf("DerivationID: got %q, want %q", id, dv.DerivationID())
+	}
+
+	resp, err := srv.Client().Get(srv.URL + "/whitened?n=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if id := resp.Header.Get(DerivationHeader); id != dv.DerivationID() {
+		t.Fatalf("%s: got %q, want %q", DerivationHeader, id, dv.DerivationID())
+	}
+
+	src.Close()
+
+	_, err = src.ReadWhitened(buf)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("ReadWhitened after Close: %v", err)
+	}
+}
+
+func TestRemoteDeviceAuth(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	key := []byte("device key")
+
+	srv := httptest.NewServer(httpauth.Wrap(dv.Handler(), httpauth.WithAPIKeys("token"), httpauth.WithResponseMAC(key)))
+	defer srv.Close()
+
+	src := NewRemoteDevice(srv.URL, &http.Client{Transport: &httpauth.Transport{APIKey: "token", MACKey: key}})
+
+	buf := make([]byte, testBytes)
+
+	n, err := src.ReadWhitened(buf)
+	if err != nil || n != len(buf) {
+		t.Fatalf("ReadWhitened: %d, %v", n, err)
+	}
+
+	if status, _ := src.Health(); status != HealthOK {
+		t.Fatalf("Health: %v", status)
+	}
+
+	_, err = NewRemoteDevice(srv.URL, srv.Client()).ReadWhitened(buf)
+	if err == nil {
+		t.Fatal("read without the token")
+	}
+
+	_, err = NewRemoteDevice(srv.URL, &http.Client{Transport: &httpauth.Transport{APIKey: "token", MACKey: []byte("other")}}).ReadWhitened(buf)
+	if !errors.Is(err, httpauth.ErrBadMAC) {
+		t.Fatalf("read signed under another key: %v", err)
+	}
+}
+
+func TestHandlerRate(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	srv := httptest.NewServer(dv.Handler(WithHandlerClientRate(1000)))
+	defer srv.Close()
+
+	get := func(timeout time.Duration) error {
+		client := srv.Client()
+
+		client.Timeout = timeout
+
+		resp, err := client.Get(srv.URL + "/whitened?n=1000")
+		if err != nil {
+			return err
+		}
+
+		defer resp.Body.Close()
+
+		_, err = io.ReadAll(resp.Body)
+
+		return err
+	}
+
+	// The first second's worth goes out at once.
+	err := get(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next would take another second.
+	err = get(100 * time.Millisecond)
+	if err == nil {
+		t.Fatal("second burst served without waiting for the client limit")
+	}
+}
+
+func TestUnixSocketClient(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	path := filepath.Join(t.TempDir(), "infnoise.sock")
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	srv := &http.Server{Handler: dv.Handler()}
+
+	go srv.Serve(ln)
+
+	defer srv.Close()
+
+	src := NewRemoteDevice("http://infnoise", UnixSocketClient(path))
+
+	err = src.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer src.Close()
+
+	buf := make([]byte, testBytes)
+
+	n, err := src.ReadWhitened(buf)
+	if err != nil || n != len(buf) {
+		t.Fatalf("ReadWhitened: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,211 @@
+package infnoise
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Report is a snapshot of a HealthCheck, marshaled to JSON for compliance records and log pipelines.
+type Report struct {
+	Time   time.Time    `json:"time"`
+	Status HealthStatus `json:"status"`
+
+	// TotalBits is the number of bits observed; the tolerance test applies once WindowFilled, i.e. once
+	// TotalBits reaches WindowBits.
+	TotalBits    uint64 `json:"totalBits"`
+	WindowBits   uint64 `json:"windowBits"`
+	WindowFilled bool   `json:"windowFilled"`
+
+	// Sampling is the fraction of batches analyzed (see WithHealthSampling); SkippedBits counts the bits of the
+	// others, which only the continuous tests saw and TotalBits leaves out.
+	Sampling    float64 `json:"sampling"`
+	SkippedBits uint64  `json:"skippedBits"`
+
+	TargetEntropy float64 `json:"targetEntropy"`
+	Tolerance     float64 `json:"tolerance"`
+
+	Estimates []Estimate   `json:"estimates"`
+	Tests     []TestResult `json:"tests"`
+
+	// Cutoffs are the thresholds of the continuous tests, reported for auditors.
+	Cutoffs Cutoffs `json:"cutoffs"`
+
+	// Predictions are the SP 800-90B prediction estimators run on sampled blocks of the raw bits (see
+	// WithPredictors); the lowest of their bounds is the prediction-bound estimate.
+	Predictions []Prediction `json:"predictions,omitempty"`
+
+	// Autocorrelation is the latest result of the autocorrelation monitor.
+	Autocorrelation Autocorrelation `json:"autocorrelation"`
+
+	// Bias is the bit-position and switch-address breakdown of the ones density, for diagnosing board faults.
+	Bias BiasMap `json:"bias"`
+
+	// Contexts holds the zero and one counts following each history of HistoryBits bits, indexed by the history
+	// (the most recent bit in bit 0), for fitting Markov models offline (see WriteContexts).
+	HistoryBits int         `json:"historyBits"`
+	Contexts    [][2]uint32 `json:"contexts"`
+}
+
+// Estimate is the entropy per bit according to one estimator:
+//
+//	context-prediction   the health check's estimate: the cost of predicting each bit from the preceding
+//	                     HistoryBits (7 by default)
+//	context-min-entropy  min-entropy of the final context counts
+//	bit-frequency        Shannon entropy of the overall zero/one balance, blind to correlations
+//	prediction-bound     the lowest min-entropy bound of the SP 800-90B prediction estimators, if any ran
+type Estimate struct {
+	Name       string  `json:"name"`
+	BitsPerBit float64 `json:"bitsPerBit"`
+}
+
+// TestResult is the outcome of one health test.
+type TestResult struct {
+	Name   string `json:"name"`
+	Pass   bool   `json:"pass"`
+	Detail string `json:"detail"`
+}
+
+// Report returns a snapshot of the health check's counts, estimates, and test results.
+func (h *HealthCheck) Report() Report {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var estimate float64
+
+	if h.totalBits > 0 {
+		estimate = h.entropySum / float64(h.totalBits)
+	}
+
+	r := Report{
+		Time:          time.Now(),
+		TotalBits:     h.totalBits,
+		WindowBits:    h.window,
+		WindowFilled:  h.totalBits >= h.window,
+		Sampling:      1 / float64(h.sampling),
+		SkippedBits:   h.skipped,
+		TargetEntropy: h.TargetEntropy,
+		Tolerance:     h.Tolerance,
+		HistoryBits:   h.historyBits,
+		Contexts:      slices.Clone(h.counts),
+		Cutoffs:       h.continuous.cutoffs,
+		Bias:          h.bias,
+
+		Autocorrelation: h.autocorr.report(),
+		Estimates: []Estimate{
+			{Name: "context-prediction", BitsPerBit: estimate},
+			{Name: "context-min-entropy", BitsPerBit: contextMinEntropy(h.counts)},
+			{Name: "bit-frequency", BitsPerBit: bitFrequencyEntropy(h.counts)},
+		},
+	}
+
+	if h.predict != nil {
+		r.Predictions = h.predict.results()
+	}
+
+	if len(r.Predictions) > 0 {
+		bound := r.Predictions[0].MinEntropy
+
+		for _, p := range r.Predictions[1:] {
+			bound = min(bound, p.MinEntropy)
+		}
+
+		r.Estimates = append(r.Estimates, Estimate{Name: "prediction-bound", BitsPerBit: bound})
+	}
+
+	tolerance := TestResult{Name: "entropy-tolerance", Pass: true}
+
+	lo, hi := h.TargetEntropy*(1-h.Tolerance), h.TargetEntropy*(1+h.Tolerance)
+
+	if r.WindowFilled {
+		tolerance.Pass = h.withinTolerance(estimate)
+		tolerance.Detail = fmt.Sprintf("estimate %.4f, allowed %.4f to %.4f", estimate, lo, hi)
+	} else {
+		tolerance.Detail = fmt.Sprintf("window not filled (%d of %d bits)", h.totalBits, h.window)
+	}
+
+	r.Tests = append([]TestResult{tolerance}, h.continuous.results()...)
+	r.Tests = append(r.Tests, h.autocorr.result())
+	r.Tests = append(r.Tests, h.recorded...)
+
+	for _, t := range r.Tests {
+		if !t.Pass {
+			r.Status = HealthFailed
+		}
+	}
+
+	return r
+}
+
+// HealthReport returns a Report of the device's health check.
+func (d *Device) HealthReport() Report {
+	return d.health.Report()
+}
+
+// RecordTest adds the result of a test run on the device's output outside its health check, such as an external
+// test suite (see package external), to its HealthReport (see HealthCheck.Record).
+func (d *Device) RecordTest(r TestResult) {
+	d.health.Record(r)
+}
+
+// WriteContexts writes the context counts as CSV with a header, one row per history: the history as a string of
+// HistoryBits bits (oldest first), then the zero and one counts that followed it.
+func (r Report) WriteContexts(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	cw.Write([]string{"history", "zeros", "ones"})
+
+	for i, c := range r.Contexts {
+		cw.Write([]string{
+			fmt.Sprintf("%0*b", r.HistoryBits, i),
+			strconv.FormatUint(uint64(c[0]), 10),
+			strconv.FormatUint(uint64(c[1]), 10),
+		})
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func contextMinEntropy(counts [][2]uint32) float64 {
+	var total, sum float64
+
+	for _, c := range counts {
+		n := float64(c[0]) + float64(c[1])
+		if n == 0 {
+			continue
+		}
+
+		total += n
+		sum += n * -math.Log2(float64(max(c[0], c[1]))/n)
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return sum / total
+}
+
+func bitFrequencyEntropy(counts [][2]uint32) float64 {
+	var zeros, ones float64
+
+	for _, c := range counts {
+		zeros += float64(c[0])
+		ones += float64(c[1])
+	}
+
+	total := zeros + ones
+	if zeros == 0 || ones == 0 {
+		return 0
+	}
+
+	p0, p1 := zeros/total, ones/total
+
+	return -p0*math.Log2(p0) - p1*math.Log2(p1)
+}
@@ -0,0 +1,70 @@
+package infnoise
+
+import (
+	"crypto/rand"
+	"crypto/sha3"
+	"encoding/binary"
+	"time"
+)
+
+// reseedLabel is the cSHAKE256 customization string of the step that folds a reseed into the chaining value.
+const reseedLabel = "infnoise reseed"
+
+// reseed replaces the chaining value with a digest of itself, the reseed counter, and seed, so every later chunk
+// depends on seed.
+func (w *whitener) reseed(counter uint64, seed []byte) {
+	h := sha3.NewCSHAKE256(nil, []byte(reseedLabel))
+
+	h.Write(w.chain[:])
+	h.Write(binary.LittleEndian.AppendUint64(nil, counter))
+	h.Write(seed)
+
+	h.Read(w.chain[:])
+
+	h.Reset()
+}
+
+// Reseed folds 32 bytes of OS entropy and a counter into the whitener and discards buffered whitened output, so
+// everything ReadWhitened returns afterwards depends on the fresh seed. The device's own entropy remains the
+// source of the output; reseeding guards against a conditioner state that was captured or never well seeded.
+func (d *Device) Reseed() {
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+
+	d.reseed()
+}
+
+// reseed implements Reseed. poolMu must be held.
+func (d *Device) reseed() {
+	var seed [32]byte
+
+	rand.Read(seed[:])
+
+	n := d.reseeds.Add(1)
+
+	d.whitener.reseed(n, seed[:])
+
+	if d.auditLog != nil {
+		d.auditLog.record("reseed", struct {
+			Count uint64 `json:"count"`
+		}{n})
+	}
+
+	clear(seed[:])
+	clear(d.pool)
+
+	d.pool = nil
+
+	d.lastReseed.Store(time.Now().UnixNano())
+}
+
+// reseedDue reports whether WithReseedInterval calls for a reseed before the next chunk.
+func (d *Device) reseedDue() bool {
+	if d.reseedInterval <= 0 {
+		return false
+	}
+
+	last := d.lastReseed.Load()
+
+	return last == 0 || time.Since(time.Unix(0, last)) >= d.reseedInterval
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package seedfile
+
+import "errors"
+
+func markCreditable(path string) error {
+	return errors.New("crediting seed files is only supported on Linux")
+}
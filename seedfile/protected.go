@@ -0,0 +1,60 @@
+package seedfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Scope is who can decrypt a protected seed file (see WriteProtected).
+type Scope int
+
+const (
+	// ScopeUser lets only the user account that wrote the file decrypt it.
+	ScopeUser Scope = iota
+
+	// ScopeMachine lets any account on the machine decrypt it, so the file's ACL decides who can read the seed.
+	ScopeMachine
+)
+
+// WriteProtected is Write for seeds that must not rest on disk in the clear, for applications that cannot read
+// the device themselves: the n bytes are encrypted with the Windows Data Protection API (DPAPI) under scope before
+// they are stored. Consumers decrypt the file with ReadProtected, or with CryptUnprotectData (ProtectedData.Unprotect
+// in .NET) and no additional entropy. It is only supported on Windows.
+func WriteProtected(src io.Reader, path string, n int, perm fs.FileMode, scope Scope) error {
+	seed, err := readSeed(src, n)
+	if err != nil {
+		return err
+	}
+
+	defer clear(seed)
+
+	blob, err := protect(seed, scope)
+	if err != nil {
+		return fmt.Errorf("protect seed: %w", err)
+	}
+
+	return writeAtomic(path, blob, perm)
+}
+
+// ReadProtected reads and decrypts a seed file written by WriteProtected. The caller should wipe the seed once it
+// is used.
+func ReadProtected(path string) ([]byte, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blob) == 0 {
+		return nil, errors.New("empty seed file")
+	}
+
+	seed, err := unprotect(blob)
+	if err != nil {
+		return nil, fmt.Errorf("unprotect seed: %w", err)
+	}
+
+	return seed, nil
+}
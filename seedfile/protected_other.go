@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package seedfile
+
+import "errors"
+
+var errNoDPAPI = errors.New("protected seed files are only supported on Windows")
+
+func protect(seed []byte, scope Scope) ([]byte, error) {
+	return nil, errNoDPAPI
+}
+
+func unprotect(blob []byte) ([]byte, error) {
+	return nil, errNoDPAPI
+}
@@ -0,0 +1,64 @@
+//go:build windows
+// +build windows
+
+package seedfile
+
+import (
+	"bytes"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// protectDescription is the description DPAPI stores in the clear alongside the encrypted seed.
+const protectDescription = "infnoise seed"
+
+func protect(seed []byte, scope Scope) ([]byte, error) {
+	flags := uint32(windows.CRYPTPROTECT_UI_FORBIDDEN)
+
+	if scope == ScopeMachine {
+		flags |= windows.CRYPTPROTECT_LOCAL_MACHINE
+	}
+
+	name, err := windows.UTF16PtrFromString(protectDescription)
+	if err != nil {
+		return nil, err
+	}
+
+	var out windows.DataBlob
+
+	err = windows.CryptProtectData(blobOf(seed), name, nil, 0, nil, flags, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	return takeBlob(&out), nil
+}
+
+func unprotect(blob []byte) ([]byte, error) {
+	var out windows.DataBlob
+
+	err := windows.CryptUnprotectData(blobOf(blob), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	return takeBlob(&out), nil
+}
+
+func blobOf(b []byte) *windows.DataBlob {
+	return &windows.DataBlob{Size: uint32(len(b)), Data: unsafe.SliceData(b)}
+}
+
+// takeBlob copies a blob DPAPI allocated into the Go heap, then wipes and frees it.
+func takeBlob(b *windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(b.Data)))
+
+	data := unsafe.Slice(b.Data, b.Size)
+
+	out := bytes.Clone(data)
+
+	clear(data)
+
+	return out
+}
@@ -0,0 +1,170 @@
+// Package seedfile writes random seed files for embedded images and hosts that load a seed at boot
+// (the /var/lib/urandom/random-seed pattern).
+//
+// Files are written to a temporary file in the target directory, fsynced, and renamed into place,
+// so a crash or power cut leaves either the old seed or the complete new one, never a partial file.
+//
+// On Windows, WriteProtected stores seeds encrypted with DPAPI for local applications that cannot
+// read the device themselves.
+package seedfile
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultName is the seed file name used by Rotate.
+const DefaultName = "random-seed"
+
+// Write reads n bytes from src and atomically stores them at path with the given permissions.
+func Write(src io.Reader, path string, n int, perm fs.FileMode) error {
+	seed, err := readSeed(src, n)
+	if err != nil {
+		return err
+	}
+
+	defer clear(seed)
+
+	return writeAtomic(path, seed, perm)
+}
+
+// readSeed reads a seed of n bytes from src; the caller wipes it.
+func readSeed(src io.Reader, n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid seed size %d", n)
+	}
+
+	seed := make([]byte, n)
+
+	_, err := io.ReadFull(src, seed)
+	if err != nil {
+		clear(seed)
+
+		return nil, fmt.Errorf("read seed: %w", err)
+	}
+
+	return seed, nil
+}
+
+// writeAtomic stores data at path through a synced temporary file in the same directory.
+func writeAtomic(path string, data []byte, perm fs.FileMode) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	tmpName := tmp.Name()
+
+	err = writeSync(tmp, data, perm)
+	if err != nil {
+		os.Remove(tmpName)
+
+		return err
+	}
+
+	err = os.Rename(tmpName, path)
+	if err != nil {
+		os.Remove(tmpName)
+
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// Rotate shifts DefaultName to DefaultName.1, DefaultName.1 to DefaultName.2 and so on inside dir, skipping
+// missing generations, and deletes every generation beyond keep, even past a gap in the numbering. Call Write
+// afterwards to put a fresh seed in place.
+func Rotate(dir string, keep int) error {
+	base := filepath.Join(dir, DefaultName)
+
+	generation := func(i int) string {
+		if i == 0 {
+			return base
+		}
+
+		return base + "." + strconv.Itoa(i)
+	}
+
+	// Remove generations that would fall off the end, including stragglers from a larger previous keep and any
+	// beyond a gap in the numbering.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		i, ok := generationNumber(e.Name())
+		if !ok || i < keep {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(dir, e.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	for i := keep - 1; i >= 0; i-- {
+		err := os.Rename(generation(i), generation(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return syncDir(dir)
+}
+
+// generationNumber returns the generation Rotate gave the file name: 0 for DefaultName, i for DefaultName.i.
+func generationNumber(name string) (int, bool) {
+	if name == DefaultName {
+		return 0, true
+	}
+
+	suffix, ok := strings.CutPrefix(name, DefaultName+".")
+	if !ok {
+		return 0, false
+	}
+
+	i, err := strconv.Atoi(suffix)
+	if err != nil || i <= 0 || strconv.Itoa(i) != suffix {
+		return 0, false
+	}
+
+	return i, true
+}
+
+func writeSync(f *os.File, data []byte, perm fs.FileMode) error {
+	err := f.Chmod(perm)
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,169 @@
+package seedfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWriteRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DefaultName)
+
+	for i := range 4 {
+		err := Rotate(dir, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = Write(bytes.NewReader(bytes.Repeat([]byte{byte(i)}, 512)), path, 512, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("got %d files, want current seed plus 2 generations", len(entries))
+	}
+
+	for gen, want := range map[string]byte{"": 3, ".1": 2, ".2": 1} {
+		data, err := os.ReadFile(path + gen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(data) != 512 || data[0] != want {
+			t.Fatalf("%s%s: got %d bytes starting with %d, want 512 starting with %d", DefaultName, gen, len(data), data[0], want)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("got permissions %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestRotateGaps(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DefaultName)
+
+	// Generations 2 and 4 are missing, and 5 and 7 are left over from a larger keep.
+	for gen, b := range map[string]byte{"": 0, ".1": 1, ".3": 3, ".5": 5, ".7": 7} {
+		err := os.WriteFile(path+gen, []byte{b}, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := Rotate(dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	want := []string{DefaultName + ".1", DefaultName + ".2", DefaultName + ".4"}
+
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %v after rotating, want %v", names, want)
+	}
+
+	for gen, want := range map[string]byte{".1": 0, ".2": 1, ".4": 3} {
+		data, err := os.ReadFile(path + gen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(data, []byte{want}) {
+			t.Fatalf("%s%s holds %v, want generation %d", DefaultName, gen, data, want)
+		}
+	}
+}
+
+func TestWriteSystemd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultName)
+
+	err := WriteSystemd(bytes.NewReader(bytes.Repeat([]byte{7}, SystemdSize)), path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) != SystemdSize {
+		t.Fatalf("got %d bytes, %v, want %d", len(data), err, SystemdSize)
+	}
+
+	unit := SystemdUnit("/usr/bin/infnoise")
+
+	for _, want := range []string{"Before=systemd-random-seed.service", "ExecStart=/usr/bin/infnoise seed", "RequiresMountsFor=" + SystemdPath} {
+		if !strings.Contains(unit, want) {
+			t.Fatalf("unit lacks %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestWriteProtected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed.dpapi")
+	seed := bytes.Repeat([]byte{0x5a}, 64)
+
+	err := WriteProtected(bytes.NewReader(seed), path, len(seed), 0o600, ScopeUser)
+
+	if runtime.GOOS != "windows" {
+		if err == nil {
+			t.Fatal("wrote a protected seed file without DPAPI")
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("left a seed file behind")
+		}
+
+		return
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(blob, seed[:16]) {
+		t.Fatal("the seed is stored in the clear")
+	}
+
+	got, err := ReadProtected(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, seed) {
+		t.Fatal("decrypted seed differs")
+	}
+}
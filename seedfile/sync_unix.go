@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package seedfile
+
+import "os"
+
+// syncDir makes a preceding rename in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,55 @@
+package seedfile
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// SystemdPath is the seed file systemd-random-seed.service loads into the kernel at boot and refreshes at
+	// shutdown.
+	SystemdPath = "/var/lib/systemd/random-seed"
+
+	// SystemdSize is the seed size systemd-random-seed writes itself.
+	SystemdSize = 512
+
+	// SystemdUnitName is the name the unit from SystemdUnit should be installed under.
+	SystemdUnitName = "infnoise-seed.service"
+)
+
+// WriteSystemd writes a seed for systemd-random-seed to path (normally SystemdPath), readable by root only. If
+// creditable is set, the file is also marked with the user.random-seed-creditable extended attribute, so systemd
+// 250 and later credit it to the kernel's entropy count when loading it; only do this for seeds from a healthy board.
+// Marking fails on platforms other than Linux.
+func WriteSystemd(src io.Reader, path string, creditable bool) error {
+	err := Write(src, path, SystemdSize, 0o600)
+	if err != nil || !creditable {
+		return err
+	}
+
+	return markCreditable(path)
+}
+
+// SystemdUnit returns a oneshot unit that runs "exe seed" in early boot, just before systemd-random-seed loads the
+// seed file, and again at shutdown, just after systemd-random-seed saved its own. The file it loads at the next boot
+// thus always comes from the board. A missing board only fails this unit; systemd-random-seed runs regardless.
+func SystemdUnit(exe string) string {
+	return fmt.Sprintf(`[Unit]
+Description=Refresh the random seed from the Infinite Noise TRNG
+DefaultDependencies=no
+After=systemd-remount-fs.service systemd-udev-trigger.service
+Before=systemd-random-seed.service shutdown.target
+Conflicts=shutdown.target
+RequiresMountsFor=%[2]s
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=%[1]s seed
+ExecStop=%[1]s seed
+TimeoutSec=30s
+
+[Install]
+WantedBy=sysinit.target
+`, exe, SystemdPath)
+}
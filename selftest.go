@@ -0,0 +1,124 @@
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrBitbangPath means the FTDI chip or the USB link misbehaved during SelfTest.
+	ErrBitbangPath = errors.New("FTDI/USB bitbang path fault")
+
+	// ErrNoiseSource means the bitbang path works but the analog noise source does not.
+	ErrNoiseSource = errors.New("noise source fault")
+)
+
+// selfTestMaxRoundTrip bounds one echo round trip; a healthy chip needs a few milliseconds.
+const selfTestMaxRoundTrip = time.Second
+
+// SelfTest checks the device in two stages so a bad output can be attributed. It first clocks every combination
+// of the output-only pins and verifies that synchronous bitbang mode echoes each one on the following sample
+// within a second; failures wrap ErrBitbangPath. It then samples the noise source and verifies that
+// both comparators toggle; failures wrap ErrNoiseSource. SelfTest output is discarded and not fed to the health check.
+func (d *Device) SelfTest() error {
+	d.turn.lock()
+	defer d.turn.unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running {
+		return ErrNotStarted
+	}
+
+	if d.paused {
+		return ErrPaused
+	}
+
+	err := d.selfTestEcho()
+	if err != nil {
+		d.resync = true
+
+		return err
+	}
+
+	return d.selfTestNoise()
+}
+
+func (d *Device) selfTestEcho() error {
+	mask := d.profile.Mask()
+
+	// Every output pin combination, then the pattern's last byte so normal batches resume from the usual pin state.
+	out := make([]byte, 0, 258)
+
+	out = append(out, d.outPattern[BufLen-1])
+
+	for v := range 256 {
+		out = append(out, byte(v)&mask)
+	}
+
+	out = append(out, d.outPattern[BufLen-1])
+
+	in := make([]byte, len(out))
+
+	start := time.Now()
+
+	err := d.usbDev.Write(out)
+	if err == nil {
+		err = d.usbDev.Read(in)
+	}
+
+	elapsed := time.Since(start)
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBitbangPath, err)
+	}
+
+	if elapsed > selfTestMaxRoundTrip {
+		return fmt.Errorf("%w: %d-sample round trip took %v", ErrBitbangPath, len(out), elapsed)
+	}
+
+	// Each sample shows the pins as they were before the corresponding write was applied.
+	for i := 1; i < len(out); i++ {
+		if in[i]&mask != out[i-1] {
+			return fmt.Errorf("%w: sample %d echoed %#02x on the output pins, want %#02x", ErrBitbangPath, i, in[i]&mask, out[i-1])
+		}
+	}
+
+	return nil
+}
+
+func (d *Device) selfTestNoise() error {
+	buf := make([]byte, BufLen)
+
+	defer clear(buf)
+
+	needIn := len(buf) * 8
+
+	err := d.usbDev.Write(d.outBulk[:needIn])
+	if err == nil {
+		err = d.usbDev.Read(d.inBulk[:needIn])
+	}
+
+	if err != nil {
+		d.resync = true
+
+		return fmt.Errorf("%w: %w", ErrBitbangPath, err)
+	}
+
+	var seen [2][2]bool
+
+	for _, v := range d.inBulk[:needIn] {
+		seen[0][(v>>d.profile.COMP1)&1] = true
+		seen[1][(v>>d.profile.COMP2)&1] = true
+	}
+
+	for i, pin := range []string{"COMP1", "COMP2"} {
+		if !seen[i][0] || !seen[i][1] {
+			return fmt.Errorf("%w: %s stuck over %d samples", ErrNoiseSource, pin, needIn)
+		}
+	}
+
+	return nil
+}
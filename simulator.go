@@ -0,0 +1,342 @@
+package infnoise
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+)
+
+const (
+	// DefaultSimulatorGain is the loop gain of the simulated multiplier; log2(1.82) ≈ 0.864 bits of entropy per bit.
+	DefaultSimulatorGain = 1.82
+
+	simulatorNoise = 1e-3
+
+	// simulatorUserArea is the size of the simulated EEPROM user area.
+	simulatorUserArea = 64
+)
+
+// ErrSimulatorDisconnected is returned by a Simulator after an injected FaultDisconnect until it is reopened.
+var ErrSimulatorDisconnected = errors.New("simulated device disconnected")
+
+// FaultKind is a transport failure a Simulator can be scripted to produce (see InjectFault).
+type FaultKind int
+
+const (
+	// FaultTimeout makes Read fail with an error wrapping os.ErrDeadlineExceeded, leaving the samples queued.
+	FaultTimeout FaultKind = iota
+
+	// FaultShortRead makes Read deliver the samples before the fault offset and then fail with an error wrapping
+	// ErrIO.
+	FaultShortRead
+
+	// FaultStall makes Read block as after SetStalled(true).
+	FaultStall
+
+	// FaultDisconnect makes every call fail with ErrSimulatorDisconnected until the simulator is reopened.
+	FaultDisconnect
+)
+
+type fault struct {
+	at   uint64
+	kind FaultKind
+}
+
+// Simulator is an in-memory Backend that models the Infinite Noise Multiplier circuit
+// behind an FTDI chip in synchronous bitbang mode. Output is fully determined by the seed.
+type Simulator struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	rng   *rand.Rand
+	gain  float64
+	state float64
+
+	open        bool
+	stalled     bool
+	interrupted bool
+
+	mask    byte
+	mode    byte
+	prevOut byte
+	lastBit byte
+	pending []byte
+
+	// delivered counts the samples returned by Read, the offsets faults are scheduled at.
+	delivered    uint64
+	faults       []fault
+	disconnected bool
+
+	// userArea persists across Close, like the EEPROM it models.
+	userArea [simulatorUserArea]byte
+}
+
+func init() {
+	RegisterBackend(BackendSimulator, func() Backend {
+		return NewSimulator(rand.Uint64())
+	})
+}
+
+// NewSimulator returns a simulated device whose noise source is seeded with seed.
+func NewSimulator(seed uint64) *Simulator {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	s := &Simulator{
+		rng:   rng,
+		gain:  DefaultSimulatorGain,
+		state: rng.Float64(),
+	}
+
+	s.cond = sync.NewCond(&s.mu)
+
+	return s
+}
+
+// SetStalled makes Read block, as a wedged USB transfer would, until stalled is reset or the simulator is
+// interrupted or closed.
+func (s *Simulator) SetStalled(stalled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stalled = stalled
+
+	s.cond.Broadcast()
+}
+
+// Interrupt wakes a blocked Read, which then fails until the simulator is reopened.
+func (s *Simulator) Interrupt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interrupted = true
+
+	s.cond.Broadcast()
+}
+
+// InjectFault schedules kind for the Read that reaches sample offset at, counted over all samples Read has
+// returned (8 per raw output byte). Each fault fires once; a Read triggers at most one.
+func (s *Simulator) InjectFault(at uint64, kind FaultKind) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, _ := slices.BinarySearchFunc(s.faults, at, func(f fault, at uint64) int {
+		return cmp.Compare(f.at, at)
+	})
+
+	s.faults = slices.Insert(s.faults, i, fault{at: at, kind: kind})
+}
+
+// SetGain changes the loop gain of the simulated multiplier (default 1.82).
+// Gains closer to 1 model a degraded board with less entropy per bit.
+func (s *Simulator) SetGain(k float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gain = k
+}
+
+// Open marks the simulated device as connected. vid and pid are ignored.
+func (s *Simulator) Open(vid, pid uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.open = true
+	s.interrupted = false
+	s.disconnected = false
+	s.pending = s.pending[:0]
+
+	return nil
+}
+
+// SetBitMode records the bitbang configuration and purges pending samples.
+func (s *Simulator) SetBitMode(mask byte, mode byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.check()
+	if err != nil {
+		return err
+	}
+
+	s.mask = mask
+	s.mode = mode
+	s.pending = s.pending[:0]
+
+	return nil
+}
+
+// Write clocks p out and queues one sampled input byte per written byte.
+func (s *Simulator) Write(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.check()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range p {
+		s.pending = append(s.pending, s.sample())
+
+		s.prevOut = b
+	}
+
+	return nil
+}
+
+// Read returns previously queued samples.
+func (s *Simulator) Read(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	short := -1
+
+	if len(s.faults) > 0 && s.faults[0].at < s.delivered+uint64(len(p)) {
+		f := s.faults[0]
+
+		s.faults = s.faults[1:]
+
+		switch f.kind {
+		case FaultTimeout:
+			return fmt.Errorf("simulator read: %w", os.ErrDeadlineExceeded)
+		case FaultShortRead:
+			short = int(max(f.at, s.delivered) - s.delivered)
+		case FaultStall:
+			s.stalled = true
+		case FaultDisconnect:
+			s.disconnected = true
+		}
+	}
+
+	for s.stalled && s.open && !s.interrupted && !s.disconnected {
+		s.cond.Wait()
+	}
+
+	err := s.check()
+	if err != nil {
+		return err
+	}
+
+	if s.interrupted {
+		return errors.New("simulator interrupted")
+	}
+
+	if len(s.pending) < len(p) {
+		return fmt.Errorf("simulator read stall: have %d, want %d", len(s.pending), len(p))
+	}
+
+	if short >= 0 {
+		p = p[:short]
+	}
+
+	n := copy(p, s.pending)
+
+	s.pending = s.pending[:copy(s.pending, s.pending[n:])]
+	s.delivered += uint64(n)
+
+	if short >= 0 {
+		return fmt.Errorf("simulator short read: got %d samples: %w", n, ErrIO)
+	}
+
+	return nil
+}
+
+// check fails unless the simulated device is connected. s.mu must be held.
+func (s *Simulator) check() error {
+	if s.disconnected {
+		return ErrSimulatorDisconnected
+	}
+
+	if !s.open {
+		return errors.New("simulator not open")
+	}
+
+	return nil
+}
+
+// ReadUserArea returns the simulated EEPROM user area, which starts out zeroed.
+func (s *Simulator) ReadUserArea() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Clone(s.userArea[:]), nil
+}
+
+// WriteUserArea writes p to the start of the simulated EEPROM user area.
+func (s *Simulator) WriteUserArea(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.check()
+	if err != nil {
+		return err
+	}
+
+	if len(p) > len(s.userArea) {
+		return fmt.Errorf("%d bytes exceed the %d-byte user area", len(p), len(s.userArea))
+	}
+
+	copy(s.userArea[:], p)
+
+	return nil
+}
+
+// Close disconnects the simulated device.
+func (s *Simulator) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.open = false
+	s.pending = nil
+
+	s.cond.Broadcast()
+
+	return nil
+}
+
+// sample returns the pin states as seen before the current write is applied,
+// mirroring how synchronous bitbang mode reads the bus before driving it.
+func (s *Simulator) sample() byte {
+	bit := s.step()
+
+	in := s.prevOut & s.mask
+
+	if s.prevOut&(1<<SWEN2) != 0 {
+		in |= bit<<COMP2 | s.lastBit<<COMP1
+	} else {
+		in |= bit<<COMP1 | s.lastBit<<COMP2
+	}
+
+	s.lastBit = bit
+
+	return in
+}
+
+func (s *Simulator) step() byte {
+	var bit byte
+
+	if s.state >= 0.5 {
+		bit = 1
+
+		s.state = float64(s.gain*(s.state-1)) + 1
+	} else {
+		s.state = s.gain * s.state
+	}
+
+	// The explicit conversions prevent fused multiply-adds, whose rounding differs between architectures and
+	// would break the guarantee that a seed always yields the same output.
+	s.state += float64(s.rng.NormFloat64() * simulatorNoise)
+
+	s.state = min(max(s.state, 0), 1-1e-12)
+
+	return bit
+}
@@ -0,0 +1,251 @@
+package infnoise
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/coalaura/infnoise/sts"
+)
+
+const (
+	// SoakFailPValue is the p-value below which a test of the statistical battery fails a soak check. A soak runs
+	// the battery at every check, so at sts.Alpha a healthy board would fail one test in a hundred by chance.
+	SoakFailPValue = 1e-6
+
+	// soakSample bounds the whitened output a check runs the battery over, a million bits as SP 800-22 recommends.
+	soakSample = 1 << 17
+)
+
+// SoakOptions configures Soak.
+type SoakOptions struct {
+	// Duration is how long to read for.
+	Duration time.Duration
+
+	// Interval is the time between checks (default one minute).
+	Interval time.Duration
+
+	// ResetEvery, if positive, closes and restarts the device on this schedule to exercise reconnects.
+	ResetEvery time.Duration
+
+	// Log, if set, receives each SoakCheck as a line of JSON.
+	Log io.Writer
+
+	// now replaces time.Now in tests.
+	now func() time.Time
+}
+
+// SoakCheck is one periodic check of a soak test.
+type SoakCheck struct {
+	Time time.Time `json:"time"`
+
+	// Bytes is the whitened output read since the soak started.
+	Bytes uint64 `json:"bytes"`
+
+	// SelfTest is the SelfTest error, or empty if it passed.
+	SelfTest string `json:"selfTest,omitempty"`
+
+	// Battery holds the results of the SP 800-22 tests (see package sts) over up to 128 KiB of the output read since
+	// the previous check; it is empty if too little was read. Tests below SoakFailPValue fail the check.
+	Battery []sts.Result `json:"battery,omitempty"`
+
+	Status   HealthStatus `json:"status"`
+	Estimate float64      `json:"estimate"`
+	LongTerm float64      `json:"longTerm"`
+
+	// Reset is set if the device was restarted just before this check.
+	Reset bool `json:"reset,omitempty"`
+
+	Pass bool `json:"pass"`
+}
+
+// SoakReport is the outcome of a soak test.
+type SoakReport struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	Bytes  uint64 `json:"bytes"`
+	Checks int    `json:"checks"`
+	Resets int    `json:"resets"`
+
+	// Failures lists what went wrong, in order; Interrupted is set if the soak was cancelled before Duration.
+	Failures    []string `json:"failures"`
+	Interrupted bool     `json:"interrupted,omitempty"`
+
+	// Health is the final health report.
+	Health Report `json:"health"`
+
+	// Pass is set if the soak ran its full duration without failures.
+	Pass bool `json:"pass"`
+}
+
+// Soak burns in a started device: it reads whitened output continuously for opts.Duration, runs SelfTest and the
+// statistical battery and records the health estimates every opts.Interval, and restarts the device every
+// opts.ResetEvery. Read and restart errors are recorded as failures and followed by a restart, so a single glitch
+// does not end the run. Soak returns early if ctx is cancelled; the error is only set if opts is invalid or the
+// device cannot be restarted.
+func Soak(ctx context.Context, d *Device, opts SoakOptions) (SoakReport, error) {
+	if opts.Interval == 0 {
+		opts.Interval = time.Minute
+	}
+
+	if opts.Duration <= 0 || opts.Interval < 0 || opts.ResetEvery < 0 {
+		return SoakReport{}, fmt.Errorf("invalid soak duration %s, interval %s, or reset period %s", opts.Duration, opts.Interval, opts.ResetEvery)
+	}
+
+	now := opts.now
+	if now == nil {
+		now = time.Now
+	}
+
+	r := SoakReport{Start: now(), Failures: []string{}}
+
+	fail := func(format string, args ...any) {
+		r.Failures = append(r.Failures, now().UTC().Format(time.RFC3339)+": "+fmt.Sprintf(format, args...))
+	}
+
+	end := r.Start.Add(opts.Duration)
+	nextCheck := r.Start.Add(opts.Interval)
+
+	var nextReset time.Time
+
+	if opts.ResetEvery > 0 {
+		nextReset = r.Start.Add(opts.ResetEvery)
+	}
+
+	var (
+		reset bool
+		err   error
+	)
+
+	buf := make([]byte, WhitenedChunkSize)
+	sample := make([]byte, 0, soakSample)
+
+	defer func() {
+		clear(buf)
+		clear(sample[:cap(sample)])
+	}()
+
+	for t := now(); t.Before(end); t = now() {
+		if ctx.Err() != nil {
+			r.Interrupted = true
+
+			break
+		}
+
+		if !nextReset.IsZero() && !t.Before(nextReset) {
+			err = soakRestart(d)
+			if err != nil {
+				break
+			}
+
+			r.Resets++
+			reset = true
+
+			nextReset = t.Add(opts.ResetEvery)
+		}
+
+		if !t.Before(nextCheck) {
+			check := soakCheck(d, t, r.Bytes, sample, reset)
+
+			clear(sample)
+
+			sample = sample[:0]
+
+			if !check.Pass {
+				fail("check failed: self-test %q, status %s, estimate %.4f, battery failures %q", check.SelfTest, check.Status, check.Estimate, strings.Join(batteryFailures(check.Battery), ", "))
+			}
+
+			if opts.Log != nil {
+				json.NewEncoder(opts.Log).Encode(check)
+			}
+
+			r.Checks++
+			reset = false
+
+			nextCheck = t.Add(opts.Interval)
+		}
+
+		n, rerr := d.ReadWhitened(buf)
+
+		r.Bytes += uint64(n)
+
+		sample = append(sample, buf[:min(n, cap(sample)-len(sample))]...)
+
+		if rerr != nil {
+			fail("read failed: %v", rerr)
+
+			err = soakRestart(d)
+			if err != nil {
+				break
+			}
+
+			r.Resets++
+			reset = true
+		}
+	}
+
+	r.End = now()
+	r.Health = d.HealthReport()
+
+	for _, t := range r.Health.Tests {
+		if !t.Pass {
+			fail("%s: %s", t.Name, t.Detail)
+		}
+	}
+
+	if err != nil {
+		fail("restart failed: %v", err)
+	}
+
+	r.Pass = len(r.Failures) == 0 && !r.Interrupted
+
+	return r, err
+}
+
+func soakCheck(d *Device, t time.Time, bytes uint64, sample []byte, reset bool) SoakCheck {
+	c := SoakCheck{
+		Time:  t,
+		Bytes: bytes,
+		Reset: reset,
+	}
+
+	err := d.SelfTest()
+	if err != nil {
+		c.SelfTest = err.Error()
+	}
+
+	c.Status, c.Estimate = d.Health()
+	c.LongTerm = d.Drift().LongTermEntropy
+
+	if len(sample) >= sts.MinBytes {
+		c.Battery, _ = sts.Run(sample)
+	}
+
+	c.Pass = err == nil && c.Status == HealthOK && len(batteryFailures(c.Battery)) == 0
+
+	return c
+}
+
+// batteryFailures returns the names of the tests below SoakFailPValue.
+func batteryFailures(results []sts.Result) []string {
+	var failed []string
+
+	for _, r := range results {
+		if r.PValue < SoakFailPValue {
+			failed = append(failed, r.Name)
+		}
+	}
+
+	return failed
+}
+
+// soakRestart reopens the device. Close errors are ignored: a device that failed may not close cleanly.
+func soakRestart(d *Device) error {
+	d.Close()
+
+	return d.Start()
+}
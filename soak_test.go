@@ -0,0 +1,100 @@
+package infnoise
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stepClock returns a clock that advances by step whenever it is read, so a soak runs a fixed number of reads
+// however fast the machine is.
+func stepClock(step time.Duration) func() time.Time {
+	t := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	return func() time.Time {
+		t = t.Add(step)
+
+		return t
+	}
+}
+
+func TestSoak(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	var log bytes.Buffer
+
+	// 29 reads, checked after every fifth and restarted after the 12th and 24th.
+	r, err := Soak(context.Background(), dv, SoakOptions{
+		Duration:   300 * time.Millisecond,
+		Interval:   50 * time.Millisecond,
+		ResetEvery: 120 * time.Millisecond,
+		Log:        &log,
+		now:        stepClock(10 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.Pass || r.Checks != 5 || r.Resets != 2 || r.Bytes != 29*WhitenedChunkSize {
+		t.Fatalf("report: %+v", r)
+	}
+
+	var lines int
+
+	for sc := bufio.NewScanner(&log); sc.Scan(); {
+		var c SoakCheck
+
+		err := json.Unmarshal(sc.Bytes(), &c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !c.Pass || len(c.Battery) == 0 {
+			t.Fatalf("check %d: %+v", lines, c)
+		}
+
+		lines++
+	}
+
+	if lines != r.Checks {
+		t.Fatalf("logged %d checks, report counts %d", lines, r.Checks)
+	}
+
+	c := soakCheck(dv, time.Now(), 0, make([]byte, 4096), false)
+
+	if c.Pass || len(batteryFailures(c.Battery)) == 0 {
+		t.Fatalf("constant output passed the battery: %+v", c)
+	}
+}
+
+func TestSoakFailure(t *testing.T) {
+	dv, sim := openSimulator(t, 1, DefaultSimulatorGain)
+
+	sim.InjectFault(8*100000, FaultDisconnect)
+
+	r, err := Soak(context.Background(), dv, SoakOptions{
+		Duration: time.Second,
+		Interval: 250 * time.Millisecond,
+		now:      stepClock(10 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Pass || r.Resets != 1 || len(r.Failures) != 1 || !strings.Contains(r.Failures[0], "read failed") {
+		t.Fatalf("report: %+v", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	r, err = Soak(ctx, dv, SoakOptions{Duration: time.Hour})
+	if err != nil || r.Pass || !r.Interrupted {
+		t.Fatalf("cancelled soak: %+v, %v", r, err)
+	}
+}
@@ -0,0 +1,181 @@
+package infnoise
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Stats reports counters of the device's operation since New. They survive Close and a later Start.
+type Stats struct {
+	// Reseeds is the number of times OS entropy was folded into the whitener (see Reseed); LastReseed is when
+	// that last happened, or zero.
+	Reseeds    uint64    `json:"reseeds"`
+	LastReseed time.Time `json:"lastReseed"`
+
+	// Retries is the number of batches retried under WithRetry after a transient transport error.
+	Retries uint64 `json:"retries"`
+
+	// Reconnects is the number of times the USB handle was re-opened under WithReconnect.
+	Reconnects uint64 `json:"reconnects"`
+
+	// StaleDiscarded and StaleServed count the whitened bytes found older than WithMaxPoolAge, which were discarded
+	// or served according to the StalePolicy. A growing StaleServed indicates a consumer too slow for its chunk size.
+	StaleDiscarded uint64 `json:"staleDiscarded"`
+	StaleServed    uint64 `json:"staleServed"`
+
+	// Reads is the number of calls to Read and ReadWhitened, including failed ones.
+	Reads uint64 `json:"reads"`
+
+	// ReadLatency summarizes how long those calls took.
+	ReadLatency LatencyStats `json:"readLatency"`
+
+	// ReadSizes is the distribution of the sizes requested, in power-of-two buckets; empty buckets are omitted.
+	ReadSizes []SizeBucket `json:"readSizes"`
+
+	// TransportErrors, ProtocolErrors, and HealthErrors count the reads that failed with an error of each class.
+	TransportErrors uint64 `json:"transportErrors"`
+	ProtocolErrors  uint64 `json:"protocolErrors"`
+	HealthErrors    uint64 `json:"healthErrors"`
+}
+
+// LatencyStats are percentiles of a latency distribution. Percentiles are the upper bound of the power-of-two
+// bucket they fall in, so they overestimate by up to a factor of two; Max is exact.
+type LatencyStats struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// SizeBucket counts the reads of more than half of UpTo and at most UpTo bytes (UpTo 0 counts empty reads).
+type SizeBucket struct {
+	UpTo  int    `json:"upTo"`
+	Count uint64 `json:"count"`
+}
+
+const (
+	// latencyBuckets covers 1µs to about 17 minutes in powers of two; slower reads land in the last bucket.
+	latencyBuckets = 31
+
+	// sizeBuckets covers 0 bytes to 1 GiB in powers of two.
+	sizeBuckets = 32
+)
+
+// readStats records the latency and size of every read without locking.
+type readStats struct {
+	count   atomic.Uint64
+	max     atomic.Int64
+	latency [latencyBuckets]atomic.Uint64
+	sizes   [sizeBuckets]atomic.Uint64
+	failed  [ClassClosed + 1]atomic.Uint64
+
+	// served is when a read last returned output, in Unix nanoseconds.
+	served atomic.Int64
+}
+
+func (s *readStats) record(size int, took time.Duration, err error) {
+	s.count.Add(1)
+
+	if err != nil {
+		s.failed[Classify(err)].Add(1)
+	} else if size > 0 {
+		s.served.Store(time.Now().UnixNano())
+	}
+
+	s.latency[min(bits.Len64(uint64(took.Microseconds())), latencyBuckets-1)].Add(1)
+	s.sizes[min(bits.Len(uint(max(size-1, 0)))+min(size, 1), sizeBuckets-1)].Add(1)
+
+	for {
+		cur := s.max.Load()
+		if int64(took) <= cur || s.max.CompareAndSwap(cur, int64(took)) {
+			break
+		}
+	}
+}
+
+func (s *readStats) latencyStats() LatencyStats {
+	var (
+		counts [latencyBuckets]uint64
+		total  uint64
+	)
+
+	for i := range counts {
+		counts[i] = s.latency[i].Load()
+		total += counts[i]
+	}
+
+	l := LatencyStats{Max: time.Duration(s.max.Load())}
+
+	if total == 0 {
+		return l
+	}
+
+	percentile := func(p float64) time.Duration {
+		rank := uint64(p * float64(total))
+
+		var seen uint64
+
+		for i, c := range counts {
+			seen += c
+
+			if seen > rank {
+				// Bucket i holds latencies below 2^i µs.
+				return min(time.Duration(1<<i)*time.Microsecond, l.Max)
+			}
+		}
+
+		return l.Max
+	}
+
+	l.P50, l.P90, l.P99 = percentile(0.5), percentile(0.9), percentile(0.99)
+
+	return l
+}
+
+func (s *readStats) sizeStats() []SizeBucket {
+	var out []SizeBucket
+
+	for i := range s.sizes {
+		c := s.sizes[i].Load()
+		if c == 0 {
+			continue
+		}
+
+		upTo := 0
+		if i > 0 {
+			upTo = 1 << (i - 1)
+		}
+
+		out = append(out, SizeBucket{UpTo: upTo, Count: c})
+	}
+
+	return out
+}
+
+// Stats returns a snapshot of the device's counters. It does not wait for reads in progress.
+func (d *Device) Stats() Stats {
+	var s Stats
+
+	s.Reseeds = d.reseeds.Load()
+
+	if last := d.lastReseed.Load(); last != 0 {
+		s.LastReseed = time.Unix(0, last)
+	}
+
+	s.Retries = d.retried.Load()
+	s.Reconnects = d.reconnected.Load()
+
+	s.StaleDiscarded = d.staleDiscarded.Load()
+	s.StaleServed = d.staleServed.Load()
+
+	s.Reads = d.readStats.count.Load()
+	s.ReadLatency = d.readStats.latencyStats()
+	s.ReadSizes = d.readStats.sizeStats()
+
+	s.TransportErrors = d.readStats.failed[ClassTransport].Load()
+	s.ProtocolErrors = d.readStats.failed[ClassProtocol].Load()
+	s.HealthErrors = d.readStats.failed[ClassHealth].Load()
+
+	return s
+}
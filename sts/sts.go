@@ -0,0 +1,351 @@
+// Package sts implements a subset of the NIST SP 800-22 statistical test suite: the frequency, block frequency,
+// runs, longest run of ones, cumulative sums, approximate entropy, and serial tests.
+//
+// The tests look for patterns a uniform random sequence is unlikely to show. They are a sanity check for whitened
+// output, not a certification; raw Infinite Noise output is expected to fail them, since it is neither unbiased nor
+// uncorrelated.
+package sts
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// Alpha is the significance level: a test passes if its p-value is at least Alpha.
+	Alpha = 0.01
+
+	// MinBytes is the shortest input Run accepts; NIST recommends a million bits or more for meaningful results.
+	MinBytes = 64
+)
+
+// Result is the outcome of one test. Tests yielding two statistics report each as its own Result.
+type Result struct {
+	Name   string  `json:"name"`
+	PValue float64 `json:"pValue"`
+	Pass   bool    `json:"pass"`
+}
+
+// Run applies every test to data, read most significant bit first. Block lengths are chosen for the input size
+// within the limits SP 800-22 recommends.
+func Run(data []byte) ([]Result, error) {
+	if len(data) < MinBytes {
+		return nil, fmt.Errorf("input too short: %d bytes (need at least %d)", len(data), MinBytes)
+	}
+
+	seq := make([]byte, 8*len(data))
+
+	for i := range seq {
+		seq[i] = data[i/8] >> (7 - i%8) & 1
+	}
+
+	n := len(seq)
+
+	// Approximate entropy needs m < log2(n) - 5, and the serial test m >= 3.
+	m := min(10, bits.Len(uint(n))-7)
+
+	serial1, serial2 := serial(seq, m)
+
+	var out []Result
+
+	for _, r := range []struct {
+		name string
+		p    float64
+	}{
+		{"frequency", frequency(seq)},
+		{"block-frequency", blockFrequency(seq, max(128, n/99+1))},
+		{"runs", runs(seq)},
+		{"longest-run", longestRun(seq)},
+		{"cumulative-sums-forward", cumulativeSums(seq, false)},
+		{"cumulative-sums-reverse", cumulativeSums(seq, true)},
+		{"approximate-entropy", approximateEntropy(seq, m)},
+		{"serial-1", serial1},
+		{"serial-2", serial2},
+	} {
+		out = append(out, Result{Name: r.name, PValue: r.p, Pass: r.p >= Alpha})
+	}
+
+	return out, nil
+}
+
+// frequency is the frequency (monobit) test (SP 800-22 2.1).
+func frequency(seq []byte) float64 {
+	var s int
+
+	for _, b := range seq {
+		s += 2*int(b) - 1
+	}
+
+	return math.Erfc(math.Abs(float64(s)) / math.Sqrt(float64(len(seq))) / math.Sqrt2)
+}
+
+// blockFrequency is the frequency test within blocks of m bits (SP 800-22 2.2).
+func blockFrequency(seq []byte, m int) float64 {
+	blocks := len(seq) / m
+
+	var chi2 float64
+
+	for i := range blocks {
+		var ones int
+
+		for _, b := range seq[i*m : (i+1)*m] {
+			ones += int(b)
+		}
+
+		d := float64(ones)/float64(m) - 0.5
+
+		chi2 += d * d
+	}
+
+	chi2 *= 4 * float64(m)
+
+	return igamc(float64(blocks)/2, chi2/2)
+}
+
+// runs is the runs test (SP 800-22 2.3).
+func runs(seq []byte) float64 {
+	n := float64(len(seq))
+
+	var ones int
+
+	for _, b := range seq {
+		ones += int(b)
+	}
+
+	pi := float64(ones) / n
+
+	// The frequency prerequisite: a sequence this biased fails without counting runs.
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(n) {
+		return 0
+	}
+
+	v := 1
+
+	for i := 1; i < len(seq); i++ {
+		if seq[i] != seq[i-1] {
+			v++
+		}
+	}
+
+	return math.Erfc(math.Abs(float64(v)-2*n*pi*(1-pi)) / (2 * math.Sqrt(2*n) * pi * (1 - pi)))
+}
+
+// longestRunClasses are the block lengths, the class bounds of the longest run of ones per block, and the class
+// probabilities of SP 800-22 2.4.4, for inputs of at least minBits.
+var longestRunClasses = []struct {
+	minBits, m, lo int
+	probs          []float64
+}{
+	{750000, 10000, 10, []float64{0.0882, 0.2092, 0.2483, 0.1933, 0.1208, 0.0675, 0.0727}},
+	{6272, 128, 4, []float64{0.1174, 0.2430, 0.2493, 0.1752, 0.1027, 0.1124}},
+	{128, 8, 1, []float64{0.2148, 0.3672, 0.2305, 0.1875}},
+}
+
+// longestRun is the test for the longest run of ones in a block (SP 800-22 2.4); seq holds at least 128 bits.
+func longestRun(seq []byte) float64 {
+	c := longestRunClasses[len(longestRunClasses)-1]
+
+	for _, class := range longestRunClasses {
+		if len(seq) >= class.minBits {
+			c = class
+
+			break
+		}
+	}
+
+	blocks := len(seq) / c.m
+	counts := make([]int, len(c.probs))
+
+	for i := range blocks {
+		var run, longest int
+
+		for _, b := range seq[i*c.m : (i+1)*c.m] {
+			if b == 0 {
+				run = 0
+
+				continue
+			}
+
+			run++
+			longest = max(longest, run)
+		}
+
+		counts[min(max(longest-c.lo, 0), len(counts)-1)]++
+	}
+
+	var chi2 float64
+
+	for i, p := range c.probs {
+		e := float64(blocks) * p
+		d := float64(counts[i]) - e
+
+		chi2 += d * d / e
+	}
+
+	return igamc(float64(len(c.probs)-1)/2, chi2/2)
+}
+
+// cumulativeSums is the cumulative sums test (SP 800-22 2.13), walking seq backwards if reverse is set.
+func cumulativeSums(seq []byte, reverse bool) float64 {
+	var s, z int
+
+	for i := range seq {
+		b := seq[i]
+		if reverse {
+			b = seq[len(seq)-1-i]
+		}
+
+		s += 2*int(b) - 1
+		z = max(z, s, -s)
+	}
+
+	n, zf := float64(len(seq)), float64(z)
+	sqrtN := math.Sqrt(n)
+
+	phi := func(x float64) float64 {
+		return 0.5 * math.Erfc(-x/math.Sqrt2)
+	}
+
+	// The summation bounds are truncated towards zero, as in the reference implementation.
+	var sum1, sum2 float64
+
+	for k := math.Trunc((-n/zf + 1) / 4); k <= math.Trunc((n/zf-1)/4); k++ {
+		sum1 += phi((4*k+1)*zf/sqrtN) - phi((4*k-1)*zf/sqrtN)
+	}
+
+	for k := math.Trunc((-n/zf - 3) / 4); k <= math.Trunc((n/zf-1)/4); k++ {
+		sum2 += phi((4*k+3)*zf/sqrtN) - phi((4*k+1)*zf/sqrtN)
+	}
+
+	return 1 - sum1 + sum2
+}
+
+// patternCounts counts the overlapping m-bit patterns of seq, wrapping around at the end.
+func patternCounts(seq []byte, m int) []int {
+	counts := make([]int, 1<<m)
+
+	if m == 0 {
+		return counts
+	}
+
+	mask := 1<<m - 1
+
+	var w int
+
+	for i := range len(seq) + m - 1 {
+		w = (w<<1 | int(seq[i%len(seq)])) & mask
+
+		if i >= m-1 {
+			counts[w]++
+		}
+	}
+
+	return counts
+}
+
+// approximateEntropy is the approximate entropy test with blocks of m bits (SP 800-22 2.12).
+func approximateEntropy(seq []byte, m int) float64 {
+	n := float64(len(seq))
+
+	phi := func(m int) float64 {
+		var sum float64
+
+		for _, c := range patternCounts(seq, m) {
+			if c > 0 {
+				p := float64(c) / n
+
+				sum += p * math.Log(p)
+			}
+		}
+
+		return sum
+	}
+
+	chi2 := 2 * n * (math.Ln2 - (phi(m) - phi(m+1)))
+
+	return igamc(math.Exp2(float64(m-1)), chi2/2)
+}
+
+// serial is the serial test with blocks of m >= 3 bits (SP 800-22 2.11), returning both p-values.
+func serial(seq []byte, m int) (float64, float64) {
+	n := float64(len(seq))
+
+	psi2 := func(m int) float64 {
+		if m == 0 {
+			return 0
+		}
+
+		var sum float64
+
+		for _, c := range patternCounts(seq, m) {
+			sum += float64(c) * float64(c)
+		}
+
+		return sum*math.Exp2(float64(m))/n - n
+	}
+
+	pm, pm1, pm2 := psi2(m), psi2(m-1), psi2(m-2)
+
+	return igamc(math.Exp2(float64(m-2)), (pm-pm1)/2), igamc(math.Exp2(float64(m-3)), (pm-2*pm1+pm2)/2)
+}
+
+// igamc is the regularized upper incomplete gamma function Q(a, x), by its series below a+1 and its continued
+// fraction above.
+func igamc(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+
+	lg, _ := math.Lgamma(a)
+
+	front := math.Exp(a*math.Log(x) - x - lg)
+
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+
+		for k := 1.0; k < 1000; k++ {
+			term *= x / (a + k)
+			sum += term
+
+			if term < sum*1e-15 {
+				break
+			}
+		}
+
+		return max(0, 1-front*sum)
+	}
+
+	// Modified Lentz evaluation of the continued fraction.
+	const tiny = 1e-300
+
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+
+	for i := 1.0; i < 1000; i++ {
+		an := -i * (i - a)
+		b += 2
+
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+
+		d = 1 / d
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+
+	return front * h
+}
@@ -0,0 +1,96 @@
+package sts
+
+import (
+	"crypto/sha256"
+	"math"
+	"testing"
+)
+
+// epsilon100 is the 100-bit example sequence of SP 800-22 2.1.8 and the sections reusing it.
+const epsilon100 = "1100100100001111110110101010001000100001011010001100001000110100110001001100011001100010100010111000"
+
+func bitString(s string) []byte {
+	seq := make([]byte, len(s))
+
+	for i := range s {
+		seq[i] = s[i] - '0'
+	}
+
+	return seq
+}
+
+func TestExamples(t *testing.T) {
+	eps := bitString(epsilon100)
+
+	serial1, serial2 := serial(bitString("0011011101"), 3)
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"frequency", frequency(eps), 0.109599},
+		{"block-frequency", blockFrequency(eps, 10), 0.706438},
+		{"runs", runs(eps), 0.500798},
+		{"longest-run", longestRun(bitString("11001100000101010110110001001100111000000000001001001101010100010001001111010110100000001101011111001100111001101101100010110010")), 0.180609},
+		{"cumulative-sums-forward", cumulativeSums(eps, false), 0.219194},
+		{"cumulative-sums-reverse", cumulativeSums(eps, true), 0.114866},
+		{"approximate-entropy", approximateEntropy(eps, 2), 0.235301},
+		{"serial-1", serial1, 0.808792},
+		{"serial-2", serial2, 0.670320},
+	}
+
+	// The published p-values went through intermediate rounding.
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 5e-5 {
+			t.Errorf("%s: p = %.6f, want %.6f", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	// A SHA-256 counter stream stands in for good random data.
+	var data []byte
+
+	for i := range 4096 {
+		sum := sha256.Sum256([]byte{byte(i), byte(i >> 8)})
+
+		data = append(data, sum[:]...)
+	}
+
+	results, err := Run(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 9 {
+		t.Fatalf("%d results", len(results))
+	}
+
+	for _, r := range results {
+		if !r.Pass {
+			t.Errorf("%s failed on hashed data: p = %.6f", r.Name, r.PValue)
+		}
+	}
+
+	for i := range data {
+		data[i] = 0x55
+	}
+
+	results, _ = Run(data)
+
+	// Alternating bits are perfectly balanced, so only the tests looking at order catch them.
+	for _, r := range results {
+		switch r.Name {
+		case "runs", "longest-run", "approximate-entropy", "serial-1", "serial-2":
+			if r.Pass {
+				t.Errorf("%s passed alternating bits: p = %.6f", r.Name, r.PValue)
+			}
+		}
+	}
+
+	_, err = Run(data[:MinBytes-1])
+	if err == nil {
+		t.Fatal("accepted a short input")
+	}
+}
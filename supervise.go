@@ -0,0 +1,55 @@
+package infnoise
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// panics counts the panics recovered by Supervise since the process started.
+var panics atomic.Uint64
+
+// PanicError is the failure of a supervised goroutine that panicked. The panic is recovered, so it fails the work
+// relying on the goroutine instead of crashing the process.
+type PanicError struct {
+	// Goroutine names the goroutine that panicked, such as "usb reader".
+	Goroutine string
+
+	// Value is what was passed to panic, and Stack the goroutine's stack when it panicked.
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", e.Goroutine, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+
+	return err
+}
+
+// Supervise runs fn and returns its error or, if it panics, a *PanicError. The package's background goroutines (the
+// backends' USB readers, the Broker, and pipes) run under it, and applications can run their own, such as a kernel
+// feeder, so every recovered panic is counted in Panics.
+func Supervise(goroutine string, fn func() error) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+
+		panics.Add(1)
+
+		err = &PanicError{Goroutine: goroutine, Value: v, Stack: debug.Stack()}
+	}()
+
+	return fn()
+}
+
+// Panics returns the number of panics Supervise has recovered since the process started.
+func Panics() uint64 {
+	return panics.Load()
+}
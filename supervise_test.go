@@ -0,0 +1,86 @@
+package infnoise
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSupervise(t *testing.T) {
+	if err := Supervise("ok", func() error { return nil }); err != nil {
+		t.Fatalf("Supervise = %v", err)
+	}
+
+	if err := Supervise("eof", func() error { return io.EOF }); err != io.EOF {
+		t.Fatalf("Supervise = %v, want the error returned", err)
+	}
+
+	before := Panics()
+
+	err := Supervise("reader", func() error {
+		panic(io.ErrUnexpectedEOF)
+	})
+
+	var pe *PanicError
+
+	if !errors.As(err, &pe) || pe.Goroutine != "reader" || len(pe.Stack) == 0 {
+		t.Fatalf("Supervise = %#v, want a *PanicError", err)
+	}
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("the panic value does not unwrap")
+	}
+
+	if Panics() != before+1 {
+		t.Fatalf("Panics = %d, want %d", Panics(), before+1)
+	}
+}
+
+// panickingBackend panics in the next Read after armed is set.
+type panickingBackend struct {
+	*Simulator
+
+	armed *atomic.Bool
+}
+
+func (b panickingBackend) Read(p []byte) error {
+	if b.armed.CompareAndSwap(true, false) {
+		panic("injected")
+	}
+
+	return b.Simulator.Read(p)
+}
+
+func TestBrokerPanic(t *testing.T) {
+	var armed atomic.Bool
+
+	dv := New(WithBackend(registerSimulator(t, panickingBackend{Simulator: NewSimulator(1), armed: &armed})), WithChunkSize(64))
+
+	err := dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dv.Close()
+
+	b := NewBroker(dv)
+	defer b.Close()
+
+	s := b.Subscribe()
+	defer s.Close()
+
+	armed.Store(true)
+
+	var pe *PanicError
+
+	_, err = s.Read(make([]byte, 16))
+	if !errors.As(err, &pe) {
+		t.Fatalf("Read = %v, want the backend's panic", err)
+	}
+
+	_, err = s.Read(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("the broker did not survive a panic: %v", err)
+	}
+}
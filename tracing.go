@@ -0,0 +1,61 @@
+package infnoise
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the package's spans.
+const tracerName = "github.com/coalaura/infnoise"
+
+// Span attributes recorded on reads, batches, and USB transfers.
+const (
+	attrRequested = attribute.Key("infnoise.requested_bytes")
+	attrRead      = attribute.Key("infnoise.read_bytes")
+	attrMode      = attribute.Key("infnoise.read_mode")
+	attrBatch     = attribute.Key("infnoise.batch_bytes")
+	attrQueued    = attribute.Key("infnoise.queued_ms")
+	attrRecovery  = attribute.Key("infnoise.quarantine_recovery")
+	attrTransfer  = attribute.Key("infnoise.usb.bytes")
+)
+
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		return noop.Tracer{}
+	}
+
+	return tp.Tracer(tracerName)
+}
+
+// endSpan records the outcome of an operation that returned n bytes and ends the span.
+func endSpan(span trace.Span, n int, err error) {
+	span.SetAttributes(attrRead.Int(n))
+
+	finishSpan(span, err)
+}
+
+// finishSpan records err, if any, and ends the span.
+func finishSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// usbSpan starts a span for a USB transfer of n bytes in the current batch. mu must be held.
+func (d *Device) usbSpan(name string, n int) trace.Span {
+	ctx := d.batchCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, span := d.tracer.Start(ctx, name, trace.WithAttributes(attrTransfer.Int(n)))
+
+	return span
+}
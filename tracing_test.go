@@ -0,0 +1,107 @@
+package infnoise
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recorder is a TracerProvider that keeps the name, parent, and attributes of every ended span.
+type recorder struct {
+	noop.TracerProvider
+
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	noop.Span
+
+	rec    *recorder
+	name   string
+	parent string
+	attrs  map[attribute.Key]attribute.Value
+}
+
+type recordingTracer struct {
+	noop.Tracer
+
+	rec *recorder
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{rec: r}
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordedSpan{rec: t.rec, name: name, attrs: map[attribute.Key]attribute.Value{}}
+
+	if parent, ok := trace.SpanFromContext(ctx).(*recordedSpan); ok {
+		span.parent = parent.name
+	}
+
+	cfg := trace.NewSpanStartConfig(opts...)
+
+	span.SetAttributes(cfg.Attributes()...)
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+
+	s.rec.spans = append(s.rec.spans, s)
+}
+
+func TestTracing(t *testing.T) {
+	rec := &recorder{}
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithTracerProvider(rec), WithReadMode(ModeRawTap))
+
+	_, err := dv.ReadWhitened(make([]byte, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dv.Read(make([]byte, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parents := map[string]string{}
+
+	for _, s := range rec.spans {
+		parents[s.name] = s.parent
+
+		if s.name == "infnoise.batch" && s.attrs[attrBatch].AsInt64() == 0 {
+			t.Fatal("batch span lacks its size")
+		}
+	}
+
+	want := map[string]string{
+		"infnoise.ReadWhitened": "",
+		"infnoise.harvest":      "infnoise.ReadWhitened",
+		"infnoise.batch":        "infnoise.harvest",
+		"infnoise.usb.write":    "infnoise.batch",
+		"infnoise.usb.read":     "infnoise.batch",
+		"infnoise.Read":         "",
+	}
+
+	for name, parent := range want {
+		got, ok := parents[name]
+		if !ok || got != parent {
+			t.Errorf("span %s: recorded %v, parent %q (want %q)", name, ok, got, parent)
+		}
+	}
+}
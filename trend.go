@@ -0,0 +1,183 @@
+package infnoise
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TrendFormat is the encoding of a trend sink (see WithTrendSink).
+type TrendFormat int
+
+const (
+	// TrendCSV writes a header line and then one "time,estimate,long_term,status" row per point.
+	TrendCSV TrendFormat = iota
+
+	// TrendJSONL writes one JSON-encoded TrendPoint per line.
+	TrendJSONL
+)
+
+// TrendPoint is one sample of the long-term trend recorder.
+type TrendPoint struct {
+	Time time.Time `json:"time"`
+
+	// Estimate is the health check's entropy estimate since Start; LongTerm is DriftStats.LongTermEntropy.
+	Estimate float64 `json:"estimate"`
+	LongTerm float64 `json:"longTerm"`
+
+	Status HealthStatus `json:"status"`
+}
+
+// trendRecorder samples the device at a fixed interval into a ring of the most recent points and, optionally, a
+// sink. The series outlives Close, so history is kept across reconnects.
+type trendRecorder struct {
+	interval time.Duration
+
+	mu     sync.Mutex
+	points []TrendPoint
+	head   int
+	count  int
+	timer  *time.Timer
+	sample func() TrendPoint
+
+	// sink stops receiving points after its first write error.
+	sink   io.Writer
+	format TrendFormat
+	csv    *csv.Writer
+}
+
+func newTrendRecorder(interval time.Duration, points int, sink io.Writer, format TrendFormat) *trendRecorder {
+	t := &trendRecorder{
+		interval: interval,
+		points:   make([]TrendPoint, points),
+		sink:     sink,
+		format:   format,
+	}
+
+	if sink != nil && format == TrendCSV {
+		t.csv = csv.NewWriter(sink)
+
+		t.writeCSV([]string{"time", "estimate", "long_term", "status"})
+	}
+
+	return t
+}
+
+func (t *trendRecorder) start(sample func() TrendPoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil {
+		return
+	}
+
+	t.sample = sample
+	t.timer = time.AfterFunc(t.interval, t.tick)
+}
+
+func (t *trendRecorder) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+
+		t.timer = nil
+	}
+}
+
+func (t *trendRecorder) tick() {
+	t.mu.Lock()
+	sample := t.sample
+	running := t.timer != nil
+	t.mu.Unlock()
+
+	if !running {
+		return
+	}
+
+	p := sample()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer == nil {
+		return
+	}
+
+	t.add(p)
+
+	t.timer.Reset(t.interval)
+}
+
+func (t *trendRecorder) add(p TrendPoint) {
+	t.points[t.head] = p
+
+	t.head = (t.head + 1) % len(t.points)
+	t.count = min(t.count+1, len(t.points))
+
+	switch {
+	case t.sink == nil:
+	case t.csv != nil:
+		t.writeCSV([]string{
+			p.Time.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(p.Estimate, 'f', 6, 64),
+			strconv.FormatFloat(p.LongTerm, 'f', 6, 64),
+			p.Status.String(),
+		})
+	default:
+		err := json.NewEncoder(t.sink).Encode(p)
+		if err != nil {
+			t.sink = nil
+		}
+	}
+}
+
+func (t *trendRecorder) writeCSV(record []string) {
+	t.csv.Write(record)
+	t.csv.Flush()
+
+	if t.csv.Error() != nil {
+		t.sink = nil
+		t.csv = nil
+	}
+}
+
+// series returns the recorded points, oldest first.
+func (t *trendRecorder) series() []TrendPoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]TrendPoint, 0, t.count)
+
+	start := (t.head - t.count + len(t.points)) % len(t.points)
+
+	for i := range t.count {
+		out = append(out, t.points[(start+i)%len(t.points)])
+	}
+
+	return out
+}
+
+// Trend returns the points recorded by WithTrend, oldest first, or nil if trend recording is off.
+func (d *Device) Trend() []TrendPoint {
+	if d.trend == nil {
+		return nil
+	}
+
+	return d.trend.series()
+}
+
+func (d *Device) trendPoint() TrendPoint {
+	status, estimate := d.Health()
+
+	return TrendPoint{
+		Time:     time.Now(),
+		Estimate: estimate,
+		LongTerm: d.Drift().LongTermEntropy,
+		Status:   status,
+	}
+}
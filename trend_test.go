@@ -0,0 +1,72 @@
+package infnoise
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrend(t *testing.T) {
+	var sink bytes.Buffer
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithTrend(5*time.Millisecond, 3), WithTrendSink(&sink, TrendJSONL))
+
+	_, err := dv.Read(make([]byte, testBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	dv.Close()
+
+	points := dv.Trend()
+	if len(points) != 3 {
+		t.Fatalf("kept %d points, want the 3 most recent", len(points))
+	}
+
+	for i, p := range points {
+		if p.Estimate <= 0 || p.Status != HealthOK || (i > 0 && !p.Time.After(points[i-1].Time)) {
+			t.Fatalf("point %d: %+v", i, p)
+		}
+	}
+
+	var lines int
+
+	sc := bufio.NewScanner(&sink)
+
+	for sc.Scan() {
+		var p TrendPoint
+
+		err := json.Unmarshal(sc.Bytes(), &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines++
+	}
+
+	if lines < len(points) {
+		t.Fatalf("sink has %d lines, want at least %d", lines, len(points))
+	}
+
+	if last := dv.Trend(); len(last) != 3 || last[2] != points[2] {
+		t.Fatal("trend kept recording after Close")
+	}
+}
+
+func TestTrendCSV(t *testing.T) {
+	var sink strings.Builder
+
+	rec := newTrendRecorder(time.Hour, 2, &sink, TrendCSV)
+
+	rec.add(TrendPoint{Time: time.Unix(0, 0), Estimate: 0.86, LongTerm: 0.865})
+
+	want := "time,estimate,long_term,status\n1970-01-01T00:00:00Z,0.860000,0.865000,ok\n"
+	if sink.String() != want {
+		t.Fatalf("csv:\n%s\nwant:\n%s", sink.String(), want)
+	}
+}
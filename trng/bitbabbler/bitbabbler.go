@@ -0,0 +1,170 @@
+// Package bitbabbler drives BitBabbler USB random number generators, which expose their noise source through an FTDI FT232H in MPSSE mode.
+package bitbabbler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/trng"
+)
+
+const (
+	VendorID  = 0x0403
+	ProductID = 0x7840
+
+	// DefaultBitRate is the MPSSE clock used to shift noise in, in bits per second.
+	DefaultBitRate = 2_500_000
+
+	// MaxTransfer is the largest read a single MPSSE command can request.
+	MaxTransfer = 65536
+
+	mpsseDisableDiv5      = 0x8A
+	mpsseDisableAdaptive  = 0x97
+	mpsseDisable3Phase    = 0x8D
+	mpsseSetClockDivisor  = 0x86
+	mpsseSetBitsLow       = 0x80
+	mpsseLoopbackOff      = 0x85
+	mpsseReadBytesPosMSB  = 0x20
+	mpsseSendImmediate    = 0x87
+	mpsseBaseClock        = 60_000_000
+	mpsseLowPinDirections = 0x0B
+)
+
+var _ trng.Source = (*Device)(nil)
+
+// Device represents a connection to a BitBabbler hardware unit.
+type Device struct {
+	mu      sync.Mutex
+	backend infnoise.BackendName
+	usbDev  infnoise.Backend
+	bitRate int
+	health  *infnoise.HealthCheck
+	running bool
+
+	cmd []byte
+}
+
+// New initializes a new BitBabbler device.
+func New(opts ...option) *Device {
+	conf := &options{
+		bitRate:       DefaultBitRate,
+		targetEntropy: 1.0,
+		tolerance:     0.05,
+		window:        80000,
+	}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	return &Device{
+		backend: conf.backend,
+		bitRate: conf.bitRate,
+		health:  infnoise.NewHealthCheck(conf.targetEntropy, conf.tolerance, conf.window),
+
+		cmd: make([]byte, 4),
+	}
+}
+
+// Start opens the USB connection and configures the MPSSE engine to clock in noise.
+func (d *Device) Start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.bitRate <= 0 || d.bitRate > mpsseBaseClock/2 {
+		return fmt.Errorf("bit rate %d out of range", d.bitRate)
+	}
+
+	handle, err := infnoise.OpenBackend(d.backend, VendorID, ProductID)
+	if err != nil {
+		return err
+	}
+
+	err = handle.SetBitMode(0, infnoise.BitModeMPSSE)
+	if err != nil {
+		handle.Close()
+
+		return err
+	}
+
+	div := mpsseBaseClock/(2*d.bitRate) - 1
+
+	setup := []byte{
+		mpsseDisableDiv5,
+		mpsseDisableAdaptive,
+		mpsseDisable3Phase,
+		mpsseLoopbackOff,
+		mpsseSetClockDivisor, byte(div), byte(div >> 8),
+		mpsseSetBitsLow, 0x00, mpsseLowPinDirections,
+	}
+
+	err = handle.Write(setup)
+	if err != nil {
+		handle.Close()
+
+		return err
+	}
+
+	d.usbDev = handle
+	d.running = true
+
+	return nil
+}
+
+// Read fills p with the raw bitstream shifted in from the noise source, which passes through a health check; its
+// failures wrap infnoise.ErrHealthCheck.
+func (d *Device) Read(p []byte) (n int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running {
+		return 0, errors.New("device not started")
+	}
+
+	for n < len(p) {
+		chunk := min(len(p)-n, MaxTransfer)
+
+		d.cmd[0] = mpsseReadBytesPosMSB
+		d.cmd[1] = byte(chunk - 1)
+		d.cmd[2] = byte((chunk - 1) >> 8)
+		d.cmd[3] = mpsseSendImmediate
+
+		err := d.usbDev.Write(d.cmd)
+		if err != nil {
+			return n, err
+		}
+
+		err = d.usbDev.Read(p[n : n+chunk])
+		if err != nil {
+			return n, err
+		}
+
+		if !d.health.Add(p[n : n+chunk]) {
+			return n, d.health.Err()
+		}
+
+		n += chunk
+	}
+
+	return n, nil
+}
+
+// Close stops the device and releases the underlying USB handle.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.running = false
+
+	if d.usbDev != nil {
+		err := d.usbDev.Close()
+
+		d.usbDev = nil
+
+		return err
+	}
+
+	return nil
+}
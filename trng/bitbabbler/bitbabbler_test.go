@@ -0,0 +1,121 @@
+package bitbabbler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/coalaura/infnoise"
+)
+
+// fakeMPSSE stands in for the FT232H: it records the commands written to it and answers reads from src.
+type fakeMPSSE struct {
+	src    io.Reader
+	mode   byte
+	writes [][]byte
+}
+
+func (f *fakeMPSSE) Open(vid, pid uint16) error {
+	if vid != VendorID || pid != ProductID {
+		return infnoise.ErrDeviceNotFound
+	}
+
+	return nil
+}
+
+func (f *fakeMPSSE) SetBitMode(mask byte, mode byte) error {
+	f.mode = mode
+
+	return nil
+}
+
+func (f *fakeMPSSE) Write(p []byte) error {
+	f.writes = append(f.writes, bytes.Clone(p))
+
+	return nil
+}
+
+func (f *fakeMPSSE) Read(p []byte) error {
+	_, err := io.ReadFull(f.src, p)
+
+	return err
+}
+
+func (f *fakeMPSSE) Close() error {
+	return nil
+}
+
+func openFake(t *testing.T, src io.Reader, opts ...option) (*Device, *fakeMPSSE) {
+	t.Helper()
+
+	fake := &fakeMPSSE{src: src}
+
+	name := infnoise.BackendName("test-bitbabbler-" + t.Name())
+
+	infnoise.RegisterBackend(name, func() infnoise.Backend {
+		return fake
+	})
+
+	d := New(append([]option{WithBackend(name)}, opts...)...)
+
+	err := d.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		d.Close()
+	})
+
+	return d, fake
+}
+
+func TestRead(t *testing.T) {
+	d, fake := openFake(t, rand.NewChaCha8([32]byte{1}), WithBitRate(1_000_000))
+
+	if fake.mode != infnoise.BitModeMPSSE {
+		t.Fatalf("bit mode %#x, want MPSSE", fake.mode)
+	}
+
+	// 60 MHz / (2 * (29 + 1)) = 1 MHz.
+	if setup := fake.writes[0]; !bytes.Contains(setup, []byte{mpsseSetClockDivisor, 29, 0}) {
+		t.Fatalf("setup % x does not set the clock divisor to 29", setup)
+	}
+
+	got := make([]byte, MaxTransfer+100)
+
+	n, err := d.Read(got)
+	if err != nil || n != len(got) {
+		t.Fatalf("read %d bytes, %v", n, err)
+	}
+
+	// Reads are split into MPSSE transfers of at most MaxTransfer bytes, each length encoded minus one.
+	want := [][]byte{
+		{mpsseReadBytesPosMSB, 0xff, 0xff, mpsseSendImmediate},
+		{mpsseReadBytesPosMSB, 99, 0, mpsseSendImmediate},
+	}
+
+	if cmds := fake.writes[1:]; len(cmds) != 2 || !bytes.Equal(cmds[0], want[0]) || !bytes.Equal(cmds[1], want[1]) {
+		t.Fatalf("read commands % x, want % x", cmds, want)
+	}
+
+	stream := make([]byte, len(got))
+
+	rand.NewChaCha8([32]byte{1}).Read(stream)
+
+	if !bytes.Equal(got, stream) {
+		t.Fatal("output differs from the bitstream shifted in")
+	}
+}
+
+func TestHealthFailure(t *testing.T) {
+	// A noise source stuck low shifts in zeros.
+	d, _ := openFake(t, bytes.NewReader(make([]byte, 1<<20)))
+
+	_, err := d.Read(make([]byte, 1<<20))
+	if !errors.Is(err, infnoise.ErrHealthCheck) || infnoise.Classify(err) != infnoise.ClassHealth {
+		t.Fatalf("stuck output: %v, want a health check failure", err)
+	}
+}
@@ -0,0 +1,49 @@
+package bitbabbler
+
+import "github.com/coalaura/infnoise"
+
+type options struct {
+	backend infnoise.BackendName
+	bitRate int
+
+	targetEntropy float64
+	tolerance     float64
+	window        uint64
+}
+
+type option func(*options)
+
+// WithBackend selects the USB transport used by Start (default: the platform's native backend).
+func WithBackend(name infnoise.BackendName) option {
+	return func(o *options) {
+		o.backend = name
+	}
+}
+
+// WithBitRate sets the MPSSE clock used to shift in noise (default 2.5 Mbps).
+func WithBitRate(bps int) option {
+	return func(o *options) {
+		o.bitRate = bps
+	}
+}
+
+// WithTargetEntropy overrides the expected entropy per bit of the raw bitstream (default 1.0).
+func WithTargetEntropy(bits float64) option {
+	return func(o *options) {
+		o.targetEntropy = bits
+	}
+}
+
+// WithTolerance sets the allowed deviation from the target (default 0.05).
+func WithTolerance(percent float64) option {
+	return func(o *options) {
+		o.tolerance = percent
+	}
+}
+
+// WithHealthWindow sets the number of bits required before the health check begins enforcing the tolerance (default 80,000).
+func WithHealthWindow(bits uint64) option {
+	return func(o *options) {
+		o.window = bits
+	}
+}
@@ -0,0 +1,187 @@
+// Package jitter implements a CPU timing-jitter entropy source in the spirit of jitterentropy.
+//
+// It is far slower and weaker than a hardware TRNG and is meant as an emergency fallback
+// (see trng.WithFallback) so consumers keep receiving output while every hardware source is unhealthy.
+package jitter
+
+import (
+	"crypto/sha3"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coalaura/infnoise/trng"
+)
+
+const (
+	blockSize = 32
+
+	// Each output bit is backed by this many non-stuck timing samples at oversampling 1.
+	samplesPerBit = 1
+
+	// A block fails if fewer than 1 in maxStuckRatio samples show fresh jitter.
+	maxStuckRatio = 10
+)
+
+var _ trng.Source = (*Source)(nil)
+
+// Source collects entropy from the execution-time jitter of a memory-walking loop.
+type Source struct {
+	mu      sync.Mutex
+	osr     int
+	mem     []byte
+	memIdx  int
+	running bool
+
+	// now reads the timer in nanoseconds; tests replace it.
+	now func() int64
+
+	prevTime   int64
+	prevDelta  int64
+	prevDelta2 int64
+
+	pool  [blockSize]byte
+	block [blockSize]byte
+	avail int
+}
+
+// New initializes a jitter entropy source.
+func New(opts ...option) *Source {
+	conf := &options{
+		oversampling: 3,
+		memSize:      64 * 1024,
+	}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	return &Source{
+		osr: max(conf.oversampling, 1),
+		mem: make([]byte, max(conf.memSize, 1)),
+		now: func() int64 {
+			return time.Now().UnixNano()
+		},
+	}
+}
+
+// Start verifies that the timer shows usable jitter on this machine.
+func (s *Source) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prevTime = s.now()
+
+	err := s.generate()
+	if err != nil {
+		return err
+	}
+
+	s.running = true
+
+	return nil
+}
+
+// Read fills p with conditioned jitter entropy.
+func (s *Source) Read(p []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return 0, errors.New("source not started")
+	}
+
+	for n < len(p) {
+		if s.avail == 0 {
+			err := s.generate()
+			if err != nil {
+				return n, err
+			}
+		}
+
+		c := copy(p[n:], s.block[blockSize-s.avail:])
+
+		s.avail -= c
+		n += c
+	}
+
+	return n, nil
+}
+
+// Close stops the source and wipes its internal state.
+func (s *Source) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running = false
+
+	clear(s.pool[:])
+	clear(s.block[:])
+
+	s.avail = 0
+
+	return nil
+}
+
+// generate produces one conditioned block from blockSize*8*osr non-stuck timing samples.
+func (s *Source) generate() error {
+	h := sha3.New256()
+	h.Write(s.pool[:])
+
+	var (
+		sample [8]byte
+
+		want     = blockSize * 8 * samplesPerBit * s.osr
+		got      int
+		attempts int
+	)
+
+	for got < want {
+		attempts++
+
+		if attempts > want*maxStuckRatio {
+			return fmt.Errorf("timer shows too little jitter (%d of %d samples usable)", got, attempts)
+		}
+
+		delta, stuck := s.measure()
+
+		binary.LittleEndian.PutUint64(sample[:], uint64(delta))
+
+		h.Write(sample[:])
+
+		if !stuck {
+			got++
+		}
+	}
+
+	h.Sum(s.pool[:0])
+
+	// Output is a separate hash of the pool so the chaining state is never exposed.
+	s.block = sha3.Sum256(append([]byte("infnoise-jitter-out"), s.pool[:]...))
+	s.avail = blockSize
+
+	return nil
+}
+
+// measure times one pass of the memory walk and applies the jitterentropy stuck test:
+// a sample is stuck if its delta or its first or second derivative is zero.
+func (s *Source) measure() (int64, bool) {
+	for i := range 64 {
+		s.mem[s.memIdx] ^= byte(i)
+		s.memIdx = (s.memIdx + 4093) % len(s.mem)
+	}
+
+	now := s.now()
+
+	delta := now - s.prevTime
+	delta2 := delta - s.prevDelta
+	delta3 := delta2 - s.prevDelta2
+
+	s.prevTime = now
+	s.prevDelta = delta
+	s.prevDelta2 = delta2
+
+	return delta, delta == 0 || delta2 == 0 || delta3 == 0
+}
@@ -0,0 +1,106 @@
+package jitter
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// cycleClock advances by the given deltas in turn. With 1, 2, 5 no delta, nor its first or second difference, is ever
+// zero, so every sample counts.
+func cycleClock(deltas ...int64) func() int64 {
+	var t int64
+
+	i := 0
+
+	return func() int64 {
+		t += deltas[i%len(deltas)]
+		i++
+
+		return t
+	}
+}
+
+func TestConditioning(t *testing.T) {
+	s := New(WithOversampling(1))
+
+	s.now = cycleClock(1, 2, 5)
+
+	err := s.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	got := make([]byte, blockSize)
+
+	_, err = s.Read(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first block hashes the 256 timing deltas into the zero pool, then hashes the pool again for output. Start
+	// took the first reading of the clock as its reference.
+	pool := sha3.New256()
+
+	pool.Write(make([]byte, blockSize))
+
+	deltas := []int64{1, 2, 5}
+
+	for i := range blockSize * 8 {
+		pool.Write(binary.LittleEndian.AppendUint64(nil, uint64(deltas[(i+1)%3])))
+	}
+
+	want := sha3.Sum256(append([]byte("infnoise-jitter-out"), pool.Sum(nil)...))
+
+	if !bytes.Equal(got, want[:]) {
+		t.Fatalf("first block %x, want %x", got, want)
+	}
+
+	next := make([]byte, blockSize)
+
+	_, err = s.Read(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same timings chained through the pool give a different block.
+	if bytes.Equal(next, got) {
+		t.Fatal("second block repeats the first")
+	}
+}
+
+func TestStuckTimer(t *testing.T) {
+	s := New()
+
+	// A timer that never moves shows no jitter at all.
+	s.now = func() int64 {
+		return 42
+	}
+
+	if err := s.Start(); err == nil || !strings.Contains(err.Error(), "too little jitter") {
+		t.Fatalf("Start with a stuck timer = %v", err)
+	}
+
+	s = New(WithOversampling(1))
+
+	s.now = cycleClock(1, 2, 5)
+
+	err := s.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	// A timer that later advances in equal steps has a zero second difference on every sample.
+	s.now = cycleClock(5)
+
+	_, err = s.Read(make([]byte, 2*blockSize))
+	if err == nil || !strings.Contains(err.Error(), "too little jitter") {
+		t.Fatalf("Read after the timer stopped jittering = %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package jitter
+
+type options struct {
+	oversampling int
+	memSize      int
+}
+
+type option func(*options)
+
+// WithOversampling sets how many non-stuck timing samples back each output bit (default 3).
+func WithOversampling(n int) option {
+	return func(o *options) {
+		o.oversampling = n
+	}
+}
+
+// WithMemorySize sets the size of the buffer walked between timer reads (default 64 KiB).
+// Buffers larger than the CPU's L1 cache produce more jitter.
+func WithMemorySize(bytes int) option {
+	return func(o *options) {
+		o.memSize = bytes
+	}
+}
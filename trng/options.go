@@ -0,0 +1,24 @@
+package trng
+
+import "time"
+
+type poolOptions struct {
+	fallback Source
+	retry    time.Duration
+}
+
+type poolOption func(*poolOptions)
+
+// WithFallback sets a source that is only read while every primary source is failing, such as jitter.New().
+func WithFallback(src Source) poolOption {
+	return func(o *poolOptions) {
+		o.fallback = src
+	}
+}
+
+// WithRetryInterval sets how long a failed source is skipped before it is tried again (default 30s).
+func WithRetryInterval(d time.Duration) poolOption {
+	return func(o *poolOptions) {
+		o.retry = d
+	}
+}
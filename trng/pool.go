@@ -0,0 +1,220 @@
+package trng
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ Source = (*Pool)(nil)
+
+// Pool reads from the first working source in priority order and fails over to the next one when a read fails.
+// When every source has failed, reads are served from the fallback source (if any) and the pool reports itself as degraded.
+type Pool struct {
+	mu       sync.Mutex
+	members  []*member
+	fallback Source
+	retry    time.Duration
+
+	fallbackBytes uint64
+	degraded      bool
+}
+
+type member struct {
+	src      Source
+	started  bool
+	failedAt time.Time
+	err      error
+	bytes    uint64
+}
+
+// PoolStats describes the state of a Pool.
+type PoolStats struct {
+	// Sources is the number of primary sources in the pool.
+	Sources int
+
+	// Healthy is the number of primary sources not currently marked as failed.
+	Healthy int
+
+	// Degraded is true while output is being served from the fallback source.
+	Degraded bool
+
+	// SourceBytes is the number of bytes read from each primary source, in priority order.
+	SourceBytes []uint64
+
+	// FallbackBytes is the total number of bytes served from the fallback source.
+	FallbackBytes uint64
+}
+
+// NewPool creates a pool over sources, highest priority first.
+func NewPool(sources []Source, opts ...poolOption) *Pool {
+	conf := &poolOptions{
+		retry: 30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	p := &Pool{
+		fallback: conf.fallback,
+		retry:    conf.retry,
+	}
+
+	for _, src := range sources {
+		p.members = append(p.members, &member{
+			src: src,
+		})
+	}
+
+	return p
+}
+
+// Start starts every source. Sources that fail to start are marked as failed; Start only returns an error if nothing can produce output.
+func (p *Pool) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		errs    []error
+		started int
+	)
+
+	for _, m := range p.members {
+		err := m.src.Start()
+		if err != nil {
+			m.fail(err)
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		m.started = true
+
+		started++
+	}
+
+	if p.fallback != nil {
+		err := p.fallback.Start()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fallback: %w", err))
+		} else {
+			started++
+		}
+	}
+
+	if started == 0 {
+		return fmt.Errorf("no source could be started: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Read fills p from the highest priority source that is not marked as failed. A source is retried once the retry
+// interval has passed since it failed; one that failed to start is started again first.
+func (p *Pool) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+
+	now := time.Now()
+
+	for _, m := range p.members {
+		if m.err != nil && now.Sub(m.failedAt) < p.retry {
+			errs = append(errs, m.err)
+
+			continue
+		}
+
+		if !m.started {
+			err := m.src.Start()
+			if err != nil {
+				m.fail(err)
+
+				errs = append(errs, err)
+
+				continue
+			}
+
+			m.started = true
+		}
+
+		n, err := m.src.Read(b)
+		if err == nil {
+			m.err = nil
+			m.bytes += uint64(n)
+
+			p.degraded = false
+
+			return n, nil
+		}
+
+		m.fail(err)
+
+		errs = append(errs, err)
+
+		// Partial output from a failing source is discarded.
+		clear(b[:n])
+	}
+
+	if p.fallback == nil {
+		return 0, fmt.Errorf("all sources failed: %w", errors.Join(errs...))
+	}
+
+	p.degraded = true
+
+	n, err := p.fallback.Read(b)
+
+	p.fallbackBytes += uint64(n)
+
+	return n, err
+}
+
+// Close closes every source.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+
+	for _, m := range p.members {
+		errs = append(errs, m.src.Close())
+	}
+
+	if p.fallback != nil {
+		errs = append(errs, p.fallback.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stats returns a snapshot of the pool's state.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := PoolStats{
+		Sources:       len(p.members),
+		Degraded:      p.degraded,
+		FallbackBytes: p.fallbackBytes,
+		SourceBytes:   make([]uint64, len(p.members)),
+	}
+
+	for i, m := range p.members {
+		if m.err == nil {
+			st.Healthy++
+		}
+
+		st.SourceBytes[i] = m.bytes
+	}
+
+	return st
+}
+
+func (m *member) fail(err error) {
+	m.err = err
+	m.failedAt = time.Now()
+}
@@ -0,0 +1,106 @@
+package trng_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/trng"
+	"github.com/coalaura/infnoise/trng/jitter"
+)
+
+func TestPoolFallback(t *testing.T) {
+	infnoise.RegisterBackend("test-degraded", func() infnoise.Backend {
+		sim := infnoise.NewSimulator(1)
+		sim.SetGain(1.5)
+
+		return sim
+	})
+
+	pool := trng.NewPool(
+		[]trng.Source{infnoise.New(infnoise.WithBackend("test-degraded"))},
+		trng.WithFallback(jitter.New()),
+	)
+
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pool.Close()
+
+	buf := make([]byte, 32*1024)
+
+	n, err := pool.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := pool.Stats()
+
+	if !st.Degraded || st.Healthy != 0 || st.FallbackBytes != uint64(n) {
+		t.Fatalf("unexpected stats after hardware failure: %+v", st)
+	}
+}
+
+// flakySource fails to start the first time and then produces zeros.
+type flakySource struct {
+	starts int
+}
+
+func (s *flakySource) Start() error {
+	s.starts++
+
+	if s.starts == 1 {
+		return errors.New("not plugged in yet")
+	}
+
+	return nil
+}
+
+func (s *flakySource) Read(b []byte) (int, error) {
+	if s.starts < 2 {
+		return 0, errors.New("read before start")
+	}
+
+	clear(b)
+
+	return len(b), nil
+}
+
+func (s *flakySource) Close() error {
+	return nil
+}
+
+func TestPoolRestartsSource(t *testing.T) {
+	src := &flakySource{}
+
+	pool := trng.NewPool([]trng.Source{src}, trng.WithFallback(jitter.New()), trng.WithRetryInterval(time.Millisecond))
+
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pool.Close()
+
+	if st := pool.Stats(); st.Healthy != 0 {
+		t.Fatalf("source that failed to start counted as healthy: %+v", st)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	buf := make([]byte, 64)
+
+	n, err := pool.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := pool.Stats()
+
+	if src.starts != 2 || st.Degraded || st.SourceBytes[0] != uint64(n) {
+		t.Fatalf("source not restarted after the retry interval: %d starts, %+v", src.starts, st)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package serial
+
+import (
+	"io"
+	"os"
+)
+
+func openPort(path string) (io.ReadCloser, error) {
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
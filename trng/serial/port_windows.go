@@ -0,0 +1,60 @@
+//go:build windows
+// +build windows
+
+package serial
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	pSetCommTimeouts = kernel32.NewProc("SetCommTimeouts")
+)
+
+type commTimeouts struct {
+	ReadIntervalTimeout         uint32
+	ReadTotalTimeoutMultiplier  uint32
+	ReadTotalTimeoutConstant    uint32
+	WriteTotalTimeoutMultiplier uint32
+	WriteTotalTimeoutConstant   uint32
+}
+
+func openPort(path string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(path, `\\.\`) {
+		path = `\\.\` + path
+	}
+
+	pathZ, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// COM ports must be opened without sharing.
+	h, err := syscall.CreateFile(pathZ, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return as soon as any bytes are available, otherwise wait up to readTimeout.
+	timeouts := commTimeouts{
+		ReadIntervalTimeout:        0xFFFFFFFF,
+		ReadTotalTimeoutMultiplier: 0xFFFFFFFF,
+		ReadTotalTimeoutConstant:   uint32(readTimeout.Milliseconds()),
+	}
+
+	ok, _, err := pSetCommTimeouts.Call(uintptr(h), uintptr(unsafe.Pointer(&timeouts)))
+	if ok == 0 {
+		syscall.CloseHandle(h)
+
+		return nil, fmt.Errorf("SetCommTimeouts failed: %w", err)
+	}
+
+	return timeoutPort{ReadCloser: os.NewFile(uintptr(h), path), name: path}, nil
+}
@@ -0,0 +1,129 @@
+// Package serial reads from TRNGs that enumerate as USB CDC-ACM serial ports, such as the TrueRNG family or ChaosKey.
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coalaura/infnoise"
+	"github.com/coalaura/infnoise/trng"
+)
+
+// readTimeout is how long a read waits for the device on platforms where openPort sets a timeout.
+const readTimeout = time.Second
+
+var _ trng.Source = (*Device)(nil)
+
+// Device represents a TRNG attached as a serial port (/dev/ttyACM0, COM3, ...).
+type Device struct {
+	mu      sync.Mutex
+	path    string
+	port    io.ReadCloser
+	health  *infnoise.HealthCheck
+	running bool
+
+	// open opens the port; tests replace openPort with a fake.
+	open func(path string) (io.ReadCloser, error)
+}
+
+// New initializes a serial TRNG at the given device path. The port is not opened until Start.
+func New(path string, opts ...option) *Device {
+	conf := &options{
+		targetEntropy: 1.0,
+		tolerance:     0.05,
+		window:        80000,
+	}
+
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	return &Device{
+		path:   path,
+		health: infnoise.NewHealthCheck(conf.targetEntropy, conf.tolerance, conf.window),
+		open:   openPort,
+	}
+}
+
+// Start opens the serial port and switches it to raw mode.
+func (d *Device) Start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	port, err := d.open(d.path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", d.path, err)
+	}
+
+	d.port = port
+	d.running = true
+
+	return nil
+}
+
+// Read fills p with output from the device. A device that sends nothing for the read timeout, where the platform
+// sets one, fails the read with an error wrapping os.ErrDeadlineExceeded; a health check failure wraps
+// infnoise.ErrHealthCheck.
+func (d *Device) Read(p []byte) (n int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running {
+		return 0, errors.New("device not started")
+	}
+
+	for n < len(p) {
+		m, err := d.port.Read(p[n:])
+		if err != nil {
+			return n, err
+		}
+
+		if !d.health.Add(p[n : n+m]) {
+			return n, d.health.Err()
+		}
+
+		n += m
+	}
+
+	return n, nil
+}
+
+// Close releases the serial port.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.running = false
+
+	if d.port != nil {
+		err := d.port.Close()
+
+		d.port = nil
+
+		return err
+	}
+
+	return nil
+}
+
+// timeoutPort reports a read that timed out, which Windows signals by returning no bytes and os.File turns into
+// io.EOF, as an error instead, so an idle device does not look like the end of the stream.
+type timeoutPort struct {
+	io.ReadCloser
+
+	name string
+}
+
+func (t timeoutPort) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+
+	if n == 0 && len(p) > 0 && errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("%s: no data within %s: %w", t.name, readTimeout, os.ErrDeadlineExceeded)
+	}
+
+	return n, err
+}
@@ -0,0 +1,101 @@
+package serial
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"testing"
+
+	"github.com/coalaura/infnoise"
+)
+
+// fakePort serves src in reads of at most 64 bytes, as a CDC-ACM device hands over USB packets.
+type fakePort struct {
+	src    io.Reader
+	closed bool
+}
+
+func (f *fakePort) Read(p []byte) (int, error) {
+	return f.src.Read(p[:min(len(p), 64)])
+}
+
+func (f *fakePort) Close() error {
+	f.closed = true
+
+	return nil
+}
+
+func openFake(t *testing.T, src io.Reader, opts ...option) (*Device, *fakePort) {
+	t.Helper()
+
+	port := &fakePort{src: src}
+
+	d := New("/dev/ttyACM-test", opts...)
+
+	d.open = func(path string) (io.ReadCloser, error) {
+		return port, nil
+	}
+
+	err := d.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d, port
+}
+
+func TestRead(t *testing.T) {
+	d, port := openFake(t, rand.NewChaCha8([32]byte{1}))
+
+	got := make([]byte, 64*1024)
+
+	n, err := d.Read(got)
+	if err != nil || n != len(got) {
+		t.Fatalf("read %d bytes, %v", n, err)
+	}
+
+	want := make([]byte, len(got))
+
+	rand.NewChaCha8([32]byte{1}).Read(want)
+
+	if !bytes.Equal(got, want) {
+		t.Fatal("output differs from what the port sent")
+	}
+
+	d.Close()
+
+	if !port.closed {
+		t.Fatal("Close left the port open")
+	}
+
+	if _, err := d.Read(got); err == nil {
+		t.Fatal("read from a closed device")
+	}
+}
+
+func TestHealthFailure(t *testing.T) {
+	// A stuck device sends the same byte over and over.
+	d, _ := openFake(t, bytes.NewReader(bytes.Repeat([]byte{0x55}, 1<<20)))
+
+	defer d.Close()
+
+	_, err := d.Read(make([]byte, 1<<20))
+	if !errors.Is(err, infnoise.ErrHealthCheck) || infnoise.Classify(err) != infnoise.ClassHealth {
+		t.Fatalf("stuck output: %v, want a health check failure", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	port := timeoutPort{ReadCloser: io.NopCloser(bytes.NewReader([]byte("ab"))), name: "COM3"}
+
+	d, _ := openFake(t, port)
+
+	defer d.Close()
+
+	_, err := d.Read(make([]byte, 4))
+	if !errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.EOF) {
+		t.Fatalf("idle port: %v, want a timeout", err)
+	}
+}
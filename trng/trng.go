@@ -0,0 +1,14 @@
+// Package trng defines the interface shared by the hardware random number generators supported by this module.
+package trng
+
+import "io"
+
+// Source is a hardware random number generator.
+//
+// Read fills p with output from the device; Close releases the hardware and may be called on a Source that was never started.
+type Source interface {
+	io.ReadCloser
+
+	// Start opens the underlying hardware and prepares it for reading.
+	Start() error
+}
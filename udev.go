@@ -0,0 +1,73 @@
+package infnoise
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultUdevRulePath is where InstallUdevRule puts the rule by default.
+	DefaultUdevRulePath = "/etc/udev/rules.d/75-infnoise.rules"
+
+	// DefaultUdevGroup is the group granted access by the udev rule, as in the upstream rule.
+	DefaultUdevGroup = "dialout"
+)
+
+// UdevRule returns a udev rule that gives group read/write access to Infinite Noise boards (0403:6015)
+// and links them as /dev/infnoise.
+func UdevRule(group string) string {
+	return fmt.Sprintf("SUBSYSTEM==\"usb\", ATTRS{idVendor}==\"0403\", ATTRS{idProduct}==\"6015\", SYMLINK+=\"infnoise\", GROUP=%q, MODE=\"0664\"\n", group)
+}
+
+// kernelDriverErr reports that driver holds the board's interface; err is the failed detach or claim, or nil
+// if detaching was disabled.
+func kernelDriverErr(vid, pid uint16, driver string, err error) error {
+	if driver == "usbfs" {
+		driver = "usbfs: another program has the board open"
+	}
+
+	if err == nil {
+		return fmt.Errorf("0x%04x:0x%04x: %w (%s) and detaching is disabled", vid, pid, ErrKernelDriver, driver)
+	}
+
+	return fmt.Errorf("0x%04x:0x%04x: %w (%s) and could not be detached (is a serial console or other program using the port?): %w", vid, pid, ErrKernelDriver, driver, err)
+}
+
+// InstallUdevRule writes UdevRule(group) to path (DefaultUdevRulePath if empty). udev applies it once its rules
+// are reloaded (udevadm control --reload-rules && udevadm trigger) and the board is replugged.
+func InstallUdevRule(path, group string) error {
+	if path == "" {
+		path = DefaultUdevRulePath
+	}
+
+	if group == "" {
+		group = DefaultUdevGroup
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.WriteString(UdevRule(group))
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return nil
+}
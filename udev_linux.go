@@ -0,0 +1,176 @@
+//go:build linux
+// +build linux
+
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// udevRuleDirs are searched for an installed rule covering the board.
+var udevRuleDirs = []string{"/etc/udev/rules.d", "/run/udev/rules.d", "/lib/udev/rules.d", "/usr/lib/udev/rules.d"}
+
+// diagnoseAccess explains why a board present in sysfs cannot be opened. It returns nil when no matching
+// device is attached or its usbfs node is accessible, so callers fall back to their own error.
+func diagnoseAccess(vid, pid uint16) error {
+	node, ok := usbfsNode(vid, pid)
+	if !ok {
+		return nil
+	}
+
+	f, err := os.OpenFile(node, os.O_RDWR, 0)
+	if err == nil {
+		f.Close()
+
+		return nil
+	}
+
+	if !errors.Is(err, os.ErrPermission) {
+		return nil
+	}
+
+	return fmt.Errorf("0x%04x:0x%04x found at %s but %w: %s", vid, pid, node, os.ErrPermission, accessHint(node, vid, pid))
+}
+
+// kernelDriver returns the name of the driver bound to interface 0 of the first device matching vid:pid ("usbfs"
+// if a program has claimed it), or "" if there is none.
+func kernelDriver(vid, pid uint16) string {
+	dir, ok := sysfsDevice(vid, pid)
+	if !ok {
+		return ""
+	}
+
+	link, err := os.Readlink(filepath.Join(dir+":1.0", "driver"))
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Base(link)
+}
+
+// usbipAttached reports whether the first device matching vid:pid is attached through the usbip virtual host
+// controller.
+func usbipAttached(vid, pid uint16) bool {
+	dir, ok := sysfsDevice(vid, pid)
+	if !ok {
+		return false
+	}
+
+	path, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(path, "/vhci_hcd")
+}
+
+// usbfsNode returns the /dev/bus/usb node of the first device matching vid:pid.
+func usbfsNode(vid, pid uint16) (string, bool) {
+	dir, ok := sysfsDevice(vid, pid)
+	if !ok {
+		return "", false
+	}
+
+	bus, err1 := strconv.Atoi(sysfsString(dir, "busnum"))
+	dev, err2 := strconv.Atoi(sysfsString(dir, "devnum"))
+
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+
+	return fmt.Sprintf("/dev/bus/usb/%03d/%03d", bus, dev), true
+}
+
+// sysfsDevice returns the sysfs directory of the first device matching vid:pid.
+func sysfsDevice(vid, pid uint16) (string, bool) {
+	dirs, _ := filepath.Glob(filepath.Join(sysfsUSBDevices, "*"))
+
+	for _, dir := range dirs {
+		if sysfsHex(dir, "idVendor") == vid && sysfsHex(dir, "idProduct") == pid {
+			return dir, true
+		}
+	}
+
+	return "", false
+}
+
+// accessHint names what is missing: the udev rule, or membership in the group it grants access to.
+func accessHint(node string, vid, pid uint16) string {
+	if !udevRuleInstalled(vid, pid) {
+		return fmt.Sprintf("no udev rule for %04x:%04x is installed; run \"infnoise setup-udev\" as root and replug the board", vid, pid)
+	}
+
+	var st syscall.Stat_t
+
+	if syscall.Stat(node, &st) != nil {
+		return "check the udev rule for this device"
+	}
+
+	gid := strconv.FormatUint(uint64(st.Gid), 10)
+
+	name := gid
+	if g, err := user.LookupGroupId(gid); err == nil {
+		name = g.Name
+	}
+
+	if st.Mode&0o060 != 0o060 {
+		return fmt.Sprintf("%s is not group read/writable (mode %04o); the udev rule did not apply, replug the board or run \"udevadm trigger\"", node, st.Mode&0o777)
+	}
+
+	if u, err := user.Current(); err == nil {
+		if gids, err := u.GroupIds(); err == nil && !slices.Contains(gids, gid) {
+			return fmt.Sprintf("user %s is not in group %s; run \"sudo usermod -aG %s %s\" and log in again", u.Username, name, name, u.Username)
+		}
+	}
+
+	return fmt.Sprintf("group %s has access but this process does not carry it; log in again to pick up new groups", name)
+}
+
+func udevRuleInstalled(vid, pid uint16) bool {
+	v, p := fmt.Sprintf("%04x", vid), fmt.Sprintf("%04x", pid)
+
+	for _, dir := range udevRuleDirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.rules"))
+
+		for _, file := range files {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+
+			for line := range strings.Lines(strings.ToLower(string(b))) {
+				if !strings.HasPrefix(strings.TrimSpace(line), "#") && strings.Contains(line, v) && strings.Contains(line, p) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func sysfsString(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
+
+func sysfsHex(dir, name string) uint16 {
+	v, err := strconv.ParseUint(sysfsString(dir, name), 16, 16)
+	if err != nil {
+		return 0
+	}
+
+	return uint16(v)
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package infnoise
+
+// kernelDriver only detects bound drivers on Linux.
+func kernelDriver(vid, pid uint16) string {
+	return ""
+}
+
+// usbipAttached only detects usbip on Linux.
+func usbipAttached(vid, pid uint16) bool {
+	return false
+}
+
+// diagnoseAccess only has permission checks to offer on Linux.
+func diagnoseAccess(vid, pid uint16) error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package infnoise
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallUdevRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "75-infnoise.rules")
+
+	err := InstallUdevRule(path, "plugdev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := string(b)
+
+	for _, want := range []string{`ATTRS{idVendor}=="0403"`, `ATTRS{idProduct}=="6015"`, `GROUP="plugdev"`} {
+		if !strings.Contains(rule, want) {
+			t.Fatalf("rule %q lacks %s", rule, want)
+		}
+	}
+}
+
+// boundSimulator is a Simulator whose interface is held by a kernel driver.
+type boundSimulator struct {
+	*Simulator
+
+	detach bool
+}
+
+func (s *boundSimulator) SetDetachKernelDriver(detach bool) {
+	s.detach = detach
+}
+
+func (s *boundSimulator) Open(vid, pid uint16) error {
+	if !s.detach {
+		return kernelDriverErr(vid, pid, "ftdi_sio", nil)
+	}
+
+	return s.Simulator.Open(vid, pid)
+}
+
+func TestKernelDriverDetach(t *testing.T) {
+	name := registerSimulator(t, &boundSimulator{Simulator: NewSimulator(1)})
+
+	dv := New(WithBackend(name))
+
+	err := dv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dv.Close()
+
+	dv = New(WithBackend(name), WithKernelDriverDetach(false))
+
+	err = dv.Start()
+	if !errors.Is(err, ErrKernelDriver) || !strings.Contains(err.Error(), "ftdi_sio") {
+		t.Fatalf("Start with detaching disabled: %v", err)
+	}
+}
@@ -0,0 +1,571 @@
+//go:build gousb && (linux || darwin)
+// +build gousb
+// +build linux darwin
+
+package infnoise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gousb"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	pollTimeout    = 100 * time.Millisecond
+	epInNum        = 1
+	epOutNum       = 2
+)
+
+const defaultBackend = BackendLibUSB
+
+type usbHandle struct {
+	ctx  *gousb.Context
+	dev  *gousb.Device
+	cfg  *gousb.Config
+	intf *gousb.Interface
+
+	iface int
+	epIn  *gousb.InEndpoint
+	epOut *gousb.OutEndpoint
+
+	maxPacket int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	closed bool
+	wg     sync.WaitGroup
+
+	// failure is why the reader loop stopped on its own: a transfer error or a recovered panic.
+	failure error
+
+	// timeout bounds Write and Read when positive; reads otherwise wait for data indefinitely.
+	timeout atomic.Int64
+
+	rBuf  []byte
+	rHead int
+	rTail int
+	count int
+
+	high, low int
+	paused    bool
+	pauses    uint64
+	purged    uint64
+
+	// suspended stops the reader loop (see Suspend); idle is set while the loop waits instead of polling.
+	suspended bool
+	idle      bool
+
+	// detach allows Open to take the interface from a kernel driver (see KernelDriverBackend).
+	detach bool
+}
+
+func init() {
+	RegisterBackend(BackendLibUSB, func() Backend {
+		return newUSBHandle()
+	})
+}
+
+func newUSBHandle() *usbHandle {
+	h := &usbHandle{
+		iface: 0,
+		rBuf:  make([]byte, ringBufferSize),
+		high:  defaultHighWater,
+		low:   defaultHighWater,
+
+		detach: true,
+	}
+
+	h.cond = sync.NewCond(&h.mu)
+
+	return h
+}
+
+// newContext initializes libusb. gousb panics when libusb_init fails, so the panic is turned back into an error.
+func newContext() (ctx *gousb.Context, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("libusb init: %w: %v", ErrDriverUnavailable, r)
+		}
+	}()
+
+	return gousb.NewContext(), nil
+}
+
+// Open connects to the first device matching vid:pid and starts the background reader.
+func (h *usbHandle) Open(vid, pid uint16) error {
+	ctx, err := newContext()
+	if err != nil {
+		return err
+	}
+
+	h.ctx = ctx
+
+	dev, err := h.ctx.OpenDeviceWithVIDPID(gousb.ID(vid), gousb.ID(pid))
+	if err != nil {
+		h.Close()
+
+		if derr := diagnoseAccess(vid, pid); derr != nil {
+			return derr
+		}
+
+		return fmt.Errorf("gousb open: %w", err)
+	}
+
+	if dev == nil {
+		h.Close()
+
+		return notFoundErr(vid, pid)
+	}
+
+	h.dev = dev
+	h.dev.ControlTimeout = defaultTimeout
+
+	driver := kernelDriver(vid, pid)
+	if driver != "" && !h.detach {
+		h.Close()
+
+		return kernelDriverErr(vid, pid, driver, nil)
+	}
+
+	err = h.dev.SetAutoDetach(true)
+	if err != nil {
+		h.Close()
+
+		return fmt.Errorf("gousb auto detach: %w", err)
+	}
+
+	h.cfg, err = h.dev.Config(1)
+	if err != nil {
+		h.Close()
+
+		return fmt.Errorf("gousb config: %w", err)
+	}
+
+	h.intf, err = h.cfg.Interface(h.iface, 0)
+	if err != nil {
+		h.Close()
+
+		if driver != "" {
+			return kernelDriverErr(vid, pid, driver, err)
+		}
+
+		return fmt.Errorf("gousb claim interface: %w", err)
+	}
+
+	h.epIn, err = h.intf.InEndpoint(epInNum)
+	if err != nil {
+		h.Close()
+
+		return fmt.Errorf("gousb in endpoint: %w", err)
+	}
+
+	h.epOut, err = h.intf.OutEndpoint(epOutNum)
+	if err != nil {
+		h.Close()
+
+		return fmt.Errorf("gousb out endpoint: %w", err)
+	}
+
+	h.maxPacket = 64
+
+	if mps := h.epIn.Desc.MaxPacketSize; mps > 0 {
+		h.maxPacket = mps
+	}
+
+	h.ctrlOut(sioReset, sioResetSio)
+	h.ctrlOut(sioReset, sioPurgeRx)
+	h.ctrlOut(sioReset, sioPurgeTx)
+	h.ctrlOut(sioSetBitMode, 0)
+	h.ctrlOut(sioSetLatency, 2)
+
+	time.Sleep(10 * time.Millisecond)
+
+	err = h.setBaudRate(30000)
+	if err != nil {
+		h.Close()
+
+		return err
+	}
+
+	h.wg.Add(1)
+
+	go h.readerLoop()
+
+	return nil
+}
+
+func (h *usbHandle) SetBitMode(mask byte, mode byte) error {
+	val := uint16(mask) | (uint16(mode) << 8)
+
+	err := h.ctrlOut(sioSetBitMode, val)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+
+	h.ctrlOut(sioReset, sioPurgeRx)
+	h.ctrlOut(sioReset, sioPurgeTx)
+
+	h.purged += uint64(h.count)
+
+	h.rHead = 0
+	h.rTail = 0
+	h.count = 0
+
+	h.cond.Broadcast()
+
+	h.mu.Unlock()
+
+	return nil
+}
+
+func (h *usbHandle) Write(data []byte) error {
+	var total int
+
+	for total < len(data) {
+		timeout := defaultTimeout
+
+		if t := time.Duration(h.timeout.Load()); t > 0 {
+			timeout = t
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+		xfer, err := h.epOut.WriteContext(ctx, data[total:])
+
+		cancel()
+
+		if err != nil {
+			return fmt.Errorf("gousb write: %w", err)
+		}
+
+		if xfer <= 0 {
+			return fmt.Errorf("short write: %d", xfer)
+		}
+
+		total += xfer
+	}
+
+	return nil
+}
+
+// SetLatencyTimer sets how long the chip holds back a partly filled bulk-in packet, in milliseconds.
+func (h *usbHandle) SetLatencyTimer(ms byte) error {
+	return h.ctrlOut(sioSetLatency, uint16(ms))
+}
+
+// SetTimeout bounds subsequent Write and Read calls to d.
+func (h *usbHandle) SetTimeout(d time.Duration) error {
+	h.timeout.Store(int64(d))
+
+	return nil
+}
+
+func (h *usbHandle) Read(dst []byte) error {
+	var deadline time.Time
+
+	if timeout := time.Duration(h.timeout.Load()); timeout > 0 {
+		deadline = time.Now().Add(timeout)
+
+		// sync.Cond has no timed wait; wake the waiter once the deadline passes.
+		timer := time.AfterFunc(timeout, func() {
+			h.mu.Lock()
+			h.cond.Broadcast()
+			h.mu.Unlock()
+		})
+
+		defer timer.Stop()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	totalRead := 0
+
+	for totalRead < len(dst) {
+		for h.count == 0 {
+			if h.closed {
+				if h.failure != nil {
+					return fmt.Errorf("usb reader stopped: %w", h.failure)
+				}
+
+				return errors.New("usb device closed")
+			}
+
+			if !deadline.IsZero() && !time.Now().Before(deadline) {
+				return fmt.Errorf("usb read: got %d of %d bytes: %w", totalRead, len(dst), os.ErrDeadlineExceeded)
+			}
+
+			h.cond.Wait()
+		}
+
+		available := h.count
+		end := min(h.rTail+available, len(h.rBuf))
+		contiguous := end - h.rTail
+
+		needed := len(dst) - totalRead
+		toCopy := min(contiguous, needed)
+
+		copy(dst[totalRead:], h.rBuf[h.rTail:h.rTail+toCopy])
+
+		h.rTail = (h.rTail + toCopy) % len(h.rBuf)
+
+		h.count -= toCopy
+		totalRead += toCopy
+
+		h.cond.Broadcast()
+	}
+
+	return nil
+}
+
+// readerLoop runs pump under Supervise; when it ends, reads fail once the buffer is drained, with its error, if any.
+func (h *usbHandle) readerLoop() {
+	defer h.wg.Done()
+
+	err := Supervise("usb reader", h.pump)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.closed {
+		h.failure = err
+		h.closed = true
+	}
+
+	h.cond.Broadcast()
+}
+
+// pump fills the ring from the bulk-in endpoint until the handle is closed or a transfer fails. It only holds h.mu
+// in helpers that release it on return, so a recovered panic cannot leave it locked.
+func (h *usbHandle) pump() error {
+	scratch := make([]byte, bulkReadSize)
+	payload := make([]byte, 0, len(scratch))
+	mps := h.maxPacket
+
+	for {
+		if !h.awaitRoom() {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+
+		n, err := h.epIn.ReadContext(ctx, scratch)
+
+		timedOut := ctx.Err() != nil
+
+		cancel()
+
+		if err != nil && timedOut && n == 0 {
+			continue
+		}
+
+		if err != nil && !timedOut {
+			return err
+		}
+
+		if n <= 0 {
+			continue
+		}
+
+		if !h.deliver(stripModemStatus(payload[:0], scratch[:n], mps)) {
+			return nil
+		}
+	}
+}
+
+// awaitRoom waits while the ring is full or the loop suspended, and reports whether the handle is still open.
+func (h *usbHandle) awaitRoom() bool {
+	// Apply backpressure instead of dropping samples: a lost byte would shift the COMP1/COMP2 interleaving
+	// of everything after it. While nobody reads, the FTDI FIFO fills and the chip stops clocking.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.paused && h.count >= h.high {
+		h.paused = true
+		h.pauses++
+	}
+
+	if !h.closed && (h.paused || h.suspended) {
+		h.idle = true
+		h.cond.Broadcast()
+	}
+
+	for !h.closed && (h.paused || h.suspended) {
+		h.cond.Wait()
+
+		if h.count <= h.low {
+			h.paused = false
+		}
+	}
+
+	h.idle = false
+
+	return !h.closed
+}
+
+// deliver pushes p into the ring and reports whether the handle is still open.
+func (h *usbHandle) deliver(p []byte) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	h.push(p)
+
+	h.cond.Broadcast()
+
+	return true
+}
+
+// RingStats returns the current ring buffer level and counters.
+func (h *usbHandle) RingStats() RingStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return RingStats{
+		Capacity:  len(h.rBuf),
+		Buffered:  h.count,
+		HighWater: h.high,
+		LowWater:  h.low,
+		Pauses:    h.pauses,
+		Purged:    h.purged,
+	}
+}
+
+// SetDetachKernelDriver sets whether Open may detach a kernel driver bound to the interface (default true).
+func (h *usbHandle) SetDetachKernelDriver(detach bool) {
+	h.detach = detach
+}
+
+// SetWatermarks makes the reader loop pause once high bytes are buffered and resume at or below low.
+func (h *usbHandle) SetWatermarks(high, low int) error {
+	err := checkWatermarks(high, low)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.high, h.low = high, low
+
+	h.cond.Broadcast()
+
+	return nil
+}
+
+// push appends p to the ring buffer; the reader loop ensures there is room. h.mu must be held.
+func (h *usbHandle) push(p []byte) {
+	end := h.rHead + len(p)
+
+	if end <= len(h.rBuf) {
+		copy(h.rBuf[h.rHead:], p)
+	} else {
+		firstPart := len(h.rBuf) - h.rHead
+
+		copy(h.rBuf[h.rHead:], p[:firstPart])
+		copy(h.rBuf[0:], p[firstPart:])
+	}
+
+	h.rHead = (h.rHead + len(p)) % len(h.rBuf)
+	h.count += len(p)
+}
+
+// Suspend stops the reader loop from polling the bulk-in endpoint and waits for its current transfer to finish.
+func (h *usbHandle) Suspend() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.suspended = true
+
+	h.cond.Broadcast()
+
+	for !h.closed && !h.idle {
+		h.cond.Wait()
+	}
+}
+
+// Resume lets the reader loop poll again.
+func (h *usbHandle) Resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.suspended = false
+
+	h.cond.Broadcast()
+}
+
+// Interrupt makes a blocked or future Read fail immediately; the handle must still be closed.
+func (h *usbHandle) Interrupt() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.closed {
+		h.closed = true
+		h.cond.Broadcast()
+	}
+}
+
+func (h *usbHandle) Close() error {
+	h.Interrupt()
+
+	h.wg.Wait()
+
+	if h.dev != nil {
+		h.ctrlOut(sioSetBitMode, 0)
+	}
+
+	if h.intf != nil {
+		h.intf.Close()
+
+		h.intf = nil
+	}
+
+	if h.cfg != nil {
+		h.cfg.Close()
+
+		h.cfg = nil
+	}
+
+	if h.dev != nil {
+		h.dev.Close()
+
+		h.dev = nil
+	}
+
+	if h.ctx != nil {
+		h.ctx.Close()
+
+		h.ctx = nil
+	}
+
+	return nil
+}
+
+func (h *usbHandle) ctrlOut(req uint8, val uint16) error {
+	idx := uint16(h.iface + 1)
+
+	_, err := h.dev.Control(reqOutVendor, req, val, idx, nil)
+	if err != nil {
+		return fmt.Errorf("gousb control 0x%02x: %w", req, err)
+	}
+
+	return nil
+}
+
+func (h *usbHandle) setBaudRate(baud int) error {
+	div := uint16(3000000 / baud)
+
+	return h.ctrlOut(sioSetBaudRate, div)
+}
@@ -1,5 +1,5 @@
-//go:build linux
-// +build linux
+//go:build linux && !gousb
+// +build linux,!gousb
 
 package infnoise
 
@@ -16,28 +16,14 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
-const (
-	sioReset       = 0x00
-	sioSetBaudRate = 0x03
-	sioSetBitMode  = 0x0B
-	sioSetLatency  = 0x09
-	sioResetSio    = 0x0000
-	sioPurgeRx     = 0x0001
-	sioPurgeTx     = 0x0002
-
-	reqOutVendor = 0x40
-
-	defaultTimeoutMS = 5000
-	epInAddr         = 0x81
-	epOutAddr        = 0x02
-
-	ringBufferSize = 64 * 1024
-)
+const defaultBackend = BackendLibUSB
 
 type usbHandle struct {
 	ctx  *C.libusb_context
@@ -54,48 +40,101 @@ type usbHandle struct {
 	closed bool
 	wg     sync.WaitGroup
 
+	// failure is why the reader loop stopped on its own: a transfer error or a recovered panic.
+	failure error
+
+	// timeout bounds Write and Read when positive; reads otherwise wait for data indefinitely.
+	timeout atomic.Int64
+
 	rBuf  []byte
 	rHead int
 	rTail int
 	count int
+
+	high, low int
+	paused    bool
+	pauses    uint64
+	purged    uint64
+
+	// suspended stops the reader loop (see Suspend); idle is set while the loop waits instead of polling.
+	suspended bool
+	idle      bool
+
+	// detach allows Open to take the interface from a kernel driver (see KernelDriverBackend).
+	detach bool
 }
 
-func openUSB(vid, pid uint16) (*usbHandle, error) {
+func init() {
+	RegisterBackend(BackendLibUSB, func() Backend {
+		return newUSBHandle()
+	})
+}
+
+func newUSBHandle() *usbHandle {
 	h := &usbHandle{
 		iface: 0,
 		epIn:  C.uchar(epInAddr),
 		epOut: C.uchar(epOutAddr),
 		rBuf:  make([]byte, ringBufferSize),
+		high:  defaultHighWater,
+		low:   defaultHighWater,
+
+		detach: true,
 	}
 
 	h.cond = sync.NewCond(&h.mu)
 
+	return h
+}
+
+// Open connects to the first device matching vid:pid and starts the background reader.
+func (h *usbHandle) Open(vid, pid uint16) error {
 	st := C.libusb_init(&h.ctx)
 	if st != 0 {
-		return nil, usbErr(st)
+		return usbErr(st)
 	}
 
 	h.devh = C.libusb_open_device_with_vid_pid(h.ctx, C.uint16_t(vid), C.uint16_t(pid))
 	if h.devh == nil {
-		h.close()
+		h.Close()
+
+		// libusb reports a device it may not open the same as a missing one.
+		if err := diagnoseAccess(vid, pid); err != nil {
+			return err
+		}
+
+		return notFoundErr(vid, pid)
+	}
 
-		return nil, fmt.Errorf("device 0x%04x:0x%04x not found", vid, pid)
+	driver := kernelDriver(vid, pid)
+	if driver == "" && C.libusb_kernel_driver_active(h.devh, C.int(h.iface)) == 1 {
+		driver = "unknown driver"
+	}
+
+	if driver != "" && !h.detach {
+		h.Close()
+
+		return kernelDriverErr(vid, pid, driver, nil)
 	}
 
 	C.libusb_set_auto_detach_kernel_driver(h.devh, 1)
 
 	st = C.libusb_set_configuration(h.devh, 1)
 	if st != 0 && st != C.LIBUSB_ERROR_BUSY {
-		h.close()
+		h.Close()
 
-		return nil, usbErr(st)
+		return usbErr(st)
 	}
 
 	st = C.libusb_claim_interface(h.devh, C.int(h.iface))
 	if st != 0 {
-		h.close()
+		h.Close()
+
+		if driver != "" {
+			return kernelDriverErr(vid, pid, driver, usbErr(st))
+		}
 
-		return nil, usbErr(st)
+		return usbErr(st)
 	}
 
 	h.maxPacket = 64
@@ -118,18 +157,18 @@ func openUSB(vid, pid uint16) (*usbHandle, error) {
 
 	err := h.setBaudRate(30000)
 	if err != nil {
-		h.close()
-		return nil, err
+		h.Close()
+		return err
 	}
 
 	h.wg.Add(1)
 
 	go h.readerLoop()
 
-	return h, nil
+	return nil
 }
 
-func (h *usbHandle) setBitMode(mask byte, mode byte) error {
+func (h *usbHandle) SetBitMode(mask byte, mode byte) error {
 	val := uint16(mask) | (uint16(mode) << 8)
 
 	err := h.ctrlOut(sioSetBitMode, val)
@@ -142,16 +181,20 @@ func (h *usbHandle) setBitMode(mask byte, mode byte) error {
 	h.ctrlOut(sioReset, sioPurgeRx)
 	h.ctrlOut(sioReset, sioPurgeTx)
 
+	h.purged += uint64(h.count)
+
 	h.rHead = 0
 	h.rTail = 0
 	h.count = 0
 
+	h.cond.Broadcast()
+
 	h.mu.Unlock()
 
 	return nil
 }
 
-func (h *usbHandle) write(data []byte) error {
+func (h *usbHandle) Write(data []byte) error {
 	var total int
 
 	for total < len(data) {
@@ -164,7 +207,7 @@ func (h *usbHandle) write(data []byte) error {
 			(*C.uchar)(unsafe.Pointer(&data[total])),
 			C.int(toWrite),
 			&xfer,
-			defaultTimeoutMS,
+			C.uint(timeoutMillis(time.Duration(h.timeout.Load()))),
 		)
 
 		if st != 0 {
@@ -181,7 +224,29 @@ func (h *usbHandle) write(data []byte) error {
 	return nil
 }
 
-func (h *usbHandle) read(dst []byte) error {
+// SetTimeout bounds subsequent Write and Read calls to d.
+func (h *usbHandle) SetTimeout(d time.Duration) error {
+	h.timeout.Store(int64(d))
+
+	return nil
+}
+
+func (h *usbHandle) Read(dst []byte) error {
+	var deadline time.Time
+
+	if timeout := time.Duration(h.timeout.Load()); timeout > 0 {
+		deadline = time.Now().Add(timeout)
+
+		// sync.Cond has no timed wait; wake the waiter once the deadline passes.
+		timer := time.AfterFunc(timeout, func() {
+			h.mu.Lock()
+			h.cond.Broadcast()
+			h.mu.Unlock()
+		})
+
+		defer timer.Stop()
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -190,9 +255,17 @@ func (h *usbHandle) read(dst []byte) error {
 	for totalRead < len(dst) {
 		for h.count == 0 {
 			if h.closed {
+				if h.failure != nil {
+					return fmt.Errorf("usb reader stopped: %w", h.failure)
+				}
+
 				return errors.New("usb device closed")
 			}
 
+			if !deadline.IsZero() && !time.Now().Before(deadline) {
+				return fmt.Errorf("usb read: got %d of %d bytes: %w", totalRead, len(dst), os.ErrDeadlineExceeded)
+			}
+
 			h.cond.Wait()
 		}
 
@@ -209,18 +282,42 @@ func (h *usbHandle) read(dst []byte) error {
 
 		h.count -= toCopy
 		totalRead += toCopy
+
+		h.cond.Broadcast()
 	}
 
 	return nil
 }
 
+// readerLoop runs pump under Supervise; when it ends, reads fail once the buffer is drained, with its error, if any.
 func (h *usbHandle) readerLoop() {
 	defer h.wg.Done()
 
-	scratch := make([]byte, 4096)
+	err := Supervise("usb reader", h.pump)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.closed {
+		h.failure = err
+		h.closed = true
+	}
+
+	h.cond.Broadcast()
+}
+
+// pump fills the ring from the bulk-in endpoint until the handle is closed or a transfer fails. It only holds h.mu
+// in helpers that release it on return, so a recovered panic cannot leave it locked.
+func (h *usbHandle) pump() error {
+	scratch := make([]byte, bulkReadSize)
+	payload := make([]byte, 0, len(scratch))
 	mps := h.maxPacket
 
 	for {
+		if !h.awaitRoom() {
+			return nil
+		}
+
 		var xfer C.int
 
 		st := C.libusb_bulk_transfer(
@@ -231,28 +328,12 @@ func (h *usbHandle) readerLoop() {
 			100,
 		)
 
-		if st == C.LIBUSB_ERROR_TIMEOUT {
-			h.mu.Lock()
-
-			if h.closed {
-				h.mu.Unlock()
-
-				return
-			}
-
-			h.mu.Unlock()
-
+		// A timed out transfer may still have delivered whole packets; only an empty one is skipped.
+		if st == C.LIBUSB_ERROR_TIMEOUT && xfer == 0 {
 			continue
 		}
-		if st != 0 {
-			h.mu.Lock()
-
-			h.closed = true
-			h.cond.Broadcast()
-
-			h.mu.Unlock()
-
-			return
+		if st != 0 && st != C.LIBUSB_ERROR_TIMEOUT {
+			return usbErr(st)
 		}
 
 		n := int(xfer)
@@ -260,52 +341,149 @@ func (h *usbHandle) readerLoop() {
 			continue
 		}
 
-		h.mu.Lock()
-		if h.closed {
-			h.mu.Unlock()
+		if !h.deliver(stripModemStatus(payload[:0], scratch[:n], mps)) {
+			return nil
+		}
+	}
+}
+
+// awaitRoom waits while the ring is full or the loop suspended, and reports whether the handle is still open.
+func (h *usbHandle) awaitRoom() bool {
+	// Apply backpressure instead of dropping samples: a lost byte would shift the COMP1/COMP2 interleaving
+	// of everything after it. While nobody reads, the FTDI FIFO fills and the chip stops clocking.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.paused && h.count >= h.high {
+		h.paused = true
+		h.pauses++
+	}
+
+	if !h.closed && (h.paused || h.suspended) {
+		h.idle = true
+		h.cond.Broadcast()
+	}
+
+	for !h.closed && (h.paused || h.suspended) {
+		h.cond.Wait()
 
-			return
+		if h.count <= h.low {
+			h.paused = false
 		}
+	}
+
+	h.idle = false
+
+	return !h.closed
+}
 
-		for i := 0; i < n; i += mps {
-			pktEnd := min(i+mps, n)
+// deliver pushes p into the ring and reports whether the handle is still open.
+func (h *usbHandle) deliver(p []byte) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-			if pktEnd-i > 2 {
-				payload := scratch[i+2 : pktEnd]
-				pLen := len(payload)
+	if h.closed {
+		return false
+	}
 
-				if h.count+pLen <= len(h.rBuf) {
-					end := h.rHead + pLen
+	h.push(p)
 
-					if end <= len(h.rBuf) {
-						copy(h.rBuf[h.rHead:], payload)
-					} else {
-						firstPart := len(h.rBuf) - h.rHead
+	h.cond.Broadcast()
 
-						copy(h.rBuf[h.rHead:], payload[:firstPart])
-						copy(h.rBuf[0:], payload[firstPart:])
-					}
+	return true
+}
 
-					h.rHead = (h.rHead + pLen) % len(h.rBuf)
-					h.count += pLen
-				}
-			}
-		}
+// RingStats returns the current ring buffer level and counters.
+func (h *usbHandle) RingStats() RingStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-		h.cond.Signal()
-		h.mu.Unlock()
+	return RingStats{
+		Capacity:  len(h.rBuf),
+		Buffered:  h.count,
+		HighWater: h.high,
+		LowWater:  h.low,
+		Pauses:    h.pauses,
+		Purged:    h.purged,
 	}
 }
 
-func (h *usbHandle) close() error {
+// SetDetachKernelDriver sets whether Open may detach a kernel driver bound to the interface (default true).
+func (h *usbHandle) SetDetachKernelDriver(detach bool) {
+	h.detach = detach
+}
+
+// SetWatermarks makes the reader loop pause once high bytes are buffered and resume at or below low.
+func (h *usbHandle) SetWatermarks(high, low int) error {
+	err := checkWatermarks(high, low)
+	if err != nil {
+		return err
+	}
+
 	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.high, h.low = high, low
+
+	h.cond.Broadcast()
+
+	return nil
+}
+
+// push appends p to the ring buffer; the reader loop ensures there is room. h.mu must be held.
+func (h *usbHandle) push(p []byte) {
+	end := h.rHead + len(p)
+
+	if end <= len(h.rBuf) {
+		copy(h.rBuf[h.rHead:], p)
+	} else {
+		firstPart := len(h.rBuf) - h.rHead
+
+		copy(h.rBuf[h.rHead:], p[:firstPart])
+		copy(h.rBuf[0:], p[firstPart:])
+	}
+
+	h.rHead = (h.rHead + len(p)) % len(h.rBuf)
+	h.count += len(p)
+}
+
+// Suspend stops the reader loop from polling the bulk-in endpoint and waits for its current transfer to finish.
+func (h *usbHandle) Suspend() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.suspended = true
+
+	h.cond.Broadcast()
+
+	for !h.closed && !h.idle {
+		h.cond.Wait()
+	}
+}
+
+// Resume lets the reader loop poll again.
+func (h *usbHandle) Resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.suspended = false
+
+	h.cond.Broadcast()
+}
+
+// Interrupt makes a blocked or future Read fail immediately; the handle must still be closed.
+func (h *usbHandle) Interrupt() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
 	if !h.closed {
 		h.closed = true
 		h.cond.Broadcast()
 	}
+}
 
-	h.mu.Unlock()
+func (h *usbHandle) Close() error {
+	h.Interrupt()
 
 	h.wg.Wait()
 
@@ -332,7 +510,7 @@ func (h *usbHandle) ctrlOut(req uint8, val uint16) error {
 
 	st := C.libusb_control_transfer(
 		h.devh, reqOutVendor, C.uint8_t(req), C.uint16_t(val), C.uint16_t(idx),
-		nil, 0, defaultTimeoutMS,
+		nil, 0, defaultTimeoutMillis,
 	)
 
 	if st < 0 {
@@ -348,7 +526,8 @@ func (h *usbHandle) setBaudRate(baud int) error {
 	return h.ctrlOut(sioSetBaudRate, div)
 }
 
-func (h *usbHandle) setLatencyTimer(ms byte) error {
+// SetLatencyTimer sets how long the chip holds back a partly filled bulk-in packet, in milliseconds.
+func (h *usbHandle) SetLatencyTimer(ms byte) error {
 	return h.ctrlOut(sioSetLatency, uint16(ms))
 }
 
@@ -357,5 +536,29 @@ func usbErr(st C.int) error {
 		return nil
 	}
 
-	return fmt.Errorf("libusb %s (%d)", C.GoString(C.libusb_error_name(st)), int(st))
+	return libusbError(st)
+}
+
+// libusbError is a libusb error code. It matches the package's errors for the codes that have one (errors.Is), and
+// os.ErrDeadlineExceeded for LIBUSB_ERROR_TIMEOUT.
+type libusbError C.int
+
+func (e libusbError) Error() string {
+	return fmt.Sprintf("libusb %s (%d)", C.GoString(C.libusb_error_name(C.int(e))), int(e))
+}
+
+// Is reports whether target is the package error e corresponds to.
+func (e libusbError) Is(target error) bool {
+	switch e {
+	case C.LIBUSB_ERROR_IO, C.LIBUSB_ERROR_PIPE, C.LIBUSB_ERROR_OVERFLOW:
+		return target == ErrIO
+	case C.LIBUSB_ERROR_NO_DEVICE:
+		return target == ErrDisconnected
+	case C.LIBUSB_ERROR_NOT_FOUND:
+		return target == ErrDeviceNotFound
+	case C.LIBUSB_ERROR_TIMEOUT:
+		return target == os.ErrDeadlineExceeded
+	}
+
+	return false
 }
@@ -0,0 +1,29 @@
+//go:build linux && !gousb
+// +build linux,!gousb
+
+package infnoise
+
+import "testing"
+
+// BenchmarkRingBuffer moves one bulk transfer at a time through the libusb backend's ring buffer, as the reader
+// loop and Read do, without a device.
+func BenchmarkRingBuffer(b *testing.B) {
+	h := newUSBHandle()
+
+	chunk := make([]byte, bulkReadSize-bulkReadSize/64*ftdiStatusLen)
+	dst := make([]byte, len(chunk))
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(chunk)))
+
+	for b.Loop() {
+		h.mu.Lock()
+		h.push(chunk)
+		h.mu.Unlock()
+
+		err := h.Read(dst)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
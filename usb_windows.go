@@ -6,9 +6,13 @@ package infnoise
 import (
 	"errors"
 	"fmt"
+	"os"
+	"runtime"
 	"syscall"
 	"time"
 	"unsafe"
+
+	"golang.org/x/sys/windows"
 )
 
 var (
@@ -31,149 +35,228 @@ var (
 
 	pFT_Write = ftd2xx.NewProc("FT_Write")
 	pFT_Read  = ftd2xx.NewProc("FT_Read")
+
+	pFT_EE_UASize  = ftd2xx.NewProc("FT_EE_UASize")
+	pFT_EE_UARead  = ftd2xx.NewProc("FT_EE_UARead")
+	pFT_EE_UAWrite = ftd2xx.NewProc("FT_EE_UAWrite")
 )
 
 const (
-	FT_OK = 0
+	ftOK = 0
 
-	FT_PURGE_RX = 1
-	FT_PURGE_TX = 2
+	ftPurgeRx = 1
+	ftPurgeTx = 2
 
-	FT_OPEN_BY_SERIAL_NUMBER = 1
+	ftOpenBySerialNumber = 1
 
-	FT_FLOW_NONE = 0x0000
+	ftFlowNone = 0x0000
 )
 
+const defaultBackend = BackendD2XX
+
 type usbHandle struct {
 	ftHandle uintptr
 }
 
-func openUSB(vid, pid uint16) (*usbHandle, error) {
+func init() {
+	RegisterBackend(BackendD2XX, func() Backend {
+		return &usbHandle{}
+	})
+}
+
+// Open connects to the first device matching vid:pid through ftd2xx.dll.
+func (h *usbHandle) Open(vid, pid uint16) error {
 	err := ftd2xx.Load()
+	if errors.Is(err, windows.ERROR_BAD_EXE_FORMAT) {
+		return fmt.Errorf("ftd2xx.dll does not match this %d-bit %s process; install the matching D2XX driver: %w: %w", 8*unsafe.Sizeof(uintptr(0)), runtime.GOARCH, ErrDriverUnavailable, err)
+	}
+
 	if err != nil {
-		return nil, fmt.Errorf("ftd2xx.dll not available: %w", err)
+		return fmt.Errorf("ftd2xx.dll not available: %w: %w", ErrDriverUnavailable, err)
 	}
 
 	serial, err := findFirstDeviceSerial(vid, pid)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	serialZ, err := syscall.BytePtrFromString(serial)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var handle uintptr
 
-	st, _, _ := pFT_OpenEx.Call(uintptr(unsafe.Pointer(serialZ)), FT_OPEN_BY_SERIAL_NUMBER, uintptr(unsafe.Pointer(&handle)))
-	if st != FT_OK {
-		return nil, fmt.Errorf("FT_OpenEx(by serial=%q) failed: %d", serial, st)
+	st, _, _ := pFT_OpenEx.Call(uintptr(unsafe.Pointer(serialZ)), ftOpenBySerialNumber, uintptr(unsafe.Pointer(&handle)))
+	if st != ftOK {
+		return fmt.Errorf("FT_OpenEx(by serial=%q) failed: %w", serial, FTStatus(st))
 	}
 
-	h := &usbHandle{
-		ftHandle: handle,
-	}
+	h.ftHandle = handle
 
 	st, _, _ = pFT_ResetDevice.Call(h.ftHandle)
-	if st != FT_OK {
-		h.close()
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_ResetDevice failed: %d", st)
+		return fmt.Errorf("FT_ResetDevice failed: %w", FTStatus(st))
 	}
 
-	st, _, _ = pFT_Purge.Call(h.ftHandle, FT_PURGE_RX|FT_PURGE_TX)
-	if st != FT_OK {
-		h.close()
+	st, _, _ = pFT_Purge.Call(h.ftHandle, ftPurgeRx|ftPurgeTx)
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_Purge failed: %d", st)
+		return fmt.Errorf("FT_Purge failed: %w", FTStatus(st))
 	}
 
 	st, _, _ = pFT_SetUSBParameters.Call(h.ftHandle, 65536, 65536)
-	if st != FT_OK {
-		h.close()
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_SetUSBParameters failed: %d", st)
+		return fmt.Errorf("FT_SetUSBParameters failed: %w", FTStatus(st))
 	}
 
 	st, _, _ = pFT_SetChars.Call(h.ftHandle, 0, 0, 0, 0)
-	if st != FT_OK {
-		h.close()
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_SetChars failed: %d", st)
+		return fmt.Errorf("FT_SetChars failed: %w", FTStatus(st))
 	}
 
-	st, _, _ = pFT_SetFlowControl.Call(h.ftHandle, FT_FLOW_NONE, 0, 0)
-	if st != FT_OK {
-		h.close()
+	st, _, _ = pFT_SetFlowControl.Call(h.ftHandle, ftFlowNone, 0, 0)
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_SetFlowControl failed: %d", st)
+		return fmt.Errorf("FT_SetFlowControl failed: %w", FTStatus(st))
 	}
 
 	st, _, _ = pFT_SetLatencyTimer.Call(h.ftHandle, 2)
-	if st != FT_OK {
-		h.close()
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_SetLatencyTimer failed: %d", st)
+		return fmt.Errorf("FT_SetLatencyTimer failed: %w", FTStatus(st))
 	}
 
-	st, _, _ = pFT_SetTimeouts.Call(h.ftHandle, 5000, 5000)
-	if st != FT_OK {
-		h.close()
+	err = h.SetTimeout(0)
+	if err != nil {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_SetTimeouts failed: %d", st)
+		return err
 	}
 
 	st, _, _ = pFT_SetBitMode.Call(h.ftHandle, 0, 0)
-	if st != FT_OK {
-		h.close()
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_SetBitMode(reset) failed: %d", st)
+		return fmt.Errorf("FT_SetBitMode(reset) failed: %w", FTStatus(st))
 	}
 
 	time.Sleep(50 * time.Millisecond)
 
 	st, _, _ = pFT_SetBaudRate.Call(h.ftHandle, 30000)
-	if st != FT_OK {
-		h.close()
+	if st != ftOK {
+		h.Close()
 
-		return nil, fmt.Errorf("FT_SetBaudRate failed: %d", st)
+		return fmt.Errorf("FT_SetBaudRate failed: %w", FTStatus(st))
 	}
 
-	return h, nil
+	return nil
 }
 
-func (h *usbHandle) setBitMode(mask byte, mode byte) error {
+func (h *usbHandle) SetBitMode(mask byte, mode byte) error {
 	st, _, _ := pFT_SetBitMode.Call(h.ftHandle, uintptr(mask), uintptr(mode))
-	if st != FT_OK {
-		return fmt.Errorf("FT_SetBitMode(mask=0x%02x, mode=0x%02x) failed: %d", mask, mode, st)
+	if st != ftOK {
+		return fmt.Errorf("FT_SetBitMode(mask=0x%02x, mode=0x%02x) failed: %w", mask, mode, FTStatus(st))
 	}
 
-	buf := make([]byte, 64)
+	// Only bitbang mode echoes one byte per byte written; other modes would stall the prime read.
+	if mode == BitModeSyncBitbang {
+		buf := make([]byte, 64)
 
-	err := h.writeExact(buf)
-	if err != nil {
-		return fmt.Errorf("prime write failed: %w", err)
+		err := h.writeExact(buf)
+		if err != nil {
+			return fmt.Errorf("prime write failed: %w", err)
+		}
+
+		err = h.readExact(buf)
+		if err != nil {
+			return fmt.Errorf("prime read failed: %w", err)
+		}
 	}
 
-	err = h.readExact(buf)
-	if err != nil {
-		return fmt.Errorf("prime read failed: %w", err)
+	st, _, _ = pFT_Purge.Call(h.ftHandle, ftPurgeRx|ftPurgeTx)
+	if st != ftOK {
+		return fmt.Errorf("FT_Purge(after bitmode) failed: %w", FTStatus(st))
 	}
 
-	st, _, _ = pFT_Purge.Call(h.ftHandle, FT_PURGE_RX|FT_PURGE_TX)
-	if st != FT_OK {
-		return fmt.Errorf("FT_Purge(after bitmode) failed: %d", st)
+	return nil
+}
+
+// ReadUserArea returns the EEPROM user area.
+func (h *usbHandle) ReadUserArea() ([]byte, error) {
+	var size uint32
+
+	st, _, _ := pFT_EE_UASize.Call(h.ftHandle, uintptr(unsafe.Pointer(&size)))
+	if st != ftOK {
+		return nil, fmt.Errorf("FT_EE_UASize failed: %w", FTStatus(st))
+	}
+
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+
+	var got uint32
+
+	st, _, _ = pFT_EE_UARead.Call(h.ftHandle, uintptr(unsafe.Pointer(&buf[0])), uintptr(size), uintptr(unsafe.Pointer(&got)))
+	if st != ftOK {
+		return nil, fmt.Errorf("FT_EE_UARead failed: %w", FTStatus(st))
+	}
+
+	return buf[:got], nil
+}
+
+// WriteUserArea writes p to the start of the EEPROM user area.
+func (h *usbHandle) WriteUserArea(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	st, _, _ := pFT_EE_UAWrite.Call(h.ftHandle, uintptr(unsafe.Pointer(&p[0])), uintptr(len(p)))
+	if st != ftOK {
+		return fmt.Errorf("FT_EE_UAWrite failed: %w", FTStatus(st))
+	}
+
+	return nil
+}
+
+// SetLatencyTimer sets how long the chip holds back a partly filled bulk-in packet, in milliseconds.
+func (h *usbHandle) SetLatencyTimer(ms byte) error {
+	st, _, _ := pFT_SetLatencyTimer.Call(h.ftHandle, uintptr(ms))
+	if st != ftOK {
+		return fmt.Errorf("FT_SetLatencyTimer failed: %w", FTStatus(st))
+	}
+
+	return nil
+}
+
+// SetTimeout bounds subsequent Write and Read calls to d.
+func (h *usbHandle) SetTimeout(d time.Duration) error {
+	ms := uintptr(timeoutMillis(d))
+
+	st, _, _ := pFT_SetTimeouts.Call(h.ftHandle, ms, ms)
+	if st != ftOK {
+		return fmt.Errorf("FT_SetTimeouts failed: %w", FTStatus(st))
 	}
 
 	return nil
 }
 
-func (h *usbHandle) write(data []byte) error {
+func (h *usbHandle) Write(data []byte) error {
 	return h.writeExact(data)
 }
 
-func (h *usbHandle) read(data []byte) error {
+func (h *usbHandle) Read(data []byte) error {
 	return h.readExact(data)
 }
 
@@ -191,8 +274,8 @@ func (h *usbHandle) writeExact(data []byte) error {
 		uintptr(unsafe.Pointer(&bytesWritten)),
 	)
 
-	if st != FT_OK {
-		return fmt.Errorf("FT_Write failed: %d", st)
+	if st != ftOK {
+		return fmt.Errorf("FT_Write failed: %w", FTStatus(st))
 	}
 
 	if int(bytesWritten) != len(data) {
@@ -221,12 +304,12 @@ func (h *usbHandle) readExact(data []byte) error {
 			uintptr(unsafe.Pointer(&got)),
 		)
 
-		if st != FT_OK {
-			return fmt.Errorf("FT_Read failed: %d", st)
+		if st != ftOK {
+			return fmt.Errorf("FT_Read failed: %w", FTStatus(st))
 		}
 
 		if got == 0 {
-			return fmt.Errorf("FT_Read timeout/stall: got %d, want %d", total, len(data))
+			return fmt.Errorf("FT_Read timeout/stall: got %d, want %d: %w", total, len(data), os.ErrDeadlineExceeded)
 		}
 
 		total += int(got)
@@ -235,7 +318,7 @@ func (h *usbHandle) readExact(data []byte) error {
 	return nil
 }
 
-func (h *usbHandle) close() error {
+func (h *usbHandle) Close() error {
 	if h.ftHandle != 0 {
 		pFT_SetBitMode.Call(h.ftHandle, 0, 0)
 		pFT_Close.Call(h.ftHandle)
@@ -250,12 +333,12 @@ func findFirstDeviceSerial(vid, pid uint16) (string, error) {
 	var n uint32
 
 	st, _, _ := pFT_CreateDeviceInfoList.Call(uintptr(unsafe.Pointer(&n)))
-	if st != FT_OK {
-		return "", fmt.Errorf("FT_CreateDeviceInfoList failed: %d", st)
+	if st != ftOK {
+		return "", fmt.Errorf("FT_CreateDeviceInfoList failed: %w", FTStatus(st))
 	}
 
 	if n == 0 {
-		return "", errors.New("no FTDI devices found")
+		return "", fmt.Errorf("no FTDI devices found: %w", ErrDeviceNotFound)
 	}
 
 	wantID := (uint32(vid) << 16) | uint32(pid)
@@ -284,7 +367,7 @@ func findFirstDeviceSerial(vid, pid uint16) (string, error) {
 			uintptr(unsafe.Pointer(&dummyHandle)),
 		)
 
-		if st != FT_OK {
+		if st != ftOK {
 			continue
 		}
 
@@ -300,7 +383,7 @@ func findFirstDeviceSerial(vid, pid uint16) (string, error) {
 		return s, nil
 	}
 
-	return "", fmt.Errorf("no matching FTDI device found for VID=0x%04x PID=0x%04x", vid, pid)
+	return "", fmt.Errorf("no matching FTDI device found for VID=0x%04x PID=0x%04x: %w", vid, pid, ErrDeviceNotFound)
 }
 
 func cString(b []byte) string {
@@ -0,0 +1,448 @@
+package infnoise
+
+import (
+	"context"
+	"crypto/sha3"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrModeConflict is returned when a read conflicts with the device's ReadMode.
+	ErrModeConflict = errors.New("read mode conflict")
+
+	// ErrDuplicateBlock is returned by ReadWhitened after two consecutive whitened chunks came out identical, which
+	// only a conditioner or buffer-reuse bug can cause. The failure is permanent until the device is restarted.
+	ErrDuplicateBlock = errors.New("continuous test failed: whitened chunk repeated")
+)
+
+// ReadMode controls how Read (raw) and ReadWhitened share the bitstream.
+type ReadMode int
+
+const (
+	// ModeExclusive gives the stream to whichever of Read, ReadWhitened, and ReadFolded is called first after Start;
+	// the others fail with ErrModeConflict until the device is restarted. Raw consumers thus see a contiguous
+	// stream, and whitened output is derived from every harvested bit.
+	ModeExclusive ReadMode = iota
+
+	// ModeRawTap makes ReadWhitened the owner of the stream and Read an observer: Read returns copies of the raw
+	// bytes absorbed into the whitened output, harvesting a chunk itself when none are waiting. Up to tapLimit
+	// (or two chunks, if larger) uncollected bytes are kept; older ones are dropped. A tap reader can reconstruct the whitened output, so
+	// this mode is meant for auditing and testing.
+	ModeRawTap
+)
+
+func (m ReadMode) String() string {
+	switch m {
+	case ModeExclusive:
+		return "exclusive"
+	case ModeRawTap:
+		return "raw-tap"
+	default:
+		return fmt.Sprintf("ReadMode(%d)", int(m))
+	}
+}
+
+// MarshalText encodes the mode as its name, "exclusive" or "raw-tap".
+func (m ReadMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText parses a name produced by MarshalText.
+func (m *ReadMode) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "exclusive":
+		*m = ModeExclusive
+	case "raw-tap":
+		*m = ModeRawTap
+	default:
+		return fmt.Errorf("unknown read mode %q", b)
+	}
+
+	return nil
+}
+
+// StalePolicy says what ReadWhitened does with buffered whitened output older than WithMaxPoolAge.
+type StalePolicy int
+
+const (
+	// StaleDiscard wipes stale output and harvests a fresh chunk in its place, for policies that forbid serving
+	// entropy generated long before use.
+	StaleDiscard StalePolicy = iota
+
+	// StaleFlag serves stale output and counts it in Stats, to detect slow consumers without wasting the device's
+	// output.
+	StaleFlag
+)
+
+func (s StalePolicy) String() string {
+	switch s {
+	case StaleDiscard:
+		return "discard"
+	case StaleFlag:
+		return "flag"
+	default:
+		return fmt.Sprintf("StalePolicy(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the policy as its name, "discard" or "flag".
+func (s StalePolicy) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses a name produced by MarshalText.
+func (s *StalePolicy) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "discard":
+		*s = StaleDiscard
+	case "flag":
+		*s = StaleFlag
+	default:
+		return fmt.Errorf("unknown stale policy %q", b)
+	}
+
+	return nil
+}
+
+const (
+	// tapLimit bounds the raw bytes kept for Read in ModeRawTap.
+	tapLimit = 4 * IOBatch / 8
+
+	// whitenLabel is the cSHAKE256 customization string of the conditioner.
+	whitenLabel = "infnoise whitening"
+
+	// ratchetLabel is the cSHAKE256 customization string of the step that ratchets the chaining value.
+	ratchetLabel = "infnoise ratchet"
+
+	// mixLabel is the cSHAKE256 customization string under which MixIn input is collected.
+	mixLabel = "infnoise mix-in"
+)
+
+// Stream owners for ModeExclusive.
+const (
+	ownerNone int32 = iota
+	ownerRaw
+	ownerWhitened
+	ownerFolded
+)
+
+// whitener conditions raw output into full-entropy bytes with cSHAKE256. Each chunk absorbs the chaining value
+// and twice its length in raw bytes (at the nominal 0.864 bits per raw bit, 1.7 bits per output bit), then
+// squeezes the chunk and the next chaining value.
+//
+// The Keccak permutation is invertible, so a sponge left in its squeezed state would let anyone who later reads
+// memory recover the output squeezed from it. With ratchet set, the sponge is wiped after every chunk and the
+// chaining value passes through a separate one-way step, so only data that cannot be run backwards is carried
+// forward.
+type whitener struct {
+	h       *sha3.SHAKE
+	ratchet *sha3.SHAKE
+	chain   [32]byte
+
+	// mix collects MixIn input until the next chunk absorbs its digest; mixed is set while it holds any.
+	mix   *sha3.SHAKE
+	mixed bool
+
+	// last is a digest of the previous chunk for the continuous test; failed makes its failure stick.
+	last    [32]byte
+	hasLast bool
+	failed  bool
+}
+
+func newWhitener(ratchet bool) *whitener {
+	w := &whitener{
+		h:   sha3.NewCSHAKE256(nil, []byte(whitenLabel)),
+		mix: sha3.NewCSHAKE256(nil, []byte(mixLabel)),
+	}
+
+	if ratchet {
+		w.ratchet = sha3.NewCSHAKE256(nil, []byte(ratchetLabel))
+	}
+
+	return w
+}
+
+// whiten fills out from raw, which must be 2*len(out) bytes.
+func (w *whitener) whiten(raw, out []byte) {
+	w.h.Reset()
+
+	w.h.Write(w.chain[:])
+	w.h.Write(raw)
+
+	if w.mixed {
+		var digest [32]byte
+
+		w.mix.Read(digest[:])
+		w.h.Write(digest[:])
+
+		clear(digest[:])
+
+		w.mix.Reset()
+
+		w.mixed = false
+	}
+
+	w.h.Read(out)
+	w.h.Read(w.chain[:])
+
+	if w.ratchet != nil {
+		w.h.Reset()
+
+		w.ratchet.Write(w.chain[:])
+		w.ratchet.Read(w.chain[:])
+
+		w.ratchet.Reset()
+	}
+}
+
+// mixIn collects data for the next chunk. Each input is length-prefixed so that distinct sequences of inputs
+// never collide.
+func (w *whitener) mixIn(data []byte) {
+	w.mix.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(data))))
+	w.mix.Write(data)
+
+	w.mixed = true
+}
+
+// check runs the continuous duplicate-block test on a freshly squeezed chunk. Only a digest is kept, so the
+// previous output does not linger in memory.
+func (w *whitener) check(out []byte) error {
+	if w.failed {
+		return ErrDuplicateBlock
+	}
+
+	sum := sha3.Sum256(out)
+
+	if w.hasLast && sum == w.last {
+		w.failed = true
+
+		return ErrDuplicateBlock
+	}
+
+	w.last = sum
+	w.hasLast = true
+
+	return nil
+}
+
+func (w *whitener) reset() {
+	w.restart()
+
+	w.mixed = false
+
+	w.mix.Reset()
+}
+
+// restart wipes the chaining value and the continuous test's state but keeps pending MixIn input.
+func (w *whitener) restart() {
+	clear(w.chain[:])
+	clear(w.last[:])
+
+	w.hasLast = false
+	w.failed = false
+
+	w.h.Reset()
+
+	if w.ratchet != nil {
+		w.ratchet.Reset()
+	}
+}
+
+// claim registers owner as the user of the stream under ModeExclusive.
+func (d *Device) claim(owner int32) error {
+	if d.mode != ModeExclusive {
+		return nil
+	}
+
+	if d.owner.CompareAndSwap(ownerNone, owner) || d.owner.Load() == owner {
+		return nil
+	}
+
+	return ErrModeConflict
+}
+
+// ReadWhitened fills p with conditioned output (see ReadMode for how it shares the stream with Read). Every
+// chunk (see WithChunkSize) is derived from twice as many raw bytes that passed the health check. Failures are
+// *Error values, as for Read.
+func (d *Device) ReadWhitened(p []byte) (n int, err error) {
+	ctx, span := d.tracer.Start(context.Background(), "infnoise.ReadWhitened", trace.WithAttributes(attrRequested.Int(len(p))))
+
+	start := time.Now()
+
+	defer func() {
+		endSpan(span, n, err)
+
+		err = classified(err)
+
+		d.readStats.record(len(p), time.Since(start), err)
+	}()
+
+	err = d.claim(ownerWhitened)
+	if err != nil {
+		return 0, err
+	}
+
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+
+	for n < len(p) {
+		d.dropQuarantined()
+
+		stale := len(d.pool) != 0 && d.maxPoolAge > 0 && time.Since(d.poolTime) > d.maxPoolAge
+
+		if stale && d.stalePolicy == StaleDiscard {
+			d.staleDiscarded.Add(uint64(len(d.pool)))
+
+			clear(d.pool)
+
+			d.pool = nil
+		}
+
+		if len(d.pool) == 0 {
+			err = d.harvest(ctx)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		m := copy(p[n:], d.pool)
+
+		if stale && d.stalePolicy == StaleFlag {
+			d.staleServed.Add(uint64(m))
+		}
+
+		clear(d.pool[:m])
+
+		d.pool = d.pool[m:]
+		n += m
+	}
+
+	return n, nil
+}
+
+// MixIn absorbs caller-supplied material, such as sensor readings or packet timings, into the whitener alongside
+// the device's entropy. It takes effect from the next chunk harvested; output already buffered is unchanged. Mixed-in
+// data adds to the entropy of the output but is never relied upon, so it is safe to pass anything, even data an
+// attacker may know or control. Raw bytes tapped in ModeRawTap no longer reproduce the whitened output of chunks that
+// absorbed it. Pending input is discarded by Close.
+func (d *Device) MixIn(data []byte) {
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+
+	d.whitener.mixIn(data)
+}
+
+// readTap serves Read in ModeRawTap.
+func (d *Device) readTap(ctx context.Context, p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+
+	if len(d.tap) == 0 {
+		// The whitened output of this batch replaces whatever was pooled; discarding it is harmless.
+		err := d.harvest(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.tap)
+
+	d.tap = d.tap[:copy(d.tap, d.tap[n:])]
+
+	return n, nil
+}
+
+// harvest refills the pool with one whitened chunk. poolMu must be held.
+func (d *Device) harvest(ctx context.Context) (err error) {
+	ctx, span := d.tracer.Start(ctx, "infnoise.harvest", trace.WithAttributes(attrRequested.Int(len(d.rawPool))))
+
+	defer func() {
+		finishSpan(span, err)
+	}()
+
+	_, err = d.readRaw(ctx, d.rawPool)
+	if err != nil {
+		clear(d.rawPool)
+
+		return err
+	}
+
+	// A quarantine may have ended during this very read.
+	d.dropQuarantined()
+
+	if d.reseedDue() {
+		d.reseed()
+	}
+
+	d.labeled(labelAbsorb, func() {
+		d.whitener.whiten(d.rawPool, d.poolBuf)
+
+		err = d.whitener.check(d.poolBuf)
+	})
+	if err != nil {
+		clear(d.rawPool)
+		clear(d.poolBuf)
+
+		return err
+	}
+
+	if d.auditLog != nil {
+		d.auditLog.chunk(d.poolBuf)
+	}
+
+	d.pool = d.poolBuf
+	d.poolTime = time.Now()
+
+	if d.mode == ModeRawTap {
+		limit := max(tapLimit, 2*len(d.rawPool))
+
+		if over := len(d.tap) + len(d.rawPool) - limit; over > 0 {
+			d.tap = d.tap[:copy(d.tap, d.tap[over:])]
+		}
+
+		d.tap = append(d.tap, d.rawPool...)
+	}
+
+	clear(d.rawPool)
+
+	return nil
+}
+
+// dropQuarantined restarts the whitener from a fresh OS seed and discards the pooled output when the device has
+// entered or left quarantine, so nothing derived from bits before the failure reaches later output, and the first
+// chunk afterwards is not a function of raw bytes alone. Pending MixIn input is kept. poolMu must be held.
+func (d *Device) dropQuarantined() {
+	if !d.rewhiten.Swap(false) {
+		return
+	}
+
+	d.whitener.restart()
+
+	d.reseed()
+}
+
+// clearPool wipes all buffered whitened and raw bytes and the chaining value.
+func (d *Device) clearPool() {
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+
+	clear(d.poolBuf)
+	clear(d.rawPool)
+	clear(d.tap[:cap(d.tap)])
+
+	d.pool = nil
+	d.tap = d.tap[:0]
+
+	d.whitener.reset()
+
+	d.rewhiten.Store(false)
+}
@@ -0,0 +1,400 @@
+package infnoise
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestModeExclusive(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	_, err := dv.Read(make([]byte, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dv.ReadWhitened(make([]byte, 64))
+	if !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("whitened read after raw read: got %v, want ErrModeConflict", err)
+	}
+
+	dv, _ = openSimulator(t, 2, DefaultSimulatorGain)
+
+	a := make([]byte, WhitenedChunkSize)
+	b := make([]byte, WhitenedChunkSize)
+
+	for _, p := range [][]byte{a, b} {
+		_, err = dv.ReadWhitened(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if bytes.Equal(a, b) {
+		t.Fatal("consecutive whitened chunks are identical")
+	}
+
+	_, err = dv.Read(make([]byte, 64))
+	if !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("raw read after whitened read: got %v, want ErrModeConflict", err)
+	}
+}
+
+func TestRawTap(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithReadMode(ModeRawTap))
+
+	got := make([]byte, 2*WhitenedChunkSize)
+
+	_, err := dv.ReadWhitened(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := make([]byte, 0, 4*WhitenedChunkSize)
+
+	for len(raw) < cap(raw) {
+		buf := make([]byte, 1000)
+
+		n, err := dv.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw = append(raw, buf[:n]...)
+
+		if len(dv.tap) == 0 {
+			break
+		}
+	}
+
+	if len(raw) != cap(raw) {
+		t.Fatalf("tap returned %d raw bytes, want the %d absorbed", len(raw), cap(raw))
+	}
+
+	w := newWhitener(true)
+	want := make([]byte, 2*WhitenedChunkSize)
+
+	w.whiten(raw[:2*WhitenedChunkSize], want[:WhitenedChunkSize])
+	w.whiten(raw[2*WhitenedChunkSize:], want[WhitenedChunkSize:])
+
+	if !bytes.Equal(got, want) {
+		t.Fatal("tapped raw bytes do not reproduce the whitened output")
+	}
+}
+
+func TestQuarantineRestartsWhitener(t *testing.T) {
+	dv, sim := openSimulator(t, 1, DefaultSimulatorGain, WithQuarantine(3), WithReadMode(ModeRawTap))
+
+	got := make([]byte, WhitenedChunkSize)
+
+	// Leave output pooled and a chaining value behind.
+	_, err := dv.ReadWhitened(got[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim.SetGain(1.5)
+
+	// The tap serves what is already absorbed before the next harvest runs into the fault.
+	for i := 0; err == nil && i < 10; i++ {
+		_, err = dv.Read(make([]byte, 4*WhitenedChunkSize))
+	}
+
+	if err == nil || !dv.Quarantined() {
+		t.Fatalf("read from degraded device: got %v, want quarantine", err)
+	}
+
+	sim.SetGain(DefaultSimulatorGain)
+
+	dv.poolMu.Lock()
+	dv.tap = dv.tap[:0]
+	dv.poolMu.Unlock()
+
+	_, err = dv.ReadWhitened(got)
+	if err != nil {
+		t.Fatalf("repaired device still quarantined: %v", err)
+	}
+
+	raw := make([]byte, 2*WhitenedChunkSize)
+
+	_, err = dv.Read(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first chunk after the quarantine is reseeded from the OS, so it is not what the raw bytes alone give.
+	fixed := make([]byte, WhitenedChunkSize)
+
+	newWhitener(true).whiten(raw, fixed)
+
+	if bytes.Equal(got, fixed) {
+		t.Fatal("whitened output after quarantine is a function of the raw bytes alone")
+	}
+
+	if n := dv.reseeds.Load(); n != 2 {
+		t.Fatalf("got %d reseeds, want one as the quarantine began and one as it ended", n)
+	}
+}
+
+func TestHandles(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	w := dv.Whitened()
+
+	seen := make(map[[8]byte]struct{})
+
+	for range 100 {
+		var b [8]byte
+
+		_, err := w.Read(b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen[b] = struct{}{}
+	}
+
+	if len(seen) != 100 {
+		t.Fatalf("got %d distinct values out of 100", len(seen))
+	}
+
+	_, err := dv.Raw().Read(make([]byte, 8))
+	if !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("raw handle on a whitened device: got %v, want ErrModeConflict", err)
+	}
+}
+
+func TestByteReader(t *testing.T) {
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	var r interface {
+		io.ByteReader
+		io.RuneReader
+	} = dv.Whitened()
+
+	var counts [256]int
+
+	for range 4096 {
+		c, err := r.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts[c]++
+	}
+
+	for c, n := range counts {
+		if n == 0 || n > 64 {
+			t.Fatalf("byte %#02x seen %d times in 4096", c, n)
+		}
+	}
+
+	ch, size, err := r.ReadRune()
+	if err != nil || size != 1 || ch > 0xff {
+		t.Fatalf("ReadRune: %d, %d, %v", ch, size, err)
+	}
+}
+
+func TestMixIn(t *testing.T) {
+	a, _ := openSimulator(t, 1, DefaultSimulatorGain)
+	b, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	read := func(dv *Device) []byte {
+		buf := make([]byte, WhitenedChunkSize)
+
+		_, err := dv.ReadWhitened(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return buf
+	}
+
+	if !bytes.Equal(read(a), read(b)) {
+		t.Fatal("same seed, different output")
+	}
+
+	b.MixIn([]byte("sensor reading"))
+
+	if bytes.Equal(read(a), read(b)) {
+		t.Fatal("mixed-in data did not change the output")
+	}
+
+	// The chaining value carries the mixed-in data forward.
+	if bytes.Equal(read(a), read(b)) {
+		t.Fatal("output converged again after mixing in")
+	}
+}
+
+func TestReseed(t *testing.T) {
+	a, _ := openSimulator(t, 1, DefaultSimulatorGain)
+	b, _ := openSimulator(t, 1, DefaultSimulatorGain)
+
+	read := func(dv *Device) []byte {
+		buf := make([]byte, 64)
+
+		_, err := dv.ReadWhitened(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return buf
+	}
+
+	if !bytes.Equal(read(a), read(b)) {
+		t.Fatal("same seed, different output")
+	}
+
+	b.Reseed()
+
+	// The rest of the buffered chunk is discarded, so the next bytes already depend on the seed.
+	if bytes.Equal(read(a), read(b)) {
+		t.Fatal("output unchanged after Reseed")
+	}
+
+	if s := b.Stats(); s.Reseeds != 1 || s.LastReseed.IsZero() {
+		t.Fatalf("stats after Reseed: %+v", s)
+	}
+
+	if s := a.Stats(); s.Reseeds != 0 {
+		t.Fatalf("stats without Reseed: %+v", s)
+	}
+
+	dv, _ := openSimulator(t, 2, DefaultSimulatorGain, WithReseedInterval(time.Nanosecond), WithChunkSize(64))
+
+	for range 3 {
+		read(dv)
+	}
+
+	if s := dv.Stats(); s.Reseeds != 3 {
+		t.Fatalf("reseeded %d times for 3 chunks with an interval of 1ns", s.Reseeds)
+	}
+}
+
+func TestRatchet(t *testing.T) {
+	raw := make([]byte, 4*WhitenedChunkSize)
+
+	DecodeRaw(simulatorSamples(t, 8*len(raw)), raw)
+
+	whiten := func(ratchet bool) []byte {
+		w := newWhitener(ratchet)
+		out := make([]byte, 2*WhitenedChunkSize)
+
+		w.whiten(raw[:2*WhitenedChunkSize], out[:WhitenedChunkSize])
+		w.whiten(raw[2*WhitenedChunkSize:], out[WhitenedChunkSize:])
+
+		return out
+	}
+
+	on, off := whiten(true), whiten(false)
+
+	// The ratchet only affects what is carried into the next chunk.
+	if !bytes.Equal(on[:WhitenedChunkSize], off[:WhitenedChunkSize]) {
+		t.Fatal("ratchet changed the first chunk")
+	}
+
+	if bytes.Equal(on[WhitenedChunkSize:], off[WhitenedChunkSize:]) {
+		t.Fatal("ratchet did not change the chaining value")
+	}
+
+	dv, _ := openSimulator(t, 1, DefaultSimulatorGain, WithRatchet(false))
+
+	if dv.whitener.ratchet != nil {
+		t.Fatal("WithRatchet(false) ignored")
+	}
+}
+
+func TestContinuousTest(t *testing.T) {
+	w := newWhitener(true)
+
+	a := bytes.Repeat([]byte{1}, 64)
+	b := bytes.Repeat([]byte{2}, 64)
+
+	for _, chunk := range [][]byte{a, b, a} {
+		err := w.check(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := w.check(a)
+	if !errors.Is(err, ErrDuplicateBlock) {
+		t.Fatalf("repeated chunk: got %v, want ErrDuplicateBlock", err)
+	}
+
+	err = w.check(b)
+	if !errors.Is(err, ErrDuplicateBlock) {
+		t.Fatalf("chunk after a failure: got %v, want ErrDuplicateBlock", err)
+	}
+
+	w.reset()
+
+	err = w.check(a)
+	if err != nil {
+		t.Fatalf("after reset: %v", err)
+	}
+}
+
+func BenchmarkWhiten(b *testing.B) {
+	raw := make([]byte, 2*WhitenedChunkSize)
+
+	DecodeRaw(simulatorSamples(b, 8*len(raw)), raw)
+
+	w := newWhitener(true)
+	out := make([]byte, WhitenedChunkSize)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+
+	for b.Loop() {
+		w.whiten(raw, out)
+	}
+}
+
+func TestMaxPoolAge(t *testing.T) {
+	read := func(dv *Device) []byte {
+		buf := make([]byte, 16)
+
+		_, err := dv.ReadWhitened(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return buf
+	}
+
+	ref, _ := openSimulator(t, 1, DefaultSimulatorGain, WithChunkSize(64))
+	discard, _ := openSimulator(t, 1, DefaultSimulatorGain, WithChunkSize(64), WithMaxPoolAge(time.Millisecond, StaleDiscard))
+	flag, _ := openSimulator(t, 1, DefaultSimulatorGain, WithChunkSize(64), WithMaxPoolAge(time.Millisecond, StaleFlag))
+
+	for _, dv := range []*Device{ref, discard, flag} {
+		read(dv)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	want := read(ref)
+
+	if !bytes.Equal(read(flag), want) {
+		t.Fatal("StaleFlag changed the output")
+	}
+
+	if bytes.Equal(read(discard), want) {
+		t.Fatal("StaleDiscard served stale output")
+	}
+
+	if s := discard.Stats(); s.StaleDiscarded != 48 || s.StaleServed != 0 {
+		t.Fatalf("StaleDiscard stats %+v", s)
+	}
+
+	if s := flag.Stats(); s.StaleServed != 16 || s.StaleDiscarded != 0 {
+		t.Fatalf("StaleFlag stats %+v", s)
+	}
+
+	if s := ref.Stats(); s.StaleServed != 0 || s.StaleDiscarded != 0 {
+		t.Fatalf("stats without a maximum age %+v", s)
+	}
+}
@@ -0,0 +1,301 @@
+//go:build windows
+// +build windows
+
+package infnoise
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	winusb = windows.NewLazySystemDLL("winusb.dll")
+
+	pWinUsb_Initialize      = winusb.NewProc("WinUsb_Initialize")
+	pWinUsb_Free            = winusb.NewProc("WinUsb_Free")
+	pWinUsb_ControlTransfer = winusb.NewProc("WinUsb_ControlTransfer")
+	pWinUsb_SetPipePolicy   = winusb.NewProc("WinUsb_SetPipePolicy")
+	pWinUsb_WritePipe       = winusb.NewProc("WinUsb_WritePipe")
+	pWinUsb_ReadPipe        = winusb.NewProc("WinUsb_ReadPipe")
+)
+
+// guidDevInterfaceUSBDevice is GUID_DEVINTERFACE_USB_DEVICE, under which every USB device is listed.
+var guidDevInterfaceUSBDevice = windows.GUID{
+	Data1: 0xa5dcbf10,
+	Data2: 0x6530,
+	Data3: 0x11d2,
+	Data4: [8]byte{0x90, 0x1f, 0x00, 0xc0, 0x4f, 0xb9, 0x51, 0xed},
+}
+
+const (
+	winusbPipeTransferTimeout = 0x03
+
+	// winusbMaxPacket is the bulk packet size of the full-speed FT240X.
+	winusbMaxPacket = 64
+)
+
+func init() {
+	RegisterBackend(BackendWinUSB, func() Backend {
+		return &winusbHandle{}
+	})
+
+	defaultFallback = BackendWinUSB
+}
+
+// winusbHandle drives the FTDI chip through the WinUSB driver, for machines where D2XX cannot be installed.
+// The device must be bound to WinUSB (or libusbK, which exposes the same API), e.g. with Zadig.
+type winusbHandle struct {
+	file windows.Handle
+	usb  uintptr
+
+	// timeout is the SetTimeout value, which also bounds how long Read waits across status-only packets.
+	timeout time.Duration
+
+	scratch []byte
+	pending []byte
+}
+
+// Open connects to the first WinUSB-bound device matching vid:pid.
+func (h *winusbHandle) Open(vid, pid uint16) error {
+	err := winusb.Load()
+	if err != nil {
+		return fmt.Errorf("winusb.dll not available: %w: %w", ErrDriverUnavailable, err)
+	}
+
+	paths, err := windows.CM_Get_Device_Interface_List("", &guidDevInterfaceUSBDevice, windows.CM_GET_DEVICE_INTERFACE_LIST_PRESENT)
+	if err != nil {
+		return fmt.Errorf("listing USB devices: %w", err)
+	}
+
+	want := fmt.Sprintf("vid_%04x&pid_%04x", vid, pid)
+
+	var lastErr error
+
+	for _, path := range paths {
+		if !strings.Contains(strings.ToLower(path), want) {
+			continue
+		}
+
+		lastErr = h.openPath(path)
+		if lastErr == nil {
+			return h.init()
+		}
+	}
+
+	if lastErr != nil {
+		return fmt.Errorf("0x%04x:0x%04x is not bound to WinUSB: %w", vid, pid, lastErr)
+	}
+
+	return fmt.Errorf("0x%04x:0x%04x: %w", vid, pid, ErrDeviceNotFound)
+}
+
+func (h *winusbHandle) openPath(path string) error {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := windows.CreateFile(
+		name,
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_ATTRIBUTE_NORMAL|windows.FILE_FLAG_OVERLAPPED,
+		0,
+	)
+	if err != nil {
+		return err
+	}
+
+	var usb uintptr
+
+	ok, _, err := pWinUsb_Initialize.Call(uintptr(file), uintptr(unsafe.Pointer(&usb)))
+	if ok == 0 {
+		windows.CloseHandle(file)
+
+		return fmt.Errorf("WinUsb_Initialize: %w", err)
+	}
+
+	h.file = file
+	h.usb = usb
+
+	return nil
+}
+
+// init puts the chip into the same state as the libusb backend.
+func (h *winusbHandle) init() error {
+	h.scratch = make([]byte, bulkReadSize)
+
+	err := h.SetTimeout(0)
+	if err == nil {
+		err = h.ctrlOut(sioReset, sioResetSio)
+	}
+
+	if err == nil {
+		err = h.ctrlOut(sioSetBitMode, 0)
+	}
+
+	if err == nil {
+		err = h.ctrlOut(sioSetLatency, 2)
+	}
+
+	if err == nil {
+		// 3 MHz / 30000 sets the bitbang clock the other backends use.
+		err = h.ctrlOut(sioSetBaudRate, 3000000/30000)
+	}
+
+	if err != nil {
+		h.Close()
+
+		return err
+	}
+
+	return nil
+}
+
+// SetTimeout bounds subsequent Write and Read calls to d.
+func (h *winusbHandle) SetTimeout(d time.Duration) error {
+	h.timeout = d
+
+	ms := timeoutMillis(d)
+
+	for _, pipe := range []uintptr{epInAddr, epOutAddr} {
+		ok, _, err := pWinUsb_SetPipePolicy.Call(h.usb, pipe, winusbPipeTransferTimeout, 4, uintptr(unsafe.Pointer(&ms)))
+		if ok == 0 {
+			return fmt.Errorf("WinUsb_SetPipePolicy: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetLatencyTimer sets how long the chip holds back a partly filled bulk-in packet, in milliseconds.
+func (h *winusbHandle) SetLatencyTimer(ms byte) error {
+	return h.ctrlOut(sioSetLatency, uint16(ms))
+}
+
+func (h *winusbHandle) SetBitMode(mask byte, mode byte) error {
+	err := h.ctrlOut(sioSetBitMode, uint16(mask)|uint16(mode)<<8)
+	if err != nil {
+		return err
+	}
+
+	err = h.ctrlOut(sioReset, sioPurgeRx)
+	if err == nil {
+		err = h.ctrlOut(sioReset, sioPurgeTx)
+	}
+
+	h.pending = h.pending[:0]
+
+	return err
+}
+
+func (h *winusbHandle) Write(data []byte) error {
+	for len(data) > 0 {
+		var n uint32
+
+		ok, _, err := pWinUsb_WritePipe.Call(h.usb, epOutAddr, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), uintptr(unsafe.Pointer(&n)), 0)
+		if ok == 0 {
+			return winusbErr("WinUsb_WritePipe", err)
+		}
+
+		if n == 0 {
+			return fmt.Errorf("short write: %d bytes left", len(data))
+		}
+
+		data = data[n:]
+	}
+
+	return nil
+}
+
+// Read fills data from the bulk-in pipe, whose packets each start with two modem status bytes.
+func (h *winusbHandle) Read(data []byte) error {
+	deadline := time.Now().Add(time.Duration(timeoutMillis(h.timeout)) * time.Millisecond)
+
+	for len(data) > 0 {
+		if len(h.pending) == 0 {
+			// The chip sends status-only packets while it has no data, so each pipe read returns promptly.
+			if time.Now().After(deadline) {
+				return fmt.Errorf("WinUsb_ReadPipe: %d bytes missing: %w", len(data), os.ErrDeadlineExceeded)
+			}
+
+			var n uint32
+
+			ok, _, err := pWinUsb_ReadPipe.Call(h.usb, epInAddr, uintptr(unsafe.Pointer(&h.scratch[0])), uintptr(len(h.scratch)), uintptr(unsafe.Pointer(&n)), 0)
+			if ok == 0 {
+				return winusbErr("WinUsb_ReadPipe", err)
+			}
+
+			h.pending = stripModemStatus(h.pending[:0], h.scratch[:n], winusbMaxPacket)
+
+			continue
+		}
+
+		n := copy(data, h.pending)
+
+		data = data[n:]
+		h.pending = h.pending[n:]
+	}
+
+	return nil
+}
+
+func (h *winusbHandle) Close() error {
+	if h.usb != 0 {
+		h.ctrlOut(sioSetBitMode, 0)
+
+		pWinUsb_Free.Call(h.usb)
+
+		h.usb = 0
+	}
+
+	if h.file != 0 {
+		windows.CloseHandle(h.file)
+
+		h.file = 0
+	}
+
+	return nil
+}
+
+func (h *winusbHandle) ctrlOut(req uint8, val uint16) error {
+	// WINUSB_SETUP_PACKET is passed by value: one register on 64-bit targets, two stack words on 32-bit ones.
+	packet := uint64(reqOutVendor) | uint64(req)<<8 | uint64(val)<<16 | uint64(1)<<32
+
+	var (
+		transferred uint32
+		ok          uintptr
+		err         error
+	)
+
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		ok, _, err = pWinUsb_ControlTransfer.Call(h.usb, uintptr(packet), 0, 0, uintptr(unsafe.Pointer(&transferred)), 0)
+	} else {
+		ok, _, err = pWinUsb_ControlTransfer.Call(h.usb, uintptr(uint32(packet)), uintptr(packet>>32), 0, 0, uintptr(unsafe.Pointer(&transferred)), 0)
+	}
+
+	if ok == 0 {
+		return winusbErr("WinUsb_ControlTransfer", err)
+	}
+
+	return nil
+}
+
+func winusbErr(op string, err error) error {
+	switch {
+	case errors.Is(err, windows.ERROR_SEM_TIMEOUT):
+		return fmt.Errorf("%s: %w", op, os.ErrDeadlineExceeded)
+	case errors.Is(err, windows.ERROR_DEVICE_NOT_CONNECTED), errors.Is(err, windows.ERROR_BAD_COMMAND):
+		return fmt.Errorf("%s: %w: %w", op, ErrDisconnected, err)
+	default:
+		return fmt.Errorf("%s: %w: %w", op, ErrIO, err)
+	}
+}